@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"github.com/lukashambsch/anygym.api/store"
+)
+
+// API serves the endpoints. Everything it reads or writes goes through its
+// store, so it can run on the database or in memory. Tokens are signed and
+// checked with keys, from LoadKeys.
+type API struct {
+	store *store.Store
+	keys  *KeySet
+}
+
+func New(s *store.Store, keys *KeySet) *API {
+	return &API{store: s, keys: keys}
+}
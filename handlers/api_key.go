@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+const APIKeyID = "api_key_id"
+
+// apiKeyPrefix marks our keys so they are easy to spot in config files and
+// by secret scanners.
+const apiKeyPrefix = "agk_"
+
+var ErrAPIKeyInactive = errors.New("API key has expired or been revoked")
+
+var apiKeyFields map[string]string = map[string]string{
+	"api_key_id": "int",
+	"key_name":   "string",
+	"key_prefix": "string",
+	"created_on": "date",
+}
+
+// scopes are resource:action, e.g. visits:read or gym_locations:write.
+var scopePattern = regexp.MustCompile(`^[a-z_]+:(read|write)$`)
+
+type APIKeyRequest struct {
+	KeyName   string     `json:"key_name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresOn *time.Time `json:"expires_on"`
+}
+
+// NewAPIKey is only returned when a key is created. The key itself can't be
+// looked up again after that.
+type NewAPIKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// verifyAPIKey resolves an API key to the gym it belongs to. Keys act with
+// the gym role, on behalf of the gym's user if it has one.
+func (api *API) verifyAPIKey(ctx context.Context, key string) (*Claims, error) {
+	apiKey, err := api.store.APIKeys.GetByHash(ctx, hashToken(key))
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey.RevokedOn != nil || (apiKey.ExpiresOn != nil && !time.Now().Before(*apiKey.ExpiresOn)) {
+		return nil, ErrAPIKeyInactive
+	}
+
+	gym, err := api.store.Gyms.Get(ctx, apiKey.GymID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = api.store.APIKeys.Touch(ctx, apiKey.APIKeyID)
+	if err != nil {
+		log.Printf("Recording use of API key %d failed: %s", apiKey.APIKeyID, err)
+	}
+
+	claims := &Claims{Roles: []string{models.GymRole}, APIKey: apiKey}
+	if gym.UserID != nil {
+		claims.UserID = *gym.UserID
+	}
+
+	return claims, nil
+}
+
+// requiredScope names the scope a request needs, from the first path
+// segment after the version and the method, e.g. GET /api/v1/visits needs
+// visits:read.
+func requiredScope(r *http.Request) string {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) > 2 && parts[0] == "api" {
+		parts = parts[2:]
+	}
+
+	action := "write"
+	if r.Method == "GET" || r.Method == "HEAD" || r.Method == "OPTIONS" {
+		action = "read"
+	}
+
+	return fmt.Sprintf("%s:%s", parts[0], action)
+}
+
+// hasScope reports whether apiKey may be used for scope. Keys without any
+// scopes aren't restricted.
+func hasScope(apiKey *models.APIKey, scope string) bool {
+	if len(apiKey.Scopes) == 0 {
+		return true
+	}
+
+	for _, s := range apiKey.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canManageAPIKeys lets admins manage any gym's keys and gym users manage
+// their own gym's. Keys can never be managed with another API key.
+func (api *API) canManageAPIKeys(r *http.Request, gym *models.Gym) (bool, error) {
+	claims, ok := GetClaims(r)
+	if !ok || claims.APIKey != nil {
+		return false, nil
+	}
+
+	roles, err := api.rolesFor(r.Context(), claims)
+	if err != nil {
+		return false, err
+	}
+
+	if hasRole(roles, []string{models.AdminRole}) {
+		return true, nil
+	}
+
+	return gym.UserID != nil && *gym.UserID == claims.UserID, nil
+}
+
+// getManagedGym loads the gym from the path and checks the caller may
+// manage its keys, writing the error response if not.
+func (api *API) getManagedGym(w http.ResponseWriter, r *http.Request) (*models.Gym, bool) {
+	gymID, message := GetID(w, r, GymID)
+	if message != nil {
+		return nil, false
+	}
+
+	gym, err := api.store.Gyms.Get(r.Context(), gymID)
+	if err != nil {
+		WriteError(w, r, err)
+		return nil, false
+	}
+
+	ok, err := api.canManageAPIKeys(r, gym)
+	if err != nil {
+		WriteError(w, r, err)
+		return nil, false
+	}
+	if !ok {
+		WriteJSON(w, http.StatusForbidden, APIErrorMessage{Message: "Forbidden"})
+		return nil, false
+	}
+
+	return gym, true
+}
+
+func (api *API) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	gym, ok := api.getManagedGym(w, r)
+	if !ok {
+		return
+	}
+
+	statement, err := BuildQuery(apiKeyFields, APIKeyID, r.URL.Query())
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+		return
+	}
+	statement.Filters = append(statement.Filters, datastore.Where(GymID, gym.GymID).Filters...)
+
+	apiKeys, err := api.store.APIKeys.List(r.Context(), statement)
+	if err != nil {
+		WriteServerError(w, r, err, "Error getting api key list.")
+		return
+	}
+
+	count, err := api.store.APIKeys.Count(r.Context(), statement)
+	if err != nil {
+		WriteServerError(w, r, err, "Error getting api key count.")
+		return
+	}
+
+	WritePage(w, r, statement, *count, apiKeys)
+}
+
+func (api *API) PostAPIKey(w http.ResponseWriter, r *http.Request) {
+	gym, ok := api.getManagedGym(w, r)
+	if !ok {
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+
+	request := APIKeyRequest{}
+	err := json.Unmarshal(body, &request)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+		return
+	}
+
+	for _, scope := range request.Scopes {
+		if !scopePattern.MatchString(scope) {
+			WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: fmt.Sprintf("Invalid scope %q", scope)})
+			return
+		}
+	}
+
+	if request.ExpiresOn != nil && !request.ExpiresOn.After(time.Now()) {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: "expires_on must be in the future"})
+		return
+	}
+
+	token, err := newRandomToken()
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	key := apiKeyPrefix + token
+
+	created, err := api.store.APIKeys.Create(r.Context(), models.APIKey{
+		GymID:     gym.GymID,
+		KeyName:   request.KeyName,
+		KeyPrefix: key[:len(apiKeyPrefix)+8],
+		KeyHash:   hashToken(key),
+		Scopes:    request.Scopes,
+		ExpiresOn: request.ExpiresOn,
+	})
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	noStore(w)
+	WriteJSON(w, http.StatusCreated, NewAPIKey{APIKey: *created, Key: key})
+}
+
+func (api *API) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	gym, ok := api.getManagedGym(w, r)
+	if !ok {
+		return
+	}
+
+	apiKeyID, message := GetID(w, r, APIKeyID)
+	if message != nil {
+		return
+	}
+
+	apiKey, err := api.store.APIKeys.Get(r.Context(), apiKeyID)
+	if err == sql.ErrNoRows || (err == nil && apiKey.GymID != gym.GymID) {
+		WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: "Not Found", Code: NotFoundCode})
+		return
+	}
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	err = api.store.APIKeys.Revoke(r.Context(), apiKey.APIKeyID)
+	if err != nil && err != sql.ErrNoRows {
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
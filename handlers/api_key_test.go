@@ -0,0 +1,203 @@
+package handlers_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// RequestAPIKey is Request authenticated with an API key instead of a token.
+func RequestAPIKey(method string, url string, key string, payload []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, strings.NewReader(string(payload)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "ApiKey "+key)
+
+	return http.DefaultClient.Do(req)
+}
+
+var _ = Describe("APIKey API", func() {
+	var (
+		server    *httptest.Server
+		apiKeyURL string
+		visitURL  string
+		res       *http.Response
+		data      []byte
+		token     string
+		created   handlers.NewAPIKey
+		errRes    handlers.APIErrorMessage
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		token, _ = RequestToken(server.URL)
+		apiKeyURL = fmt.Sprintf("%s%s/gyms/1/api_keys", server.URL, router.V1URLBase)
+		visitURL = fmt.Sprintf("%s%s/visits", server.URL, router.V1URLBase)
+	})
+
+	AfterEach(func() {
+		testStore.APIKeys.Delete(ctx, created.APIKeyID)
+		server.Close()
+	})
+
+	Describe("PostAPIKey endpoint", func() {
+		Describe("Successful POST", func() {
+			BeforeEach(func() {
+				res, data, _ = Request("POST", apiKeyURL, token, []byte(`{"key_name": "Check ins", "scopes": ["visits:read"]}`))
+				json.Unmarshal(data, &created)
+			})
+
+			It("should return status code 201", func() {
+				Expect(res.StatusCode).To(Equal(http.StatusCreated))
+			})
+
+			It("should show the key once", func() {
+				Expect(created.Key).To(HavePrefix("agk_"))
+				Expect(created.Key).To(HavePrefix(created.KeyPrefix))
+			})
+
+			It("should only store a hash of the key", func() {
+				saved, _ := testStore.APIKeys.Get(ctx, created.APIKeyID)
+				Expect(saved.KeyHash).ToNot(Equal(created.Key))
+				Expect(strings.Contains(string(data), saved.KeyHash)).To(BeFalse())
+			})
+		})
+
+		Describe("Unsuccessful POST", func() {
+			It("should return status code 400 for an invalid scope", func() {
+				res, data, _ = Request("POST", apiKeyURL, token, []byte(`{"scopes": ["everything"]}`))
+				json.Unmarshal(data, &errRes)
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(errRes.Message).ToNot(BeEmpty())
+			})
+
+			It("should return status code 400 for an expiry in the past", func() {
+				res, _, _ = Request("POST", apiKeyURL, token, []byte(`{"expires_on": "2001-01-01T00:00:00Z"}`))
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+
+			It("should return status code 403 for an admin without two-factor authentication", func() {
+				now := time.Now()
+				user, _ := testStore.Users.Create(ctx, models.User{Email: "admin@email.com", Password: "testing", EmailVerifiedOn: &now})
+				testStore.Users.AddRole(ctx, user.UserID, models.AdminRole)
+				testStore.Users.AddRole(ctx, user.UserID, models.GymRole)
+				tokens, _ := RequestUserTokens(server.URL, "admin@email.com", "testing")
+
+				res, _, _ = Request("POST", apiKeyURL, tokens.AccessToken, []byte(`{"key_name": "Check ins"}`))
+				Expect(res.StatusCode).To(Equal(http.StatusForbidden))
+			})
+
+			It("should return status code 404 for a non existent gym", func() {
+				res, _, _ = Request("POST", fmt.Sprintf("%s%s/gyms/99999/api_keys", server.URL, router.V1URLBase), token, []byte(`{}`))
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("GetAPIKeys endpoint", func() {
+		var apiKeys []models.APIKey
+
+		BeforeEach(func() {
+			_, data, _ = Request("POST", apiKeyURL, token, []byte(`{"key_name": "Check ins"}`))
+			json.Unmarshal(data, &created)
+			res, data, _ = Request("GET", apiKeyURL, token, nil)
+			json.Unmarshal(data, &apiKeys)
+		})
+
+		It("should return status code 200", func() {
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("should list the gym's keys without the key itself", func() {
+			Expect(len(apiKeys)).To(Equal(1))
+			Expect(apiKeys[0].KeyName).To(Equal("Check ins"))
+			Expect(strings.Contains(string(data), created.Key)).To(BeFalse())
+		})
+	})
+
+	Describe("Using an API key", func() {
+		BeforeEach(func() {
+			_, data, _ = Request("POST", apiKeyURL, token, []byte(`{"scopes": ["visits:read"]}`))
+			json.Unmarshal(data, &created)
+		})
+
+		It("should allow requests within its scopes", func() {
+			res, _ = RequestAPIKey("GET", visitURL, created.Key, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("should return status code 403 outside its scopes", func() {
+			res, _ = RequestAPIKey("POST", visitURL, created.Key, []byte(`{"member_id": 1, "gym_location_id": 1, "status_id": 1}`))
+			Expect(res.StatusCode).To(Equal(http.StatusForbidden))
+		})
+
+		It("should return status code 401 for an unknown key", func() {
+			res, _ = RequestAPIKey("GET", visitURL, "agk_unknown", nil)
+			Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("should return status code 401 once revoked", func() {
+			Request("DELETE", fmt.Sprintf("%s/%d", apiKeyURL, created.APIKeyID), token, nil)
+			res, _ = RequestAPIKey("GET", visitURL, created.Key, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("should return status code 401 once expired", func() {
+			sum := sha256.Sum256([]byte("agk_expired"))
+			expired := time.Now().Add(-time.Minute)
+			expiredKey, _ := testStore.APIKeys.Create(ctx, models.APIKey{
+				GymID:     1,
+				KeyPrefix: "agk_expired",
+				KeyHash:   hex.EncodeToString(sum[:]),
+				ExpiresOn: &expired,
+			})
+			defer testStore.APIKeys.Delete(ctx, expiredKey.APIKeyID)
+
+			res, _ = RequestAPIKey("GET", visitURL, "agk_expired", nil)
+			Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("should record when the key was last used", func() {
+			RequestAPIKey("GET", visitURL, created.Key, nil)
+			used, _ := testStore.APIKeys.Get(ctx, created.APIKeyID)
+			Expect(used.LastUsedOn).ToNot(BeNil())
+		})
+	})
+
+	Describe("DeleteAPIKey endpoint", func() {
+		BeforeEach(func() {
+			_, data, _ = Request("POST", apiKeyURL, token, []byte(`{}`))
+			json.Unmarshal(data, &created)
+		})
+
+		It("should not let a key manage keys", func() {
+			res, _ = RequestAPIKey("DELETE", fmt.Sprintf("%s/%d", apiKeyURL, created.APIKeyID), created.Key, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusForbidden))
+		})
+
+		It("should return status code 404 for another gym's key", func() {
+			res, _, _ = Request("DELETE", fmt.Sprintf("%s%s/gyms/2/api_keys/%d", server.URL, router.V1URLBase, created.APIKeyID), token, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		It("should revoke the key", func() {
+			res, _, _ = Request("DELETE", fmt.Sprintf("%s/%d", apiKeyURL, created.APIKeyID), token, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusNoContent))
+
+			revoked, _ := testStore.APIKeys.Get(ctx, created.APIKeyID)
+			Expect(revoked.RevokedOn).ToNot(BeNil())
+		})
+	})
+})
@@ -1,10 +1,18 @@
 package handlers
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,11 +23,24 @@ import (
 	"github.com/lukashambsch/anygym.api/store/datastore"
 )
 
+// Claims identifies the user a token was issued to.
 type Claims struct {
-	Username string `json:"username"`
+	UserID int64    `json:"user_id"`
+	Email  string   `json:"email"`
+	Roles  []string `json:"roles"`
 	jwt.StandardClaims
+
+	// APIKey is set instead of a session when the request used an API key.
+	APIKey *models.APIKey `json:"-"`
+	// TwoFactor is copied from the token's session, so it covers tokens
+	// issued before the session passed two-factor authentication.
+	TwoFactor bool `json:"-"`
 }
 
+type contextKey string
+
+const claimsKey contextKey = "claims"
+
 type Route struct {
 	Path   string
 	Method string
@@ -27,42 +48,181 @@ type Route struct {
 
 var noAuthRoutes []Route = []Route{
 	Route{Path: "api/v1/users", Method: "POST"},
+	Route{Path: "api/v1/signup", Method: "POST"},
 	Route{Path: "api/v1/authenticate", Method: "POST"},
+	Route{Path: "api/v1/authenticate/2fa", Method: "POST"},
+	Route{Path: "api/v1/token/refresh", Method: "POST"},
+	Route{Path: "api/v1/password/forgot", Method: "POST"},
+	Route{Path: "api/v1/password/reset", Method: "POST"},
+	Route{Path: "api/v1/email/verify", Method: "POST"},
+	Route{Path: "api/v1/email/verify/resend", Method: "POST"},
+	Route{Path: ".well-known/jwks.json", Method: "GET"},
 	Route{Path: "", Method: "OPTIONS"},
 }
-var mySigningKey = []byte("secret")
 
-func SetToken(email string, password string) (string, error) {
-	userMatches, err := datastore.GetUserList(fmt.Sprintf("WHERE email = '%s'", email))
+var (
+	ErrSessionExpired   = errors.New("Session has expired")
+	ErrEmailNotVerified = errors.New("Email address has not been verified.")
+	// ErrInvalidCredentials doesn't say which of the two was wrong so it
+	// can't be used to find out which emails are registered.
+	ErrInvalidCredentials = errors.New("Invalid email or password.")
+)
+
+const (
+	accessTokenLifetime  = 15 * time.Minute
+	refreshTokenLifetime = 30 * 24 * time.Hour
+)
+
+// Tokens is returned on login and refresh. The refresh token is only ever
+// shown to the client here, the server keeps a hash of it.
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (api *API) Authenticate(ctx context.Context, email string, password string) (*models.User, error) {
+	user, err := api.store.Users.GetByEmail(ctx, email)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidCredentials
+	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if len(userMatches) == 0 {
-		return "", fmt.Errorf("No user matches that email address")
+	err = bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password))
+	if err != nil {
+		return nil, ErrInvalidCredentials
 	}
-	user := userMatches[0]
 
-	err = bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password))
+	return user, nil
+}
+
+// StartSession opens a new session for user and issues its first pair of
+// tokens. twoFactor is whether the user passed two-factor authentication to
+// open it.
+func (api *API) StartSession(ctx context.Context, user *models.User, twoFactor bool) (*Tokens, error) {
+	refreshToken, err := newRandomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := api.store.Sessions.Create(ctx, models.Session{
+		UserID:           user.UserID,
+		RefreshTokenHash: hashToken(refreshToken),
+		ExpiresOn:        time.Now().Add(refreshTokenLifetime),
+		TwoFactor:        twoFactor,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return api.issueTokens(user, session.SessionID, refreshToken)
+}
+
+// RefreshSession exchanges a refresh token for a new access token. The
+// refresh token is rotated, so each one can only be used once. If two
+// requests race to use the same one, the session is revoked and both the
+// loser and the winner's tokens stop working.
+func (api *API) RefreshSession(ctx context.Context, refreshToken string) (*Tokens, error) {
+	oldHash := hashToken(refreshToken)
+	session, err := api.store.Sessions.GetByTokenHash(ctx, oldHash)
 	if err != nil {
-		return "", fmt.Errorf("Invalid Password")
+		return nil, err
 	}
 
-	expireToken := time.Now().Add(time.Hour * 12).Unix()
+	if !isActive(session) {
+		return nil, ErrSessionExpired
+	}
 
-	claims := jwt.StandardClaims{
-		ExpiresAt: expireToken,
-		Issuer:    "test",
+	user, err := api.store.Users.Get(ctx, session.UserID)
+	if err != nil {
+		return nil, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	refreshToken, err = newRandomToken()
+	if err != nil {
+		return nil, err
+	}
 
-	signedToken, _ := token.SignedString(mySigningKey)
+	session.RefreshTokenHash = hashToken(refreshToken)
+	session.ExpiresOn = time.Now().Add(refreshTokenLifetime)
+	_, err = api.store.Sessions.Rotate(ctx, session.SessionID, oldHash, *session)
+	if err == sql.ErrNoRows {
+		err = api.store.Sessions.Revoke(ctx, session.SessionID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, sql.ErrNoRows
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	return signedToken, nil
+	return api.issueTokens(user, session.SessionID, refreshToken)
 }
 
-func VerifyToken(h http.Handler) http.Handler {
+func (api *API) issueTokens(user *models.User, sessionID int64, refreshToken string) (*Tokens, error) {
+	expireToken := time.Now().Add(accessTokenLifetime).Unix()
+
+	claims := Claims{
+		UserID: user.UserID,
+		Email:  user.Email,
+		StandardClaims: jwt.StandardClaims{
+			Id:        strconv.FormatInt(sessionID, 10),
+			ExpiresAt: expireToken,
+			Issuer:    tokenIssuer(),
+			Subject:   strconv.FormatInt(user.UserID, 10),
+		},
+	}
+	for _, role := range user.Roles {
+		claims.Roles = append(claims.Roles, role.RoleName)
+	}
+
+	signedToken, err := api.keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		AccessToken:  signedToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(accessTokenLifetime / time.Second),
+	}, nil
+}
+
+// newRandomToken returns an unguessable url safe token, used for refresh and
+// password reset tokens.
+func newRandomToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is used for refresh and reset tokens, which are random enough that a plain
+// sha256 is sufficient and lets us look sessions up by it.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func isActive(session *models.Session) bool {
+	return session.RevokedOn == nil && time.Now().Before(session.ExpiresOn)
+}
+
+// VerifyToken authenticates the request with either a Bearer access token or
+// a gym's API key (Authorization: ApiKey <key>).
+func (api *API) VerifyToken(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if isNoAuth(r) {
 			h.ServeHTTP(w, r)
@@ -74,33 +234,219 @@ func VerifyToken(h http.Handler) http.Handler {
 			return
 		}
 
-		splitHeader := strings.Split(authHeader, "Bearer ")
-		if len(splitHeader) != 2 {
+		var (
+			claims *Claims
+			err    error
+		)
+		if strings.HasPrefix(authHeader, "ApiKey ") {
+			claims, err = api.verifyAPIKey(r.Context(), strings.TrimPrefix(authHeader, "ApiKey "))
+		} else if strings.HasPrefix(authHeader, "Bearer ") {
+			claims, err = api.verifyAccessToken(r.Context(), strings.TrimPrefix(authHeader, "Bearer "))
+		} else {
+			err = errors.New("Unsupported authorization scheme")
+		}
+
+		// a session lookup that didn't finish in time says nothing about
+		// the token
+		if err != nil && (r.Context().Err() != nil || isQueryTimeout(err)) {
+			WriteError(w, r, err)
+			return
+		}
+		if err != nil {
 			WriteJSON(w, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
 
-		tokenString := splitHeader[1]
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte("secret"), nil
-		})
+		if claims.APIKey != nil && !hasScope(claims.APIKey, requiredScope(r)) {
+			WriteJSON(w, http.StatusForbidden, APIErrorMessage{Message: fmt.Sprintf("API key is missing the %s scope", requiredScope(r))})
+			return
+		}
 
-		if err != nil {
-			WriteJSON(w, http.StatusUnauthorized, "Unauthorized")
+		ctx := context.WithValue(r.Context(), claimsKey, claims)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// verifyAccessToken checks the token's signature and that the session it
+// was issued for is still active.
+func (api *API) verifyAccessToken(ctx context.Context, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := api.keys.Parse(tokenString, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, errors.New("Invalid token")
+	}
+
+	sessionID, err := strconv.ParseInt(claims.Id, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := api.store.Sessions.Get(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isActive(session) {
+		return nil, ErrSessionExpired
+	}
+
+	claims.TwoFactor = session.TwoFactor
+	return claims, nil
+}
+
+// GetClaims returns the identity VerifyToken attached to the request.
+func GetClaims(r *http.Request) (*Claims, bool) {
+	claims, ok := r.Context().Value(claimsKey).(*Claims)
+	return claims, ok
+}
+
+// Authorize only lets the request through to h if the caller holds at least
+// one of roles. Calling it without roles allows any authenticated user.
+func (api *API) Authorize(h http.HandlerFunc, roles ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(roles) == 0 {
+			h(w, r)
 			return
 		}
 
-		if !token.Valid {
+		claims, ok := GetClaims(r)
+		if !ok {
 			WriteJSON(w, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
-		h.ServeHTTP(w, r)
-	})
+
+		userRoles, err := api.rolesFor(r.Context(), claims)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if !hasRole(userRoles, roles) {
+			WriteJSON(w, http.StatusForbidden, APIErrorMessage{Message: "Forbidden"})
+			return
+		}
+		h(w, r)
+	}
+}
+
+// rolesFor returns the roles the caller holds. API keys only ever get the
+// gym role, whatever roles the gym's user has, and admins don't get the admin
+// role in sessions that didn't pass two-factor authentication.
+func (api *API) rolesFor(ctx context.Context, claims *Claims) ([]*models.Role, error) {
+	if claims.APIKey != nil {
+		return []*models.Role{&models.Role{RoleName: models.GymRole}}, nil
+	}
+
+	roles, err := api.store.Users.Roles(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// admins have to turn on two-factor authentication, and pass it in the
+	// session they're using, before they can use the admin role
+	if hasRole(roles, []string{models.AdminRole}) {
+		if !claims.TwoFactor {
+			return withoutRole(roles, models.AdminRole), nil
+		}
+
+		enabled, err := api.twoFactorEnabled(ctx, claims.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !enabled {
+			return withoutRole(roles, models.AdminRole), nil
+		}
+	}
+
+	return roles, nil
+}
+
+func withoutRole(roles []*models.Role, name string) []*models.Role {
+	var kept []*models.Role
+	for _, role := range roles {
+		if role.RoleName != name {
+			kept = append(kept, role)
+		}
+	}
+
+	return kept
+}
+
+// scopeFor limits datastore queries to what the caller may see.
+func (api *API) scopeFor(r *http.Request) (datastore.Scope, error) {
+	claims, ok := GetClaims(r)
+	if !ok {
+		return datastore.Scope{}, errors.New("Unauthorized")
+	}
+
+	roles, err := api.rolesFor(r.Context(), claims)
+	if err != nil {
+		return datastore.Scope{}, err
+	}
+
+	scope := datastore.Scope{UserID: claims.UserID}
+	if claims.APIKey != nil {
+		scope.GymID = claims.APIKey.GymID
+	}
+	for _, role := range roles {
+		scope.Roles = append(scope.Roles, role.RoleName)
+	}
+	if r.URL.Query().Get("include_deleted") == "true" {
+		scope.Deleted = hasRole(roles, []string{models.AdminRole})
+	}
+
+	return scope, nil
+}
+
+// writeScopeFor limits datastore lookups to the rows the caller may change.
+// Handlers load the row they're about to write through it, so anything
+// outside it is a 404.
+func (api *API) writeScopeFor(r *http.Request) (datastore.Scope, error) {
+	scope, err := api.scopeFor(r)
+	scope.Write = true
+	scope.Deleted = false
+
+	return scope, err
 }
 
+// seesAll reports whether scope covers every row, so what the caller writes
+// doesn't have to stay within it.
+func seesAll(scope datastore.Scope) bool {
+	if scope.All {
+		return true
+	}
+
+	for _, role := range scope.Roles {
+		if role == models.AdminRole || role == models.EmployeeRole {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasRole(userRoles []*models.Role, allowed []string) bool {
+	for _, role := range userRoles {
+		for _, name := range allowed {
+			if role.RoleName == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isNoAuth matches the whole path, so e.g. POST /users being public doesn't
+// make POST /users/{user_id}/unlock public as well. An empty path matches any.
 func isNoAuth(r *http.Request) bool {
+	path := strings.Trim(r.URL.Path, "/")
 	for _, route := range noAuthRoutes {
-		if strings.Contains(r.URL.Path, route.Path) && r.Method == route.Method {
+		if (route.Path == "" || path == route.Path) && r.Method == route.Method {
 			return true
 		}
 	}
@@ -108,24 +454,124 @@ func isNoAuth(r *http.Request) bool {
 	return false
 }
 
-func Logout(w http.ResponseWriter, r *http.Request) {
-	return
+func (api *API) Logout(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaims(r)
+	if !ok {
+		WriteJSON(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID, err := strconv.ParseInt(claims.Id, 10, 64)
+	if err != nil {
+		WriteJSON(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	err = api.store.Sessions.Revoke(r.Context(), sessionID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, nil)
 }
 
-func Login(w http.ResponseWriter, r *http.Request) {
+func (api *API) Login(w http.ResponseWriter, r *http.Request) {
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body.Close()
 
-	user := models.User{}
-	err := json.Unmarshal(body, &user)
+	credentials := models.User{}
+	err := json.Unmarshal(body, &credentials)
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+		return
 	}
 
-	signedToken, err := SetToken(user.Email, user.Password)
+	emailKey, ipKey := emailThrottleKey(credentials.Email), ipThrottleKey(r)
+	locked, err := api.lockedFor(r.Context(), emailKey, ipKey)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
+		return
+	}
+	if locked > 0 {
+		writeTooManyAttempts(w, locked)
+		return
+	}
+
+	user, err := api.Authenticate(r.Context(), credentials.Email, credentials.Password)
+	if err == ErrInvalidCredentials {
+		api.writeLoginFailure(w, r, emailKey, ipKey, ErrInvalidCredentials.Error())
+		return
+	}
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	if !isVerified(user) {
+		WriteJSON(w, http.StatusForbidden, APIErrorMessage{Message: ErrEmailNotVerified.Error()})
+		return
+	}
+
+	// failed attempts aren't cleared until the second factor is checked too
+	enabled, err := api.twoFactorEnabled(r.Context(), user.UserID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if enabled {
+		challenge, err := api.newTwoFactorChallenge(user)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		noStore(w)
+		WriteJSON(w, http.StatusOK, challenge)
+		return
+	}
+
+	// the ip count is left alone, otherwise logging into one account would
+	// reset an attack on others from the same address
+	err = api.store.LoginThrottles.Delete(r.Context(), emailKey)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	tokens, err := api.StartSession(r.Context(), user, false)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	noStore(w)
+	WriteJSON(w, http.StatusOK, tokens)
+}
+
+func (api *API) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+
+	refresh := RefreshRequest{}
+	err := json.Unmarshal(body, &refresh)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+		return
+	}
+
+	tokens, err := api.RefreshSession(r.Context(), refresh.RefreshToken)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			WriteJSON(w, http.StatusUnauthorized, APIErrorMessage{Message: "Invalid refresh token"})
+		} else if err == ErrSessionExpired {
+			WriteJSON(w, http.StatusUnauthorized, APIErrorMessage{Message: err.Error()})
+		} else {
+			WriteError(w, r, err)
+		}
+		return
 	}
 
-	w.Write([]byte(signedToken))
+	noStore(w)
+	WriteJSON(w, http.StatusOK, tokens)
 }
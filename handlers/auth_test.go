@@ -0,0 +1,233 @@
+package handlers_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/lukashambsch/anygym.api/config"
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	"github.com/lukashambsch/anygym.api/store"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// RequestUserTokens logs in, answering the two-factor challenge for users
+// enrolled with a secret from twoFactorSecrets.
+func RequestUserTokens(serverURL string, email string, password string) (handlers.Tokens, error) {
+	var (
+		tokens    handlers.Tokens
+		challenge handlers.TwoFactorChallenge
+	)
+
+	_, data, err := Request(
+		"POST",
+		fmt.Sprintf("%s%s/authenticate", serverURL, router.V1URLBase),
+		"",
+		[]byte(fmt.Sprintf(`{"email": "%s", "password": "%s"}`, email, password)),
+	)
+	json.Unmarshal(data, &challenge)
+	if !challenge.TwoFactorRequired {
+		json.Unmarshal(data, &tokens)
+		return tokens, err
+	}
+
+	data, err = AnswerTwoFactorChallenge(serverURL, email, challenge.ChallengeToken)
+	json.Unmarshal(data, &tokens)
+	return tokens, err
+}
+
+var _ = Describe("Authorization", func() {
+	var (
+		server *httptest.Server
+		res    *http.Response
+		data   []byte
+		user   *models.User
+		token  string
+		errRes handlers.APIErrorMessage
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		now := time.Now()
+		user, _ = testStore.Users.Create(ctx, models.User{Email: "employee@email.com", Password: "testing", EmailVerifiedOn: &now})
+		testStore.Users.AddRole(ctx, user.UserID, models.EmployeeRole)
+		tokens, _ := RequestUserTokens(server.URL, "employee@email.com", "testing")
+		token = tokens.AccessToken
+	})
+
+	AfterEach(func() {
+		testStore.Users.Delete(ctx, user.UserID, 0)
+		server.Close()
+	})
+
+	Describe("Allowed role", func() {
+		It("should return status code 200", func() {
+			res, _, _ = Request("GET", fmt.Sprintf("%s%s/users", server.URL, router.V1URLBase), token, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("Disallowed role", func() {
+		It("should return status code 403 with a message", func() {
+			res, data, _ = Request("DELETE", fmt.Sprintf("%s%s/statuses/1", server.URL, router.V1URLBase), token, nil)
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusForbidden))
+			Expect(errRes.Message).To(Equal("Forbidden"))
+		})
+
+		It("should not run the handler", func() {
+			Request("DELETE", fmt.Sprintf("%s%s/statuses/1", server.URL, router.V1URLBase), token, nil)
+			status, err := testStore.Statuses.Get(ctx, 1)
+			Expect(err).To(BeNil())
+			Expect(status.StatusID).To(Equal(int64(1)))
+		})
+	})
+
+	Describe("No token", func() {
+		It("should return status code 401", func() {
+			res, _, _ = Request("GET", fmt.Sprintf("%s%s/users", server.URL, router.V1URLBase), "", nil)
+			Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+	})
+})
+
+// racedSessions rotates each session it looks up by token hash before
+// returning it, as if another request with the same refresh token got there
+// first.
+type racedSessions struct {
+	store.SessionRepository
+}
+
+func (r racedSessions) GetByTokenHash(ctx context.Context, refreshTokenHash string) (*models.Session, error) {
+	session, err := r.SessionRepository.GetByTokenHash(ctx, refreshTokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated := *session
+	rotated.RefreshTokenHash = "raced"
+	r.SessionRepository.Rotate(ctx, session.SessionID, refreshTokenHash, rotated)
+	return session, nil
+}
+
+var _ = Describe("Sessions", func() {
+	var (
+		server     *httptest.Server
+		refreshURL string
+		logoutURL  string
+		res        *http.Response
+		data       []byte
+		tokens     handlers.Tokens
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		refreshURL = fmt.Sprintf("%s%s/token/refresh", server.URL, router.V1URLBase)
+		logoutURL = fmt.Sprintf("%s%s/logout", server.URL, router.V1URLBase)
+		tokens, _ = RequestUserTokens(server.URL, "lukas.hambsch@gmail.com", "testpass")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Login", func() {
+		It("should return an access and a refresh token", func() {
+			Expect(tokens.AccessToken).ToNot(BeEmpty())
+			Expect(tokens.RefreshToken).ToNot(BeEmpty())
+			Expect(tokens.ExpiresIn).To(Equal(int64(15 * 60)))
+		})
+
+		It("should sign the access token with the configured issuer", func() {
+			var claims jwt.StandardClaims
+			testKeys.Parse(tokens.AccessToken, &claims)
+			Expect(claims.Issuer).To(Equal(config.C.GetString("auth.issuer")))
+			Expect(claims.Issuer).NotTo(BeEmpty())
+		})
+	})
+
+	Describe("RefreshToken endpoint", func() {
+		var (
+			refreshed handlers.Tokens
+			payload   []byte
+		)
+
+		BeforeEach(func() {
+			payload = []byte(fmt.Sprintf(`{"refresh_token": "%s"}`, tokens.RefreshToken))
+		})
+
+		Describe("Successful POST", func() {
+			BeforeEach(func() {
+				res, data, _ = Request("POST", refreshURL, "", payload)
+				json.Unmarshal(data, &refreshed)
+			})
+
+			It("should return status code 200", func() {
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+			})
+
+			It("should rotate the refresh token", func() {
+				Expect(refreshed.RefreshToken).ToNot(BeEmpty())
+				Expect(refreshed.RefreshToken).ToNot(Equal(tokens.RefreshToken))
+			})
+
+			It("should return a usable access token", func() {
+				res, _, _ = Request("GET", fmt.Sprintf("%s%s/me", server.URL, router.V1URLBase), refreshed.AccessToken, nil)
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+			})
+
+			It("should not accept the old refresh token again", func() {
+				res, _, _ = Request("POST", refreshURL, "", payload)
+				Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Describe("Unsuccessful POST", func() {
+			It("should return status code 401 for an unknown refresh token", func() {
+				res, _, _ = Request("POST", refreshURL, "", []byte(`{"refresh_token": "invalid"}`))
+				Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+
+			It("should revoke the session if the refresh token was used concurrently", func() {
+				raced := *testStore
+				raced.Sessions = racedSessions{testStore.Sessions}
+
+				_, err := handlers.New(&raced, testKeys).RefreshSession(ctx, tokens.RefreshToken)
+				Expect(err).To(Equal(sql.ErrNoRows))
+
+				res, _, _ = Request("GET", fmt.Sprintf("%s%s/me", server.URL, router.V1URLBase), tokens.AccessToken, nil)
+				Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+
+			It("should return status code 401 after logout", func() {
+				Request("POST", logoutURL, tokens.AccessToken, nil)
+				res, _, _ = Request("POST", refreshURL, "", payload)
+				Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+		})
+	})
+
+	Describe("Logout endpoint", func() {
+		BeforeEach(func() {
+			res, _, _ = Request("POST", logoutURL, tokens.AccessToken, nil)
+		})
+
+		It("should return status code 200", func() {
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("should reject the access token afterwards", func() {
+			res, _, _ = Request("GET", fmt.Sprintf("%s%s/me", server.URL, router.V1URLBase), tokens.AccessToken, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+	})
+})
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/lukashambsch/anygym.api/config"
+	"github.com/lukashambsch/anygym.api/mailer"
+	"github.com/lukashambsch/anygym.api/store"
+)
+
+const (
+	InvalidBatch     = "A batch takes 1 to %d requests."
+	NestedBatch      = "A batch can't contain another batch."
+	InvalidBatchItem = "A request in a batch needs a method and a path starting with /."
+	BatchRolledBack  = "Rolled back, request %d in the batch failed."
+)
+
+const (
+	batchKey  contextKey = "batch"
+	outboxKey contextKey = "outbox"
+)
+
+// maxBatchSize caps how many requests a batch can hold.
+var maxBatchSize = loadMaxBatchSize()
+
+func loadMaxBatchSize() int {
+	if config.C.IsSet("batch.max_requests") {
+		return config.C.GetInt("batch.max_requests")
+	}
+
+	return 100
+}
+
+// errBatchFailed rolls back a batch run in a transaction.
+var errBatchFailed = errors.New("batch failed")
+
+// Batch is the body of POST /batch. With Transaction set its requests are
+// all saved or none are.
+type Batch struct {
+	Transaction bool           `json:"transaction"`
+	Requests    []BatchRequest `json:"requests"`
+}
+
+type BatchRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResponse is what one request in a batch got back. Body is null for
+// responses without one.
+type BatchResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers"`
+	Body    interface{} `json:"body"`
+}
+
+// Batch runs the requests in a batch one after the other on the routes
+// built by routes, and responds with what each got back. Each request is
+// authenticated again, with the caller's Authorization header unless it sets
+// its own, so it gets the same checks it would on its own.
+//
+// In a transaction the batch stops at the first request that fails and
+// rolls back. That request keeps its response, every other one gets a 424.
+// The email its requests send goes out once it's committed.
+func (api *API) Batch(routes func(s *store.Store) http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Context().Value(batchKey) != nil {
+			WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: NestedBatch})
+			return
+		}
+
+		var batch Batch
+		err := json.NewDecoder(r.Body).Decode(&batch)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+			return
+		}
+		if len(batch.Requests) == 0 || len(batch.Requests) > maxBatchSize {
+			WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: fmt.Sprintf(InvalidBatch, maxBatchSize)})
+			return
+		}
+
+		if !batch.Transaction {
+			writeBatch(w, runBatch(r, api.VerifyToken(routes(api.store)), batch.Requests, false))
+			return
+		}
+
+		var responses []BatchResponse
+		out := &outbox{}
+		r = r.WithContext(context.WithValue(r.Context(), outboxKey, out))
+		err = api.store.Transaction(r.Context(), func(tx *store.Store) error {
+			responses = runBatch(r, New(tx, api.keys).VerifyToken(routes(tx)), batch.Requests, true)
+			// runBatch stops at the first failure, so it's the last response
+			if responses[len(responses)-1].Status >= http.StatusBadRequest {
+				return errBatchFailed
+			}
+			return nil
+		})
+		if err != nil && err != errBatchFailed {
+			WriteError(w, r, err)
+			return
+		}
+
+		if err == errBatchFailed {
+			responses = rolledBack(responses, len(batch.Requests))
+		} else {
+			out.send()
+		}
+		writeBatch(w, responses)
+	}
+}
+
+// writeBatch responds with the batch's responses. If any of them hands out
+// credentials the whole batch is no-store.
+func writeBatch(w http.ResponseWriter, responses []BatchResponse) {
+	for _, res := range responses {
+		if isNoStore(res.Headers) {
+			noStore(w)
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, responses)
+}
+
+// runBatch serves requests on h. With stopOnError it stops after the first
+// request that fails, so its response is the last one.
+func runBatch(r *http.Request, h http.Handler, requests []BatchRequest, stopOnError bool) []BatchResponse {
+	// the caller's claims are dropped, h sets each request's own
+	ctx := context.WithValue(r.Context(), batchKey, true)
+	ctx = context.WithValue(ctx, claimsKey, nil)
+
+	responses := make([]BatchResponse, 0, len(requests))
+	for _, item := range requests {
+		res := serveBatchRequest(ctx, r, h, item)
+		responses = append(responses, res)
+
+		if stopOnError && res.Status >= http.StatusBadRequest {
+			break
+		}
+	}
+
+	return responses
+}
+
+func serveBatchRequest(ctx context.Context, r *http.Request, h http.Handler, item BatchRequest) BatchResponse {
+	rec := &batchRecorder{header: http.Header{}}
+
+	req, err := http.NewRequest(strings.ToUpper(item.Method), item.Path, bytes.NewReader(item.Body))
+	if err != nil || item.Method == "" || !strings.HasPrefix(item.Path, "/") || req.URL.Host != "" {
+		WriteJSON(rec, http.StatusBadRequest, APIErrorMessage{Message: InvalidBatchItem})
+		return rec.response()
+	}
+
+	req = req.WithContext(ctx)
+	req.RemoteAddr = r.RemoteAddr
+	req.Header.Set("Authorization", r.Header.Get("Authorization"))
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range item.Headers {
+		req.Header.Set(name, value)
+	}
+
+	h.ServeHTTP(rec, req)
+
+	return rec.response()
+}
+
+// outbox holds the email the requests in a transaction send until it's
+// committed, so nobody is emailed about rows that are rolled back.
+type outbox struct {
+	messages []mailer.Message
+}
+
+func (out *outbox) send() {
+	for _, msg := range out.messages {
+		err := mailer.Default.Send(msg)
+		if err != nil {
+			log.Printf("Email to %q failed: %s", msg.To, err)
+		}
+	}
+}
+
+// sendMail sends msg, or puts it in the outbox of the transaction the
+// request is running in.
+func sendMail(ctx context.Context, msg mailer.Message) error {
+	if out, ok := ctx.Value(outboxKey).(*outbox); ok {
+		out.messages = append(out.messages, msg)
+		return nil
+	}
+
+	return mailer.Default.Send(msg)
+}
+
+// rolledBack replaces the responses of the requests that were saved before
+// the last one failed, and adds ones for the requests that didn't run.
+func rolledBack(responses []BatchResponse, count int) []BatchResponse {
+	failed := len(responses) - 1
+
+	result := make([]BatchResponse, count)
+	for i := range result {
+		if i == failed {
+			result[i] = responses[i]
+			continue
+		}
+
+		result[i] = BatchResponse{
+			Status:  http.StatusFailedDependency,
+			Headers: http.Header{},
+			Body:    APIErrorMessage{Message: fmt.Sprintf(BatchRolledBack, failed+1), Code: RolledBackCode},
+		}
+	}
+
+	return result
+}
+
+// batchRecorder keeps what a handler writes for a request in a batch.
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *batchRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *batchRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *batchRecorder) Write(data []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	return rec.body.Write(data)
+}
+
+// response is what was written, a body that isn't json is kept as a string.
+func (rec *batchRecorder) response() BatchResponse {
+	res := BatchResponse{Status: rec.status, Headers: rec.header}
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+
+	body := bytes.TrimSpace(rec.body.Bytes())
+	if len(body) == 0 {
+		return res
+	}
+
+	var decoded interface{}
+	if json.Unmarshal(body, &decoded) == nil {
+		res.Body = json.RawMessage(body)
+	} else {
+		res.Body = string(body)
+	}
+
+	return res
+}
@@ -0,0 +1,226 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/mailer"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Batch API", func() {
+	var (
+		server    *httptest.Server
+		batchURL  string
+		res       *http.Response
+		data      []byte
+		token     string
+		responses []handlers.BatchResponse
+		errRes    handlers.APIErrorMessage
+	)
+
+	statusCount := func() int {
+		count, _ := testStore.Statuses.Count(ctx, datastore.Query{})
+		return *count
+	}
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		token, _ = RequestToken(server.URL)
+		batchURL = fmt.Sprintf("%s%s/batch", server.URL, router.V1URLBase)
+		responses = nil
+		errRes = handlers.APIErrorMessage{}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Without a transaction", func() {
+		var before int
+
+		BeforeEach(func() {
+			before = statusCount()
+			res, data, _ = Request("POST", batchURL, token, []byte(`{"requests": [
+				{"method": "POST", "path": "/api/v1/statuses", "body": {"status_name": "Batched"}},
+				{"method": "GET", "path": "/api/v1/statuses/999"},
+				{"method": "GET", "path": "/api/v1/statuses/1"}
+			]}`))
+			json.Unmarshal(data, &responses)
+		})
+
+		It("should return status code 200 with a response per request", func() {
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(len(responses)).To(Equal(3))
+			Expect(responses[0].Status).To(Equal(http.StatusCreated))
+			Expect(responses[1].Status).To(Equal(http.StatusNotFound))
+			Expect(responses[2].Status).To(Equal(http.StatusOK))
+		})
+
+		It("should return each body", func() {
+			body, _ := json.Marshal(responses[2].Body)
+			var status models.Status
+			json.Unmarshal(body, &status)
+			Expect(status.StatusID).To(Equal(int64(1)))
+		})
+
+		It("should return each response's headers", func() {
+			Expect(responses[2].Headers.Get("ETag")).NotTo(BeEmpty())
+		})
+
+		It("should keep what succeeded", func() {
+			Expect(statusCount()).To(Equal(before + 1))
+		})
+	})
+
+	Describe("In a transaction", func() {
+		var before int
+
+		BeforeEach(func() {
+			before = statusCount()
+		})
+
+		It("should save every request", func() {
+			res, data, _ = Request("POST", batchURL, token, []byte(`{"transaction": true, "requests": [
+				{"method": "POST", "path": "/api/v1/statuses", "body": {"status_name": "First"}},
+				{"method": "POST", "path": "/api/v1/statuses", "body": {"status_name": "Second"}}
+			]}`))
+			json.Unmarshal(data, &responses)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(responses[0].Status).To(Equal(http.StatusCreated))
+			Expect(responses[1].Status).To(Equal(http.StatusCreated))
+			Expect(statusCount()).To(Equal(before + 2))
+		})
+
+		Describe("with a request that fails", func() {
+			BeforeEach(func() {
+				res, data, _ = Request("POST", batchURL, token, []byte(`{"transaction": true, "requests": [
+					{"method": "POST", "path": "/api/v1/statuses", "body": {"status_name": "Rolled back"}},
+					{"method": "GET", "path": "/api/v1/statuses/999"},
+					{"method": "POST", "path": "/api/v1/statuses", "body": {"status_name": "Never run"}}
+				]}`))
+				json.Unmarshal(data, &responses)
+			})
+
+			It("should save none of them", func() {
+				Expect(statusCount()).To(Equal(before))
+			})
+
+			It("should return the failed request's response", func() {
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+				Expect(responses[1].Status).To(Equal(http.StatusNotFound))
+			})
+
+			It("should return status code 424 for the others", func() {
+				body, _ := json.Marshal(responses[2].Body)
+				json.Unmarshal(body, &errRes)
+				Expect(responses[0].Status).To(Equal(http.StatusFailedDependency))
+				Expect(responses[2].Status).To(Equal(http.StatusFailedDependency))
+				Expect(errRes.Code).To(Equal(handlers.RolledBackCode))
+			})
+		})
+
+		Describe("with a request that sends email", func() {
+			BeforeEach(func() {
+				mailer.Default.(*mailer.MemoryMailer).Reset()
+			})
+
+			It("should send it once the batch is saved", func() {
+				Request("POST", batchURL, token, []byte(`{"transaction": true, "requests": [
+					{"method": "POST", "path": "/api/v1/users", "body": {"email": "batched@email.com", "password": "testing"}}
+				]}`))
+				_, ok := mailer.Default.(*mailer.MemoryMailer).Last("batched@email.com")
+				Expect(ok).To(BeTrue())
+			})
+
+			It("should not send it if the batch is rolled back", func() {
+				Request("POST", batchURL, token, []byte(`{"transaction": true, "requests": [
+					{"method": "POST", "path": "/api/v1/users", "body": {"email": "batched@email.com", "password": "testing"}},
+					{"method": "GET", "path": "/api/v1/statuses/999"}
+				]}`))
+				Expect(mailer.Default.(*mailer.MemoryMailer).Messages()).To(BeEmpty())
+			})
+		})
+	})
+
+	It("should return status code 403 for admins without two-factor authentication", func() {
+		now := time.Now()
+		user, _ := testStore.Users.Create(ctx, models.User{Email: "batch@email.com", Password: "testing", EmailVerifiedOn: &now})
+		testStore.Users.AddRole(ctx, user.UserID, models.AdminRole)
+		tokens, _ := RequestUserTokens(server.URL, "batch@email.com", "testing")
+
+		res, _, _ = Request("POST", batchURL, tokens.AccessToken, []byte(`{"requests": [
+			{"method": "DELETE", "path": "/api/v1/statuses/1"}
+		]}`))
+		Expect(res.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	Describe("Authentication", func() {
+		var memberToken string
+
+		BeforeEach(func() {
+			now := time.Now()
+			user, _ := testStore.Users.Create(ctx, models.User{Email: "batchmember@email.com", Password: "testing", EmailVerifiedOn: &now})
+			testStore.Users.AddRole(ctx, user.UserID, models.MemberRole)
+			tokens, _ := RequestUserTokens(server.URL, "batchmember@email.com", "testing")
+			memberToken = tokens.AccessToken
+		})
+
+		It("should check each request as whoever it's sent as", func() {
+			res, data, _ = Request("POST", batchURL, token, []byte(fmt.Sprintf(`{"requests": [
+				{"method": "DELETE", "path": "/api/v1/statuses/1", "headers": {"Authorization": "Bearer %s"}}
+			]}`, memberToken)))
+			json.Unmarshal(data, &responses)
+			Expect(responses[0].Status).To(Equal(http.StatusForbidden))
+
+			_, err := testStore.Statuses.Get(ctx, 1)
+			Expect(err).To(BeNil())
+		})
+
+		It("should return status code 401 for a request with an invalid token", func() {
+			res, data, _ = Request("POST", batchURL, token, []byte(`{"requests": [
+				{"method": "GET", "path": "/api/v1/me", "headers": {"Authorization": "Bearer invalid"}}
+			]}`))
+			json.Unmarshal(data, &responses)
+			Expect(responses[0].Status).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	It("should return status code 400 for a request without a path", func() {
+		res, data, _ = Request("POST", batchURL, token, []byte(`{"requests": [{"method": "GET"}]}`))
+		json.Unmarshal(data, &responses)
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(responses[0].Status).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should return status code 400 for a batch in a batch", func() {
+		res, data, _ = Request("POST", batchURL, token, []byte(`{"requests": [
+			{"method": "POST", "path": "/api/v1/batch", "body": {"requests": [{"method": "GET", "path": "/api/v1/statuses"}]}}
+		]}`))
+		json.Unmarshal(data, &responses)
+		body, _ := json.Marshal(responses[0].Body)
+		json.Unmarshal(body, &errRes)
+		Expect(responses[0].Status).To(Equal(http.StatusBadRequest))
+		Expect(errRes.Message).To(Equal(handlers.NestedBatch))
+	})
+
+	It("should return status code 400 for an empty batch", func() {
+		res, _, _ = Request("POST", batchURL, token, []byte(`{"requests": []}`))
+		Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should return status code 400 for too many requests", func() {
+		requests := strings.TrimSuffix(strings.Repeat(`{"method": "GET", "path": "/api/v1/statuses"},`, 101), ",")
+		res, _, _ = Request("POST", batchURL, token, []byte(fmt.Sprintf(`{"requests": [%s]}`, requests)))
+		Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
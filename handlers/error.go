@@ -1,5 +1,160 @@
 package handlers
 
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+const (
+	RequestTimedOut = "The request took too long"
+	QueryTimedOut   = "The database took too long to respond"
+	InternalError   = "Something went wrong"
+)
+
+// Error codes let clients tell failures apart without parsing messages.
+const (
+	NotFoundCode         = "not_found"
+	DuplicateCode        = "duplicate"
+	InvalidReferenceCode = "invalid_reference"
+	InUseCode            = "in_use"
+	InvalidValueCode     = "invalid_value"
+	ReadOnlyCode         = "read_only"
+	ReusedKeyCode        = "idempotency_key_reused"
+	KeyInProgressCode    = "idempotency_key_in_progress"
+	RolledBackCode       = "rolled_back"
+	VersionMismatchCode  = "version_mismatch"
+	RequestTimeoutCode   = "request_timeout"
+	QueryTimeoutCode     = "query_timeout"
+	InternalCode         = "internal"
+)
+
+// APIErrorMessage is the body of every error response. Code and Field are
+// only set for some errors: Field is the request field the error is about.
 type APIErrorMessage struct {
 	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`
+	Field   string `json:"field,omitempty"`
+}
+
+// WriteError responds with the status that fits err: 404 if nothing matched,
+// 412 if the row isn't at the version If-Match asked for, 409 if a unique
+// column's value is taken, 422 if a foreign key or check constraint was
+// violated. Anything else goes to WriteServerError.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case sql.ErrNoRows:
+		WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: "Not Found", Code: NotFoundCode})
+		return
+	case datastore.ErrVersionMismatch:
+		WriteJSON(w, http.StatusPreconditionFailed, APIErrorMessage{Message: VersionMismatch, Code: VersionMismatchCode})
+		return
+	}
+
+	pqErr, ok := err.(*pq.Error)
+	if !ok {
+		WriteServerError(w, r, err, InternalError)
+		return
+	}
+
+	field := constraintField(pqErr)
+	switch pqErr.Code {
+	case "23505": // unique_violation
+		WriteJSON(w, http.StatusConflict, APIErrorMessage{
+			Message: fmt.Sprintf("The %s is already taken.", field),
+			Code:    DuplicateCode,
+			Field:   field,
+		})
+	case "23503": // foreign_key_violation
+		if strings.Contains(pqErr.Detail, "still referenced") {
+			WriteJSON(w, http.StatusUnprocessableEntity, APIErrorMessage{
+				Message: fmt.Sprintf("It's still referenced by %s.", pqErr.Table),
+				Code:    InUseCode,
+				Field:   field,
+			})
+			return
+		}
+		WriteJSON(w, http.StatusUnprocessableEntity, APIErrorMessage{
+			Message: fmt.Sprintf("The %s doesn't exist.", field),
+			Code:    InvalidReferenceCode,
+			Field:   field,
+		})
+	case "23514", "23502": // check_violation, not_null_violation
+		WriteJSON(w, http.StatusUnprocessableEntity, APIErrorMessage{
+			Message: fmt.Sprintf("Invalid value for %s.", field),
+			Code:    InvalidValueCode,
+			Field:   field,
+		})
+	default:
+		WriteServerError(w, r, err, InternalError)
+	}
+}
+
+// keyDetail picks the columns out of the detail postgres gives for unique
+// and foreign key violations, e.g. `Key (email)=(a@b.com) already exists.`
+var keyDetail = regexp.MustCompile(`^Key \(([^)]+)\)`)
+
+// checkFields are the columns the check constraints are reported against.
+// They're named per table, several tables have a valid_name check.
+var checkFields = map[string]string{
+	"users.valid_name":                        "email",
+	"members.valid_name":                      "first_name",
+	"features.name_or_description":            "feature_name",
+	"business_hours.holiday_or_day":           "day_id",
+	"outside_memberships.gym_location_or_gym": "gym_location_id",
+	"support_requests.has_content":            "content",
+}
+
+// constraintField names the column a constraint error is about.
+func constraintField(pqErr *pq.Error) string {
+	if pqErr.Column != "" {
+		return pqErr.Column
+	}
+	if match := keyDetail.FindStringSubmatch(pqErr.Detail); match != nil {
+		return match[1]
+	}
+	if field, ok := checkFields[pqErr.Table+"."+pqErr.Constraint]; ok {
+		return field
+	}
+
+	return pqErr.Constraint
+}
+
+// WriteServerError responds to a request that failed with err. If the
+// request itself ran out of time, or the client went away, it's a 503. If
+// only a query timed out it's a 504, otherwise a 500 with message. err is
+// only logged, it can name tables and columns clients shouldn't see.
+func WriteServerError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	log.Printf("%s %s failed: %s", r.Method, r.URL.Path, err)
+
+	if r.Context().Err() != nil {
+		WriteJSON(w, http.StatusServiceUnavailable, APIErrorMessage{Message: RequestTimedOut, Code: RequestTimeoutCode})
+		return
+	}
+	if isQueryTimeout(err) {
+		WriteJSON(w, http.StatusGatewayTimeout, APIErrorMessage{Message: QueryTimedOut, Code: QueryTimeoutCode})
+		return
+	}
+
+	WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: message, Code: InternalCode})
+}
+
+// isQueryTimeout reports whether err is from a query that ran past its
+// deadline, either before it got to postgres or while postgres was running it
+// (query_canceled).
+func isQueryTimeout(err error) bool {
+	if err == context.DeadlineExceeded {
+		return true
+	}
+
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "57014"
 }
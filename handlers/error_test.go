@@ -0,0 +1,105 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	"github.com/lukashambsch/anygym.api/store"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// slowStatuses is a status repository whose queries run until they time out.
+type slowStatuses struct {
+	store.StatusRepository
+}
+
+func (slowStatuses) List(ctx context.Context, q datastore.Query) ([]models.Status, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// brokenStatuses is a status repository whose queries fail with an error
+// clients shouldn't see.
+type brokenStatuses struct {
+	store.StatusRepository
+}
+
+func (brokenStatuses) Get(ctx context.Context, statusID int64) (*models.Status, error) {
+	return nil, &pq.Error{Code: "42703", Message: `column "secret_column" does not exist`}
+}
+
+var _ = Describe("Internal errors", func() {
+	It("should return status code 500 without the error's detail", func() {
+		var errRes handlers.APIErrorMessage
+		testStore.Statuses = brokenStatuses{testStore.Statuses}
+		server := httptest.NewServer(router.Load(testStore, testKeys))
+		defer server.Close()
+
+		token, _ := RequestToken(server.URL)
+		res, data, _ := Request("GET", fmt.Sprintf("%s%s/statuses/1", server.URL, router.V1URLBase), token, nil)
+		json.Unmarshal(data, &errRes)
+		Expect(res.StatusCode).To(Equal(http.StatusInternalServerError))
+		Expect(errRes.Message).To(Equal(handlers.InternalError))
+		Expect(string(data)).NotTo(ContainSubstring("secret_column"))
+	})
+})
+
+var _ = Describe("Timeouts", func() {
+	var (
+		server *httptest.Server
+		url    string
+		token  string
+		errRes handlers.APIErrorMessage
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		url = fmt.Sprintf("%s%s/statuses", server.URL, router.V1URLBase)
+		token, _ = RequestToken(server.URL)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Query timeout", func() {
+		It("should return status code 504", func() {
+			testStore.Statuses = slowStatuses{testStore.Statuses}
+			res, data, _ := Request("GET", url, token, nil)
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusGatewayTimeout))
+			Expect(errRes.Message).To(Equal(handlers.QueryTimedOut))
+		})
+	})
+
+	Describe("Request timeout", func() {
+		It("should return status code 503", func() {
+			ctx, cancel := context.WithTimeout(ctx, time.Nanosecond)
+			defer cancel()
+			<-ctx.Done()
+
+			req, _ := http.NewRequest("GET", url, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			res := httptest.NewRecorder()
+			router.Load(testStore, testKeys).ServeHTTP(res, req.WithContext(ctx))
+
+			json.Unmarshal(res.Body.Bytes(), &errRes)
+			Expect(res.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(errRes.Message).To(Equal(handlers.RequestTimedOut))
+		})
+	})
+})
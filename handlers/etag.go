@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	VersionMismatch = "It has changed since it was read, get it again and retry."
+	InvalidIfMatch  = "If-Match takes a single ETag or *."
+)
+
+// ETag is the entity tag of a resource at version. It only covers the
+// resource's own fields, not the ones embedded with ?include=.
+func ETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", ETag(version))
+}
+
+// notModified answers with a 304 if the request's If-None-Match names the
+// ETag set for the response, or is *, and reports whether it did. It's no use
+// for responses with included resources, those can change while the ETag
+// stays the same.
+func notModified(w http.ResponseWriter, r *http.Request) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	etag := w.Header().Get("ETag")
+	for _, tag := range strings.Split(header, ",") {
+		// If-None-Match compares weakly
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
+
+// ifMatch returns the version the request's If-Match header makes an update
+// or delete conditional on: 0, any version, if there's no header or it's *.
+// A tag that isn't one of ours can't match, so it gets -1, which no row is
+// at. It answers with a 400 and returns false for a list of tags.
+func ifMatch(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	switch {
+	case header == "" || header == "*":
+		return 0, true
+	case strings.Contains(header, ","):
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidIfMatch})
+		return 0, false
+	}
+
+	tag, err := strconv.Unquote(header)
+	if err != nil || !strings.HasPrefix(header, `"`) {
+		return -1, true
+	}
+
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 {
+		return -1, true
+	}
+
+	return version, true
+}
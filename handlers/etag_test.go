@@ -0,0 +1,147 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// RequestWithHeader is Request with one more header, like If-Match.
+func RequestWithHeader(method string, url string, token string, header string, value string, payload []byte) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(header, value)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	return res, data, err
+}
+
+var _ = Describe("ETags", func() {
+	var (
+		server   *httptest.Server
+		visitURL string
+		res      *http.Response
+		data     []byte
+		token    string
+		errRes   handlers.APIErrorMessage
+		payload  = []byte(`{"member_id": 1, "gym_location_id": 1, "status_id": 2}`)
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		token, _ = RequestToken(server.URL)
+		visitURL = fmt.Sprintf("%s%s/visits/1", server.URL, router.V1URLBase)
+		errRes = handlers.APIErrorMessage{}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("GET", func() {
+		It("should send the version as the ETag", func() {
+			res, _, _ = Request("GET", visitURL, token, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(res.Header.Get("ETag")).To(Equal(`"1"`))
+		})
+
+		It("should return 304 if If-None-Match has the ETag", func() {
+			res, data, _ = RequestWithHeader("GET", visitURL, token, "If-None-Match", `"1"`, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusNotModified))
+			Expect(data).To(BeEmpty())
+		})
+
+		It("should return the visit if it changed since", func() {
+			res, _, _ = RequestWithHeader("GET", visitURL, token, "If-None-Match", `"0", W/"2"`, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("should return the visit if resources are included", func() {
+			res, _, _ = RequestWithHeader("GET", visitURL+"?include=status", token, "If-None-Match", `"1"`, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("PUT", func() {
+		It("should update a visit at the If-Match version", func() {
+			var visit models.Visit
+			res, data, _ = RequestWithHeader("PUT", visitURL, token, "If-Match", `"1"`, payload)
+			json.Unmarshal(data, &visit)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(visit.Version).To(Equal(int64(2)))
+			Expect(res.Header.Get("ETag")).To(Equal(`"2"`))
+		})
+
+		It("should return 412 if the visit changed since", func() {
+			Request("PUT", visitURL, token, payload)
+
+			res, data, _ = RequestWithHeader("PUT", visitURL, token, "If-Match", `"1"`, payload)
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusPreconditionFailed))
+			Expect(errRes.Code).To(Equal(handlers.VersionMismatchCode))
+
+			visit, _ := testStore.Visits.Get(ctx, datastore.Unscoped, 1)
+			Expect(visit.Version).To(Equal(int64(2)))
+		})
+
+		It("should return 412 for an ETag that isn't ours", func() {
+			res, _, _ = RequestWithHeader("PUT", visitURL, token, "If-Match", `"abc"`, payload)
+			Expect(res.StatusCode).To(Equal(http.StatusPreconditionFailed))
+		})
+
+		It("should update any version for *", func() {
+			res, _, _ = RequestWithHeader("PUT", visitURL, token, "If-Match", "*", payload)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("should return 400 for a list of ETags", func() {
+			res, data, _ = RequestWithHeader("PUT", visitURL, token, "If-Match", `"1", "2"`, payload)
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(errRes.Message).To(Equal(handlers.InvalidIfMatch))
+		})
+
+		It("should return 404 for a missing visit", func() {
+			url := fmt.Sprintf("%s%s/visits/5000", server.URL, router.V1URLBase)
+			res, _, _ = RequestWithHeader("PUT", url, token, "If-Match", `"1"`, payload)
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("DELETE", func() {
+		It("should return 412 if the visit changed since", func() {
+			Request("PUT", visitURL, token, payload)
+
+			res, _, _ = RequestWithHeader("DELETE", visitURL, token, "If-Match", `"1"`, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusPreconditionFailed))
+
+			_, err := testStore.Visits.Get(ctx, datastore.Unscoped, 1)
+			Expect(err).To(BeNil())
+		})
+
+		It("should delete a visit at the If-Match version", func() {
+			res, _, _ = RequestWithHeader("DELETE", visitURL, token, "If-Match", `"1"`, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusNoContent))
+		})
+	})
+})
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+const GymID = "gym_id"
+const InvalidGymID = "Invalid " + GymID
+
+// DeleteGym marks the gym deleted, which also stops its API keys from
+// working until it's restored.
+func (api *API) DeleteGym(w http.ResponseWriter, r *http.Request) {
+	gymID, message := GetID(w, r, GymID)
+	if message != nil {
+		return
+	}
+
+	err := api.store.Gyms.Delete(r.Context(), gymID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *API) RestoreGym(w http.ResponseWriter, r *http.Request) {
+	gymID, message := GetID(w, r, GymID)
+	if message != nil {
+		return
+	}
+
+	restored, err := api.store.Gyms.Restore(r.Context(), gymID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, restored)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -25,54 +26,104 @@ var gym_locationFields map[string]string = map[string]string{
 	"phone_number":       "string",
 	"website_url":        "string",
 	"in_network":         "bool",
-	"monthly_member_fee": "int",
+	"monthly_member_fee": "float",
+	"user_id":            "int",
+	"deleted_on":         "date",
 }
 
-func GetGymLocation(w http.ResponseWriter, r *http.Request) {
+// gymLocationPatchFields are the fields a PATCH can change, and whether they can be null.
+var gymLocationPatchFields = map[string]bool{
+	"gym_id":             false,
+	"address_id":         false,
+	"location_name":      false,
+	"phone_number":       false,
+	"website_url":        false,
+	"in_network":         false,
+	"monthly_member_fee": true,
+	"user_id":            true,
+}
+
+func (api *API) GetGymLocation(w http.ResponseWriter, r *http.Request) {
 	gymLocationID, message := GetID(w, r, GymLocationID)
 	if message != nil {
 		return
 	}
 
-	gym_location, err := datastore.GetGymLocation(gymLocationID)
+	scope, err := api.scopeFor(r)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: "Not Found"})
-		} else {
-			WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
-		}
+		WriteError(w, r, err)
+		return
+	}
+
+	includes, err := ParseIncludes(r.URL.Query(), gymLocationIncludes)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, gym_location)
+	gym_location, err := api.store.GymLocations.Get(r.Context(), scope, gymLocationID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	setETag(w, gym_location.Version)
+	if len(includes) == 0 && notModified(w, r) {
+		return
+	}
+
+	gymLocations := []models.GymLocation{*gym_location}
+	err = api.includeGymLocations(r.Context(), gymLocations, includes)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, &gymLocations[0])
 }
 
-func GetGymLocations(w http.ResponseWriter, r *http.Request) {
-	var statement string
+func (api *API) GetGymLocations(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
-	where, err := BuildWhere(gym_locationFields, query)
+	scope, err := api.scopeFor(r)
 	if err != nil {
-		WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 
-	sort, err := BuildSort(gym_locationFields, query)
+	includes, err := ParseIncludes(query, gymLocationIncludes)
 	if err != nil {
-		WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: err.Error()})
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
 		return
 	}
 
-	statement = fmt.Sprintf("%s %s", where, sort)
-	statuses, err := datastore.GetGymLocationList(statement)
+	statement, err := BuildQuery(gym_locationFields, GymLocationID, query)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: "Error getting gym_location list."})
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, statuses)
+	statuses, err := api.store.GymLocations.List(r.Context(), scope, statement)
+	if err != nil {
+		WriteServerError(w, r, err, "Error getting gym_location list.")
+		return
+	}
+
+	err = api.includeGymLocations(r.Context(), statuses, includes)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	count, err := api.store.GymLocations.Count(r.Context(), scope, statement)
+	if err != nil {
+		WriteServerError(w, r, err, "Error getting gym_location count.")
+		return
+	}
+
+	WritePage(w, r, statement, *count, statuses)
 }
 
-func PostGymLocation(w http.ResponseWriter, r *http.Request) {
+func (api *API) PostGymLocation(w http.ResponseWriter, r *http.Request) {
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body.Close()
 
@@ -83,16 +134,39 @@ func PostGymLocation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	created, err := datastore.CreateGymLocation(*gym_location)
+	scope, err := api.writeScopeFor(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	// gyms can only add locations to themselves
+	if !seesAll(scope) {
+		owned, err := api.ownsGym(r.Context(), scope, gym_location.GymID)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		if !owned {
+			WriteJSON(w, http.StatusUnprocessableEntity, APIErrorMessage{
+				Message: fmt.Sprintf("The %s doesn't exist.", GymID),
+				Code:    InvalidReferenceCode,
+				Field:   GymID,
+			})
+			return
+		}
+	}
+
+	created, err := api.store.GymLocations.Create(r.Context(), *gym_location)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 
 	WriteJSON(w, http.StatusCreated, created)
 }
 
-func PutGymLocation(w http.ResponseWriter, r *http.Request) {
+func (api *API) PutGymLocation(w http.ResponseWriter, r *http.Request) {
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body.Close()
 
@@ -102,6 +176,23 @@ func PutGymLocation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	scope, err := api.writeScopeFor(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	current, err := api.store.GymLocations.Get(r.Context(), scope, gymLocationID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
 	gym_location := &models.GymLocation{}
 	err = json.Unmarshal(body, gym_location)
 	if err != nil {
@@ -109,27 +200,119 @@ func PutGymLocation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updated, err := datastore.UpdateGymLocation(gymLocationID, *gym_location)
+	// gyms and locations can't move a location to another gym
+	if !seesAll(scope) {
+		gym_location.GymID = current.GymID
+	}
+
+	gym_location.Version = version
+	updated, err := api.store.GymLocations.Update(r.Context(), gymLocationID, *gym_location)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 
+	setETag(w, updated.Version)
 	WriteJSON(w, http.StatusOK, updated)
 }
 
-func DeleteGymLocation(w http.ResponseWriter, r *http.Request) {
+func (api *API) PatchGymLocation(w http.ResponseWriter, r *http.Request) {
+	gymLocationID, err := strconv.ParseInt(mux.Vars(r)[GymLocationID], 10, 64)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidGymLocationID})
+		return
+	}
+
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	scope, err := api.writeScopeFor(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	current, err := api.store.GymLocations.Get(r.Context(), scope, gymLocationID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	gymLocation := &models.GymLocation{}
+	fields, ok := readPatch(w, r, gymLocationPatchFields, gymLocation)
+	if !ok {
+		return
+	}
+
+	if !seesAll(scope) {
+		gymLocation.GymID = current.GymID
+	}
+
+	gymLocation.Version = version
+	patched, err := api.store.GymLocations.Patch(r.Context(), gymLocationID, *gymLocation, fields)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	setETag(w, patched.Version)
+	WriteJSON(w, http.StatusOK, patched)
+}
+
+func (api *API) DeleteGymLocation(w http.ResponseWriter, r *http.Request) {
+	gymLocationID, err := strconv.ParseInt(mux.Vars(r)[GymLocationID], 10, 64)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidGymLocationID})
+		return
+	}
+
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	err = api.store.GymLocations.Delete(r.Context(), gymLocationID, version)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *API) RestoreGymLocation(w http.ResponseWriter, r *http.Request) {
 	gymLocationID, err := strconv.ParseInt(mux.Vars(r)[GymLocationID], 10, 64)
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidGymLocationID})
 		return
 	}
 
-	err = datastore.DeleteGymLocation(gymLocationID)
+	restored, err := api.store.GymLocations.Restore(r.Context(), gymLocationID)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, nil)
+	setETag(w, restored.Version)
+	WriteJSON(w, http.StatusOK, restored)
+}
+
+// ownsGym reports whether the gym is the caller's, through an API key of the
+// gym or by being the gym's user.
+func (api *API) ownsGym(ctx context.Context, scope datastore.Scope, gymID int64) (bool, error) {
+	if scope.GymID != 0 {
+		return gymID == scope.GymID, nil
+	}
+
+	gym, err := api.store.Gyms.Get(ctx, gymID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return gym.UserID != nil && *gym.UserID == scope.UserID, nil
 }
@@ -0,0 +1,74 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GymLocation API", func() {
+	var (
+		server         *httptest.Server
+		gymLocationURL string
+		res            *http.Response
+		data           []byte
+		token          string
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		token, _ = RequestToken(server.URL)
+		gymLocationURL = fmt.Sprintf("%s%s/gym_locations", server.URL, router.V1URLBase)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("DeleteGymLocation endpoint", func() {
+		BeforeEach(func() {
+			res, _, _ = Request("DELETE", gymLocationURL+"/2", token, nil)
+		})
+
+		It("should return status code 204", func() {
+			Expect(res.StatusCode).To(Equal(http.StatusNoContent))
+		})
+
+		It("should leave the gym location out of the list", func() {
+			var gymLocations []models.GymLocation
+			_, data, _ = Request("GET", gymLocationURL, token, nil)
+			json.Unmarshal(data, &gymLocations)
+			Expect(len(gymLocations)).To(Equal(1))
+		})
+
+		It("should list it for admins asking for deleted gym locations", func() {
+			var gymLocations []models.GymLocation
+			_, data, _ = Request("GET", gymLocationURL+"?include_deleted=true&deleted_on[is_null]=false", token, nil)
+			json.Unmarshal(data, &gymLocations)
+			Expect(len(gymLocations)).To(Equal(1))
+			Expect(gymLocations[0].GymLocationID).To(Equal(int64(2)))
+		})
+	})
+
+	Describe("RestoreGymLocation endpoint", func() {
+		It("should bring a deleted gym location back", func() {
+			var restored models.GymLocation
+			testStore.GymLocations.Delete(ctx, 2, 0)
+
+			res, data, _ = Request("POST", gymLocationURL+"/2/restore", token, nil)
+			json.Unmarshal(data, &restored)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(restored.DeletedOn).To(BeNil())
+
+			_, err := testStore.GymLocations.Get(ctx, datastore.Unscoped, 2)
+			Expect(err).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,100 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Gym API", func() {
+	var (
+		server *httptest.Server
+		gymURL string
+		res    *http.Response
+		data   []byte
+		token  string
+		errRes handlers.APIErrorMessage
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		token, _ = RequestToken(server.URL)
+		gymURL = fmt.Sprintf("%s%s/gyms", server.URL, router.V1URLBase)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("DeleteGym endpoint", func() {
+		Describe("Successful DELETE", func() {
+			var created handlers.NewAPIKey
+
+			BeforeEach(func() {
+				_, data, _ = Request("POST", gymURL+"/1/api_keys", token, []byte(`{"key_name": "Check ins"}`))
+				json.Unmarshal(data, &created)
+
+				res, _, _ = Request("DELETE", gymURL+"/1", token, nil)
+			})
+
+			AfterEach(func() {
+				testStore.APIKeys.Delete(ctx, created.APIKeyID)
+			})
+
+			It("should return status code 204", func() {
+				Expect(res.StatusCode).To(Equal(http.StatusNoContent))
+			})
+
+			It("should delete the gym", func() {
+				_, err := testStore.Gyms.Get(ctx, 1)
+				Expect(err).ToNot(BeNil())
+			})
+
+			It("should stop the gym's API keys from working", func() {
+				res, _ = RequestAPIKey("GET", fmt.Sprintf("%s%s/visits", server.URL, router.V1URLBase), created.Key, nil)
+				Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+		})
+
+		Describe("Unsuccessful DELETE", func() {
+			It("should return status code 400 with a message", func() {
+				res, data, _ = Request("DELETE", gymURL+"/a", token, nil)
+				json.Unmarshal(data, &errRes)
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(errRes.Message).To(Equal(handlers.InvalidGymID))
+			})
+
+			It("should return status code 404 for a missing id", func() {
+				res, _, _ = Request("DELETE", gymURL+"/5000", token, nil)
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("RestoreGym endpoint", func() {
+		It("should bring a deleted gym back", func() {
+			var restored models.Gym
+			testStore.Gyms.Delete(ctx, 1)
+
+			res, data, _ = Request("POST", gymURL+"/1/restore", token, nil)
+			json.Unmarshal(data, &restored)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(restored.DeletedOn).To(BeNil())
+
+			_, err := testStore.Gyms.Get(ctx, 1)
+			Expect(err).To(BeNil())
+		})
+
+		It("should return status code 404 for a missing id", func() {
+			res, _, _ = Request("POST", gymURL+"/5000/restore", token, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+})
@@ -4,6 +4,12 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store"
+	"github.com/lukashambsch/anygym.api/store/memory"
+
+	"context"
 	"testing"
 )
 
@@ -11,3 +17,25 @@ func TestHandlers(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Handlers Suite")
 }
+
+// testStore is what the API under test runs on. Every spec gets a fresh one
+// with just the static data. testKeys are the keys from config/test.json.
+var (
+	testStore *store.Store
+	testKeys  *handlers.KeySet
+	ctx       = context.Background()
+)
+
+var _ = BeforeSuite(func() {
+	var err error
+	testKeys, err = handlers.LoadKeys()
+	Expect(err).To(BeNil())
+})
+
+// admins need two-factor authentication for their role to count, so the
+// seeded admin is enrolled in every spec
+var _ = BeforeEach(func() {
+	testStore = memory.New()
+	testStore.TwoFactors.Save(ctx, models.TwoFactor{UserID: 1, Secret: twoFactorSecrets["lukas.hambsch@gmail.com"]})
+	testStore.TwoFactors.Enable(ctx, 1)
+})
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/config"
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+const (
+	InvalidIdempotencyKey    = "Idempotency-Key takes 1 to 255 characters."
+	IdempotencyKeyReused     = "The Idempotency-Key was already used for a different request."
+	IdempotencyKeyInProgress = "A request with this Idempotency-Key is still running."
+)
+
+// idempotencyWindow is how long the response to a POST with an
+// Idempotency-Key is kept to be replayed.
+var idempotencyWindow = loadIdempotencyWindow()
+
+func loadIdempotencyWindow() time.Duration {
+	if config.C.IsSet("idempotency.window") {
+		return config.C.GetDuration("idempotency.window")
+	}
+
+	return 24 * time.Hour
+}
+
+// Idempotent lets clients retry a POST safely by sending an Idempotency-Key
+// header. The first request with a key runs as usual and its response is
+// kept, retries with the same key and body get that response again instead
+// of running again. A key whose request is still running gets a 409, one
+// reused for a different request a 422.
+//
+// Responses with a 5xx status aren't kept, the request can be retried with
+// the same key. Only the status and headers of no-store responses are.
+// Anonymous requests ignore the header: there's nobody to keep their keys
+// apart, so one client could get another's response.
+func (api *API) Idempotent(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.Header.Get("Idempotency-Key")
+		owner, ok := idempotencyOwner(r)
+		if r.Method != "POST" || name == "" || !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if len(name) > 255 {
+			WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidIdempotencyKey})
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		key := models.IdempotencyKey{
+			Owner:       owner,
+			Key:         name,
+			Fingerprint: fingerprint(r, body),
+		}
+		stored, claimed, err := api.store.IdempotencyKeys.Claim(r.Context(), key, idempotencyWindow)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		if !claimed {
+			replay(w, stored, key.Fingerprint)
+			return
+		}
+
+		// The request's context may have run out, the key has to be saved or
+		// released regardless. It's released if h panics as well, so the
+		// request can be retried.
+		ctx := context.Background()
+		saved := false
+		defer func() {
+			if !saved {
+				api.releaseIdempotencyKey(ctx, key)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		h.ServeHTTP(recorder, r)
+		if recorder.status == 0 {
+			recorder.WriteHeader(http.StatusOK)
+		}
+		if recorder.status >= 500 {
+			return
+		}
+
+		key.ResponseStatus = recorder.status
+		key.ResponseHeaders = recorder.header
+		// credentials are only handed out once, retries get the status and
+		// headers without them
+		if !isNoStore(recorder.header) {
+			key.ResponseBody = recorder.body.Bytes()
+		}
+		err = api.store.IdempotencyKeys.SaveResponse(ctx, key)
+		if err != nil {
+			log.Printf("Saving the response for Idempotency-Key %q failed: %s", key.Key, err)
+			return
+		}
+		saved = true
+	})
+}
+
+func (api *API) releaseIdempotencyKey(ctx context.Context, key models.IdempotencyKey) {
+	err := api.store.IdempotencyKeys.Delete(ctx, key.Owner, key.Key)
+	if err != nil {
+		log.Printf("Releasing Idempotency-Key %q failed: %s", key.Key, err)
+	}
+}
+
+// noStore marks a response that hands out credentials: tokens, API keys,
+// two-factor secrets or recovery codes. Nothing should keep a copy of it,
+// Idempotent included.
+func noStore(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+func isNoStore(header http.Header) bool {
+	return header.Get("Cache-Control") == "no-store"
+}
+
+// idempotencyOwner is who a key belongs to: the API key or user that sent
+// the request. It returns false for anonymous requests.
+func idempotencyOwner(r *http.Request) (string, bool) {
+	claims, ok := GetClaims(r)
+	switch {
+	case !ok:
+		return "", false
+	case claims.APIKey != nil:
+		return fmt.Sprintf("api_key:%d", claims.APIKey.APIKeyID), true
+	default:
+		return fmt.Sprintf("user:%d", claims.UserID), true
+	}
+}
+
+// fingerprint tells requests apart by their route and body.
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", r.Method, r.URL.RequestURI())
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, stored *models.IdempotencyKey, fingerprint string) {
+	switch {
+	case stored.ResponseStatus == 0:
+		WriteJSON(w, http.StatusConflict, APIErrorMessage{Message: IdempotencyKeyInProgress, Code: KeyInProgressCode})
+		return
+	case stored.Fingerprint != fingerprint:
+		WriteJSON(w, http.StatusUnprocessableEntity, APIErrorMessage{Message: IdempotencyKeyReused, Code: ReusedKeyCode})
+		return
+	}
+
+	for name, values := range stored.ResponseHeaders {
+		w.Header()[name] = values
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(stored.ResponseStatus)
+	w.Write(stored.ResponseBody)
+}
+
+// responseRecorder keeps a copy of the response it writes through to the
+// client.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+		rec.header = http.Header{}
+		for name, values := range rec.Header() {
+			rec.header[name] = append([]string(nil), values...)
+		}
+	}
+
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(data)
+
+	return rec.ResponseWriter.Write(data)
+}
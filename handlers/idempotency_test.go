@@ -0,0 +1,160 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Idempotency keys", func() {
+	var (
+		server   *httptest.Server
+		visitURL string
+		res      *http.Response
+		data     []byte
+		token    string
+		errRes   handlers.APIErrorMessage
+		payload  = []byte(`{"member_id": 1, "gym_location_id": 1, "status_id": 1}`)
+	)
+
+	visitCount := func() int {
+		count, _ := testStore.Visits.Count(ctx, datastore.Unscoped, datastore.Query{})
+		return *count
+	}
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		token, _ = RequestToken(server.URL)
+		visitURL = fmt.Sprintf("%s%s/visits", server.URL, router.V1URLBase)
+		errRes = handlers.APIErrorMessage{}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Retried POST", func() {
+		var (
+			first, second models.Visit
+			before        int
+		)
+
+		BeforeEach(func() {
+			before = visitCount()
+			res, data, _ = RequestWithHeader("POST", visitURL, token, "Idempotency-Key", "check-in-1", payload)
+			json.Unmarshal(data, &first)
+			res, data, _ = RequestWithHeader("POST", visitURL, token, "Idempotency-Key", "check-in-1", payload)
+			json.Unmarshal(data, &second)
+		})
+
+		It("should only create the visit once", func() {
+			Expect(visitCount()).To(Equal(before + 1))
+		})
+
+		It("should replay the first response", func() {
+			Expect(res.StatusCode).To(Equal(http.StatusCreated))
+			Expect(res.Header.Get("Idempotent-Replayed")).To(Equal("true"))
+			Expect(second.VisitID).To(Equal(first.VisitID))
+		})
+
+		It("should return status code 422 for the same key with a different body", func() {
+			other := []byte(`{"member_id": 2, "gym_location_id": 1, "status_id": 1}`)
+			res, data, _ = RequestWithHeader("POST", visitURL, token, "Idempotency-Key", "check-in-1", other)
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+			Expect(errRes.Code).To(Equal(handlers.ReusedKeyCode))
+			Expect(visitCount()).To(Equal(before + 1))
+		})
+	})
+
+	It("should run requests with different keys", func() {
+		before := visitCount()
+		RequestWithHeader("POST", visitURL, token, "Idempotency-Key", "check-in-1", payload)
+		RequestWithHeader("POST", visitURL, token, "Idempotency-Key", "check-in-2", payload)
+		Expect(visitCount()).To(Equal(before + 2))
+	})
+
+	It("should run the request again once the key has expired", func() {
+		testStore.IdempotencyKeys.Claim(ctx, models.IdempotencyKey{Owner: "user:1", Key: "expired"}, -time.Second)
+
+		res, _, _ = RequestWithHeader("POST", visitURL, token, "Idempotency-Key", "expired", payload)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		Expect(res.Header.Get("Idempotent-Replayed")).To(BeEmpty())
+	})
+
+	It("should return status code 409 while the first request is still running", func() {
+		testStore.IdempotencyKeys.Claim(ctx, models.IdempotencyKey{Owner: "user:1", Key: "running"}, time.Hour)
+
+		res, data, _ = RequestWithHeader("POST", visitURL, token, "Idempotency-Key", "running", []byte{})
+		json.Unmarshal(data, &errRes)
+		Expect(res.StatusCode).To(Equal(http.StatusConflict))
+		Expect(errRes.Code).To(Equal(handlers.KeyInProgressCode))
+	})
+
+	It("should return status code 400 for a key that's too long", func() {
+		res, data, _ = RequestWithHeader("POST", visitURL, token, "Idempotency-Key", strings.Repeat("k", 256), payload)
+		json.Unmarshal(data, &errRes)
+		Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(errRes.Message).To(Equal(handlers.InvalidIdempotencyKey))
+	})
+
+	It("should not keep API keys it hands out", func() {
+		var created, replayed handlers.NewAPIKey
+		apiKeyURL := fmt.Sprintf("%s%s/gyms/1/api_keys", server.URL, router.V1URLBase)
+		body := []byte(`{"key_name": "Front desk"}`)
+
+		res, data, _ = RequestWithHeader("POST", apiKeyURL, token, "Idempotency-Key", "new-key", body)
+		json.Unmarshal(data, &created)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		Expect(created.Key).ToNot(BeEmpty())
+
+		stored, _ := testStore.IdempotencyKeys.Get(ctx, "user:1", "new-key")
+		Expect(stored.ResponseBody).To(BeEmpty())
+
+		res, data, _ = RequestWithHeader("POST", apiKeyURL, token, "Idempotency-Key", "new-key", body)
+		json.Unmarshal(data, &replayed)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		Expect(res.Header.Get("Idempotent-Replayed")).To(Equal("true"))
+		Expect(replayed.Key).To(BeEmpty())
+	})
+
+	It("should release the key if the request panics", func() {
+		api := handlers.New(testStore, testKeys)
+		h := api.VerifyToken(api.Idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})))
+		req := httptest.NewRequest("POST", "/api/v1/visits", strings.NewReader("{}"))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Idempotency-Key", "panics")
+
+		Expect(func() { h.ServeHTTP(httptest.NewRecorder(), req) }).To(Panic())
+		_, err := testStore.IdempotencyKeys.Get(ctx, "user:1", "panics")
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("should not share keys between anonymous clients", func() {
+		var first, second models.User
+		userURL := fmt.Sprintf("%s%s/users", server.URL, router.V1URLBase)
+
+		res, data, _ = RequestWithHeader("POST", userURL, "", "Idempotency-Key", "signup", []byte(`{"email": "first@email.com", "password": "testing"}`))
+		json.Unmarshal(data, &first)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+
+		res, data, _ = RequestWithHeader("POST", userURL, "", "Idempotency-Key", "signup", []byte(`{"email": "second@email.com", "password": "testing"}`))
+		json.Unmarshal(data, &second)
+		Expect(res.StatusCode).To(Equal(http.StatusCreated))
+		Expect(res.Header.Get("Idempotent-Replayed")).To(BeEmpty())
+		Expect(second.Email).To(Equal("second@email.com"))
+		Expect(second.UserID).NotTo(Equal(first.UserID))
+	})
+})
@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+const InvalidInclude = "Can't include %s."
+
+// The related resources each resource can embed with ?include=. Nested
+// resources take their own includes after a dot, e.g. gym_location.address.
+var (
+	gymLocationIncludes = []string{"address", "business_hours", "features", "images", "gym"}
+	memberIncludes      = []string{"user", "address", "image"}
+	visitIncludes       = append(append(
+		[]string{"member", "gym_location", "status"},
+		nested("member", memberIncludes)...),
+		nested("gym_location", gymLocationIncludes)...)
+)
+
+func nested(name string, includes []string) []string {
+	var paths []string
+	for _, include := range includes {
+		paths = append(paths, name+"."+include)
+	}
+
+	return paths
+}
+
+// Includes are the related resources a request asked to embed.
+type Includes map[string]bool
+
+// ParseIncludes reads the comma separated include param. Including a nested
+// resource includes its parent as well.
+func ParseIncludes(params url.Values, allowed []string) (Includes, error) {
+	includes := Includes{}
+	if params.Get("include") == "" {
+		return includes, nil
+	}
+
+	for _, include := range strings.Split(params.Get("include"), ",") {
+		include = strings.TrimSpace(include)
+		if !hasInclude(allowed, include) {
+			return nil, fmt.Errorf(InvalidInclude, include)
+		}
+
+		parts := strings.Split(include, ".")
+		for i := range parts {
+			includes[strings.Join(parts[:i+1], ".")] = true
+		}
+	}
+
+	return includes, nil
+}
+
+func hasInclude(allowed []string, include string) bool {
+	for _, a := range allowed {
+		if a == include {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Nested returns the includes of the name resource, without its prefix.
+func (i Includes) Nested(name string) Includes {
+	nested := Includes{}
+	for include := range i {
+		if strings.HasPrefix(include, name+".") {
+			nested[strings.TrimPrefix(include, name+".")] = true
+		}
+	}
+
+	return nested
+}
+
+// idsFilter matches the rows whose field is one of ids, so a batch of
+// related records is loaded with a single query.
+func idsFilter(field string, ids []int64) datastore.Query {
+	values := []interface{}{}
+	seen := map[int64]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			values = append(values, id)
+		}
+	}
+
+	return datastore.Query{Filters: []datastore.Filter{{Field: field, Operator: datastore.In, Value: values}}}
+}
+
+// includeVisits embeds the related resources into visits. Members and gym
+// locations are looked up within the caller's scope.
+func (api *API) includeVisits(ctx context.Context, scope datastore.Scope, visits []models.Visit, includes Includes) error {
+	if len(visits) == 0 {
+		return nil
+	}
+
+	if includes["member"] {
+		var ids []int64
+		for _, visit := range visits {
+			ids = append(ids, visit.MemberID)
+		}
+
+		members, err := api.store.Members.List(ctx, scope, idsFilter("member_id", ids))
+		if err != nil {
+			return err
+		}
+		err = api.includeMembers(ctx, scope, members, includes.Nested("member"))
+		if err != nil {
+			return err
+		}
+
+		byID := map[int64]*models.Member{}
+		for i := range members {
+			byID[members[i].MemberID] = &members[i]
+		}
+		for i := range visits {
+			visits[i].Member = byID[visits[i].MemberID]
+		}
+	}
+
+	if includes["gym_location"] {
+		var ids []int64
+		for _, visit := range visits {
+			ids = append(ids, visit.GymLocationID)
+		}
+
+		gymLocations, err := api.store.GymLocations.List(ctx, scope, idsFilter("gym_location_id", ids))
+		if err != nil {
+			return err
+		}
+		err = api.includeGymLocations(ctx, gymLocations, includes.Nested("gym_location"))
+		if err != nil {
+			return err
+		}
+
+		byID := map[int64]*models.GymLocation{}
+		for i := range gymLocations {
+			byID[gymLocations[i].GymLocationID] = &gymLocations[i]
+		}
+		for i := range visits {
+			visits[i].GymLocation = byID[visits[i].GymLocationID]
+		}
+	}
+
+	if includes["status"] {
+		var ids []int64
+		for _, visit := range visits {
+			ids = append(ids, visit.StatusID)
+		}
+
+		statuses, err := api.store.Statuses.List(ctx, idsFilter("status_id", ids))
+		if err != nil {
+			return err
+		}
+
+		byID := map[int64]*models.Status{}
+		for i := range statuses {
+			byID[statuses[i].StatusID] = &statuses[i]
+		}
+		for i := range visits {
+			visits[i].Status = byID[visits[i].StatusID]
+		}
+	}
+
+	return nil
+}
+
+// includeMembers embeds the related resources into members. Their user is
+// always loaded.
+func (api *API) includeMembers(ctx context.Context, scope datastore.Scope, members []models.Member, includes Includes) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	if includes["address"] {
+		var ids []int64
+		for _, member := range members {
+			if member.AddressID != nil {
+				ids = append(ids, *member.AddressID)
+			}
+		}
+
+		addresses, err := api.store.Addresses.List(ctx, idsFilter("address_id", ids))
+		if err != nil {
+			return err
+		}
+
+		byID := map[int64]*models.Address{}
+		for i := range addresses {
+			byID[addresses[i].AddressID] = &addresses[i]
+		}
+		for i := range members {
+			if members[i].AddressID != nil {
+				members[i].Address = byID[*members[i].AddressID]
+			}
+		}
+	}
+
+	if includes["image"] {
+		var ids []int64
+		for _, member := range members {
+			if member.ImageID != nil {
+				ids = append(ids, *member.ImageID)
+			}
+		}
+
+		images, err := api.store.Images.List(ctx, idsFilter("image_id", ids))
+		if err != nil {
+			return err
+		}
+
+		byID := map[int64]*models.Image{}
+		for i := range images {
+			byID[images[i].ImageID] = &images[i]
+		}
+		for i := range members {
+			if members[i].ImageID != nil {
+				members[i].Image = byID[*members[i].ImageID]
+			}
+		}
+	}
+
+	return nil
+}
+
+// includeGymLocations embeds the related resources into gym locations.
+// Lists already come with their address and business hours.
+func (api *API) includeGymLocations(ctx context.Context, gymLocations []models.GymLocation, includes Includes) error {
+	if len(gymLocations) == 0 {
+		return nil
+	}
+
+	var locationIDs, gymIDs []int64
+	for _, gymLocation := range gymLocations {
+		locationIDs = append(locationIDs, gymLocation.GymLocationID)
+		gymIDs = append(gymIDs, gymLocation.GymID)
+	}
+
+	if includes["address"] {
+		var ids []int64
+		for _, gymLocation := range gymLocations {
+			if gymLocation.Address == nil {
+				ids = append(ids, gymLocation.AddressID)
+			}
+		}
+
+		addresses, err := api.store.Addresses.List(ctx, idsFilter("address_id", ids))
+		if err != nil {
+			return err
+		}
+
+		byID := map[int64]*models.Address{}
+		for i := range addresses {
+			byID[addresses[i].AddressID] = &addresses[i]
+		}
+		for i := range gymLocations {
+			if gymLocations[i].Address == nil {
+				gymLocations[i].Address = byID[gymLocations[i].AddressID]
+			}
+		}
+	}
+
+	if includes["business_hours"] {
+		var ids []int64
+		for _, gymLocation := range gymLocations {
+			if gymLocation.BusinessHours == nil {
+				ids = append(ids, gymLocation.GymLocationID)
+			}
+		}
+
+		businessHours, err := api.store.BusinessHours.List(ctx, idsFilter("gym_location_id", ids))
+		if err != nil {
+			return err
+		}
+
+		byLocation := map[int64][]models.BusinessHour{}
+		for _, businessHour := range businessHours {
+			byLocation[businessHour.GymLocationID] = append(byLocation[businessHour.GymLocationID], businessHour)
+		}
+		for i := range gymLocations {
+			if gymLocations[i].BusinessHours == nil {
+				gymLocations[i].BusinessHours = byLocation[gymLocations[i].GymLocationID]
+			}
+		}
+	}
+
+	if includes["images"] {
+		images, err := api.store.Images.List(ctx, idsFilter("gym_location_id", locationIDs))
+		if err != nil {
+			return err
+		}
+
+		byLocation := map[int64][]models.Image{}
+		for _, image := range images {
+			byLocation[*image.GymLocationID] = append(byLocation[*image.GymLocationID], image)
+		}
+		for i := range gymLocations {
+			gymLocations[i].Images = byLocation[gymLocations[i].GymLocationID]
+		}
+	}
+
+	// features belong to the gym, every location of it has them
+	if includes["features"] {
+		gymFeatures, err := api.store.GymFeatures.List(ctx, idsFilter("gym_id", gymIDs))
+		if err != nil {
+			return err
+		}
+
+		var featureIDs []int64
+		for _, gymFeature := range gymFeatures {
+			featureIDs = append(featureIDs, gymFeature.FeatureID)
+		}
+
+		features, err := api.store.Features.List(ctx, idsFilter("feature_id", featureIDs))
+		if err != nil {
+			return err
+		}
+
+		featuresByID := map[int64]models.Feature{}
+		for _, feature := range features {
+			featuresByID[feature.FeatureID] = feature
+		}
+		byGym := map[int64][]models.Feature{}
+		for _, gymFeature := range gymFeatures {
+			byGym[gymFeature.GymID] = append(byGym[gymFeature.GymID], featuresByID[gymFeature.FeatureID])
+		}
+		for i := range gymLocations {
+			gymLocations[i].Features = byGym[gymLocations[i].GymID]
+		}
+	}
+
+	if includes["gym"] {
+		gyms, err := api.store.Gyms.List(ctx, idsFilter("gym_id", gymIDs))
+		if err != nil {
+			return err
+		}
+
+		byID := map[int64]*models.Gym{}
+		for i := range gyms {
+			byID[gyms[i].GymID] = &gyms[i]
+		}
+		for i := range gymLocations {
+			gymLocations[i].Gym = byID[gymLocations[i].GymID]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Includes", func() {
+	var allowed = []string{"member", "member.address", "status"}
+
+	Describe("ParseIncludes", func() {
+		It("should return no includes without the param", func() {
+			includes, err := handlers.ParseIncludes(url.Values{}, allowed)
+			Expect(err).To(BeNil())
+			Expect(includes).To(BeEmpty())
+		})
+
+		It("should split a comma separated list", func() {
+			includes, err := handlers.ParseIncludes(url.Values{"include": {"member, status"}}, allowed)
+			Expect(err).To(BeNil())
+			Expect(includes).To(Equal(handlers.Includes{"member": true, "status": true}))
+		})
+
+		It("should include the parent of a nested include", func() {
+			includes, err := handlers.ParseIncludes(url.Values{"include": {"member.address"}}, allowed)
+			Expect(err).To(BeNil())
+			Expect(includes).To(Equal(handlers.Includes{"member": true, "member.address": true}))
+			Expect(includes.Nested("member")).To(Equal(handlers.Includes{"address": true}))
+		})
+
+		It("should return an error for an unknown include", func() {
+			_, err := handlers.ParseIncludes(url.Values{"include": {"member,password_hash"}}, allowed)
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(Equal(fmt.Sprintf(handlers.InvalidInclude, "password_hash")))
+		})
+	})
+
+	Describe("Endpoints", func() {
+		var (
+			server *httptest.Server
+			token  string
+			res    *http.Response
+			data   []byte
+		)
+
+		BeforeEach(func() {
+			server = httptest.NewServer(router.Load(testStore, testKeys))
+			token, _ = RequestToken(server.URL)
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should embed a visit's member, gym location and status", func() {
+			var visits []models.Visit
+			res, data, _ = Request("GET", fmt.Sprintf("%s%s/visits?include=member.image,gym_location.gym,status", server.URL, router.V1URLBase), token, nil)
+			json.Unmarshal(data, &visits)
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(len(visits)).To(Equal(5))
+			for _, visit := range visits {
+				Expect(visit.Member.MemberID).To(Equal(visit.MemberID))
+				Expect(visit.Member.Image).ToNot(BeNil())
+				Expect(visit.GymLocation.GymLocationID).To(Equal(visit.GymLocationID))
+				Expect(visit.GymLocation.Gym.GymID).To(Equal(visit.GymLocation.GymID))
+				Expect(visit.Status.StatusID).To(Equal(visit.StatusID))
+			}
+		})
+
+		It("should not embed anything without the param", func() {
+			var visit models.Visit
+			res, data, _ = Request("GET", fmt.Sprintf("%s%s/visits/1", server.URL, router.V1URLBase), token, nil)
+			json.Unmarshal(data, &visit)
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(visit.Member).To(BeNil())
+			Expect(visit.Status).To(BeNil())
+		})
+
+		It("should embed a single gym location's address and business hours", func() {
+			var gymLocation models.GymLocation
+			res, data, _ = Request("GET", fmt.Sprintf("%s%s/gym_locations/1?include=address,business_hours", server.URL, router.V1URLBase), token, nil)
+			json.Unmarshal(data, &gymLocation)
+
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(gymLocation.Address.AddressID).To(Equal(gymLocation.AddressID))
+			Expect(gymLocation.BusinessHours).ToNot(BeEmpty())
+		})
+
+		It("should return 400 for an unknown include", func() {
+			var errRes handlers.APIErrorMessage
+			res, data, _ = Request("GET", fmt.Sprintf("%s%s/members?include=password_hash", server.URL, router.V1URLBase), token, nil)
+			json.Unmarshal(data, &errRes)
+
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(errRes.Message).To(Equal(fmt.Sprintf(handlers.InvalidInclude, "password_hash")))
+		})
+	})
+})
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sort"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/lukashambsch/anygym.api/config"
+)
+
+// KeyConfig is an entry in the auth.keys config list. HMAC keys use secret,
+// RSA and ECDSA keys take a PEM encoded private key either inline or from a
+// file. Keys that are being rotated out can be left with only a public key so
+// tokens they signed keep verifying until they expire.
+type KeyConfig struct {
+	ID             string `mapstructure:"kid"`
+	Algorithm      string `mapstructure:"algorithm"`
+	Secret         string `mapstructure:"secret"`
+	PrivateKey     string `mapstructure:"private_key"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	PublicKey      string `mapstructure:"public_key"`
+	PublicKeyFile  string `mapstructure:"public_key_file"`
+}
+
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeySet holds every key tokens may be verified with and the one new tokens
+// are signed with.
+type KeySet struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// JWK is a public key in RFC 7517 format.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// tokenIssuer goes in the iss claim of the tokens we sign. It's left out if
+// auth.issuer isn't set.
+func tokenIssuer() string {
+	return config.C.GetString("auth.issuer")
+}
+
+// LoadKeys builds the KeySet from the auth section of the config.
+func LoadKeys() (*KeySet, error) {
+	var keyConfigs []KeyConfig
+
+	err := config.C.UnmarshalKey("auth.keys", &keyConfigs)
+	if err != nil {
+		return nil, err
+	}
+
+	keySet := &KeySet{keys: map[string]*SigningKey{}}
+	for _, keyConfig := range keyConfigs {
+		key, err := newSigningKey(keyConfig)
+		if err != nil {
+			return nil, fmt.Errorf("Signing key %q: %s", keyConfig.ID, err)
+		}
+
+		if _, ok := keySet.keys[key.ID]; ok {
+			return nil, fmt.Errorf("Duplicate signing key %q", key.ID)
+		}
+		keySet.keys[key.ID] = key
+	}
+
+	activeID := config.C.GetString("auth.signing_key")
+	active, ok := keySet.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("Signing key %q is not configured", activeID)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("Signing key %q has no private key", activeID)
+	}
+	keySet.active = active
+
+	return keySet, nil
+}
+
+func newSigningKey(keyConfig KeyConfig) (*SigningKey, error) {
+	if keyConfig.ID == "" {
+		return nil, fmt.Errorf("kid is required")
+	}
+
+	key := &SigningKey{ID: keyConfig.ID, Method: jwt.GetSigningMethod(keyConfig.Algorithm)}
+	if key.Method == nil {
+		return nil, fmt.Errorf("Unsupported algorithm %q", keyConfig.Algorithm)
+	}
+
+	if _, ok := key.Method.(*jwt.SigningMethodHMAC); ok {
+		if keyConfig.Secret == "" {
+			return nil, fmt.Errorf("secret is required for %s", keyConfig.Algorithm)
+		}
+		key.signKey = []byte(keyConfig.Secret)
+		key.verifyKey = key.signKey
+		return key, nil
+	}
+
+	privatePEM, err := readPEM(keyConfig.PrivateKey, keyConfig.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	publicPEM, err := readPEM(keyConfig.PublicKey, keyConfig.PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if privatePEM == nil && publicPEM == nil {
+		return nil, fmt.Errorf("A private or public key is required for %s", keyConfig.Algorithm)
+	}
+
+	switch key.Method.(type) {
+	case *jwt.SigningMethodRSA:
+		if privatePEM != nil {
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = privateKey
+			key.verifyKey = &privateKey.PublicKey
+		} else {
+			key.verifyKey, err = jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+		}
+	case *jwt.SigningMethodECDSA:
+		if privatePEM != nil {
+			privateKey, err := jwt.ParseECPrivateKeyFromPEM(privatePEM)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey = privateKey
+			key.verifyKey = &privateKey.PublicKey
+		} else {
+			key.verifyKey, err = jwt.ParseECPublicKeyFromPEM(publicPEM)
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported algorithm %q", keyConfig.Algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func readPEM(inline string, file string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if file != "" {
+		return ioutil.ReadFile(file)
+	}
+
+	return nil, nil
+}
+
+// Sign signs claims with the active key and records its kid in the header.
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.Method, claims)
+	token.Header["kid"] = k.active.ID
+
+	return token.SignedString(k.active.signKey)
+}
+
+// Parse verifies tokenString with the key named by its kid header and
+// decodes it into claims.
+func (k *KeySet) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("Unknown signing key %q", kid)
+		}
+
+		// never let the token pick a different algorithm than the key's
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("Unexpected signing method %q", token.Method.Alg())
+		}
+
+		return key.verifyKey, nil
+	})
+}
+
+// JWKS returns the public half of every asymmetric key. HMAC secrets are
+// never published.
+func (k *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+
+	for _, key := range k.keys {
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+
+		switch publicKey := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = encodeBigInt(publicKey.N, 0)
+			jwk.E = encodeBigInt(big.NewInt(int64(publicKey.E)), 0)
+		case *ecdsa.PublicKey:
+			size := (publicKey.Curve.Params().BitSize + 7) / 8
+			jwk.KeyType = "EC"
+			jwk.Curve = publicKey.Curve.Params().Name
+			jwk.X = encodeBigInt(publicKey.X, size)
+			jwk.Y = encodeBigInt(publicKey.Y, size)
+		default:
+			continue
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].KeyID < jwks.Keys[j].KeyID })
+
+	return jwks
+}
+
+// encodeBigInt base64url encodes n, left padding it with zeros to size bytes.
+func encodeBigInt(n *big.Int, size int) string {
+	b := n.Bytes()
+	if len(b) < size {
+		b = append(make([]byte, size-len(b)), b...)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func (api *API) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, http.StatusOK, api.keys.JWKS())
+}
@@ -0,0 +1,104 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/router"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Signing keys", func() {
+	var keySet *handlers.KeySet
+
+	BeforeEach(func() {
+		keySet, _ = handlers.LoadKeys()
+	})
+
+	Describe("Sign", func() {
+		It("should put the active kid in the header", func() {
+			signed, _ := keySet.Sign(jwt.StandardClaims{Subject: "1"})
+			token, _ := keySet.Parse(signed, &jwt.StandardClaims{})
+			Expect(token.Header["kid"]).To(Equal("test-rs256"))
+			Expect(token.Method.Alg()).To(Equal("RS256"))
+		})
+	})
+
+	Describe("Parse", func() {
+		It("should accept tokens signed by any configured key", func() {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{Subject: "1"})
+			token.Header["kid"] = "test-hs256"
+			signed, _ := token.SignedString([]byte("secret"))
+
+			parsed, err := keySet.Parse(signed, &jwt.StandardClaims{})
+			Expect(err).To(BeNil())
+			Expect(parsed.Valid).To(BeTrue())
+		})
+
+		It("should reject tokens with an unknown kid", func() {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{Subject: "1"})
+			token.Header["kid"] = "unknown"
+			signed, _ := token.SignedString([]byte("secret"))
+
+			_, err := keySet.Parse(signed, &jwt.StandardClaims{})
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("should reject tokens using a different algorithm than their key", func() {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{Subject: "1"})
+			token.Header["kid"] = "test-rs256"
+			signed, _ := token.SignedString([]byte("secret"))
+
+			_, err := keySet.Parse(signed, &jwt.StandardClaims{})
+			Expect(err).ToNot(BeNil())
+		})
+	})
+})
+
+var _ = Describe("JWKS API", func() {
+	var (
+		server  *httptest.Server
+		jwksURL string
+		res     *http.Response
+		data    []byte
+		jwks    handlers.JWKS
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		jwksURL = fmt.Sprintf("%s/.well-known/jwks.json", server.URL)
+		res, data, _ = Request("GET", jwksURL, "", nil)
+		json.Unmarshal(data, &jwks)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("GetJWKS endpoint", func() {
+		It("should return status code 200 without a token", func() {
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		It("should publish the asymmetric keys", func() {
+			Expect(len(jwks.Keys)).To(Equal(2))
+			Expect(jwks.Keys[0].KeyID).To(Equal("test-es256"))
+			Expect(jwks.Keys[0].KeyType).To(Equal("EC"))
+			Expect(jwks.Keys[0].Curve).To(Equal("P-256"))
+			Expect(jwks.Keys[1].KeyID).To(Equal("test-rs256"))
+			Expect(jwks.Keys[1].KeyType).To(Equal("RSA"))
+			Expect(jwks.Keys[1].E).To(Equal("AQAB"))
+		})
+
+		It("should never publish hmac secrets", func() {
+			Expect(strings.Contains(string(data), "test-hs256")).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+// Me is the current user along with their member profile, if they have one.
+type Me struct {
+	models.User
+	Member *models.Member `json:"member"`
+}
+
+func (api *API) GetMe(w http.ResponseWriter, r *http.Request) {
+	claims, ok := GetClaims(r)
+	if !ok {
+		WriteJSON(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	user, err := api.store.Users.Get(r.Context(), claims.UserID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	me := Me{User: *user}
+	me.Member, err = api.store.Members.GetByUserID(r.Context(), user.UserID)
+	if err != nil && err != sql.ErrNoRows {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, me)
+}
@@ -0,0 +1,70 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Me API", func() {
+	var (
+		server *httptest.Server
+		meURL  string
+		res    *http.Response
+		data   []byte
+		token  string
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		token, _ = RequestToken(server.URL)
+		meURL = fmt.Sprintf("%s%s/me", server.URL, router.V1URLBase)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("GetMe endpoint", func() {
+		var me handlers.Me
+
+		Describe("Successful GET", func() {
+			BeforeEach(func() {
+				res, data, _ = Request("GET", meURL, token, nil)
+				json.Unmarshal(data, &me)
+			})
+
+			It("should return status code 200", func() {
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+			})
+
+			It("should contain the current user", func() {
+				Expect(me.Email).To(Equal("lukas.hambsch@gmail.com"))
+			})
+
+			It("should contain the user's roles", func() {
+				Expect(len(me.Roles)).To(Equal(1))
+				Expect(me.Roles[0].RoleName).To(Equal(models.AdminRole))
+			})
+
+			It("should contain the member profile", func() {
+				Expect(me.Member).ToNot(BeNil())
+				Expect(me.Member.UserID).To(Equal(me.UserID))
+			})
+		})
+
+		Describe("Unsuccessful GET", func() {
+			It("should return status code 401 without a token", func() {
+				res, _, _ = Request("GET", meURL, "", nil)
+				Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+		})
+	})
+})
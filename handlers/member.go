@@ -1,9 +1,7 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
@@ -11,7 +9,6 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store/datastore"
 )
 
 const MemberID = "member_id"
@@ -23,62 +20,115 @@ var memberFields map[string]string = map[string]string{
 	"address_id": "int",
 	"first_name": "string",
 	"last_name":  "string",
+	"deleted_on": "date",
 }
 
-func GetMember(w http.ResponseWriter, r *http.Request) {
+// memberPatchFields are the fields a PATCH can change, and whether they can be null.
+var memberPatchFields = map[string]bool{
+	"user_id":    false,
+	"image_id":   true,
+	"address_id": true,
+	"first_name": false,
+	"last_name":  false,
+}
+
+func (api *API) GetMember(w http.ResponseWriter, r *http.Request) {
 	memberID, message := GetID(w, r, MemberID)
 	if message != nil {
 		return
 	}
 
-	member, err := datastore.GetMember(memberID)
+	scope, err := api.scopeFor(r)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: "Not Found"})
-		} else {
-			WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
-		}
+		WriteError(w, r, err)
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, member)
+	includes, err := ParseIncludes(r.URL.Query(), memberIncludes)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+		return
+	}
+
+	member, err := api.store.Members.Get(r.Context(), scope, memberID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	setETag(w, member.Version)
+	if len(includes) == 0 && notModified(w, r) {
+		return
+	}
+
+	members := []models.Member{*member}
+	err = api.includeMembers(r.Context(), scope, members, includes)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, &members[0])
 }
 
-func GetMembers(w http.ResponseWriter, r *http.Request) {
-	var statement string
+func (api *API) GetMembers(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
+	scope, err := api.scopeFor(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	includes, err := ParseIncludes(query, memberIncludes)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+		return
+	}
+
 	if _, ok := query["email"]; ok {
-		members, err := datastore.GetMemberByEmail(query["email"][0])
+		member, err := api.store.Members.GetByEmail(r.Context(), scope, query["email"][0])
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		members := []models.Member{*member}
+		err = api.includeMembers(r.Context(), scope, members, includes)
 		if err != nil {
-			WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: "Error getting member."})
+			WriteError(w, r, err)
 			return
 		}
-		WriteJSON(w, http.StatusOK, members)
+		WriteJSON(w, http.StatusOK, &members[0])
 	} else {
-		where, err := BuildWhere(memberFields, query)
+		statement, err := BuildQuery(memberFields, MemberID, query)
 		if err != nil {
-			WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: err.Error()})
+			WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
 			return
 		}
 
-		sort, err := BuildSort(memberFields, query)
+		members, err := api.store.Members.List(r.Context(), scope, statement)
 		if err != nil {
-			WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: err.Error()})
+			WriteServerError(w, r, err, "Error getting member list.")
 			return
 		}
 
-		statement = fmt.Sprintf("%s %s", where, sort)
-		members, err := datastore.GetMemberList(statement)
+		err = api.includeMembers(r.Context(), scope, members, includes)
 		if err != nil {
-			WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: "Error getting member list."})
+			WriteError(w, r, err)
 			return
 		}
 
-		WriteJSON(w, http.StatusOK, members)
+		count, err := api.store.Members.Count(r.Context(), scope, statement)
+		if err != nil {
+			WriteServerError(w, r, err, "Error getting member count.")
+			return
+		}
+
+		WritePage(w, r, statement, *count, members)
 	}
 }
 
-func PostMember(w http.ResponseWriter, r *http.Request) {
+func (api *API) PostMember(w http.ResponseWriter, r *http.Request) {
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body.Close()
 
@@ -89,16 +139,27 @@ func PostMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	created, err := datastore.CreateMember(*member)
+	scope, err := api.writeScopeFor(r)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
+		return
+	}
+
+	// members can only create a member for themselves
+	if !seesAll(scope) {
+		member.UserID = scope.UserID
+	}
+
+	created, err := api.store.Members.Create(r.Context(), *member)
+	if err != nil {
+		WriteError(w, r, err)
 		return
 	}
 
 	WriteJSON(w, http.StatusCreated, created)
 }
 
-func PutMember(w http.ResponseWriter, r *http.Request) {
+func (api *API) PutMember(w http.ResponseWriter, r *http.Request) {
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body.Close()
 
@@ -108,6 +169,23 @@ func PutMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	scope, err := api.writeScopeFor(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	_, err = api.store.Members.Get(r.Context(), scope, memberID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
 	member := &models.Member{}
 	err = json.Unmarshal(body, member)
 	if err != nil {
@@ -115,27 +193,101 @@ func PutMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updated, err := datastore.UpdateMember(memberID, *member)
+	// members can't hand themselves over to another user
+	if !seesAll(scope) {
+		member.UserID = scope.UserID
+	}
+
+	member.Version = version
+	updated, err := api.store.Members.Update(r.Context(), memberID, *member)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 
+	setETag(w, updated.Version)
 	WriteJSON(w, http.StatusOK, updated)
 }
 
-func DeleteMember(w http.ResponseWriter, r *http.Request) {
+func (api *API) PatchMember(w http.ResponseWriter, r *http.Request) {
+	memberID, err := strconv.ParseInt(mux.Vars(r)[MemberID], 10, 64)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidMemberID})
+		return
+	}
+
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	scope, err := api.writeScopeFor(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	_, err = api.store.Members.Get(r.Context(), scope, memberID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	member := &models.Member{}
+	fields, ok := readPatch(w, r, memberPatchFields, member)
+	if !ok {
+		return
+	}
+
+	if !seesAll(scope) {
+		member.UserID = scope.UserID
+	}
+
+	member.Version = version
+	patched, err := api.store.Members.Patch(r.Context(), memberID, *member, fields)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	setETag(w, patched.Version)
+	WriteJSON(w, http.StatusOK, patched)
+}
+
+func (api *API) DeleteMember(w http.ResponseWriter, r *http.Request) {
+	memberID, err := strconv.ParseInt(mux.Vars(r)[MemberID], 10, 64)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidMemberID})
+		return
+	}
+
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	err = api.store.Members.Delete(r.Context(), memberID, version)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *API) RestoreMember(w http.ResponseWriter, r *http.Request) {
 	memberID, err := strconv.ParseInt(mux.Vars(r)[MemberID], 10, 64)
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidMemberID})
 		return
 	}
 
-	err = datastore.DeleteMember(memberID)
+	restored, err := api.store.Members.Restore(r.Context(), memberID)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, nil)
+	setETag(w, restored.Version)
+	WriteJSON(w, http.StatusOK, restored)
 }
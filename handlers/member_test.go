@@ -9,7 +9,6 @@ import (
 	"github.com/lukashambsch/anygym.api/handlers"
 	"github.com/lukashambsch/anygym.api/models"
 	"github.com/lukashambsch/anygym.api/router"
-	"github.com/lukashambsch/anygym.api/store"
 	"github.com/lukashambsch/anygym.api/store/datastore"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -26,7 +25,7 @@ var _ = Describe("Member API", func() {
 	)
 
 	BeforeEach(func() {
-		server = httptest.NewServer(router.Load())
+		server = httptest.NewServer(router.Load(testStore, testKeys))
 		token, _ = RequestToken(server.URL)
 		memberURL = fmt.Sprintf("%s%s/members", server.URL, router.V1URLBase)
 	})
@@ -76,6 +75,11 @@ var _ = Describe("Member API", func() {
 				Expect(member.MemberID).To(Equal(int64(1)))
 			})
 
+			It("should return status code 404 for an unknown email", func() {
+				res, _, _ = Request("GET", fmt.Sprintf("%s?email=nobody@email.com", memberURL), token, nil)
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+			})
+
 			It("should return no members with a valid field but no matches", func() {
 				res, data, _ = Request("GET", fmt.Sprintf("%s?member_id=10", memberURL), token, nil)
 				json.Unmarshal(data, &members)
@@ -106,21 +110,21 @@ var _ = Describe("Member API", func() {
 			It("should return an error with an invalid field as query param", func() {
 				res, data, _ = Request("GET", fmt.Sprintf("%s?invalid=test", memberURL), token, nil)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal("Invalid field in query params."))
 			})
 
 			It("should return an error with an invalid field in order_by", func() {
 				res, data, _ = Request("GET", fmt.Sprintf("%s?order_by=invalid", memberURL), token, nil)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal("Invalid order_by field."))
 			})
 
 			It("should return an error with an invalid value for sort_order", func() {
 				res, data, _ = Request("GET", fmt.Sprintf("%s?order_by=member_id&sort_order=random", memberURL), token, nil)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal("sort_order must be either 'asc', 'desc', or ''"))
 			})
 		})
@@ -180,15 +184,15 @@ var _ = Describe("Member API", func() {
 
 		Describe("Successful POST", func() {
 			BeforeEach(func() {
-				user, _ = datastore.CreateUser(models.User{Email: "test@email.com"})
+				user, _ = testStore.Users.Create(ctx, models.User{Email: "test@email.com"})
 				payload = []byte(fmt.Sprintf(`{"user_id": %d, "first_name": "Testing", "last_name": "Post"}`, user.UserID))
 				res, data, _ = Request("POST", memberURL, token, payload)
 				json.Unmarshal(data, &member)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteMember(member.MemberID)
-				datastore.DeleteUser(user.UserID)
+				testStore.Members.Delete(ctx, member.MemberID, 0)
+				testStore.Users.Delete(ctx, user.UserID, 0)
 			})
 
 			It("should return status code 201", func() {
@@ -216,13 +220,14 @@ var _ = Describe("Member API", func() {
 				})
 			})
 
-			Describe("Internal Server Error", func() {
-				It("should return status code 500 with a message", func() {
+			Describe("Unprocessable Entity", func() {
+				It("should return status code 422 with the field", func() {
 					payload = []byte(`{"user_id": 1}`)
 					res, data, _ = Request("POST", memberURL, token, payload)
 					json.Unmarshal(data, &errRes)
-					Expect(res.StatusCode).To(Equal(http.StatusInternalServerError))
-					Expect(errRes.Message).ToNot(BeEmpty())
+					Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+					Expect(errRes.Code).To(Equal(handlers.InvalidValueCode))
+					Expect(errRes.Field).To(Equal("first_name"))
 				})
 			})
 		})
@@ -242,7 +247,7 @@ var _ = Describe("Member API", func() {
 			})
 
 			AfterEach(func() {
-				datastore.UpdateMember(memberID, models.Member{UserID: int64(1), FirstName: "McKenzie", LastName: "Hambsch"})
+				testStore.Members.Update(ctx, memberID, models.Member{UserID: int64(1), FirstName: "McKenzie", LastName: "Hambsch"})
 			})
 
 			It("should return status code 200", func() {
@@ -254,7 +259,7 @@ var _ = Describe("Member API", func() {
 			//})
 
 			It("should save the updated member", func() {
-				updated, _ := datastore.GetMember(memberID)
+				updated, _ := testStore.Members.Get(ctx, datastore.Unscoped, memberID)
 				Expect(updated.FirstName).To(Equal("Kenzie"))
 			})
 		})
@@ -276,44 +281,54 @@ var _ = Describe("Member API", func() {
 				Expect(errRes.Message).To(Equal(handlers.InvalidMemberID))
 			})
 
-			It("should return status code 500 with a message", func() {
+			It("should return status code 404 for a missing member", func() {
 				res, data, _ = Request("PUT", fmt.Sprintf("%s/5000", memberURL), token, payload)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusInternalServerError))
-				Expect(errRes.Message).ToNot(BeEmpty())
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(errRes.Code).To(Equal(handlers.NotFoundCode))
 			})
 		})
 	})
 
 	Describe("DeleteMember endpoint", func() {
-		var memberID int64 = 111
-		var user *models.User
+		var memberID int64
 
 		Describe("Successful DELETE", func() {
 			BeforeEach(func() {
-				user, _ = datastore.CreateUser(models.User{Email: "testing@gmail.com"})
-				store.DB.Exec(
-					"INSERT INTO members (member_id, user_id, first_name, last_name) VALUES ($1, $2, $3, $4)",
-					memberID,
-					user.UserID,
-					"Lukas",
-					"Hambsch",
-				)
+				user, _ := testStore.Users.Create(ctx, models.User{Email: "testing@gmail.com"})
+				member, _ := testStore.Members.Create(ctx, models.Member{UserID: user.UserID, FirstName: "Lukas", LastName: "Hambsch"})
+				memberID = member.MemberID
 
 				res, _, _ = Request("DELETE", fmt.Sprintf("%s/%d", memberURL, memberID), token, nil)
 			})
 
-			AfterEach(func() {
-				datastore.DeleteUser(user.UserID)
+			It("should return status code 204", func() {
+				Expect(res.StatusCode).To(Equal(http.StatusNoContent))
 			})
 
-			It("should return status code 200", func() {
+			It("should delete the member", func() {
+				_, err := testStore.Members.Get(ctx, datastore.Unscoped, memberID)
+				Expect(err).ToNot(BeNil())
+			})
+
+			It("should leave the member out of the list", func() {
+				var members []models.Member
+				_, data, _ = Request("GET", memberURL, token, nil)
+				json.Unmarshal(data, &members)
+				Expect(len(members)).To(Equal(2))
+			})
+
+			It("should show the member to admins asking for deleted members", func() {
+				var member models.Member
+				res, data, _ = Request("GET", fmt.Sprintf("%s/%d?include_deleted=true", memberURL, memberID), token, nil)
+				json.Unmarshal(data, &member)
 				Expect(res.StatusCode).To(Equal(http.StatusOK))
+				Expect(member.DeletedOn).NotTo(BeNil())
 			})
 
-			It("should delete the member", func() {
-				_, err := datastore.GetMember(memberID)
-				Expect(err).ToNot(BeNil())
+			It("should return status code 404 when deleted again", func() {
+				res, _, _ = Request("DELETE", fmt.Sprintf("%s/%d", memberURL, memberID), token, nil)
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
 			})
 		})
 
@@ -326,6 +341,34 @@ var _ = Describe("Member API", func() {
 				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal(handlers.InvalidMemberID))
 			})
+
+			It("should return status code 404 for a missing id", func() {
+				res, data, _ = Request("DELETE", fmt.Sprintf("%s/5000", memberURL), token, nil)
+				json.Unmarshal(data, &errRes)
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(errRes.Code).To(Equal(handlers.NotFoundCode))
+			})
+		})
+	})
+
+	Describe("RestoreMember endpoint", func() {
+		It("should bring a deleted member back", func() {
+			var restored models.Member
+			testStore.Members.Delete(ctx, 2, 0)
+
+			res, data, _ = Request("POST", fmt.Sprintf("%s/2/restore", memberURL), token, nil)
+			json.Unmarshal(data, &restored)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(restored.DeletedOn).To(BeNil())
+			Expect(res.Header.Get("ETag")).To(Equal(`"3"`))
+
+			_, err := testStore.Members.Get(ctx, datastore.Unscoped, 2)
+			Expect(err).To(BeNil())
+		})
+
+		It("should return status code 404 for a missing id", func() {
+			res, _, _ = Request("POST", fmt.Sprintf("%s/5000/restore", memberURL), token, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
 		})
 	})
 })
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/lukashambsch/anygym.api/config"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+const (
+	InvalidLimit  = "limit must be a number greater than 0."
+	InvalidOffset = "offset must be a number, 0 or greater."
+	InvalidCursor = "Invalid cursor."
+	MixedPaging   = "Use either offset or after/before, not both."
+)
+
+// pageParams aren't fields, BuildFilters leaves them alone.
+var pageParams = map[string]bool{
+	"limit":  true,
+	"offset": true,
+	"after":  true,
+	"before": true,
+}
+
+// defaultPageSize is used when the request has no limit, maxPageSize caps
+// the limit a request can ask for.
+var (
+	defaultPageSize = loadPageSize("pagination.default_limit", 50)
+	maxPageSize     = loadPageSize("pagination.max_limit", 200)
+)
+
+func loadPageSize(key string, size int) int {
+	if config.C.IsSet(key) {
+		return config.C.GetInt(key)
+	}
+
+	return size
+}
+
+// cursor is the position in a list that an after or before param points
+// at: the sort values of a row. It's opaque to clients.
+type cursor struct {
+	Fields []string      `json:"f"`
+	Values []interface{} `json:"v"`
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &cursor{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(c)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Fields) != len(c.Values) {
+		return nil, fmt.Errorf(InvalidCursor)
+	}
+
+	return c, nil
+}
+
+// rowCursor reads the sort values out of row's json.
+func rowCursor(row interface{}, sort []datastore.Sort) (*cursor, error) {
+	data, err := json.Marshal(row)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&values)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &cursor{}
+	for _, s := range sort {
+		value, ok := values[s.Field]
+		if !ok {
+			return nil, fmt.Errorf("Can't page by %s.", s.Field)
+		}
+		c.Fields = append(c.Fields, s.Field)
+		c.Values = append(c.Values, value)
+	}
+
+	return c, nil
+}
+
+// BuildPage reads the limit and either offset or an after/before cursor into
+// q. key is the list's primary key, it's added to the sort so every row has
+// a unique position for cursors to point at.
+func BuildPage(q *datastore.Query, key string, params url.Values) error {
+	limit := defaultPageSize
+	if v := params.Get("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return fmt.Errorf(InvalidLimit)
+		}
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	// one more row than the page tells WritePage whether there's another
+	q.Limit = limit + 1
+
+	if len(q.Sort) == 0 || q.Sort[len(q.Sort)-1].Field != key {
+		descending := len(q.Sort) > 0 && q.Sort[0].Descending
+		q.Sort = append(q.Sort, datastore.Sort{Field: key, Descending: descending})
+	}
+
+	after, before := params.Get("after"), params.Get("before")
+	if v := params.Get("offset"); v != "" {
+		if after != "" || before != "" {
+			return fmt.Errorf(MixedPaging)
+		}
+
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return fmt.Errorf(InvalidOffset)
+		}
+		q.Offset = offset
+		return nil
+	}
+
+	if after == "" && before == "" {
+		return nil
+	}
+	if after != "" && before != "" {
+		return fmt.Errorf(MixedPaging)
+	}
+
+	position := after
+	if before != "" {
+		position = before
+		q.Backward = true
+	}
+
+	c, err := decodeCursor(position)
+	if err != nil {
+		return fmt.Errorf(InvalidCursor)
+	}
+
+	// a cursor from a list with another sort doesn't point anywhere
+	if len(c.Fields) != len(q.Sort) {
+		return fmt.Errorf(InvalidCursor)
+	}
+	for i, s := range q.Sort {
+		if c.Fields[i] != s.Field {
+			return fmt.Errorf(InvalidCursor)
+		}
+	}
+	q.Cursor = c.Values
+
+	return nil
+}
+
+// WritePage writes a page of rows, the slice a list query built by
+// BuildQuery returned. The total count of rows matching the filters goes in
+// X-Total-Count and links to the next and previous pages in Link.
+func WritePage(w http.ResponseWriter, r *http.Request, q datastore.Query, total int, rows interface{}) {
+	page := reflect.ValueOf(rows)
+	limit := q.Limit - 1
+
+	more := page.Len() > limit
+	if more {
+		page = page.Slice(0, limit)
+	}
+	if q.Backward {
+		swap := reflect.Swapper(page.Interface())
+		for i, j := 0, page.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	links := map[string]url.Values{}
+	params := r.URL.Query()
+	params.Set("limit", strconv.Itoa(limit))
+
+	if params.Get("offset") != "" {
+		if more {
+			links["next"] = withParams(params, "offset", strconv.Itoa(q.Offset+limit))
+		}
+		if q.Offset > 0 {
+			prev := q.Offset - limit
+			if prev < 0 {
+				prev = 0
+			}
+			links["prev"] = withParams(params, "offset", strconv.Itoa(prev))
+		}
+	} else if page.Len() > 0 {
+		first, err := rowCursor(page.Index(0).Interface(), q.Sort)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		last, err := rowCursor(page.Index(page.Len()-1).Interface(), q.Sort)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+
+		if q.Backward {
+			// there's always the page we came from after this one
+			links["next"] = withParams(params, "after", last.encode())
+			if more {
+				links["prev"] = withParams(params, "before", first.encode())
+			}
+		} else {
+			if more {
+				links["next"] = withParams(params, "after", last.encode())
+			}
+			if q.Cursor != nil {
+				links["prev"] = withParams(params, "before", first.encode())
+			}
+		}
+	}
+
+	var header []string
+	for _, rel := range []string{"next", "prev"} {
+		if params, ok := links[rel]; ok {
+			header = append(header, fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, params.Encode(), rel))
+		}
+	}
+	if len(header) > 0 {
+		w.Header().Set("Link", strings.Join(header, ", "))
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	WriteJSON(w, http.StatusOK, page.Interface())
+}
+
+// withParams copies params, replacing the paging ones with key=value.
+func withParams(params url.Values, key string, value string) url.Values {
+	copied := url.Values{}
+	for k, v := range params {
+		if k != "offset" && k != "after" && k != "before" {
+			copied[k] = v
+		}
+	}
+	copied.Set(key, value)
+
+	return copied
+}
@@ -0,0 +1,173 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// pageLink returns the url of the rel link in res's Link header.
+func pageLink(res *http.Response, rel string) string {
+	pattern := regexp.MustCompile(fmt.Sprintf(`<([^>]+)>; rel="%s"`, rel))
+	match := pattern.FindStringSubmatch(res.Header.Get("Link"))
+	if match == nil {
+		return ""
+	}
+
+	return match[1]
+}
+
+func statusIDs(statuses []models.Status) []int64 {
+	var ids []int64
+	for _, status := range statuses {
+		ids = append(ids, status.StatusID)
+	}
+
+	return ids
+}
+
+var _ = Describe("Pagination", func() {
+	var (
+		server    *httptest.Server
+		statusURL string
+		res       *http.Response
+		data      []byte
+		token     string
+		statuses  []models.Status
+		errRes    handlers.APIErrorMessage
+	)
+
+	get := func(url string) {
+		statuses = nil
+		res, data, _ = Request("GET", url, token, nil)
+		json.Unmarshal(data, &statuses)
+	}
+
+	follow := func(rel string) {
+		get(server.URL + pageLink(res, rel))
+	}
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		statusURL = fmt.Sprintf("%s%s/statuses", server.URL, router.V1URLBase)
+		token, _ = RequestToken(server.URL)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Cursors", func() {
+		BeforeEach(func() {
+			get(fmt.Sprintf("%s?limit=2", statusURL))
+		})
+
+		It("should return the first page with the total count", func() {
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(statusIDs(statuses)).To(Equal([]int64{1, 2}))
+			Expect(res.Header.Get("X-Total-Count")).To(Equal("6"))
+		})
+
+		It("should let browsers read the count and links", func() {
+			exposed := res.Header.Get("Access-Control-Expose-Headers")
+			Expect(exposed).To(ContainSubstring("X-Total-Count"))
+			Expect(exposed).To(ContainSubstring("Link"))
+		})
+
+		It("should only link to the next page from the first", func() {
+			Expect(pageLink(res, "next")).To(ContainSubstring("after="))
+			Expect(pageLink(res, "prev")).To(BeEmpty())
+		})
+
+		It("should follow the next link", func() {
+			follow("next")
+			Expect(statusIDs(statuses)).To(Equal([]int64{3, 4}))
+			Expect(pageLink(res, "prev")).To(ContainSubstring("before="))
+		})
+
+		It("should follow the prev link back", func() {
+			follow("next")
+			follow("next")
+			Expect(statusIDs(statuses)).To(Equal([]int64{5, 6}))
+			Expect(pageLink(res, "next")).To(BeEmpty())
+
+			follow("prev")
+			Expect(statusIDs(statuses)).To(Equal([]int64{3, 4}))
+			follow("prev")
+			Expect(statusIDs(statuses)).To(Equal([]int64{1, 2}))
+			Expect(pageLink(res, "prev")).To(BeEmpty())
+		})
+
+		It("should keep the sort and filters", func() {
+			get(fmt.Sprintf("%s?limit=4&order_by=status_id&sort_order=desc", statusURL))
+			Expect(statusIDs(statuses)).To(Equal([]int64{6, 5, 4, 3}))
+			follow("next")
+			Expect(statusIDs(statuses)).To(Equal([]int64{2, 1}))
+
+			get(fmt.Sprintf("%s?limit=1&status_name=Denied", statusURL))
+			Expect(res.Header.Get("X-Total-Count")).To(Equal("2"))
+			follow("next")
+			Expect(len(statuses)).To(Equal(1))
+			Expect(pageLink(res, "next")).To(BeEmpty())
+		})
+
+		It("should not accept a cursor from another sort", func() {
+			next := pageLink(res, "next")
+			res, data, _ = Request("GET", server.URL+next+"&order_by=status_name", token, nil)
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(errRes.Message).To(Equal(handlers.InvalidCursor))
+		})
+
+		It("should not accept a made up cursor", func() {
+			res, data, _ = Request("GET", fmt.Sprintf("%s?after=invalid", statusURL), token, nil)
+			json.Unmarshal(data, &errRes)
+			Expect(errRes.Message).To(Equal(handlers.InvalidCursor))
+		})
+	})
+
+	Describe("Offsets", func() {
+		BeforeEach(func() {
+			get(fmt.Sprintf("%s?limit=2&offset=2", statusURL))
+		})
+
+		It("should skip offset rows", func() {
+			Expect(statusIDs(statuses)).To(Equal([]int64{3, 4}))
+			Expect(res.Header.Get("X-Total-Count")).To(Equal("6"))
+		})
+
+		It("should link to the pages around it", func() {
+			Expect(pageLink(res, "next")).To(ContainSubstring("offset=4"))
+			Expect(pageLink(res, "prev")).To(ContainSubstring("offset=0"))
+		})
+
+		It("should not mix offsets and cursors", func() {
+			res, data, _ = Request("GET", fmt.Sprintf("%s?offset=2&after=abc", statusURL), token, nil)
+			json.Unmarshal(data, &errRes)
+			Expect(errRes.Message).To(Equal(handlers.MixedPaging))
+		})
+	})
+
+	Describe("Limits", func() {
+		It("should cap the page size", func() {
+			get(fmt.Sprintf("%s?limit=1000&offset=1", statusURL))
+			Expect(len(statuses)).To(Equal(5))
+			Expect(pageLink(res, "prev")).To(ContainSubstring("limit=100"))
+		})
+
+		It("should return an error for an invalid limit", func() {
+			res, data, _ = Request("GET", fmt.Sprintf("%s?limit=0", statusURL), token, nil)
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(errRes.Message).To(Equal(handlers.InvalidLimit))
+		})
+	})
+})
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/config"
+	"github.com/lukashambsch/anygym.api/mailer"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store"
+)
+
+const passwordResetLifetime = time.Hour
+
+const InvalidResetToken string = "Invalid or expired reset token."
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ForgotPassword emails a reset link to the user. It responds the same way
+// whether or not the email belongs to an account so it can't be used to find
+// out who is registered.
+func (api *API) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+
+	forgot := ForgotPasswordRequest{}
+	err := json.Unmarshal(body, &forgot)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+		return
+	}
+
+	user, err := api.store.Users.GetByEmail(r.Context(), forgot.Email)
+	if err == nil {
+		err = api.sendPasswordReset(r.Context(), user)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Password reset for %q failed: %s", forgot.Email, err)
+	}
+
+	WriteJSON(w, http.StatusAccepted, nil)
+}
+
+func (api *API) sendPasswordReset(ctx context.Context, user *models.User) error {
+	token, err := newRandomToken()
+	if err != nil {
+		return err
+	}
+
+	_, err = api.store.PasswordResets.Create(ctx, models.PasswordReset{
+		UserID:    user.UserID,
+		TokenHash: hashToken(token),
+		ExpiresOn: time.Now().Add(passwordResetLifetime),
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendMail(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Use the link below to choose a new password. It expires in %v.\n\n%s?token=%s\n\nIf you didn't ask to reset your password you can ignore this email.\n",
+			passwordResetLifetime,
+			config.C.GetString("mail.reset_url"),
+			token,
+		),
+	})
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword. Each
+// token can only be used once, and every open session for the user is
+// revoked.
+func (api *API) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+
+	reset := ResetPasswordRequest{}
+	err := json.Unmarshal(body, &reset)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+		return
+	}
+
+	if reset.Password == "" {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: "password is required."})
+		return
+	}
+
+	// the token is only used up if the password is changed and the old
+	// sessions are revoked
+	err = api.store.Transaction(r.Context(), func(tx *store.Store) error {
+		passwordReset, err := tx.PasswordResets.Consume(r.Context(), hashToken(reset.Token))
+		if err != nil {
+			return err
+		}
+
+		err = tx.Users.UpdatePassword(r.Context(), passwordReset.UserID, reset.Password)
+		if err != nil {
+			return err
+		}
+
+		return tx.Sessions.RevokeUser(r.Context(), passwordReset.UserID)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidResetToken})
+		} else {
+			WriteError(w, r, err)
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, nil)
+}
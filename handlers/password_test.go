@@ -0,0 +1,126 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/mailer"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// mailedToken pulls the token out of the link in the last email sent to
+// email.
+func mailedToken(email string) string {
+	msg, _ := mailer.Default.(*mailer.MemoryMailer).Last(email)
+	parts := strings.SplitN(msg.Body, "?token=", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+
+	return strings.Fields(parts[1])[0]
+}
+
+var _ = Describe("Password API", func() {
+	var (
+		server    *httptest.Server
+		forgotURL string
+		resetURL  string
+		res       *http.Response
+		data      []byte
+		user      *models.User
+		errRes    handlers.APIErrorMessage
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		forgotURL = fmt.Sprintf("%s%s/password/forgot", server.URL, router.V1URLBase)
+		resetURL = fmt.Sprintf("%s%s/password/reset", server.URL, router.V1URLBase)
+		now := time.Now()
+		user, _ = testStore.Users.Create(ctx, models.User{Email: "forgetful@email.com", Password: "testing", EmailVerifiedOn: &now})
+		mailer.Default.(*mailer.MemoryMailer).Reset()
+	})
+
+	AfterEach(func() {
+		testStore.Users.Delete(ctx, user.UserID, 0)
+		server.Close()
+	})
+
+	Describe("ForgotPassword endpoint", func() {
+		It("should return status code 202 and send a reset email", func() {
+			res, _, _ = Request("POST", forgotURL, "", []byte(`{"email": "forgetful@email.com"}`))
+			Expect(res.StatusCode).To(Equal(http.StatusAccepted))
+			Expect(mailedToken("forgetful@email.com")).ToNot(BeEmpty())
+		})
+
+		It("should return status code 202 for an unknown email without sending anything", func() {
+			res, _, _ = Request("POST", forgotURL, "", []byte(`{"email": "nobody@email.com"}`))
+			Expect(res.StatusCode).To(Equal(http.StatusAccepted))
+			Expect(mailer.Default.(*mailer.MemoryMailer).Messages()).To(BeEmpty())
+		})
+	})
+
+	Describe("ResetPassword endpoint", func() {
+		var (
+			token   string
+			payload []byte
+		)
+
+		BeforeEach(func() {
+			Request("POST", forgotURL, "", []byte(`{"email": "forgetful@email.com"}`))
+			token = mailedToken("forgetful@email.com")
+			payload = []byte(fmt.Sprintf(`{"token": "%s", "password": "newpass"}`, token))
+		})
+
+		Describe("Successful POST", func() {
+			var tokens handlers.Tokens
+
+			BeforeEach(func() {
+				tokens, _ = RequestUserTokens(server.URL, "forgetful@email.com", "testing")
+				res, _, _ = Request("POST", resetURL, "", payload)
+			})
+
+			It("should return status code 200", func() {
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+			})
+
+			It("should change the password", func() {
+				newTokens, _ := RequestUserTokens(server.URL, "forgetful@email.com", "newpass")
+				Expect(newTokens.AccessToken).ToNot(BeEmpty())
+			})
+
+			It("should revoke existing sessions", func() {
+				res, _, _ = Request("GET", fmt.Sprintf("%s%s/me", server.URL, router.V1URLBase), tokens.AccessToken, nil)
+				Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+
+			It("should not accept the same token twice", func() {
+				res, data, _ = Request("POST", resetURL, "", payload)
+				json.Unmarshal(data, &errRes)
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(errRes.Message).To(Equal(handlers.InvalidResetToken))
+			})
+		})
+
+		Describe("Unsuccessful POST", func() {
+			It("should return status code 400 for an unknown token", func() {
+				res, data, _ = Request("POST", resetURL, "", []byte(`{"token": "invalid", "password": "newpass"}`))
+				json.Unmarshal(data, &errRes)
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(errRes.Message).To(Equal(handlers.InvalidResetToken))
+			})
+
+			It("should return status code 400 without a password", func() {
+				res, _, _ = Request("POST", resetURL, "", []byte(fmt.Sprintf(`{"token": "%s"}`, token)))
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+})
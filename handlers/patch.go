@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"sort"
+)
+
+const (
+	NotAPatch            = "A merge patch has to be a JSON object."
+	UnsupportedPatchType = "Send merge patches as application/merge-patch+json."
+)
+
+// readPatch reads the request's RFC 7396 merge patch into record, a pointer
+// to a model, and returns the fields it sets. patchable maps the fields that
+// can be changed to whether they can be set to null. It writes the error
+// response and returns false if the patch can't be applied.
+//
+// None of the patchable fields are objects, so there's nothing to merge
+// below the top level: each field in the patch replaces the stored one.
+func readPatch(w http.ResponseWriter, r *http.Request, patchable map[string]bool, record interface{}) ([]string, bool) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+			WriteJSON(w, http.StatusUnsupportedMediaType, APIErrorMessage{Message: UnsupportedPatchType})
+			return nil, false
+		}
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+
+	var changes map[string]json.RawMessage
+	err := json.Unmarshal(body, &changes)
+	if err != nil || changes == nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: NotAPatch})
+		return nil, false
+	}
+
+	var fields []string
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		nullable, ok := patchable[field]
+		if !ok {
+			WriteJSON(w, http.StatusUnprocessableEntity, APIErrorMessage{
+				Message: fmt.Sprintf("The %s can't be changed.", field),
+				Code:    ReadOnlyCode,
+				Field:   field,
+			})
+			return nil, false
+		}
+		if !nullable && string(changes[field]) == "null" {
+			WriteJSON(w, http.StatusUnprocessableEntity, APIErrorMessage{
+				Message: fmt.Sprintf("Invalid value for %s.", field),
+				Code:    InvalidValueCode,
+				Field:   field,
+			})
+			return nil, false
+		}
+	}
+
+	err = json.Unmarshal(body, record)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+		return nil, false
+	}
+
+	return fields, true
+}
@@ -0,0 +1,135 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Merge patches", func() {
+	var (
+		server    *httptest.Server
+		memberURL string
+		res       *http.Response
+		data      []byte
+		token     string
+		member    models.Member
+		errRes    handlers.APIErrorMessage
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		token, _ = RequestToken(server.URL)
+		memberURL = fmt.Sprintf("%s%s/members/1", server.URL, router.V1URLBase)
+		member = models.Member{}
+		errRes = handlers.APIErrorMessage{}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("Successful PATCH", func() {
+		var before *models.Member
+
+		BeforeEach(func() {
+			before, _ = testStore.Members.Get(ctx, datastore.Unscoped, 1)
+		})
+
+		It("should only change the fields in the patch", func() {
+			res, data, _ = RequestWithHeader("PATCH", memberURL, token, "Content-Type", "application/merge-patch+json", []byte(`{"first_name": "Patched"}`))
+			json.Unmarshal(data, &member)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(member.FirstName).To(Equal("Patched"))
+			Expect(member.LastName).To(Equal(before.LastName))
+			Expect(member.UserID).To(Equal(before.UserID))
+			Expect(member.Version).To(Equal(before.Version + 1))
+			Expect(res.Header.Get("ETag")).To(Equal(handlers.ETag(member.Version)))
+		})
+
+		It("should clear a field set to null", func() {
+			addressID := int64(1)
+			testStore.Members.Patch(ctx, 1, models.Member{AddressID: &addressID}, []string{"address_id"})
+
+			res, data, _ = Request("PATCH", memberURL, token, []byte(`{"address_id": null}`))
+			json.Unmarshal(data, &member)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(member.AddressID).To(BeNil())
+		})
+
+		It("should patch the member at the If-Match version", func() {
+			res, _, _ = RequestWithHeader("PATCH", memberURL, token, "If-Match", handlers.ETag(before.Version), []byte(`{"last_name": "Patched"}`))
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("Unsuccessful PATCH", func() {
+		It("should return status code 400 for a patch that isn't an object", func() {
+			res, data, _ = Request("PATCH", memberURL, token, []byte(`["first_name"]`))
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(errRes.Message).To(Equal(handlers.NotAPatch))
+		})
+
+		It("should return status code 400 for a value of the wrong type", func() {
+			res, _, _ = Request("PATCH", memberURL, token, []byte(`{"first_name": 1}`))
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("should return status code 415 for a patch that isn't JSON", func() {
+			res, _, _ = RequestWithHeader("PATCH", memberURL, token, "Content-Type", "text/plain", []byte(`{"first_name": "Patched"}`))
+			Expect(res.StatusCode).To(Equal(http.StatusUnsupportedMediaType))
+		})
+
+		It("should return status code 422 for a field that can't be changed", func() {
+			res, data, _ = Request("PATCH", memberURL, token, []byte(`{"version": 7}`))
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+			Expect(errRes.Code).To(Equal(handlers.ReadOnlyCode))
+			Expect(errRes.Field).To(Equal("version"))
+		})
+
+		It("should return status code 422 for null in a field that can't be null", func() {
+			res, data, _ = Request("PATCH", memberURL, token, []byte(`{"first_name": null}`))
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+			Expect(errRes.Code).To(Equal(handlers.InvalidValueCode))
+			Expect(errRes.Field).To(Equal("first_name"))
+		})
+
+		It("should return status code 412 if the member changed since", func() {
+			res, _, _ = RequestWithHeader("PATCH", memberURL, token, "If-Match", `"5000"`, []byte(`{"last_name": "Patched"}`))
+			Expect(res.StatusCode).To(Equal(http.StatusPreconditionFailed))
+		})
+
+		It("should return status code 404 for a missing member", func() {
+			url := fmt.Sprintf("%s%s/members/5000", server.URL, router.V1URLBase)
+			res, _, _ = Request("PATCH", url, token, []byte(`{"last_name": "Patched"}`))
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("PATCH on users", func() {
+		It("should change the email and keep the password", func() {
+			var user models.User
+			userURL := fmt.Sprintf("%s%s/users/2", server.URL, router.V1URLBase)
+			before, _ := testStore.Users.Get(ctx, 2)
+
+			res, data, _ = Request("PATCH", userURL, token, []byte(`{"email": "patched@email.com"}`))
+			json.Unmarshal(data, &user)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(user.Email).To(Equal("patched@email.com"))
+
+			after, _ := testStore.Users.Get(ctx, 2)
+			Expect(after.PasswordHash).To(Equal(before.PasswordHash))
+		})
+	})
+})
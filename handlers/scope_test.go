@@ -0,0 +1,136 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Ownership scoping", func() {
+	var (
+		server *httptest.Server
+		res    *http.Response
+		data   []byte
+		user   *models.User
+		member *models.Member
+		visit  *models.Visit
+		token  string
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		now := time.Now()
+		user, _ = testStore.Users.Create(ctx, models.User{Email: "scoped@email.com", Password: "testing", EmailVerifiedOn: &now})
+		testStore.Users.AddRole(ctx, user.UserID, models.MemberRole)
+		member, _ = testStore.Members.Create(ctx, models.Member{FirstName: "Scoped", UserID: user.UserID})
+		visit, _ = testStore.Visits.Create(ctx, models.Visit{MemberID: member.MemberID, GymLocationID: 1, StatusID: 1})
+		tokens, _ := RequestUserTokens(server.URL, "scoped@email.com", "testing")
+		token = tokens.AccessToken
+	})
+
+	AfterEach(func() {
+		testStore.Visits.Delete(ctx, visit.VisitID, 0)
+		testStore.Members.Delete(ctx, member.MemberID, 0)
+		testStore.Users.Delete(ctx, user.UserID, 0)
+		server.Close()
+	})
+
+	Describe("GetVisits endpoint", func() {
+		It("should only return the member's own visits", func() {
+			var visits []models.Visit
+			res, data, _ = Request("GET", fmt.Sprintf("%s%s/visits", server.URL, router.V1URLBase), token, nil)
+			json.Unmarshal(data, &visits)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(len(visits)).To(Equal(1))
+			Expect(visits[0].VisitID).To(Equal(visit.VisitID))
+		})
+	})
+
+	Describe("include_deleted", func() {
+		It("should only show deleted members to admins", func() {
+			var members []models.Member
+			testStore.Members.Delete(ctx, member.MemberID, 0)
+
+			res, data, _ = Request("GET", fmt.Sprintf("%s%s/members?include_deleted=true", server.URL, router.V1URLBase), token, nil)
+			json.Unmarshal(data, &members)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(members).To(BeEmpty())
+		})
+	})
+
+	Describe("GetVisit endpoint", func() {
+		It("should return status code 404 for another member's visit", func() {
+			res, _, _ = Request("GET", fmt.Sprintf("%s%s/visits/1", server.URL, router.V1URLBase), token, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("GetMembers endpoint", func() {
+		It("should only return the member themselves", func() {
+			var members []models.Member
+			res, data, _ = Request("GET", fmt.Sprintf("%s%s/members", server.URL, router.V1URLBase), token, nil)
+			json.Unmarshal(data, &members)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(len(members)).To(Equal(1))
+			Expect(members[0].MemberID).To(Equal(member.MemberID))
+		})
+
+		It("should return status code 404 for another member's email", func() {
+			res, _, _ = Request("GET", fmt.Sprintf("%s%s/members?email=lukas.hambsch@gmail.com", server.URL, router.V1URLBase), token, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("GetMember endpoint", func() {
+		It("should return status code 404 for another member", func() {
+			res, _, _ = Request("GET", fmt.Sprintf("%s%s/members/1", server.URL, router.V1URLBase), token, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("PutMember endpoint", func() {
+		It("should return status code 404 for another member", func() {
+			res, _, _ = Request("PUT", fmt.Sprintf("%s%s/members/1", server.URL, router.V1URLBase), token, []byte(fmt.Sprintf(`{"first_name": "Taken", "user_id": %d}`, user.UserID)))
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+
+			other, _ := testStore.Members.Get(ctx, datastore.Unscoped, 1)
+			Expect(other.UserID).ToNot(Equal(user.UserID))
+		})
+	})
+
+	Describe("PatchMember endpoint", func() {
+		It("should return status code 404 for another member", func() {
+			res, _, _ = Request("PATCH", fmt.Sprintf("%s%s/members/1", server.URL, router.V1URLBase), token, []byte(fmt.Sprintf(`{"user_id": %d}`, user.UserID)))
+			Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+
+			other, _ := testStore.Members.Get(ctx, datastore.Unscoped, 1)
+			Expect(other.UserID).ToNot(Equal(user.UserID))
+		})
+
+		It("should not hand the member over to another user", func() {
+			var patched models.Member
+			res, data, _ = Request("PATCH", fmt.Sprintf("%s%s/members/%d", server.URL, router.V1URLBase, member.MemberID), token, []byte(`{"user_id": 1}`))
+			json.Unmarshal(data, &patched)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(patched.UserID).To(Equal(user.UserID))
+		})
+	})
+
+	Describe("PostVisit endpoint", func() {
+		It("should check the member themselves in", func() {
+			var created models.Visit
+			res, data, _ = Request("POST", fmt.Sprintf("%s%s/visits", server.URL, router.V1URLBase), token, []byte(`{"member_id": 1, "gym_location_id": 1, "status_id": 1}`))
+			json.Unmarshal(data, &created)
+			Expect(res.StatusCode).To(Equal(http.StatusCreated))
+			Expect(created.MemberID).To(Equal(member.MemberID))
+		})
+	})
+})
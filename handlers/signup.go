@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store"
+)
+
+const (
+	MissingSignupCredentials = "Email and password are required."
+	MissingSignupName        = "A first or last name is required."
+	MissingSignupAddress     = "An address is required."
+	MissingSignupPlan        = "A plan_id is required."
+)
+
+type SignupRequest struct {
+	Email     string          `json:"email"`
+	Password  string          `json:"password"`
+	FirstName string          `json:"first_name"`
+	LastName  string          `json:"last_name"`
+	Address   *models.Address `json:"address"`
+	PlanID    int64           `json:"plan_id"`
+}
+
+// Signup is what a new member gets back: their member record, with the user
+// and address it was created with, and their first membership.
+type Signup struct {
+	Member     *models.Member     `json:"member"`
+	Membership *models.Membership `json:"membership"`
+}
+
+// PostSignup registers a new member. The user, address, member and membership
+// are created in one transaction, so a failure part way leaves none of them
+// behind.
+func (api *API) PostSignup(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+
+	signup := SignupRequest{}
+	err := json.Unmarshal(body, &signup)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+		return
+	}
+
+	switch {
+	case signup.Email == "" || signup.Password == "":
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: MissingSignupCredentials})
+		return
+	case signup.FirstName == "" && signup.LastName == "":
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: MissingSignupName})
+		return
+	case signup.Address == nil:
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: MissingSignupAddress})
+		return
+	case signup.PlanID == 0:
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: MissingSignupPlan})
+		return
+	}
+
+	var created Signup
+	err = api.store.Transaction(r.Context(), func(tx *store.Store) error {
+		// the address has to be confirmed through the emailed link, the
+		// same as for POST /users
+		user, err := tx.Users.Create(r.Context(), models.User{Email: signup.Email, Password: signup.Password})
+		if err != nil {
+			return err
+		}
+
+		role, err := tx.Users.AddRole(r.Context(), user.UserID, models.MemberRole)
+		if err != nil {
+			return err
+		}
+		user.Roles = []*models.Role{role}
+
+		address, err := tx.Addresses.Create(r.Context(), *signup.Address)
+		if err != nil {
+			return err
+		}
+
+		member, err := tx.Members.Create(r.Context(), models.Member{
+			UserID:    user.UserID,
+			AddressID: &address.AddressID,
+			FirstName: signup.FirstName,
+			LastName:  signup.LastName,
+		})
+		if err != nil {
+			return err
+		}
+		member.User = user
+		member.Address = address
+
+		membership, err := tx.Memberships.Create(r.Context(), models.Membership{
+			PlanID:    &signup.PlanID,
+			MemberID:  &member.MemberID,
+			StartDate: time.Now(),
+			Active:    true,
+		})
+		if err != nil {
+			return err
+		}
+
+		created = Signup{Member: member, Membership: membership}
+		return nil
+	})
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	err = api.sendVerification(r.Context(), created.Member.User)
+	if err != nil {
+		log.Printf("Verification email for %q failed: %s", created.Member.User.Email, err)
+	}
+
+	WriteJSON(w, http.StatusCreated, created)
+}
@@ -0,0 +1,130 @@
+package handlers_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Signup API", func() {
+	var (
+		server    *httptest.Server
+		signupURL string
+		res       *http.Response
+		data      []byte
+		errRes    handlers.APIErrorMessage
+	)
+
+	signup := func(email string, planID int64) []byte {
+		return []byte(fmt.Sprintf(`{
+			"email": "%s",
+			"password": "testing",
+			"first_name": "New",
+			"last_name": "Member",
+			"address": {"country": "USA", "city": "San Diego", "street_address": "1 Main St"},
+			"plan_id": %d
+		}`, email, planID))
+	}
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		signupURL = fmt.Sprintf("%s%s/signup", server.URL, router.V1URLBase)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("PostSignup endpoint", func() {
+		Describe("Successful POST", func() {
+			var created handlers.Signup
+
+			BeforeEach(func() {
+				res, data, _ = Request("POST", signupURL, "", signup("signup@email.com", 1))
+				json.Unmarshal(data, &created)
+			})
+
+			It("should return status code 201", func() {
+				Expect(res.StatusCode).To(Equal(http.StatusCreated))
+			})
+
+			It("should return the member with their user and address", func() {
+				Expect(created.Member.FirstName).To(Equal("New"))
+				Expect(created.Member.User.Email).To(Equal("signup@email.com"))
+				Expect(created.Member.User.Roles[0].RoleName).To(Equal("member"))
+				Expect(created.Member.Address.StreetAddress).To(Equal("1 Main St"))
+				Expect(*created.Member.AddressID).To(Equal(created.Member.Address.AddressID))
+			})
+
+			It("should start a membership on the plan", func() {
+				Expect(*created.Membership.PlanID).To(Equal(int64(1)))
+				Expect(*created.Membership.MemberID).To(Equal(created.Member.MemberID))
+				Expect(created.Membership.Active).To(BeTrue())
+			})
+
+			It("should create the member", func() {
+				member, err := testStore.Members.GetByUserID(ctx, created.Member.UserID)
+				Expect(err).To(BeNil())
+				Expect(member.MemberID).To(Equal(created.Member.MemberID))
+			})
+		})
+
+		Describe("Unsuccessful POST", func() {
+			It("should return status code 400 without a password", func() {
+				res, data, _ = Request("POST", signupURL, "", []byte(`{"email": "signup@email.com", "first_name": "New", "plan_id": 1}`))
+				json.Unmarshal(data, &errRes)
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(errRes.Message).To(Equal(handlers.MissingSignupCredentials))
+			})
+
+			It("should return status code 400 without an address", func() {
+				res, data, _ = Request("POST", signupURL, "", []byte(`{"email": "signup@email.com", "password": "testing", "first_name": "New", "plan_id": 1}`))
+				json.Unmarshal(data, &errRes)
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(errRes.Message).To(Equal(handlers.MissingSignupAddress))
+			})
+
+			It("should return status code 409 for a registered email", func() {
+				res, data, _ = Request("POST", signupURL, "", signup("lukas.hambsch@gmail.com", 1))
+				json.Unmarshal(data, &errRes)
+				Expect(res.StatusCode).To(Equal(http.StatusConflict))
+				Expect(errRes.Code).To(Equal(handlers.DuplicateCode))
+				Expect(errRes.Field).To(Equal("email"))
+			})
+
+			Describe("that fails part way", func() {
+				var addresses []models.Address
+
+				BeforeEach(func() {
+					addresses, _ = testStore.Addresses.List(ctx, datastore.Query{})
+					res, data, _ = Request("POST", signupURL, "", signup("signup@email.com", 99999))
+					json.Unmarshal(data, &errRes)
+				})
+
+				It("should return status code 422 for the plan", func() {
+					Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+					Expect(errRes.Field).To(Equal("plan_id"))
+				})
+
+				It("should not leave the user behind", func() {
+					_, err := testStore.Users.GetByEmail(ctx, "signup@email.com")
+					Expect(err).To(Equal(sql.ErrNoRows))
+				})
+
+				It("should not leave the address behind", func() {
+					after, _ := testStore.Addresses.List(ctx, datastore.Query{})
+					Expect(len(after)).To(Equal(len(addresses)))
+				})
+			})
+		})
+	})
+})
@@ -1,9 +1,7 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
@@ -11,7 +9,6 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store/datastore"
 )
 
 const StatusID = "status_id"
@@ -22,51 +19,55 @@ var statusFields map[string]string = map[string]string{
 	"status_name": "string",
 }
 
-func GetStatus(w http.ResponseWriter, r *http.Request) {
+// statusPatchFields are the fields a PATCH can change, and whether they can be null.
+var statusPatchFields = map[string]bool{
+	"status_name": false,
+}
+
+func (api *API) GetStatus(w http.ResponseWriter, r *http.Request) {
 	statusID, message := GetID(w, r, StatusID)
 	if message != nil {
 		return
 	}
 
-	status, err := datastore.GetStatus(statusID)
+	status, err := api.store.Statuses.Get(r.Context(), statusID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: "Not Found"})
-		} else {
-			WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
-		}
+		WriteError(w, r, err)
+		return
+	}
+
+	setETag(w, status.Version)
+	if notModified(w, r) {
 		return
 	}
 
 	WriteJSON(w, http.StatusOK, status)
 }
 
-func GetStatuses(w http.ResponseWriter, r *http.Request) {
-	var statement string
+func (api *API) GetStatuses(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
-	where, err := BuildWhere(statusFields, query)
+	statement, err := BuildQuery(statusFields, StatusID, query)
 	if err != nil {
-		WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: err.Error()})
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
 		return
 	}
 
-	sort, err := BuildSort(statusFields, query)
+	statuses, err := api.store.Statuses.List(r.Context(), statement)
 	if err != nil {
-		WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: err.Error()})
+		WriteServerError(w, r, err, "Error getting status list.")
 		return
 	}
 
-	statement = fmt.Sprintf("%s %s", where, sort)
-	statuses, err := datastore.GetStatusList(statement)
+	count, err := api.store.Statuses.Count(r.Context(), statement)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: "Error getting status list."})
+		WriteServerError(w, r, err, "Error getting status count.")
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, statuses)
+	WritePage(w, r, statement, *count, statuses)
 }
 
-func PostStatus(w http.ResponseWriter, r *http.Request) {
+func (api *API) PostStatus(w http.ResponseWriter, r *http.Request) {
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body.Close()
 
@@ -77,16 +78,16 @@ func PostStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	created, err := datastore.CreateStatus(*status)
+	created, err := api.store.Statuses.Create(r.Context(), *status)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 
 	WriteJSON(w, http.StatusCreated, created)
 }
 
-func PutStatus(w http.ResponseWriter, r *http.Request) {
+func (api *API) PutStatus(w http.ResponseWriter, r *http.Request) {
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body.Close()
 
@@ -96,6 +97,11 @@ func PutStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
 	status := &models.Status{}
 	err = json.Unmarshal(body, status)
 	if err != nil {
@@ -103,27 +109,63 @@ func PutStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updated, err := datastore.UpdateStatus(statusID, *status)
+	status.Version = version
+	updated, err := api.store.Statuses.Update(r.Context(), statusID, *status)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 
+	setETag(w, updated.Version)
 	WriteJSON(w, http.StatusOK, updated)
 }
 
-func DeleteStatus(w http.ResponseWriter, r *http.Request) {
+func (api *API) PatchStatus(w http.ResponseWriter, r *http.Request) {
 	statusID, err := strconv.ParseInt(mux.Vars(r)[StatusID], 10, 64)
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidStatusID})
 		return
 	}
 
-	err = datastore.DeleteStatus(statusID)
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	status := &models.Status{}
+	fields, ok := readPatch(w, r, statusPatchFields, status)
+	if !ok {
+		return
+	}
+
+	status.Version = version
+	patched, err := api.store.Statuses.Patch(r.Context(), statusID, *status, fields)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	setETag(w, patched.Version)
+	WriteJSON(w, http.StatusOK, patched)
+}
+
+func (api *API) DeleteStatus(w http.ResponseWriter, r *http.Request) {
+	statusID, err := strconv.ParseInt(mux.Vars(r)[StatusID], 10, 64)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidStatusID})
+		return
+	}
+
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	err = api.store.Statuses.Delete(r.Context(), statusID, version)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, nil)
+	w.WriteHeader(http.StatusNoContent)
 }
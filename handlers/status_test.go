@@ -8,12 +8,11 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 
 	"github.com/lukashambsch/anygym.api/handlers"
 	"github.com/lukashambsch/anygym.api/models"
 	"github.com/lukashambsch/anygym.api/router"
-	"github.com/lukashambsch/anygym.api/store"
-	"github.com/lukashambsch/anygym.api/store/datastore"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -47,13 +46,8 @@ func Request(method string, url string, token string, payload []byte) (*http.Res
 }
 
 func RequestToken(serverURL string) (string, error) {
-	_, data, _ := Request(
-		"POST",
-		fmt.Sprintf("%s%s/authenticate", serverURL, router.V1URLBase),
-		"",
-		[]byte(`{"email": "lukas.hambsch@gmail.com", "password": "testpass"}`),
-	)
-	return string(data), nil
+	tokens, err := RequestUserTokens(serverURL, "lukas.hambsch@gmail.com", "testpass")
+	return tokens.AccessToken, err
 }
 
 var _ = Describe("Status API", func() {
@@ -67,7 +61,7 @@ var _ = Describe("Status API", func() {
 	)
 
 	BeforeEach(func() {
-		server = httptest.NewServer(router.Load())
+		server = httptest.NewServer(router.Load(testStore, testKeys))
 		token, _ = RequestToken(server.URL)
 		statusURL = fmt.Sprintf("%s%s/statuses", server.URL, router.V1URLBase)
 	})
@@ -104,7 +98,7 @@ var _ = Describe("Status API", func() {
 
 			It("should return a list of matching statuses - status_name - partial match", func() {
 				correct := []models.Status{
-					models.Status{StatusID: 1, StatusName: "Pending"},
+					models.Status{StatusID: 1, StatusName: "Pending", Version: 1},
 				}
 				res, data, _ = Request("GET", fmt.Sprintf("%s?status_name=Pend", statusURL), token, nil)
 				json.Unmarshal(data, &statuses)
@@ -126,6 +120,13 @@ var _ = Describe("Status API", func() {
 				Expect(len(statuses)).To(Equal(0))
 			})
 
+			It("should treat sql in a value as part of the value", func() {
+				res, data, _ = Request("GET", fmt.Sprintf("%s?status_name=%s", statusURL, url.QueryEscape("' OR '1'='1")), token, nil)
+				json.Unmarshal(data, &statuses)
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+				Expect(len(statuses)).To(Equal(0))
+			})
+
 			It("should sort statuses by the correct field ascending", func() {
 				res, data, _ = Request("GET", fmt.Sprintf("%s?sort_order=asc&order_by=status_name", statusURL), token, nil)
 				json.Unmarshal(data, &statuses)
@@ -157,21 +158,21 @@ var _ = Describe("Status API", func() {
 			It("should return an error with an invalid field as query param", func() {
 				res, data, _ = Request("GET", fmt.Sprintf("%s?invalid=test", statusURL), token, nil)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal("Invalid field in query params."))
 			})
 
 			It("should return an error with an invalid field in order_by", func() {
 				res, data, _ = Request("GET", fmt.Sprintf("%s?order_by=invalid", statusURL), token, nil)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal("Invalid order_by field."))
 			})
 
 			It("should return an error with an invalid value for sort_order", func() {
 				res, data, _ = Request("GET", fmt.Sprintf("%s?order_by=status_name&sort_order=random", statusURL), token, nil)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal("sort_order must be either 'asc', 'desc', or ''"))
 			})
 		})
@@ -235,7 +236,7 @@ var _ = Describe("Status API", func() {
 			})
 
 			AfterEach(func() {
-				datastore.DeleteStatus(status.StatusID)
+				testStore.Statuses.Delete(ctx, status.StatusID, 0)
 			})
 
 			It("should return status code 201", func() {
@@ -263,13 +264,14 @@ var _ = Describe("Status API", func() {
 				})
 			})
 
-			Describe("Internal Server Error", func() {
-				It("should return status code 500 with a message", func() {
+			Describe("Conflict", func() {
+				It("should return status code 409 with the field", func() {
 					payload = []byte(`{"status_name": "Pending"}`)
 					res, data, _ := Request("POST", statusURL, token, payload)
 					json.Unmarshal(data, &errRes)
-					Expect(res.StatusCode).To(Equal(http.StatusInternalServerError))
-					Expect(errRes.Message).ToNot(BeEmpty())
+					Expect(res.StatusCode).To(Equal(http.StatusConflict))
+					Expect(errRes.Code).To(Equal(handlers.DuplicateCode))
+					Expect(errRes.Field).To(Equal("status_name"))
 				})
 			})
 		})
@@ -289,7 +291,7 @@ var _ = Describe("Status API", func() {
 			})
 
 			AfterEach(func() {
-				datastore.UpdateStatus(statusID, models.Status{StatusName: "Pending"})
+				testStore.Statuses.Update(ctx, statusID, models.Status{StatusName: "Pending"})
 			})
 
 			It("should return status code 200", func() {
@@ -301,7 +303,7 @@ var _ = Describe("Status API", func() {
 			})
 
 			It("should save the updated status", func() {
-				updated, _ := datastore.GetStatus(statusID)
+				updated, _ := testStore.Statuses.Get(ctx, statusID)
 				Expect(updated.StatusID).To(Equal(statusID))
 			})
 		})
@@ -323,11 +325,11 @@ var _ = Describe("Status API", func() {
 				Expect(errRes.Message).To(Equal(handlers.InvalidStatusID))
 			})
 
-			It("should return status code 500 with a message", func() {
+			It("should return status code 404 for a missing status", func() {
 				res, data, _ = Request("PUT", fmt.Sprintf("%s/7", statusURL), token, payload)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusInternalServerError))
-				Expect(errRes.Message).ToNot(BeEmpty())
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(errRes.Code).To(Equal(handlers.NotFoundCode))
 			})
 		})
 	})
@@ -340,20 +342,12 @@ var _ = Describe("Status API", func() {
 				res, _, _ = Request("DELETE", fmt.Sprintf("%s/%d", statusURL, statusID), token, nil)
 			})
 
-			AfterEach(func() {
-				store.DB.QueryRow(
-					"INSERT INTO statuses (status_id, status_name) VALUES ($1, $2)",
-					statusID,
-					"Denied - Banned",
-				)
-			})
-
-			It("should return status code 200", func() {
-				Expect(res.StatusCode).To(Equal(http.StatusOK))
+			It("should return status code 204", func() {
+				Expect(res.StatusCode).To(Equal(http.StatusNoContent))
 			})
 
 			It("should delete the status", func() {
-				_, err := datastore.GetStatus(statusID)
+				_, err := testStore.Statuses.Get(ctx, statusID)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -367,6 +361,13 @@ var _ = Describe("Status API", func() {
 				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal(handlers.InvalidStatusID))
 			})
+
+			It("should return status code 404 for a missing id", func() {
+				res, data, _ = Request("DELETE", fmt.Sprintf("%s/5000", statusURL), token, nil)
+				json.Unmarshal(data, &errRes)
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(errRes.Code).To(Equal(handlers.NotFoundCode))
+			})
 		})
 	})
 })
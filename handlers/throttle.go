@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/config"
+)
+
+const TooManyLoginAttempts string = "Too many failed login attempts. Try again later."
+
+// LockoutPolicy decides when repeated login failures lock out an email
+// address or client IP. Once MaxAttempts failures happen within Window the
+// key is locked for BaseDelay, doubling with every further failure up to
+// MaxDelay.
+type LockoutPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Window      time.Duration
+}
+
+// lockFor returns how long a key with attempts failures should be locked.
+func (p LockoutPolicy) lockFor(attempts int) time.Duration {
+	if attempts < p.MaxAttempts {
+		return 0
+	}
+
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(attempts-p.MaxAttempts))
+	if delay > float64(p.MaxDelay) {
+		return p.MaxDelay
+	}
+
+	return time.Duration(delay)
+}
+
+func loadLockoutPolicy(key string, maxAttempts int) LockoutPolicy {
+	policy := LockoutPolicy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   30 * time.Second,
+		MaxDelay:    time.Hour,
+		Window:      time.Hour,
+	}
+
+	if config.C.IsSet(key + ".max_attempts") {
+		policy.MaxAttempts = config.C.GetInt(key + ".max_attempts")
+	}
+	if config.C.IsSet("auth.lockout.base_delay") {
+		policy.BaseDelay = config.C.GetDuration("auth.lockout.base_delay")
+	}
+	if config.C.IsSet("auth.lockout.max_delay") {
+		policy.MaxDelay = config.C.GetDuration("auth.lockout.max_delay")
+	}
+	if config.C.IsSet("auth.lockout.window") {
+		policy.Window = config.C.GetDuration("auth.lockout.window")
+	}
+
+	return policy
+}
+
+// emailLockout is strict since it protects a single account, ipLockout is
+// looser so users behind a shared address aren't locked out by each other.
+// twoFactorLockout covers codes sent to the /me/2fa endpoints, where the
+// caller is already signed in.
+var (
+	emailLockout     = loadLockoutPolicy("auth.lockout.email", 5)
+	ipLockout        = loadLockoutPolicy("auth.lockout.ip", 50)
+	twoFactorLockout = loadLockoutPolicy("auth.lockout.two_factor", 5)
+)
+
+func emailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func twoFactorThrottleKey(userID int64) string {
+	return "2fa:" + strconv.FormatInt(userID, 10)
+}
+
+func ipThrottleKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// lockedFor returns how much longer any of keys is locked for.
+func (api *API) lockedFor(ctx context.Context, keys ...string) (time.Duration, error) {
+	var longest time.Duration
+
+	for _, key := range keys {
+		throttle, err := api.store.LoginThrottles.Get(ctx, key)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if throttle.LockedUntil != nil {
+			if remaining := throttle.LockedUntil.Sub(time.Now()); remaining > longest {
+				longest = remaining
+			}
+		}
+	}
+
+	return longest, nil
+}
+
+// recordLoginFailure counts a failed login against key and locks it if the
+// policy says so. It returns how long the key is now locked for.
+func (api *API) recordLoginFailure(ctx context.Context, key string, policy LockoutPolicy) (time.Duration, error) {
+	throttle, err := api.store.LoginThrottles.RecordFailure(ctx, key, policy.Window)
+	if err != nil {
+		return 0, err
+	}
+
+	lockFor := policy.lockFor(throttle.FailedAttempts)
+	if lockFor == 0 {
+		return 0, nil
+	}
+
+	throttle, err = api.store.LoginThrottles.Lock(ctx, key, lockFor)
+	if err != nil {
+		return 0, err
+	}
+
+	return throttle.LockedUntil.Sub(time.Now()), nil
+}
+
+// writeLoginFailure records a failed login against the email and ip keys and
+// responds with 429 if that locked either of them, otherwise 401 and message.
+func (api *API) writeLoginFailure(w http.ResponseWriter, r *http.Request, emailKey string, ipKey string, message string) {
+	emailLocked, err := api.recordLoginFailure(r.Context(), emailKey, emailLockout)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	ipLocked, err := api.recordLoginFailure(r.Context(), ipKey, ipLockout)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	locked := emailLocked
+	if ipLocked > locked {
+		locked = ipLocked
+	}
+	if locked > 0 {
+		writeTooManyAttempts(w, locked)
+		return
+	}
+
+	WriteJSON(w, http.StatusUnauthorized, APIErrorMessage{Message: message})
+}
+
+func writeTooManyAttempts(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	WriteJSON(w, http.StatusTooManyRequests, APIErrorMessage{Message: TooManyLoginAttempts})
+}
+
+// UnlockUser clears failed login and two-factor attempts and any lockout for
+// a user.
+func (api *API) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, message := GetID(w, r, UserID)
+	if message != nil {
+		return
+	}
+
+	user, err := api.store.Users.Get(r.Context(), userID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	for _, key := range []string{emailThrottleKey(user.Email), twoFactorThrottleKey(user.UserID)} {
+		err = api.store.LoginThrottles.Delete(r.Context(), key)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+	}
+
+	WriteJSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,128 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Login throttling", func() {
+	var (
+		server   *httptest.Server
+		loginURL string
+		res      *http.Response
+		data     []byte
+		user     *models.User
+		errRes   handlers.APIErrorMessage
+		badLogin []byte = []byte(`{"email": "locked@email.com", "password": "wrong"}`)
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		loginURL = fmt.Sprintf("%s%s/authenticate", server.URL, router.V1URLBase)
+		now := time.Now()
+		user, _ = testStore.Users.Create(ctx, models.User{Email: "locked@email.com", Password: "testing", EmailVerifiedOn: &now})
+	})
+
+	AfterEach(func() {
+		testStore.LoginThrottles.Delete(ctx, "email:locked@email.com")
+		testStore.LoginThrottles.Delete(ctx, "ip:127.0.0.1")
+		testStore.Users.Delete(ctx, user.UserID, 0)
+		server.Close()
+	})
+
+	Describe("Failed login", func() {
+		It("should return status code 401 with a message", func() {
+			res, data, _ = Request("POST", loginURL, "", badLogin)
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(errRes.Message).To(Equal(handlers.ErrInvalidCredentials.Error()))
+		})
+
+		It("should return the same message for an unknown email", func() {
+			res, data, _ = Request("POST", loginURL, "", []byte(`{"email": "nobody@email.com", "password": "wrong"}`))
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(errRes.Message).To(Equal(handlers.ErrInvalidCredentials.Error()))
+			testStore.LoginThrottles.Delete(ctx, "email:nobody@email.com")
+		})
+	})
+
+	Describe("Lockout", func() {
+		BeforeEach(func() {
+			Request("POST", loginURL, "", badLogin)
+			Request("POST", loginURL, "", badLogin)
+			res, data, _ = Request("POST", loginURL, "", badLogin)
+			json.Unmarshal(data, &errRes)
+		})
+
+		It("should return status code 429 with Retry-After", func() {
+			Expect(res.StatusCode).To(Equal(http.StatusTooManyRequests))
+			Expect(errRes.Message).To(Equal(handlers.TooManyLoginAttempts))
+
+			retryAfter, err := strconv.Atoi(res.Header.Get("Retry-After"))
+			Expect(err).To(BeNil())
+			Expect(retryAfter).To(BeNumerically(">", 0))
+			Expect(retryAfter).To(BeNumerically("<=", 30))
+		})
+
+		It("should reject the correct password while locked", func() {
+			tokens, _ := RequestUserTokens(server.URL, "locked@email.com", "testing")
+			Expect(tokens.AccessToken).To(BeEmpty())
+		})
+
+		It("should not lock other accounts", func() {
+			tokens, _ := RequestUserTokens(server.URL, "lukas.hambsch@gmail.com", "testpass")
+			Expect(tokens.AccessToken).ToNot(BeEmpty())
+		})
+	})
+
+	Describe("UnlockUser endpoint", func() {
+		var unlockURL string
+
+		BeforeEach(func() {
+			unlockURL = fmt.Sprintf("%s%s/users/%d/unlock", server.URL, router.V1URLBase, user.UserID)
+			Request("POST", loginURL, "", badLogin)
+			Request("POST", loginURL, "", badLogin)
+			Request("POST", loginURL, "", badLogin)
+		})
+
+		Describe("Successful POST", func() {
+			BeforeEach(func() {
+				token, _ := RequestToken(server.URL)
+				res, _, _ = Request("POST", unlockURL, token, nil)
+			})
+
+			It("should return status code 200", func() {
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+			})
+
+			It("should let the user log in again", func() {
+				tokens, _ := RequestUserTokens(server.URL, "locked@email.com", "testing")
+				Expect(tokens.AccessToken).ToNot(BeEmpty())
+			})
+		})
+
+		Describe("Unsuccessful POST", func() {
+			It("should return status code 401 without a token", func() {
+				res, _, _ = Request("POST", unlockURL, "", nil)
+				Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+			})
+
+			It("should return status code 404 for an unknown user", func() {
+				token, _ := RequestToken(server.URL)
+				res, _, _ = Request("POST", fmt.Sprintf("%s%s/users/99999/unlock", server.URL, router.V1URLBase), token, nil)
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+})
@@ -0,0 +1,504 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/lukashambsch/anygym.api/config"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/totp"
+)
+
+const (
+	twoFactorAudience  = "two_factor"
+	twoFactorLifetime  = 5 * time.Minute
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+)
+
+const (
+	InvalidTwoFactorCode      string = "Invalid two-factor code."
+	InvalidTwoFactorChallenge string = "Invalid or expired two-factor challenge."
+	TwoFactorNotSetUp         string = "Two-factor authentication has not been set up."
+	TwoFactorAlreadyEnabled   string = "Two-factor authentication is already enabled."
+	TwoFactorRequiredForAdmin string = "Two-factor authentication can't be turned off for admins."
+)
+
+// TwoFactorChallenge is returned by Login instead of Tokens when the user
+// has two-factor authentication enabled. The challenge token and a code are
+// then exchanged for Tokens at /authenticate/2fa.
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+// TwoFactorCodeRequest carries a code from the user's authenticator app, or
+// one of their recovery codes where that's accepted.
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorEnrolment is shown once when setting up two-factor
+// authentication, for the user to add to their authenticator app.
+type TwoFactorEnrolment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// RecoveryCodes can each be used once in place of a code if the user loses
+// their authenticator. They're only ever shown when generated.
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+// twoFactorIssuer is the account name authenticator apps show the code
+// under.
+func twoFactorIssuer() string {
+	if config.C.IsSet("auth.two_factor.issuer") {
+		return config.C.GetString("auth.two_factor.issuer")
+	}
+
+	return "AnyGym"
+}
+
+func (api *API) twoFactorEnabled(ctx context.Context, userID int64) (bool, error) {
+	twoFactor, err := api.store.TwoFactors.Get(ctx, userID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return twoFactor.EnabledOn != nil, nil
+}
+
+func (api *API) newTwoFactorChallenge(user *models.User) (*TwoFactorChallenge, error) {
+	token, err := api.keys.Sign(jwt.StandardClaims{
+		Audience:  twoFactorAudience,
+		ExpiresAt: time.Now().Add(twoFactorLifetime).Unix(),
+		Issuer:    tokenIssuer(),
+		Subject:   strconv.FormatInt(user.UserID, 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorChallenge{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresIn:         int64(twoFactorLifetime / time.Second),
+	}, nil
+}
+
+// checkTOTP accepts a code from the user's authenticator app, at most once.
+func (api *API) checkTOTP(ctx context.Context, twoFactor *models.TwoFactor, code string) (bool, error) {
+	step, ok := totp.Validate(twoFactor.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	err := api.store.TwoFactors.UseStep(ctx, twoFactor.UserID, step)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// checkTwoFactorCode accepts either a code from the user's authenticator
+// app or one of their unused recovery codes.
+func (api *API) checkTwoFactorCode(ctx context.Context, twoFactor *models.TwoFactor, code string) (bool, error) {
+	ok, err := api.checkTOTP(ctx, twoFactor, code)
+	if ok || err != nil {
+		return ok, err
+	}
+
+	err = api.store.TwoFactors.UseRecoveryCode(ctx, twoFactor.UserID, hashToken(normalizeRecoveryCode(code)))
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// newRecoveryCodes generates a fresh set of recovery codes for the user and
+// replaces their old ones.
+func (api *API) newRecoveryCodes(ctx context.Context, userID int64) (*RecoveryCodes, error) {
+	var (
+		codes  []string
+		hashes []string
+	)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, recoveryCodeLength*5/8)
+		_, err := rand.Read(b)
+		if err != nil {
+			return nil, err
+		}
+
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:recoveryCodeLength]
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, hashToken(code))
+	}
+
+	err := api.store.TwoFactors.ReplaceRecoveryCodes(ctx, userID, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RecoveryCodes{Codes: codes}, nil
+}
+
+// LoginTwoFactor is the second step of Login for users with two-factor
+// authentication. Wrong codes count towards the login lockout.
+func (api *API) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+
+	request := TwoFactorLoginRequest{}
+	err := json.Unmarshal(body, &request)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+		return
+	}
+
+	claims := &jwt.StandardClaims{}
+	token, err := api.keys.Parse(request.ChallengeToken, claims)
+	if err != nil || !token.Valid || !claims.VerifyAudience(twoFactorAudience, true) {
+		WriteJSON(w, http.StatusUnauthorized, APIErrorMessage{Message: InvalidTwoFactorChallenge})
+		return
+	}
+
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		WriteJSON(w, http.StatusUnauthorized, APIErrorMessage{Message: InvalidTwoFactorChallenge})
+		return
+	}
+
+	user, err := api.store.Users.Get(r.Context(), userID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	emailKey, ipKey := emailThrottleKey(user.Email), ipThrottleKey(r)
+	locked, err := api.lockedFor(r.Context(), emailKey, ipKey)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if locked > 0 {
+		writeTooManyAttempts(w, locked)
+		return
+	}
+
+	twoFactor, err := api.store.TwoFactors.Get(r.Context(), user.UserID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	ok, err := api.checkTwoFactorCode(r.Context(), twoFactor, request.Code)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if !ok {
+		api.writeLoginFailure(w, r, emailKey, ipKey, InvalidTwoFactorCode)
+		return
+	}
+
+	err = api.store.LoginThrottles.Delete(r.Context(), emailKey)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	tokens, err := api.StartSession(r.Context(), user, true)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	noStore(w)
+	WriteJSON(w, http.StatusOK, tokens)
+}
+
+// twoFactorUser returns the user calling one of the /me/2fa endpoints. API
+// keys can't manage two-factor authentication.
+func (api *API) twoFactorUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	claims, ok := GetClaims(r)
+	if !ok || claims.APIKey != nil {
+		WriteJSON(w, http.StatusForbidden, APIErrorMessage{Message: "Forbidden"})
+		return nil, false
+	}
+
+	user, err := api.store.Users.Get(r.Context(), claims.UserID)
+	if err != nil {
+		WriteError(w, r, err)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// readTwoFactorCode loads the user's enrolment and checks the code in the
+// request body against it, writing the error response if either fails.
+// Wrong codes count towards the user's two-factor lockout, so a stolen
+// access token can't be used to guess them.
+func (api *API) readTwoFactorCode(w http.ResponseWriter, r *http.Request, user *models.User, recovery bool) (*models.TwoFactor, bool) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+
+	request := TwoFactorCodeRequest{}
+	err := json.Unmarshal(body, &request)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+		return nil, false
+	}
+
+	throttleKey := twoFactorThrottleKey(user.UserID)
+	locked, err := api.lockedFor(r.Context(), throttleKey)
+	if err != nil {
+		WriteError(w, r, err)
+		return nil, false
+	}
+	if locked > 0 {
+		writeTooManyAttempts(w, locked)
+		return nil, false
+	}
+
+	twoFactor, err := api.store.TwoFactors.Get(r.Context(), user.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: TwoFactorNotSetUp})
+		} else {
+			WriteError(w, r, err)
+		}
+		return nil, false
+	}
+
+	var ok bool
+	if recovery {
+		ok, err = api.checkTwoFactorCode(r.Context(), twoFactor, request.Code)
+	} else {
+		ok, err = api.checkTOTP(r.Context(), twoFactor, request.Code)
+	}
+	if err != nil {
+		WriteError(w, r, err)
+		return nil, false
+	}
+	if !ok {
+		locked, err = api.recordLoginFailure(r.Context(), throttleKey, twoFactorLockout)
+		if err != nil {
+			WriteError(w, r, err)
+		} else if locked > 0 {
+			writeTooManyAttempts(w, locked)
+		} else {
+			WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidTwoFactorCode})
+		}
+		return nil, false
+	}
+
+	err = api.store.LoginThrottles.Delete(r.Context(), throttleKey)
+	if err != nil {
+		WriteError(w, r, err)
+		return nil, false
+	}
+
+	return twoFactor, true
+}
+
+// PostTwoFactor starts enrolment by generating a new secret. Two-factor
+// authentication isn't turned on until VerifyTwoFactor confirms a code.
+func (api *API) PostTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.twoFactorUser(w, r)
+	if !ok {
+		return
+	}
+
+	enabled, err := api.twoFactorEnabled(r.Context(), user.UserID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if enabled {
+		WriteJSON(w, http.StatusConflict, APIErrorMessage{Message: TwoFactorAlreadyEnabled})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	_, err = api.store.TwoFactors.Save(r.Context(), models.TwoFactor{UserID: user.UserID, Secret: secret})
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	noStore(w)
+	WriteJSON(w, http.StatusCreated, TwoFactorEnrolment{
+		Secret: secret,
+		URI:    totp.URI(twoFactorIssuer(), user.Email, secret),
+	})
+}
+
+// VerifyTwoFactor turns two-factor authentication on once the user sends a
+// valid code, signs them out everywhere else and returns their recovery
+// codes.
+func (api *API) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.twoFactorUser(w, r)
+	if !ok {
+		return
+	}
+
+	enabled, err := api.twoFactorEnabled(r.Context(), user.UserID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	if enabled {
+		WriteJSON(w, http.StatusConflict, APIErrorMessage{Message: TwoFactorAlreadyEnabled})
+		return
+	}
+
+	_, ok = api.readTwoFactorCode(w, r, user, false)
+	if !ok {
+		return
+	}
+
+	err = api.store.TwoFactors.Enable(r.Context(), user.UserID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	err = api.keepTwoFactorSession(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	codes, err := api.newRecoveryCodes(r.Context(), user.UserID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	noStore(w)
+	WriteJSON(w, http.StatusOK, codes)
+}
+
+// keepTwoFactorSession revokes the user's other sessions once they turn
+// two-factor authentication on, they were only opened with a password. The
+// session turning it on has just passed a code, so it counts as two-factor.
+func (api *API) keepTwoFactorSession(r *http.Request) error {
+	claims, _ := GetClaims(r)
+	sessionID, err := strconv.ParseInt(claims.Id, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	err = api.store.Sessions.RevokeOthers(r.Context(), claims.UserID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	session, err := api.store.Sessions.Get(r.Context(), sessionID)
+	if err != nil {
+		return err
+	}
+
+	session.TwoFactor = true
+	_, err = api.store.Sessions.Update(r.Context(), sessionID, *session)
+	return err
+}
+
+// PostRecoveryCodes replaces the user's recovery codes with new ones.
+func (api *API) PostRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.twoFactorUser(w, r)
+	if !ok {
+		return
+	}
+
+	twoFactor, ok := api.readTwoFactorCode(w, r, user, false)
+	if !ok {
+		return
+	}
+	if twoFactor.EnabledOn == nil {
+		WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: TwoFactorNotSetUp})
+		return
+	}
+
+	codes, err := api.newRecoveryCodes(r.Context(), user.UserID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	noStore(w)
+	WriteJSON(w, http.StatusCreated, codes)
+}
+
+// DeleteTwoFactor turns two-factor authentication off. Admins have to keep
+// it on.
+func (api *API) DeleteTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.twoFactorUser(w, r)
+	if !ok {
+		return
+	}
+
+	if hasRole(user.Roles, []string{models.AdminRole}) {
+		WriteJSON(w, http.StatusForbidden, APIErrorMessage{Message: TwoFactorRequiredForAdmin})
+		return
+	}
+
+	_, ok = api.readTwoFactorCode(w, r, user, true)
+	if !ok {
+		return
+	}
+
+	err := api.store.TwoFactors.Delete(r.Context(), user.UserID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	err = api.store.TwoFactors.ReplaceRecoveryCodes(r.Context(), user.UserID, nil)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
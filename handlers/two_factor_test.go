@@ -0,0 +1,307 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	"github.com/lukashambsch/anygym.api/totp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// twoFactorSecrets are the TOTP secrets of users enrolled during the tests.
+var twoFactorSecrets = map[string]string{
+	"lukas.hambsch@gmail.com": "JBSWY3DPEHPK3PXP",
+}
+
+// TwoFactorCode returns the current code for email's secret. The enrolment
+// is saved again first to clear the last used step, so tests can log in more
+// than once every 30 seconds.
+func TwoFactorCode(email string) string {
+	user, err := testStore.Users.GetByEmail(ctx, email)
+	if err == nil {
+		twoFactor, err := testStore.TwoFactors.Get(ctx, user.UserID)
+		if err == nil {
+			testStore.TwoFactors.Save(ctx, *twoFactor)
+			if twoFactor.EnabledOn != nil {
+				testStore.TwoFactors.Enable(ctx, user.UserID)
+			}
+		}
+	}
+	code, _ := totp.Code(twoFactorSecrets[email], time.Now())
+	return code
+}
+
+func AnswerTwoFactorChallenge(serverURL string, email string, challengeToken string) ([]byte, error) {
+	_, data, err := Request(
+		"POST",
+		fmt.Sprintf("%s%s/authenticate/2fa", serverURL, router.V1URLBase),
+		"",
+		[]byte(fmt.Sprintf(`{"challenge_token": "%s", "code": "%s"}`, challengeToken, TwoFactorCode(email))),
+	)
+	return data, err
+}
+
+var _ = Describe("Two-factor API", func() {
+	var (
+		server    *httptest.Server
+		loginURL  string
+		twoFAURL  string
+		meURL     string
+		res       *http.Response
+		data      []byte
+		user      *models.User
+		token     string
+		errRes    handlers.APIErrorMessage
+		enrolment handlers.TwoFactorEnrolment
+		codes     handlers.RecoveryCodes
+		challenge handlers.TwoFactorChallenge
+		login     []byte = []byte(`{"email": "twofactor@email.com", "password": "testing"}`)
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		loginURL = fmt.Sprintf("%s%s/authenticate", server.URL, router.V1URLBase)
+		twoFAURL = fmt.Sprintf("%s%s/authenticate/2fa", server.URL, router.V1URLBase)
+		meURL = fmt.Sprintf("%s%s/me/2fa", server.URL, router.V1URLBase)
+		now := time.Now()
+		user, _ = testStore.Users.Create(ctx, models.User{Email: "twofactor@email.com", Password: "testing", EmailVerifiedOn: &now})
+		testStore.Users.AddRole(ctx, user.UserID, models.MemberRole)
+		tokens, _ := RequestUserTokens(server.URL, "twofactor@email.com", "testing")
+		token = tokens.AccessToken
+	})
+
+	AfterEach(func() {
+		delete(twoFactorSecrets, "twofactor@email.com")
+		testStore.LoginThrottles.Delete(ctx, "email:twofactor@email.com")
+		testStore.LoginThrottles.Delete(ctx, fmt.Sprintf("2fa:%d", user.UserID))
+		testStore.Users.Delete(ctx, user.UserID, 0)
+		server.Close()
+	})
+
+	Describe("PostTwoFactor endpoint", func() {
+		BeforeEach(func() {
+			res, data, _ = Request("POST", meURL, token, nil)
+			json.Unmarshal(data, &enrolment)
+		})
+
+		It("should return status code 201 with a secret", func() {
+			Expect(res.StatusCode).To(Equal(http.StatusCreated))
+			Expect(enrolment.Secret).ToNot(BeEmpty())
+			Expect(enrolment.URI).To(HavePrefix("otpauth://totp/AnyGym:twofactor@email.com?"))
+		})
+
+		It("should not turn two-factor authentication on yet", func() {
+			res, data, _ = Request("POST", loginURL, "", login)
+			json.Unmarshal(data, &challenge)
+			Expect(challenge.TwoFactorRequired).To(BeFalse())
+		})
+	})
+
+	Describe("VerifyTwoFactor endpoint", func() {
+		BeforeEach(func() {
+			_, data, _ = Request("POST", meURL, token, nil)
+			json.Unmarshal(data, &enrolment)
+			twoFactorSecrets["twofactor@email.com"] = enrolment.Secret
+		})
+
+		It("should return status code 400 for a wrong code", func() {
+			res, data, _ = Request("POST", meURL+"/verify", token, []byte(`{"code": "000000"}`))
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(errRes.Message).To(Equal(handlers.InvalidTwoFactorCode))
+		})
+
+		It("should return status code 429 with Retry-After after too many wrong codes", func() {
+			for i := 0; i < 3; i++ {
+				Request("POST", meURL+"/verify", token, []byte(`{"code": "000000"}`))
+			}
+			res, data, _ = Request("POST", meURL+"/verify", token, []byte(`{"code": "000000"}`))
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusTooManyRequests))
+			Expect(res.Header.Get("Retry-After")).NotTo(BeEmpty())
+			Expect(errRes.Message).To(Equal(handlers.TooManyLoginAttempts))
+		})
+
+		It("should not accept the right code while locked", func() {
+			for i := 0; i < 3; i++ {
+				Request("POST", meURL+"/verify", token, []byte(`{"code": "000000"}`))
+			}
+			payload := []byte(fmt.Sprintf(`{"code": "%s"}`, TwoFactorCode("twofactor@email.com")))
+			res, _, _ = Request("POST", meURL+"/verify", token, payload)
+			Expect(res.StatusCode).To(Equal(http.StatusTooManyRequests))
+		})
+
+		Describe("with a valid code", func() {
+			var other string
+
+			BeforeEach(func() {
+				tokens, _ := RequestUserTokens(server.URL, "twofactor@email.com", "testing")
+				other = tokens.AccessToken
+
+				payload := []byte(fmt.Sprintf(`{"code": "%s"}`, TwoFactorCode("twofactor@email.com")))
+				res, data, _ = Request("POST", meURL+"/verify", token, payload)
+				json.Unmarshal(data, &codes)
+			})
+
+			It("should return status code 200 with recovery codes", func() {
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+				Expect(len(codes.Codes)).To(Equal(10))
+			})
+
+			It("should return status code 409 when enrolling again", func() {
+				res, _, _ = Request("POST", meURL, token, nil)
+				Expect(res.StatusCode).To(Equal(http.StatusConflict))
+			})
+
+			It("should sign the user out of their other sessions", func() {
+				res, _, _ = Request("GET", fmt.Sprintf("%s%s/me", server.URL, router.V1URLBase), other, nil)
+				Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+
+				res, _, _ = Request("GET", fmt.Sprintf("%s%s/me", server.URL, router.V1URLBase), token, nil)
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+			})
+
+			It("should challenge the next login", func() {
+				res, data, _ = Request("POST", loginURL, "", login)
+				json.Unmarshal(data, &challenge)
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+				Expect(challenge.TwoFactorRequired).To(BeTrue())
+				Expect(challenge.ChallengeToken).ToNot(BeEmpty())
+			})
+		})
+	})
+
+	Describe("LoginTwoFactor endpoint", func() {
+		var tokens handlers.Tokens
+
+		BeforeEach(func() {
+			_, data, _ = Request("POST", meURL, token, nil)
+			json.Unmarshal(data, &enrolment)
+			twoFactorSecrets["twofactor@email.com"] = enrolment.Secret
+			payload := []byte(fmt.Sprintf(`{"code": "%s"}`, TwoFactorCode("twofactor@email.com")))
+			_, data, _ = Request("POST", meURL+"/verify", token, payload)
+			json.Unmarshal(data, &codes)
+
+			_, data, _ = Request("POST", loginURL, "", login)
+			json.Unmarshal(data, &challenge)
+		})
+
+		It("should return tokens for a valid code", func() {
+			data, _ = AnswerTwoFactorChallenge(server.URL, "twofactor@email.com", challenge.ChallengeToken)
+			json.Unmarshal(data, &tokens)
+			Expect(tokens.AccessToken).ToNot(BeEmpty())
+		})
+
+		It("should not accept the same code twice", func() {
+			code := TwoFactorCode("twofactor@email.com")
+			payload := []byte(fmt.Sprintf(`{"challenge_token": "%s", "code": "%s"}`, challenge.ChallengeToken, code))
+			Request("POST", twoFAURL, "", payload)
+			res, _, _ = Request("POST", twoFAURL, "", payload)
+			Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("should accept a recovery code only once", func() {
+			payload := []byte(fmt.Sprintf(`{"challenge_token": "%s", "code": "%s"}`, challenge.ChallengeToken, codes.Codes[0]))
+			res, _, _ = Request("POST", twoFAURL, "", payload)
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			res, _, _ = Request("POST", twoFAURL, "", payload)
+			Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("should return status code 401 for a wrong code", func() {
+			payload := []byte(fmt.Sprintf(`{"challenge_token": "%s", "code": "000000"}`, challenge.ChallengeToken))
+			res, data, _ = Request("POST", twoFAURL, "", payload)
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(errRes.Message).To(Equal(handlers.InvalidTwoFactorCode))
+		})
+
+		It("should lock the account after too many wrong codes", func() {
+			payload := []byte(fmt.Sprintf(`{"challenge_token": "%s", "code": "000000"}`, challenge.ChallengeToken))
+			Request("POST", twoFAURL, "", payload)
+			Request("POST", twoFAURL, "", payload)
+			res, _, _ = Request("POST", twoFAURL, "", payload)
+			Expect(res.StatusCode).To(Equal(http.StatusTooManyRequests))
+		})
+
+		It("should return status code 401 for an invalid challenge", func() {
+			res, data, _ = Request("POST", twoFAURL, "", []byte(`{"challenge_token": "invalid", "code": "000000"}`))
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+			Expect(errRes.Message).To(Equal(handlers.InvalidTwoFactorChallenge))
+		})
+
+		It("should not accept an access token as the challenge", func() {
+			payload := []byte(fmt.Sprintf(`{"challenge_token": "%s", "code": "%s"}`, token, TwoFactorCode("twofactor@email.com")))
+			res, _, _ = Request("POST", twoFAURL, "", payload)
+			Expect(res.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	Describe("DeleteTwoFactor endpoint", func() {
+		BeforeEach(func() {
+			_, data, _ = Request("POST", meURL, token, nil)
+			json.Unmarshal(data, &enrolment)
+			twoFactorSecrets["twofactor@email.com"] = enrolment.Secret
+			payload := []byte(fmt.Sprintf(`{"code": "%s"}`, TwoFactorCode("twofactor@email.com")))
+			Request("POST", meURL+"/verify", token, payload)
+		})
+
+		It("should turn two-factor authentication off", func() {
+			payload := []byte(fmt.Sprintf(`{"code": "%s"}`, TwoFactorCode("twofactor@email.com")))
+			res, _, _ = Request("DELETE", meURL, token, payload)
+			Expect(res.StatusCode).To(Equal(http.StatusNoContent))
+
+			var loggedIn handlers.TwoFactorChallenge
+			_, data, _ = Request("POST", loginURL, "", login)
+			json.Unmarshal(data, &loggedIn)
+			Expect(loggedIn.TwoFactorRequired).To(BeFalse())
+		})
+
+		It("should not let admins turn it off", func() {
+			adminToken, _ := RequestToken(server.URL)
+			payload := []byte(fmt.Sprintf(`{"code": "%s"}`, TwoFactorCode("lukas.hambsch@gmail.com")))
+			res, data, _ = Request("DELETE", meURL, adminToken, payload)
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusForbidden))
+			Expect(errRes.Message).To(Equal(handlers.TwoFactorRequiredForAdmin))
+		})
+	})
+
+	Describe("Admin role", func() {
+		BeforeEach(func() {
+			testStore.Users.AddRole(ctx, user.UserID, models.AdminRole)
+		})
+
+		It("should not be usable without two-factor authentication", func() {
+			res, _, _ = Request("POST", fmt.Sprintf("%s%s/users/%d/unlock", server.URL, router.V1URLBase, user.UserID), token, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusForbidden))
+		})
+
+		It("should not be usable in a session opened with just the password", func() {
+			testStore.TwoFactors.Save(ctx, models.TwoFactor{UserID: user.UserID, Secret: "JBSWY3DPEHPK3PXP"})
+			testStore.TwoFactors.Enable(ctx, user.UserID)
+
+			res, _, _ = Request("POST", fmt.Sprintf("%s%s/users/%d/unlock", server.URL, router.V1URLBase, user.UserID), token, nil)
+			Expect(res.StatusCode).To(Equal(http.StatusForbidden))
+		})
+
+		It("should be usable in the session that turned two-factor authentication on", func() {
+			_, data, _ = Request("POST", meURL, token, nil)
+			json.Unmarshal(data, &enrolment)
+			twoFactorSecrets["twofactor@email.com"] = enrolment.Secret
+			Request("POST", meURL+"/verify", token, []byte(fmt.Sprintf(`{"code": "%s"}`, TwoFactorCode("twofactor@email.com"))))
+
+			res, _, _ = Request("POST", fmt.Sprintf("%s%s/users/%d/unlock", server.URL, router.V1URLBase, user.UserID), token, nil)
+			Expect(res.StatusCode).ToNot(Equal(http.StatusForbidden))
+		})
+	})
+})
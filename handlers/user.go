@@ -1,17 +1,16 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store/datastore"
 )
 
 const UserID = "user_id"
@@ -20,54 +19,64 @@ const InvalidUserID = "Invalid " + UserID
 var userFields map[string]string = map[string]string{
 	"user_id":    "int",
 	"email":      "string",
-	"created_on": "datetime",
+	"created_on": "date",
 }
 
-func GetUser(w http.ResponseWriter, r *http.Request) {
+// userPatchFields are the fields a PATCH can change, and whether they can be null.
+var userPatchFields = map[string]bool{
+	"email": false,
+}
+
+func (api *API) GetUser(w http.ResponseWriter, r *http.Request) {
 	userID, message := GetID(w, r, UserID)
 	if message != nil {
 		return
 	}
 
-	user, err := datastore.GetUser(userID)
+	user, err := api.store.Users.Get(r.Context(), userID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: "Not Found"})
-		} else {
-			WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
-		}
+		WriteError(w, r, err)
+		return
+	}
+
+	setETag(w, user.Version)
+	if notModified(w, r) {
 		return
 	}
 
 	WriteJSON(w, http.StatusOK, user)
 }
 
-func GetUsers(w http.ResponseWriter, r *http.Request) {
-	var statement string
+func (api *API) GetUsers(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
-	where, err := BuildWhere(userFields, query)
+	scope, err := api.scopeFor(r)
 	if err != nil {
-		WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 
-	sort, err := BuildSort(userFields, query)
+	statement, err := BuildQuery(userFields, UserID, query)
 	if err != nil {
-		WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: err.Error()})
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
 		return
 	}
 
-	statement = fmt.Sprintf("%s %s", where, sort)
-	statuses, err := datastore.GetUserList(statement)
+	statuses, err := api.store.Users.List(r.Context(), scope, statement)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: "Error getting user list."})
+		WriteServerError(w, r, err, "Error getting user list.")
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, statuses)
+	count, err := api.store.Users.Count(r.Context(), scope, statement)
+	if err != nil {
+		WriteServerError(w, r, err, "Error getting user count.")
+		return
+	}
+
+	WritePage(w, r, statement, *count, statuses)
 }
 
-func PostUser(w http.ResponseWriter, r *http.Request) {
+func (api *API) PostUser(w http.ResponseWriter, r *http.Request) {
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body.Close()
 
@@ -80,18 +89,34 @@ func PostUser(w http.ResponseWriter, r *http.Request) {
 
 	if user.Password == "" {
 		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: "Invalid Password"})
+		return
+	}
+
+	// the address has to be confirmed through the emailed link
+	user.EmailVerifiedOn = nil
+	created, err := api.store.Users.Create(r.Context(), *user)
+	if err != nil {
+		WriteError(w, r, err)
+		return
 	}
 
-	created, err := datastore.CreateUser(*user)
+	// self registered users start out as members
+	role, err := api.store.Users.AddRole(r.Context(), created.UserID, models.MemberRole)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
+	created.Roles = []*models.Role{role}
+
+	err = api.sendVerification(r.Context(), created)
+	if err != nil {
+		log.Printf("Verification email for %q failed: %s", created.Email, err)
+	}
 
 	WriteJSON(w, http.StatusCreated, created)
 }
 
-func PutUser(w http.ResponseWriter, r *http.Request) {
+func (api *API) PutUser(w http.ResponseWriter, r *http.Request) {
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body.Close()
 
@@ -101,6 +126,11 @@ func PutUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
 	user := &models.User{}
 	err = json.Unmarshal(body, user)
 	if err != nil {
@@ -108,27 +138,90 @@ func PutUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updated, err := datastore.UpdateUser(userID, *user)
+	existing, err := api.store.Users.Get(r.Context(), userID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	user.Version = version
+	updated, err := api.store.Users.Update(r.Context(), userID, *user)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
+	api.reverify(r.Context(), existing, updated)
 
+	setETag(w, updated.Version)
 	WriteJSON(w, http.StatusOK, updated)
 }
 
-func DeleteUser(w http.ResponseWriter, r *http.Request) {
+func (api *API) PatchUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(mux.Vars(r)[UserID], 10, 64)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidUserID})
+		return
+	}
+
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	user := &models.User{}
+	fields, ok := readPatch(w, r, userPatchFields, user)
+	if !ok {
+		return
+	}
+
+	existing, err := api.store.Users.Get(r.Context(), userID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	user.Version = version
+	patched, err := api.store.Users.Patch(r.Context(), userID, *user, fields)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+	api.reverify(r.Context(), existing, patched)
+
+	setETag(w, patched.Version)
+	WriteJSON(w, http.StatusOK, patched)
+}
+
+// reverify sends a verification email to the user's new address if an
+// update changed it. The store has already cleared email_verified_on.
+func (api *API) reverify(ctx context.Context, existing *models.User, updated *models.User) {
+	if existing.Email == updated.Email {
+		return
+	}
+
+	err := api.sendVerification(ctx, updated)
+	if err != nil {
+		log.Printf("Verification email for %q failed: %s", updated.Email, err)
+	}
+}
+
+func (api *API) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	userID, err := strconv.ParseInt(mux.Vars(r)[UserID], 10, 64)
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidUserID})
 		return
 	}
 
-	err = datastore.DeleteUser(userID)
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	err = api.store.Users.Delete(r.Context(), userID, version)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, nil)
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -1,16 +1,17 @@
 package handlers_test
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"time"
 
 	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/mailer"
 	"github.com/lukashambsch/anygym.api/models"
 	"github.com/lukashambsch/anygym.api/router"
-	"github.com/lukashambsch/anygym.api/store"
-	"github.com/lukashambsch/anygym.api/store/datastore"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -26,7 +27,7 @@ var _ = Describe("User API", func() {
 	)
 
 	BeforeEach(func() {
-		server = httptest.NewServer(router.Load())
+		server = httptest.NewServer(router.Load(testStore, testKeys))
 		token, _ = RequestToken(server.URL)
 		userURL = fmt.Sprintf("%s%s/users", server.URL, router.V1URLBase)
 	})
@@ -98,21 +99,21 @@ var _ = Describe("User API", func() {
 			It("should return an error with an invalid field as query param", func() {
 				res, data, _ = Request("GET", fmt.Sprintf("%s?invalid=test", userURL), token, nil)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal("Invalid field in query params."))
 			})
 
 			It("should return an error with an invalid field in order_by", func() {
 				res, data, _ = Request("GET", fmt.Sprintf("%s?order_by=invalid", userURL), token, nil)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal("Invalid order_by field."))
 			})
 
 			It("should return an error with an invalid value for sort_order", func() {
 				res, data, _ = Request("GET", fmt.Sprintf("%s?order_by=user_id&sort_order=random", userURL), token, nil)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal("sort_order must be either 'asc', 'desc', or ''"))
 			})
 		})
@@ -177,7 +178,7 @@ var _ = Describe("User API", func() {
 			})
 
 			AfterEach(func() {
-				datastore.DeleteUser(user.UserID)
+				testStore.Users.Delete(ctx, user.UserID, 0)
 			})
 
 			It("should return status code 201", func() {
@@ -191,6 +192,12 @@ var _ = Describe("User API", func() {
 			It("should save the user", func() {
 				Expect(user.UserID).ToNot(Equal(int64(0)))
 			})
+
+			It("should grant the member role", func() {
+				roles, _ := testStore.Users.Roles(ctx, user.UserID)
+				Expect(len(roles)).To(Equal(1))
+				Expect(roles[0].RoleName).To(Equal(models.MemberRole))
+			})
 		})
 
 		Describe("Unsuccessful POST", func() {
@@ -205,13 +212,31 @@ var _ = Describe("User API", func() {
 				})
 			})
 
-			Describe("Internal Server Error", func() {
-				It("should return status code 500 with a message", func() {
+			Describe("Empty password", func() {
+				BeforeEach(func() {
+					res, data, _ = Request("POST", userURL, token, []byte(`{"email": "nopassword@email.com", "password": ""}`))
+					json.Unmarshal(data, &errRes)
+				})
+
+				It("should return status code 400 with a message", func() {
+					Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+					Expect(errRes.Message).To(Equal("Invalid Password"))
+				})
+
+				It("should not create the user", func() {
+					_, err := testStore.Users.GetByEmail(ctx, "nopassword@email.com")
+					Expect(err).To(Equal(sql.ErrNoRows))
+				})
+			})
+
+			Describe("Conflict", func() {
+				It("should return status code 409 with the field", func() {
 					payload = []byte(`{"email": "lukas.hambsch@gmail.com", "password": "testing"}`)
 					res, data, _ = Request("POST", userURL, token, payload)
 					json.Unmarshal(data, &errRes)
-					Expect(res.StatusCode).To(Equal(http.StatusInternalServerError))
-					Expect(errRes.Message).ToNot(BeEmpty())
+					Expect(res.StatusCode).To(Equal(http.StatusConflict))
+					Expect(errRes.Code).To(Equal(handlers.DuplicateCode))
+					Expect(errRes.Field).To(Equal("email"))
 				})
 			})
 		})
@@ -231,7 +256,7 @@ var _ = Describe("User API", func() {
 			})
 
 			AfterEach(func() {
-				datastore.UpdateUser(userID, models.User{Email: "bugentry@hotmail.com.com"})
+				testStore.Users.Update(ctx, userID, models.User{Email: "bugentry@hotmail.com.com"})
 			})
 
 			It("should return status code 200", func() {
@@ -239,9 +264,17 @@ var _ = Describe("User API", func() {
 			})
 
 			It("should save the updated user", func() {
-				updated, _ := datastore.GetUser(userID)
+				updated, _ := testStore.Users.Get(ctx, userID)
 				Expect(updated.Email).To(Equal("updated@email.com"))
 			})
+
+			It("should keep the user's password", func() {
+				before, _ := testStore.Users.Get(ctx, 1)
+				Request("PUT", fmt.Sprintf("%s/1", userURL), token, []byte(`{"email": "lukas.hambsch@gmail.com"}`))
+				after, _ := testStore.Users.Get(ctx, 1)
+				Expect(after.PasswordHash).NotTo(BeEmpty())
+				Expect(after.PasswordHash).To(Equal(before.PasswordHash))
+			})
 		})
 
 		Describe("Unsuccessful PUT", func() {
@@ -261,39 +294,75 @@ var _ = Describe("User API", func() {
 				Expect(errRes.Message).To(Equal(handlers.InvalidUserID))
 			})
 
-			It("should return status code 500 with a message", func() {
+			It("should return status code 404 for a missing user", func() {
 				res, data, _ = Request("PUT", fmt.Sprintf("%s/5000", userURL), token, payload)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusInternalServerError))
-				Expect(errRes.Message).ToNot(BeEmpty())
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(errRes.Code).To(Equal(handlers.NotFoundCode))
 			})
 		})
 	})
 
+	Describe("Changing the email address", func() {
+		var user *models.User
+
+		BeforeEach(func() {
+			now := time.Now()
+			user, _ = testStore.Users.Create(ctx, models.User{Email: "reverify@email.com", Password: "testing", EmailVerifiedOn: &now})
+			mailer.Default.(*mailer.MemoryMailer).Reset()
+		})
+
+		AfterEach(func() {
+			testStore.Users.Delete(ctx, user.UserID, 0)
+		})
+
+		It("should need the new address verified after a PUT", func() {
+			res, _, _ = Request("PUT", fmt.Sprintf("%s/%d", userURL, user.UserID), token, []byte(`{"email": "reverified@email.com"}`))
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			updated, _ := testStore.Users.Get(ctx, user.UserID)
+			Expect(updated.EmailVerifiedOn).To(BeNil())
+			_, ok := mailer.Default.(*mailer.MemoryMailer).Last("reverified@email.com")
+			Expect(ok).To(BeTrue())
+		})
+
+		It("should need the new address verified after a PATCH", func() {
+			res, _, _ = Request("PATCH", fmt.Sprintf("%s/%d", userURL, user.UserID), token, []byte(`{"email": "reverified@email.com"}`))
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			updated, _ := testStore.Users.Get(ctx, user.UserID)
+			Expect(updated.EmailVerifiedOn).To(BeNil())
+			_, ok := mailer.Default.(*mailer.MemoryMailer).Last("reverified@email.com")
+			Expect(ok).To(BeTrue())
+		})
+
+		It("should keep the verification if the address is the same", func() {
+			res, _, _ = Request("PUT", fmt.Sprintf("%s/%d", userURL, user.UserID), token, []byte(`{"email": "reverify@email.com"}`))
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+
+			updated, _ := testStore.Users.Get(ctx, user.UserID)
+			Expect(updated.EmailVerifiedOn).NotTo(BeNil())
+			Expect(mailer.Default.(*mailer.MemoryMailer).Messages()).To(BeEmpty())
+		})
+	})
+
 	Describe("DeleteUser endpoint", func() {
-		var userID int64 = 111
+		var userID int64
 
 		Describe("Successful DELETE", func() {
 			BeforeEach(func() {
-				store.DB.Exec(
-					"INSERT INTO users (user_id, email) VALUES ($1, $2, $3, $4)",
-					userID,
-					"lukas.hambsch@gmail.com",
-				)
+				user, _ := testStore.Users.Create(ctx, models.User{Email: "deleted@email.com"})
+				userID = user.UserID
 
 				res, _, _ = Request("DELETE", fmt.Sprintf("%s/%d", userURL, userID), token, nil)
 			})
 
-			AfterEach(func() {
-				datastore.DeleteUser(userID)
-			})
-
-			It("should return status code 200", func() {
-				Expect(res.StatusCode).To(Equal(http.StatusOK))
+			It("should return status code 204", func() {
+				Expect(res.StatusCode).To(Equal(http.StatusNoContent))
 			})
 
 			It("should delete the user", func() {
-				_, err := datastore.GetUser(userID)
+				_, err := testStore.Users.Get(ctx, userID)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -307,6 +376,13 @@ var _ = Describe("User API", func() {
 				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal(handlers.InvalidUserID))
 			})
+
+			It("should return status code 404 for a missing id", func() {
+				res, data, _ = Request("DELETE", fmt.Sprintf("%s/5000", userURL), token, nil)
+				json.Unmarshal(data, &errRes)
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(errRes.Code).To(Equal(handlers.NotFoundCode))
+			})
 		})
 	})
 })
@@ -1,87 +1,221 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"net/url"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+
+	"github.com/lukashambsch/anygym.api/config"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+const (
+	InvalidField        = "Invalid field in query params."
+	InvalidValue        = "Invalid value for %s."
+	InvalidOperator     = "Unknown filter operator %s."
+	UnsupportedOperator = "%s can't be used on %s."
+	UnfilterableField   = "Can't filter by %s."
+)
+
+// filterOperators are the [operator] suffixes a filter param can have, e.g.
+// created_on[gte]=2017-01-01. Params without one use the field type's
+// default operator.
+var filterOperators = map[string]datastore.Operator{
+	"eq":       datastore.Equal,
+	"gt":       datastore.GreaterThan,
+	"gte":      datastore.GreaterOrEqual,
+	"lt":       datastore.LessThan,
+	"lte":      datastore.LessOrEqual,
+	"contains": datastore.Contains,
+	"in":       datastore.In,
+	"is_null":  datastore.IsNull,
+}
+
+var (
+	comparisons = []datastore.Operator{
+		datastore.Equal,
+		datastore.GreaterThan,
+		datastore.GreaterOrEqual,
+		datastore.LessThan,
+		datastore.LessOrEqual,
+		datastore.In,
+		datastore.IsNull,
+	}
+
+	// typeOperators are the operators each field type can be filtered with,
+	// the first is the default.
+	typeOperators = map[string][]datastore.Operator{
+		"string": {datastore.Contains, datastore.Equal, datastore.In, datastore.IsNull},
+		"int":    comparisons,
+		"float":  comparisons,
+		"date":   comparisons,
+		"bool":   {datastore.Equal, datastore.IsNull},
+	}
 )
 
-const InvalidField = "Invalid field in query params."
+var filterParam = regexp.MustCompile(`^(\w+)\[(\w+)\]$`)
 
-// fields represents {field: type} mappings for db fields
-func BuildWhere(fields map[string]string, params url.Values) (string, error) {
+// BuildFilters turns query params into filters on the db fields. fields
+// represents {field: type} mappings, the type decides how a param's value is
+// parsed and which operators it can be compared with.
+func BuildFilters(fields map[string]string, params url.Values) ([]datastore.Filter, error) {
 	var (
-		where string = "WHERE"
-		count int    = len(params)
-		i     int    = 1
+		filters []datastore.Filter
+		keys    []string
 	)
-	if count == 0 {
-		return "", nil
+
+	for k := range params {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 
-	for k, v := range params {
-		if _, ok := fields[k]; ok {
-			switch fields[k] {
-			case "string":
-				where = fmt.Sprintf("%s %s LIKE '%%%s%%'", where, k, v[0])
-			case "int":
-				where = fmt.Sprintf("%s %s = '%s'", where, k, v[0])
-			}
+	for _, k := range keys {
+		if k == "order_by" || k == "sort_order" || k == "include" || k == "include_deleted" || pageParams[k] {
+			continue
+		}
+
+		field, operatorName := k, ""
+		if match := filterParam.FindStringSubmatch(k); match != nil {
+			field, operatorName = match[1], match[2]
+		}
+
+		fieldType, ok := fields[field]
+		if !ok {
+			return nil, fmt.Errorf(InvalidField)
+		}
+		allowed, ok := typeOperators[fieldType]
+		if !ok {
+			return nil, fmt.Errorf(UnfilterableField, field)
+		}
 
-			if i < count {
-				where += " AND"
+		operator := allowed[0]
+		if operatorName != "" {
+			operator, ok = filterOperators[operatorName]
+			if !ok {
+				return nil, fmt.Errorf(InvalidOperator, operatorName)
 			}
-		} else if k != "order_by" && k != "sort_order" {
-			return "", fmt.Errorf(InvalidField)
+			if !hasOperator(allowed, operator) {
+				return nil, fmt.Errorf(UnsupportedOperator, operatorName, field)
+			}
+		}
+
+		value, err := parseFilterValue(fieldType, operator, params.Get(k))
+		if err != nil {
+			return nil, fmt.Errorf(InvalidValue, k)
 		}
-		i += 1
+
+		filters = append(filters, datastore.Filter{Field: field, Operator: operator, Value: value})
 	}
 
-	if where == "WHERE" {
-		return "", nil
+	return filters, nil
+}
+
+func hasOperator(operators []datastore.Operator, operator datastore.Operator) bool {
+	for _, o := range operators {
+		if o == operator {
+			return true
+		}
 	}
 
-	return where, nil
+	return false
 }
 
-func BuildSort(fields map[string]string, params url.Values) (string, error) {
-	var (
-		statement    string = "ORDER BY"
-		validOrderBy bool   = false
-	)
+// parseFilterValue parses v as the field's type. [in] takes a comma
+// separated list and [is_null] true or false, true if left empty.
+func parseFilterValue(fieldType string, operator datastore.Operator, v string) (interface{}, error) {
+	switch operator {
+	case datastore.IsNull:
+		if v == "" {
+			return true, nil
+		}
+		return strconv.ParseBool(v)
+	case datastore.In:
+		var values []interface{}
+		for _, item := range strings.Split(v, ",") {
+			value, err := parseValue(fieldType, item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	default:
+		return parseValue(fieldType, v)
+	}
+}
+
+func parseValue(fieldType string, v string) (interface{}, error) {
+	switch fieldType {
+	case "int":
+		return strconv.ParseInt(v, 10, 64)
+	case "float":
+		return strconv.ParseFloat(v, 64)
+	case "bool":
+		return strconv.ParseBool(v)
+	case "date":
+		value, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Parse("2006-01-02", v)
+		}
+		return value, nil
+	default:
+		return v, nil
+	}
+}
 
+func BuildSort(fields map[string]string, params url.Values) ([]datastore.Sort, error) {
 	sortOrder := params.Get("sort_order")
 	orderBy := params.Get("order_by")
 
 	if sortOrder == "" && orderBy == "" {
-		return "", nil
+		return nil, nil
 	}
 
 	if sortOrder == "" {
 		sortOrder = "asc"
 	}
 	if sortOrder != "asc" && sortOrder != "desc" {
-		return "", fmt.Errorf("sort_order must be either 'asc', 'desc', or ''")
+		return nil, fmt.Errorf("sort_order must be either 'asc', 'desc', or ''")
 	}
 
-	for k, _ := range fields {
-		if k == orderBy {
-			validOrderBy = true
-			break
-		}
+	if _, ok := fields[orderBy]; !ok {
+		return nil, fmt.Errorf("Invalid order_by field.")
+	}
+
+	return []datastore.Sort{{Field: orderBy, Descending: sortOrder == "desc"}}, nil
+}
+
+// BuildQuery reads the filters, sort and page of a list request. key is the
+// primary key of the list's rows.
+func BuildQuery(fields map[string]string, key string, params url.Values) (datastore.Query, error) {
+	filters, err := BuildFilters(fields, params)
+	if err != nil {
+		return datastore.Query{}, err
 	}
-	if !validOrderBy {
-		return "", fmt.Errorf("Invalid order_by field.")
+
+	sort, err := BuildSort(fields, params)
+	if err != nil {
+		return datastore.Query{}, err
 	}
 
-	statement = fmt.Sprintf("%s %s %s", statement, orderBy, sortOrder)
+	query := datastore.Query{Filters: filters, Sort: sort}
+	err = BuildPage(&query, key, params)
+	if err != nil {
+		return datastore.Query{}, err
+	}
 
-	return statement, nil
+	return query, nil
 }
 
 func GetID(w http.ResponseWriter, r *http.Request, idField string) (int64, *APIErrorMessage) {
@@ -99,8 +233,9 @@ func GetNotFoundError(w http.ResponseWriter, encoder *json.Encoder, err error) {
 		w.WriteHeader(http.StatusNotFound)
 		encoder.Encode(APIErrorMessage{Message: "Not Found"})
 	} else {
+		log.Printf("Lookup failed: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
-		encoder.Encode(APIErrorMessage{Message: err.Error()})
+		encoder.Encode(APIErrorMessage{Message: InternalError, Code: InternalCode})
 	}
 }
 
@@ -108,8 +243,9 @@ func WriteJSON(w http.ResponseWriter, statusCode int, response interface{}) {
 	// check that json can be encoded
 	_, err := json.Marshal(response)
 	if err != nil {
+		log.Printf("Encoding the response failed: %s", err)
 		statusCode = http.StatusInternalServerError
-		response = APIErrorMessage{Message: err.Error()}
+		response = APIErrorMessage{Message: InternalError, Code: InternalCode}
 	}
 
 	encoder := json.NewEncoder(w)
@@ -121,11 +257,12 @@ func CORS(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE")
 		w.Header().Set(
 			"Access-Control-Allow-Headers",
-			"Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization",
+			"Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, If-Match, If-None-Match, Idempotency-Key",
 		)
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Idempotent-Replayed, X-Total-Count, Link")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		if r.Method == "OPTIONS" {
@@ -134,3 +271,26 @@ func CORS(h http.Handler) http.Handler {
 		h.ServeHTTP(w, r)
 	})
 }
+
+// requestTimeout is how long a request has before its context is cancelled,
+// which cancels the queries it's still running.
+var requestTimeout = loadRequestTimeout()
+
+func loadRequestTimeout() time.Duration {
+	if config.C.IsSet("host.request_timeout") {
+		return config.C.GetDuration("host.request_timeout")
+	}
+
+	return 30 * time.Second
+}
+
+// Timeout gives every request requestTimeout to finish. Handlers answer the
+// ones that run out of time with a 503 through WriteServerError.
+func Timeout(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+		defer cancel()
+
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
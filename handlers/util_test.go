@@ -2,8 +2,10 @@ package handlers_test
 
 import (
 	"net/url"
+	"time"
 
 	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/store/datastore"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -11,58 +13,174 @@ import (
 
 var _ = Describe("Handlers utils", func() {
 	var fields map[string]string = map[string]string{
-		"user_id": "int",
-		"email":   "string",
-	}
-	var params url.Values = url.Values{
-		"user_id": []string{"1"},
-		"email":   []string{"test@gmail.com"},
+		"user_id":    "int",
+		"email":      "string",
+		"created_on": "date",
+		"active":     "bool",
+		"fee":        "float",
+		"scopes":     "array",
 	}
+	var params url.Values
+
+	BeforeEach(func() {
+		params = url.Values{
+			"user_id": []string{"1"},
+			"email":   []string{"test@gmail.com"},
+		}
+	})
 
-	Describe("BuildWhere function", func() {
-		var statement string
+	Describe("BuildFilters function", func() {
+		var (
+			filters []datastore.Filter
+			err     error
+		)
 
 		Describe("With valid params and fields", func() {
 			BeforeEach(func() {
-				statement, _ = handlers.BuildWhere(fields, params)
+				filters, err = handlers.BuildFilters(fields, params)
 			})
 
-			It("should start with WHERE", func() {
-				Expect(statement[:5]).To(Equal("WHERE"))
+			It("should return a filter for each param", func() {
+				Expect(err).To(BeNil())
+				Expect(len(filters)).To(Equal(2))
 			})
 
-			It("should contain AND", func() {
-				Expect(statement).To(ContainSubstring("AND"))
+			It("should construct correct filter - string", func() {
+				Expect(filters).To(ContainElement(datastore.Filter{Field: "email", Operator: datastore.Contains, Value: "test@gmail.com"}))
 			})
 
-			It("should construct correct statement - string", func() {
-				Expect(statement).To(ContainSubstring("email LIKE '%test@gmail.com%'"))
+			It("should construct correct filter - int", func() {
+				Expect(filters).To(ContainElement(datastore.Filter{Field: "user_id", Operator: datastore.Equal, Value: int64(1)}))
 			})
 
-			It("should construct correct statement - int", func() {
-				Expect(statement).To(ContainSubstring("user_id = '1'"))
+			It("should keep sql in values as a plain value", func() {
+				params.Set("email", "' OR '1'='1")
+				filters, _ = handlers.BuildFilters(fields, params)
+				Expect(filters).To(ContainElement(datastore.Filter{Field: "email", Operator: datastore.Contains, Value: "' OR '1'='1"}))
 			})
-		})
 
-		Describe("Empty return", func() {
-			It("should return empty string if no params", func() {
-				params = url.Values{}
-				statement, _ = handlers.BuildWhere(fields, params)
-				Expect(statement).To(Equal(""))
+			It("should skip the sort params", func() {
+				params.Set("order_by", "email")
+				filters, _ = handlers.BuildFilters(fields, params)
+				Expect(len(filters)).To(Equal(2))
 			})
+		})
 
-			It("should return empty string if no fields", func() {
-				fields = map[string]string{}
-				statement, _ = handlers.BuildWhere(fields, params)
-				Expect(statement).To(Equal(""))
+		Describe("Empty return", func() {
+			It("should return no filters if no params", func() {
+				filters, _ = handlers.BuildFilters(fields, url.Values{})
+				Expect(filters).To(BeEmpty())
 			})
 
-			It("should return empty string and error if invalid field", func() {
+			It("should return an error if invalid field", func() {
 				params["invalid"] = []string{""}
-				statement, err := handlers.BuildWhere(fields, params)
-				Expect(statement).To(Equal(""))
+				filters, err = handlers.BuildFilters(fields, params)
+				Expect(filters).To(BeNil())
 				Expect(err.Error()).To(Equal(handlers.InvalidField))
 			})
+
+			It("should return an error for an int that isn't a number", func() {
+				params.Set("user_id", "1 OR 1=1")
+				filters, err = handlers.BuildFilters(fields, params)
+				Expect(filters).To(BeNil())
+				Expect(err.Error()).To(Equal("Invalid value for user_id."))
+			})
+
+			It("should return an error for an invalid date", func() {
+				params.Set("created_on", "yesterday")
+				_, err = handlers.BuildFilters(fields, params)
+				Expect(err.Error()).To(Equal("Invalid value for created_on."))
+			})
+
+			It("should return an error for fields that can't be filtered", func() {
+				params.Set("scopes", "visits:read")
+				_, err = handlers.BuildFilters(fields, params)
+				Expect(err.Error()).To(Equal("Can't filter by scopes."))
+			})
+		})
+
+		Describe("Operators", func() {
+			filter := func(key string, value string) datastore.Filter {
+				filters, err := handlers.BuildFilters(fields, url.Values{key: []string{value}})
+				Expect(err).To(BeNil())
+				Expect(len(filters)).To(Equal(1))
+				return filters[0]
+			}
+
+			filterError := func(key string, value string) string {
+				_, err := handlers.BuildFilters(fields, url.Values{key: []string{value}})
+				Expect(err).ToNot(BeNil())
+				return err.Error()
+			}
+
+			It("should parse comparisons on dates", func() {
+				Expect(filter("created_on[gte]", "2017-01-01")).To(Equal(datastore.Filter{
+					Field:    "created_on",
+					Operator: datastore.GreaterOrEqual,
+					Value:    time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC),
+				}))
+				Expect(filter("created_on[lt]", "2017-01-01T10:00:00Z").Operator).To(Equal(datastore.LessThan))
+			})
+
+			It("should parse floats", func() {
+				Expect(filter("fee[lte]", "19.99")).To(Equal(datastore.Filter{Field: "fee", Operator: datastore.LessOrEqual, Value: 19.99}))
+			})
+
+			It("should parse bools", func() {
+				Expect(filter("active", "true")).To(Equal(datastore.Filter{Field: "active", Operator: datastore.Equal, Value: true}))
+			})
+
+			It("should parse lists for in", func() {
+				Expect(filter("user_id[in]", "1,2")).To(Equal(datastore.Filter{
+					Field:    "user_id",
+					Operator: datastore.In,
+					Value:    []interface{}{int64(1), int64(2)},
+				}))
+			})
+
+			It("should parse is_null", func() {
+				Expect(filter("created_on[is_null]", "").Value).To(Equal(true))
+				Expect(filter("created_on[is_null]", "false").Value).To(Equal(false))
+			})
+
+			It("should compare strings exactly with eq", func() {
+				Expect(filter("email[eq]", "test@gmail.com").Operator).To(Equal(datastore.Equal))
+			})
+
+			It("should return an error for bad values", func() {
+				Expect(filterError("fee[lte]", "cheap")).To(Equal("Invalid value for fee[lte]."))
+				Expect(filterError("user_id[in]", "1,two")).To(Equal("Invalid value for user_id[in]."))
+				Expect(filterError("active", "yes please")).To(Equal("Invalid value for active."))
+			})
+
+			It("should return an error for unknown operators", func() {
+				Expect(filterError("user_id[near]", "1")).To(Equal("Unknown filter operator near."))
+			})
+
+			It("should return an error for operators the type doesn't support", func() {
+				Expect(filterError("active[gte]", "true")).To(Equal("gte can't be used on active."))
+			})
+
+			It("should return an error for operators on unknown fields", func() {
+				Expect(filterError("invalid[gte]", "1")).To(Equal(handlers.InvalidField))
+			})
+		})
+	})
+
+	Describe("BuildSort function", func() {
+		It("should sort ascending by default", func() {
+			sort, _ := handlers.BuildSort(fields, url.Values{"order_by": []string{"email"}})
+			Expect(sort).To(Equal([]datastore.Sort{{Field: "email"}}))
+		})
+
+		It("should sort descending", func() {
+			sort, _ := handlers.BuildSort(fields, url.Values{"order_by": []string{"email"}, "sort_order": []string{"desc"}})
+			Expect(sort).To(Equal([]datastore.Sort{{Field: "email", Descending: true}}))
+		})
+
+		It("should return an error for an unknown field", func() {
+			_, err := handlers.BuildSort(fields, url.Values{"order_by": []string{"email; DROP TABLE users"}})
+			Expect(err).ToNot(BeNil())
 		})
 	})
 })
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/lukashambsch/anygym.api/config"
+	"github.com/lukashambsch/anygym.api/mailer"
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+const (
+	verifyEmailAudience = "verify_email"
+	verifyEmailLifetime = 24 * time.Hour
+)
+
+const InvalidVerificationToken string = "Invalid or expired verification token."
+
+// VerificationClaims are signed into email verification links. They are
+// tied to the address being verified so a link stops working if the user
+// changes their email.
+type VerificationClaims struct {
+	Email string `json:"email"`
+	jwt.StandardClaims
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+func isVerified(user *models.User) bool {
+	return user.EmailVerifiedOn != nil
+}
+
+func (api *API) sendVerification(ctx context.Context, user *models.User) error {
+	token, err := api.keys.Sign(VerificationClaims{
+		Email: user.Email,
+		StandardClaims: jwt.StandardClaims{
+			Audience:  verifyEmailAudience,
+			ExpiresAt: time.Now().Add(verifyEmailLifetime).Unix(),
+			Issuer:    tokenIssuer(),
+			Subject:   strconv.FormatInt(user.UserID, 10),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return sendMail(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Use the link below to verify your email address. It expires in %v.\n\n%s?token=%s\n",
+			verifyEmailLifetime,
+			config.C.GetString("mail.verify_url"),
+			token,
+		),
+	})
+}
+
+func (api *API) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+
+	verify := VerifyEmailRequest{}
+	err := json.Unmarshal(body, &verify)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+		return
+	}
+
+	claims := &VerificationClaims{}
+	token, err := api.keys.Parse(verify.Token, claims)
+	if err != nil || !token.Valid || !claims.VerifyAudience(verifyEmailAudience, true) {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidVerificationToken})
+		return
+	}
+
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidVerificationToken})
+		return
+	}
+
+	err = api.store.Users.VerifyEmail(r.Context(), userID, claims.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidVerificationToken})
+		} else {
+			WriteError(w, r, err)
+		}
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, nil)
+}
+
+// ResendVerification sends a fresh verification link. Like ForgotPassword it
+// responds the same way for unknown and already verified addresses.
+func (api *API) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+
+	resend := ResendVerificationRequest{}
+	err := json.Unmarshal(body, &resend)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+		return
+	}
+
+	user, err := api.store.Users.GetByEmail(r.Context(), resend.Email)
+	if err == nil && !isVerified(user) {
+		err = api.sendVerification(r.Context(), user)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Verification email for %q failed: %s", resend.Email, err)
+	}
+
+	WriteJSON(w, http.StatusAccepted, nil)
+}
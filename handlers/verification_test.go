@@ -0,0 +1,137 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/mailer"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/router"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Email verification API", func() {
+	var (
+		server    *httptest.Server
+		verifyURL string
+		resendURL string
+		res       *http.Response
+		data      []byte
+		user      models.User
+		errRes    handlers.APIErrorMessage
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(router.Load(testStore, testKeys))
+		verifyURL = fmt.Sprintf("%s%s/email/verify", server.URL, router.V1URLBase)
+		resendURL = fmt.Sprintf("%s%s/email/verify/resend", server.URL, router.V1URLBase)
+		mailer.Default.(*mailer.MemoryMailer).Reset()
+
+		_, data, _ = Request(
+			"POST",
+			fmt.Sprintf("%s%s/users", server.URL, router.V1URLBase),
+			"",
+			[]byte(`{"email": "unverified@email.com", "password": "testing"}`),
+		)
+		json.Unmarshal(data, &user)
+	})
+
+	AfterEach(func() {
+		testStore.Users.Delete(ctx, user.UserID, 0)
+		server.Close()
+	})
+
+	Describe("Signup", func() {
+		It("should create an unverified user", func() {
+			Expect(user.EmailVerifiedOn).To(BeNil())
+		})
+
+		It("should email a verification link", func() {
+			Expect(mailedToken("unverified@email.com")).ToNot(BeEmpty())
+		})
+
+		It("should not let the user log in", func() {
+			res, data, _ = Request(
+				"POST",
+				fmt.Sprintf("%s%s/authenticate", server.URL, router.V1URLBase),
+				"",
+				[]byte(`{"email": "unverified@email.com", "password": "testing"}`),
+			)
+			json.Unmarshal(data, &errRes)
+			Expect(res.StatusCode).To(Equal(http.StatusForbidden))
+			Expect(errRes.Message).To(Equal(handlers.ErrEmailNotVerified.Error()))
+		})
+
+		It("should not let the user check in", func() {
+			member, _ := testStore.Members.Create(ctx, models.Member{FirstName: "Unverified", UserID: user.UserID})
+			defer testStore.Members.Delete(ctx, member.MemberID, 0)
+
+			token, _ := RequestToken(server.URL)
+			res, _, _ = Request(
+				"POST",
+				fmt.Sprintf("%s%s/visits", server.URL, router.V1URLBase),
+				token,
+				[]byte(fmt.Sprintf(`{"member_id": %d, "gym_location_id": 1, "status_id": 1}`, member.MemberID)),
+			)
+			Expect(res.StatusCode).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Describe("VerifyEmail endpoint", func() {
+		Describe("Successful POST", func() {
+			BeforeEach(func() {
+				payload := []byte(fmt.Sprintf(`{"token": "%s"}`, mailedToken("unverified@email.com")))
+				res, _, _ = Request("POST", verifyURL, "", payload)
+			})
+
+			It("should return status code 200", func() {
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+			})
+
+			It("should mark the email as verified", func() {
+				verified, _ := testStore.Users.Get(ctx, user.UserID)
+				Expect(verified.EmailVerifiedOn).ToNot(BeNil())
+			})
+
+			It("should let the user log in", func() {
+				tokens, _ := RequestUserTokens(server.URL, "unverified@email.com", "testing")
+				Expect(tokens.AccessToken).ToNot(BeEmpty())
+			})
+		})
+
+		Describe("Unsuccessful POST", func() {
+			It("should return status code 400 for an invalid token", func() {
+				res, data, _ = Request("POST", verifyURL, "", []byte(`{"token": "invalid"}`))
+				json.Unmarshal(data, &errRes)
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(errRes.Message).To(Equal(handlers.InvalidVerificationToken))
+			})
+
+			It("should return status code 400 for an access token", func() {
+				token, _ := RequestToken(server.URL)
+				res, _, _ = Request("POST", verifyURL, "", []byte(fmt.Sprintf(`{"token": "%s"}`, token)))
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	Describe("ResendVerification endpoint", func() {
+		It("should return status code 202 and send another link", func() {
+			mailer.Default.(*mailer.MemoryMailer).Reset()
+			res, _, _ = Request("POST", resendURL, "", []byte(`{"email": "unverified@email.com"}`))
+			Expect(res.StatusCode).To(Equal(http.StatusAccepted))
+			Expect(mailedToken("unverified@email.com")).ToNot(BeEmpty())
+		})
+
+		It("should not send anything to verified users", func() {
+			mailer.Default.(*mailer.MemoryMailer).Reset()
+			res, _, _ = Request("POST", resendURL, "", []byte(`{"email": "lukas.hambsch@gmail.com"}`))
+			Expect(res.StatusCode).To(Equal(http.StatusAccepted))
+			Expect(mailer.Default.(*mailer.MemoryMailer).Messages()).To(BeEmpty())
+		})
+	})
+})
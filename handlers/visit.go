@@ -3,7 +3,6 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
@@ -26,51 +25,94 @@ var visitFields map[string]string = map[string]string{
 	"modified_on":     "date",
 }
 
-func GetVisit(w http.ResponseWriter, r *http.Request) {
+// visitPatchFields are the fields a PATCH can change, and whether they can be null.
+var visitPatchFields = map[string]bool{
+	"member_id":       false,
+	"gym_location_id": false,
+	"status_id":       false,
+}
+
+func (api *API) GetVisit(w http.ResponseWriter, r *http.Request) {
 	visitID, message := GetID(w, r, VisitID)
 	if message != nil {
 		return
 	}
 
-	visit, err := datastore.GetVisit(visitID)
+	scope, err := api.scopeFor(r)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: "Not Found"})
-		} else {
-			WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
-		}
+		WriteError(w, r, err)
+		return
+	}
+
+	includes, err := ParseIncludes(r.URL.Query(), visitIncludes)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
+		return
+	}
+
+	visit, err := api.store.Visits.Get(r.Context(), scope, visitID)
+	if err != nil {
+		WriteError(w, r, err)
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, visit)
+	setETag(w, visit.Version)
+	if len(includes) == 0 && notModified(w, r) {
+		return
+	}
+
+	visits := []models.Visit{*visit}
+	err = api.includeVisits(r.Context(), scope, visits, includes)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, &visits[0])
 }
 
-func GetVisits(w http.ResponseWriter, r *http.Request) {
-	var statement string
+func (api *API) GetVisits(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
-	where, err := BuildWhere(visitFields, query)
+	scope, err := api.scopeFor(r)
 	if err != nil {
-		WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 
-	sort, err := BuildSort(visitFields, query)
+	includes, err := ParseIncludes(query, visitIncludes)
 	if err != nil {
-		WriteJSON(w, http.StatusNotFound, APIErrorMessage{Message: err.Error()})
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
 		return
 	}
 
-	statement = fmt.Sprintf("%s %s", where, sort)
-	visits, err := datastore.GetVisitList(statement)
+	statement, err := BuildQuery(visitFields, VisitID, query)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: "Error getting visit list."})
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: err.Error()})
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, visits)
+	visits, err := api.store.Visits.List(r.Context(), scope, statement)
+	if err != nil {
+		WriteServerError(w, r, err, "Error getting visit list.")
+		return
+	}
+
+	err = api.includeVisits(r.Context(), scope, visits, includes)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	count, err := api.store.Visits.Count(r.Context(), scope, statement)
+	if err != nil {
+		WriteServerError(w, r, err, "Error getting visit count.")
+		return
+	}
+
+	WritePage(w, r, statement, *count, visits)
 }
 
-func PostVisit(w http.ResponseWriter, r *http.Request) {
+func (api *API) PostVisit(w http.ResponseWriter, r *http.Request) {
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body.Close()
 
@@ -81,16 +123,44 @@ func PostVisit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	created, err := datastore.CreateVisit(*visit)
+	scope, err := api.writeScopeFor(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	// members can only check themselves in
+	if !seesAll(scope) {
+		own, err := api.store.Members.GetByUserID(r.Context(), scope.UserID)
+		if err != nil {
+			WriteError(w, r, err)
+			return
+		}
+		visit.MemberID = own.MemberID
+	}
+
+	// members can't check in anywhere until they've verified their email. A
+	// member that doesn't exist is left for Create to report.
+	member, err := api.store.Members.Get(r.Context(), datastore.Unscoped, visit.MemberID)
+	if err != nil && err != sql.ErrNoRows {
+		WriteError(w, r, err)
+		return
+	}
+	if err == nil && !isVerified(member.User) {
+		WriteJSON(w, http.StatusForbidden, APIErrorMessage{Message: ErrEmailNotVerified.Error()})
+		return
+	}
+
+	created, err := api.store.Visits.Create(r.Context(), *visit)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 
 	WriteJSON(w, http.StatusCreated, created)
 }
 
-func PutVisit(w http.ResponseWriter, r *http.Request) {
+func (api *API) PutVisit(w http.ResponseWriter, r *http.Request) {
 	body, _ := ioutil.ReadAll(r.Body)
 	r.Body.Close()
 
@@ -100,6 +170,23 @@ func PutVisit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	scope, err := api.writeScopeFor(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	current, err := api.store.Visits.Get(r.Context(), scope, visitID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
 	visit := &models.Visit{}
 	err = json.Unmarshal(body, visit)
 	if err != nil {
@@ -107,27 +194,84 @@ func PutVisit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updated, err := datastore.UpdateVisit(visitID, *visit)
+	// locations can't move visits to a location they don't manage
+	if !seesAll(scope) {
+		visit.GymLocationID = current.GymLocationID
+	}
+
+	visit.Version = version
+	updated, err := api.store.Visits.Update(r.Context(), visitID, *visit)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 
+	setETag(w, updated.Version)
 	WriteJSON(w, http.StatusOK, updated)
 }
 
-func DeleteVisit(w http.ResponseWriter, r *http.Request) {
+func (api *API) PatchVisit(w http.ResponseWriter, r *http.Request) {
 	visitID, err := strconv.ParseInt(mux.Vars(r)[VisitID], 10, 64)
 	if err != nil {
 		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidVisitID})
 		return
 	}
 
-	err = datastore.DeleteVisit(visitID)
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	scope, err := api.writeScopeFor(r)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	current, err := api.store.Visits.Get(r.Context(), scope, visitID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	visit := &models.Visit{}
+	fields, ok := readPatch(w, r, visitPatchFields, visit)
+	if !ok {
+		return
+	}
+
+	if !seesAll(scope) {
+		visit.GymLocationID = current.GymLocationID
+	}
+
+	visit.Version = version
+	patched, err := api.store.Visits.Patch(r.Context(), visitID, *visit, fields)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	setETag(w, patched.Version)
+	WriteJSON(w, http.StatusOK, patched)
+}
+
+func (api *API) DeleteVisit(w http.ResponseWriter, r *http.Request) {
+	visitID, err := strconv.ParseInt(mux.Vars(r)[VisitID], 10, 64)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, APIErrorMessage{Message: InvalidVisitID})
+		return
+	}
+
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	err = api.store.Visits.Delete(r.Context(), visitID, version)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, APIErrorMessage{Message: err.Error()})
+		WriteError(w, r, err)
 		return
 	}
 
-	WriteJSON(w, http.StatusOK, nil)
+	w.WriteHeader(http.StatusNoContent)
 }
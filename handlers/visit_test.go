@@ -1,6 +1,7 @@
 package handlers_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -15,6 +16,15 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+// slowMembers times out every member lookup.
+type slowMembers struct {
+	store.MemberRepository
+}
+
+func (slowMembers) Get(ctx context.Context, scope datastore.Scope, memberID int64) (*models.Member, error) {
+	return nil, context.DeadlineExceeded
+}
+
 var _ = Describe("Visit API", func() {
 	var (
 		server     *httptest.Server
@@ -26,7 +36,7 @@ var _ = Describe("Visit API", func() {
 	)
 
 	BeforeEach(func() {
-		server = httptest.NewServer(router.Load())
+		server = httptest.NewServer(router.Load(testStore, testKeys))
 		token, _ = RequestToken(server.URL)
 		visitURL = fmt.Sprintf("%s%s/visits", server.URL, router.V1URLBase)
 	})
@@ -75,6 +85,32 @@ var _ = Describe("Visit API", func() {
 				Expect(len(visits)).To(Equal(0))
 			})
 
+			It("should return visits matching any of a list - visit_id[in]", func() {
+				res, data, _ = Request("GET", fmt.Sprintf("%s?visit_id[in]=1,2,10", visitURL), token, nil)
+				json.Unmarshal(data, &visits)
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+				Expect(len(visits)).To(Equal(2))
+			})
+
+			It("should return visits in a date range - created_on[gte], created_on[lt]", func() {
+				res, data, _ = Request("GET", fmt.Sprintf("%s?created_on[gte]=2000-01-01&created_on[lt]=2100-01-01", visitURL), token, nil)
+				json.Unmarshal(data, &visits)
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+				Expect(len(visits)).To(Equal(5))
+
+				visits = nil
+				res, data, _ = Request("GET", fmt.Sprintf("%s?created_on[lt]=2000-01-01", visitURL), token, nil)
+				json.Unmarshal(data, &visits)
+				Expect(len(visits)).To(Equal(0))
+			})
+
+			It("should return visits that were never modified - modified_on[is_null]", func() {
+				res, data, _ = Request("GET", fmt.Sprintf("%s?modified_on[is_null]=true", visitURL), token, nil)
+				json.Unmarshal(data, &visits)
+				Expect(res.StatusCode).To(Equal(http.StatusOK))
+				Expect(len(visits)).To(Equal(5))
+			})
+
 			It("should sort visits by the correct field ascending", func() {
 				res, data, _ = Request("GET", fmt.Sprintf("%s?sort_order=asc&order_by=member_id", visitURL), token, nil)
 				json.Unmarshal(data, &visits)
@@ -104,21 +140,28 @@ var _ = Describe("Visit API", func() {
 			It("should return an error with an invalid field as query param", func() {
 				res, data, _ = Request("GET", fmt.Sprintf("%s?invalid=test", visitURL), token, nil)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal("Invalid field in query params."))
 			})
 
+			It("should return an error with an invalid date", func() {
+				res, data, _ = Request("GET", fmt.Sprintf("%s?created_on[gte]=yesterday", visitURL), token, nil)
+				json.Unmarshal(data, &errRes)
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
+				Expect(errRes.Message).To(Equal("Invalid value for created_on[gte]."))
+			})
+
 			It("should return an error with an invalid field in order_by", func() {
 				res, data, _ = Request("GET", fmt.Sprintf("%s?order_by=invalid", visitURL), token, nil)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal("Invalid order_by field."))
 			})
 
 			It("should return an error with an invalid value for sort_order", func() {
 				res, data, _ = Request("GET", fmt.Sprintf("%s?order_by=member_id&sort_order=random", visitURL), token, nil)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal("sort_order must be either 'asc', 'desc', or ''"))
 			})
 		})
@@ -182,7 +225,7 @@ var _ = Describe("Visit API", func() {
 			})
 
 			AfterEach(func() {
-				datastore.DeleteVisit(visit.VisitID)
+				testStore.Visits.Delete(ctx, visit.VisitID, 0)
 			})
 
 			It("should return visit code 201", func() {
@@ -210,13 +253,30 @@ var _ = Describe("Visit API", func() {
 				})
 			})
 
-			Describe("Internal Server Error", func() {
-				It("should return visit code 500 with a message", func() {
+			Describe("Member lookup failing", func() {
+				It("should return status code 504 without saving the visit", func() {
+					slow := *testStore
+					slow.Members = slowMembers{testStore.Members}
+					slowServer := httptest.NewServer(router.Load(&slow, testKeys))
+					defer slowServer.Close()
+
+					before, _ := testStore.Visits.Count(ctx, datastore.Unscoped, datastore.Query{})
+					res, _, _ = Request("POST", fmt.Sprintf("%s%s/visits", slowServer.URL, router.V1URLBase), token, payload)
+					Expect(res.StatusCode).To(Equal(http.StatusGatewayTimeout))
+
+					after, _ := testStore.Visits.Count(ctx, datastore.Unscoped, datastore.Query{})
+					Expect(*after).To(Equal(*before))
+				})
+			})
+
+			Describe("Unprocessable Entity", func() {
+				It("should return visit code 422 with the field", func() {
 					payload = []byte(`{"member_id": 1}`)
 					res, data, _ = Request("POST", visitURL, token, payload)
 					json.Unmarshal(data, &errRes)
-					Expect(res.StatusCode).To(Equal(http.StatusInternalServerError))
-					Expect(errRes.Message).ToNot(BeEmpty())
+					Expect(res.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+					Expect(errRes.Code).To(Equal(handlers.InvalidReferenceCode))
+					Expect(errRes.Field).To(Equal("gym_location_id"))
 				})
 			})
 		})
@@ -236,7 +296,7 @@ var _ = Describe("Visit API", func() {
 			})
 
 			AfterEach(func() {
-				datastore.UpdateVisit(visitID, models.Visit{MemberID: 1})
+				testStore.Visits.Update(ctx, visitID, models.Visit{MemberID: 1})
 			})
 
 			It("should return visit code 200", func() {
@@ -248,7 +308,7 @@ var _ = Describe("Visit API", func() {
 			//})
 
 			It("should save the updated visit", func() {
-				updated, _ := datastore.GetVisit(visitID)
+				updated, _ := testStore.Visits.Get(ctx, datastore.Unscoped, visitID)
 				Expect(updated.VisitID).To(Equal(visitID))
 			})
 		})
@@ -270,11 +330,11 @@ var _ = Describe("Visit API", func() {
 				Expect(errRes.Message).To(Equal(handlers.InvalidVisitID))
 			})
 
-			It("should return visit code 500 with a message", func() {
+			It("should return visit code 404 for a missing visit", func() {
 				res, data, _ = Request("PUT", fmt.Sprintf("%s/5000", visitURL), token, payload)
 				json.Unmarshal(data, &errRes)
-				Expect(res.StatusCode).To(Equal(http.StatusInternalServerError))
-				Expect(errRes.Message).ToNot(BeEmpty())
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(errRes.Code).To(Equal(handlers.NotFoundCode))
 			})
 		})
 	})
@@ -287,22 +347,12 @@ var _ = Describe("Visit API", func() {
 				res, _, _ = Request("DELETE", fmt.Sprintf("%s/%d", visitURL, visitID), token, nil)
 			})
 
-			AfterEach(func() {
-				store.DB.Exec(
-					"INSERT INTO visits (visit_id, member_id, gym_location_id, status_id) VALUES ($1, $2, $3, $4)",
-					visitID,
-					1,
-					1,
-					1,
-				)
-			})
-
-			It("should return visit code 200", func() {
-				Expect(res.StatusCode).To(Equal(http.StatusOK))
+			It("should return visit code 204", func() {
+				Expect(res.StatusCode).To(Equal(http.StatusNoContent))
 			})
 
 			It("should delete the visit", func() {
-				_, err := datastore.GetVisit(visitID)
+				_, err := testStore.Visits.Get(ctx, datastore.Unscoped, visitID)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -316,6 +366,13 @@ var _ = Describe("Visit API", func() {
 				Expect(res.StatusCode).To(Equal(http.StatusBadRequest))
 				Expect(errRes.Message).To(Equal(handlers.InvalidVisitID))
 			})
+
+			It("should return status code 404 for a missing id", func() {
+				res, data, _ = Request("DELETE", fmt.Sprintf("%s/5000", visitURL), token, nil)
+				json.Unmarshal(data, &errRes)
+				Expect(res.StatusCode).To(Equal(http.StatusNotFound))
+				Expect(errRes.Code).To(Equal(handlers.NotFoundCode))
+			})
 		})
 	})
 })
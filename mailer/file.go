@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileMailer writes every message to its own .eml file in dir instead of
+// sending it. Meant for local development.
+type FileMailer struct {
+	dir   string
+	from  string
+	mutex sync.Mutex
+	count int
+}
+
+func NewFileMailer(dir string, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	err := os.MkdirAll(m.dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	m.count += 1
+	name := fmt.Sprintf("%s-%d.eml", time.Now().Format("20060102T150405.000000000"), m.count)
+
+	return ioutil.WriteFile(filepath.Join(m.dir, name), format(m.from, msg), 0644)
+}
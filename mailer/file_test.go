@@ -0,0 +1,40 @@
+package mailer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/lukashambsch/anygym.api/mailer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FileMailer", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "mailer")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Describe("Send", func() {
+		It("should write the message to a file", func() {
+			m := mailer.NewFileMailer(filepath.Join(dir, "outbox"), "no-reply@email.com")
+			err := m.Send(mailer.Message{To: "member@email.com", Subject: "Hello", Body: "Body text"})
+			Expect(err).To(BeNil())
+
+			files, _ := ioutil.ReadDir(filepath.Join(dir, "outbox"))
+			Expect(len(files)).To(Equal(1))
+
+			contents, _ := ioutil.ReadFile(filepath.Join(dir, "outbox", files[0].Name()))
+			Expect(string(contents)).To(ContainSubstring("From: no-reply@email.com"))
+			Expect(string(contents)).To(ContainSubstring("To: member@email.com"))
+			Expect(string(contents)).To(ContainSubstring("Subject: Hello"))
+			Expect(string(contents)).To(ContainSubstring("Body text"))
+		})
+	})
+})
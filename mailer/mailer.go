@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/lukashambsch/anygym.api/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers outgoing email.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Default is the mailer configured for the current environment.
+var Default Mailer
+
+func init() {
+	var err error
+
+	Default, err = Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Load builds the mailer selected by mail.driver in the config.
+func Load() (Mailer, error) {
+	from := config.C.GetString("mail.from")
+
+	switch driver := config.C.GetString("mail.driver"); driver {
+	case "smtp":
+		return NewSMTPMailer(
+			config.C.GetString("mail.smtp.host"),
+			config.C.GetInt("mail.smtp.port"),
+			config.C.GetString("mail.smtp.username"),
+			config.C.GetString("mail.smtp.password"),
+			from,
+		), nil
+	case "file":
+		return NewFileMailer(config.C.GetString("mail.dir"), from), nil
+	case "memory", "":
+		return NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("Unknown mail driver %q", driver)
+	}
+}
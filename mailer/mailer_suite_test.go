@@ -0,0 +1,13 @@
+package mailer_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestMailer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Mailer Suite")
+}
@@ -0,0 +1,50 @@
+package mailer
+
+import "sync"
+
+// MemoryMailer keeps sent messages in memory so tests can inspect them.
+type MemoryMailer struct {
+	mutex    sync.Mutex
+	messages []Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(msg Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns every message sent so far, oldest first.
+func (m *MemoryMailer) Messages() []Message {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
+
+// Last returns the most recently sent message to address.
+func (m *MemoryMailer) Last(address string) (Message, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == address {
+			return m.messages[i], true
+		}
+	}
+
+	return Message{}, false
+}
+
+func (m *MemoryMailer) Reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.messages = nil
+}
@@ -0,0 +1,47 @@
+package mailer_test
+
+import (
+	"github.com/lukashambsch/anygym.api/mailer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MemoryMailer", func() {
+	var m *mailer.MemoryMailer
+
+	BeforeEach(func() {
+		m = mailer.NewMemoryMailer()
+		m.Send(mailer.Message{To: "first@email.com", Subject: "First"})
+		m.Send(mailer.Message{To: "second@email.com", Subject: "Second"})
+		m.Send(mailer.Message{To: "first@email.com", Subject: "Third"})
+	})
+
+	Describe("Messages", func() {
+		It("should return every sent message in order", func() {
+			messages := m.Messages()
+			Expect(len(messages)).To(Equal(3))
+			Expect(messages[0].Subject).To(Equal("First"))
+			Expect(messages[2].Subject).To(Equal("Third"))
+		})
+	})
+
+	Describe("Last", func() {
+		It("should return the latest message to an address", func() {
+			msg, ok := m.Last("first@email.com")
+			Expect(ok).To(BeTrue())
+			Expect(msg.Subject).To(Equal("Third"))
+		})
+
+		It("should report when nothing was sent to an address", func() {
+			_, ok := m.Last("nobody@email.com")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("Reset", func() {
+		It("should forget sent messages", func() {
+			m.Reset()
+			Expect(m.Messages()).To(BeEmpty())
+		})
+	})
+})
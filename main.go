@@ -1,13 +1,26 @@
 package main
 
 import (
+	"log"
 	"net/http"
 
+	"github.com/lukashambsch/anygym.api/handlers"
 	"github.com/lukashambsch/anygym.api/router"
+	"github.com/lukashambsch/anygym.api/store"
 )
 
 func main() {
-	r := router.Load()
+	db, err := store.Open()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	keys, err := handlers.LoadKeys()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	r := router.Load(store.NewPostgres(db, store.QueryTimeout()), keys)
 
 	http.ListenAndServe(":8080", r)
 }
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// APIKey lets a gym's own systems call the API without logging in. Only a
+// hash of the key is stored, KeyPrefix is kept so keys can be told apart.
+// An empty Scopes list allows everything the gym role can do.
+type APIKey struct {
+	APIKeyID   int64      `json:"api_key_id"`
+	GymID      int64      `json:"gym_id"`
+	KeyName    string     `json:"key_name"`
+	KeyPrefix  string     `json:"key_prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedOn  time.Time  `json:"created_on"`
+	ExpiresOn  *time.Time `json:"expires_on"`
+	RevokedOn  *time.Time `json:"revoked_on"`
+	LastUsedOn *time.Time `json:"last_used_on"`
+}
@@ -1,8 +1,11 @@
 package models
 
+import "time"
+
 type Gym struct {
-	GymID            int64    `json:"gym_id"`
-	UserID           *int64   `json:"user_id"`
-	GymName          string   `json:"gym_name"`
-	MonthlyMemberFee *float64 `json:"monthly_member_fee"`
+	GymID            int64      `json:"gym_id"`
+	UserID           *int64     `json:"user_id"`
+	GymName          string     `json:"gym_name"`
+	MonthlyMemberFee *float64   `json:"monthly_member_fee"`
+	DeletedOn        *time.Time `json:"deleted_on"`
 }
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type GymLocation struct {
 	GymLocationID    int64          `json:"gym_location_id"`
 	GymID            int64          `json:"gym_id"`
@@ -9,6 +11,12 @@ type GymLocation struct {
 	WebsiteUrl       string         `json:"website_url"`
 	InNetwork        bool           `json:"in_network"`
 	MonthlyMemberFee *float64       `json:"monthly_member_fee"`
-	Address          Address        `json:"address"`
-	BusinessHours    []BusinessHour `json:"business_hours"`
+	UserID           *int64         `json:"user_id"`
+	Version          int64          `json:"version"`
+	DeletedOn        *time.Time     `json:"deleted_on"`
+	Address          *Address       `json:"address,omitempty"`
+	BusinessHours    []BusinessHour `json:"business_hours,omitempty"`
+	Features         []Feature      `json:"features,omitempty"`
+	Images           []Image        `json:"images,omitempty"`
+	Gym              *Gym           `json:"gym,omitempty"`
 }
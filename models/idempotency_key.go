@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// IdempotencyKey is a POST made with an Idempotency-Key header, and the
+// response to it. Keys belong to the user or API key that sent them, Owner,
+// so clients can't see each other's responses. ResponseStatus is 0 until the
+// request is done.
+type IdempotencyKey struct {
+	Owner           string              `json:"owner"`
+	Key             string              `json:"idempotency_key"`
+	Fingerprint     string              `json:"fingerprint"`
+	ResponseStatus  int                 `json:"response_status"`
+	ResponseHeaders map[string][]string `json:"response_headers"`
+	ResponseBody    []byte              `json:"response_body"`
+	CreatedOn       time.Time           `json:"created_on"`
+	ExpiresOn       time.Time           `json:"expires_on"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// LoginThrottle counts failed logins for an email address or client IP.
+type LoginThrottle struct {
+	ThrottleKey    string     `json:"throttle_key"`
+	FailedAttempts int        `json:"failed_attempts"`
+	LockedUntil    *time.Time `json:"locked_until"`
+	UpdatedOn      time.Time  `json:"updated_on"`
+}
@@ -1,11 +1,18 @@
 package models
 
+import "time"
+
 type Member struct {
-	MemberID  int64  `json:"member_id"`
-	UserID    int64  `json:"user_id"`
-	ImageID   *int64 `json:"image_id"`
-	AddressID *int64 `json:"address_id"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	User      *User  `json:"user"`
+	MemberID  int64      `json:"member_id"`
+	UserID    int64      `json:"user_id"`
+	ImageID   *int64     `json:"image_id"`
+	AddressID *int64     `json:"address_id"`
+	FirstName string     `json:"first_name"`
+	LastName  string     `json:"last_name"`
+	Version   int64      `json:"version"`
+	DeletedOn *time.Time `json:"deleted_on"`
+	User      *User      `json:"user"`
+
+	Address *Address `json:"address,omitempty"`
+	Image   *Image   `json:"image,omitempty"`
 }
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type PasswordReset struct {
+	PasswordResetID int64      `json:"password_reset_id"`
+	UserID          int64      `json:"user_id"`
+	TokenHash       string     `json:"-"`
+	CreatedOn       time.Time  `json:"created_on"`
+	ExpiresOn       time.Time  `json:"expires_on"`
+	UsedOn          *time.Time `json:"used_on"`
+}
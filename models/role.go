@@ -1,5 +1,14 @@
 package models
 
+// Role names seeded by the static data migration.
+const (
+	AdminRole    = "admin"
+	EmployeeRole = "employee"
+	GymRole      = "gym"
+	LocationRole = "location"
+	MemberRole   = "member"
+)
+
 type Role struct {
 	RoleID   int64  `json:"role_id"`
 	RoleName string `json:"role_name"`
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+type Session struct {
+	SessionID        int64      `json:"session_id"`
+	UserID           int64      `json:"user_id"`
+	RefreshTokenHash string     `json:"-"`
+	CreatedOn        time.Time  `json:"created_on"`
+	ExpiresOn        time.Time  `json:"expires_on"`
+	RevokedOn        *time.Time `json:"revoked_on"`
+	// TwoFactor is set if the user passed two-factor authentication in the
+	// session, not just gave their password.
+	TwoFactor bool `json:"two_factor"`
+}
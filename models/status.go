@@ -3,4 +3,5 @@ package models
 type Status struct {
 	StatusID   int64  `json:"status_id"`
 	StatusName string `json:"status_name"`
+	Version    int64  `json:"version"`
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// TwoFactor is a user's TOTP enrolment. It only protects logins once
+// EnabledOn is set, which happens after the user proves their authenticator
+// app works. LastStep is the last time step a code was accepted for, so a
+// code can't be used twice.
+type TwoFactor struct {
+	UserID    int64      `json:"user_id"`
+	Secret    string     `json:"-"`
+	CreatedOn time.Time  `json:"created_on"`
+	EnabledOn *time.Time `json:"enabled_on"`
+	LastStep  *int64     `json:"-"`
+}
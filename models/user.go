@@ -3,11 +3,13 @@ package models
 import "time"
 
 type User struct {
-	UserID       int64     `json:"user_id"`
-	Email        string    `json:"email"`
-	Token        string    `json:"-"`
-	PasswordHash []byte    `json:"-"`
-	Password     string    `json:"password"`
-	CreatedOn    time.Time `json:"created_on"`
-	Roles        []*Role   `json:"roles"`
+	UserID          int64      `json:"user_id"`
+	Email           string     `json:"email"`
+	Token           string     `json:"-"`
+	PasswordHash    []byte     `json:"-"`
+	Password        string     `json:"password"`
+	CreatedOn       time.Time  `json:"created_on"`
+	EmailVerifiedOn *time.Time `json:"email_verified_on"`
+	Version         int64      `json:"version"`
+	Roles           []*Role    `json:"roles"`
 }
@@ -9,4 +9,9 @@ type Visit struct {
 	StatusID      int64      `json:"status_id"`
 	CreatedOn     time.Time  `json:"created_on"`
 	ModifiedOn    *time.Time `json:"modified_on"`
+	Version       int64      `json:"version"`
+
+	Member      *Member      `json:"member,omitempty"`
+	GymLocation *GymLocation `json:"gym_location,omitempty"`
+	Status      *Status      `json:"status,omitempty"`
 }
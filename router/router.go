@@ -9,90 +9,164 @@ import (
 	"github.com/gorilla/mux"
 
 	"github.com/lukashambsch/anygym.api/handlers"
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store"
+)
+
+const (
+	admin    = models.AdminRole
+	employee = models.EmployeeRole
+	gym      = models.GymRole
+	location = models.LocationRole
+	member   = models.MemberRole
 )
 
 const V1URLBase string = "/api/v1"
 
-func Load() http.Handler {
+// Load builds the routes on handlers that read and write through s and sign
+// tokens with keys.
+func Load(s *store.Store, keys *handlers.KeySet) http.Handler {
+	api := handlers.New(s, keys)
+
+	router := ghandlers.LoggingHandler(os.Stdout, api.Idempotent(routes(s, keys)))
+	router = handlers.CORS(router)
+	router = api.VerifyToken(router)
+	router = handlers.Timeout(router)
 
+	return router
+}
+
+// routes is Load without the middleware. Batches run their requests on it,
+// built on the transaction's store when they run in one.
+func routes(s *store.Store, keys *handlers.KeySet) http.Handler {
+	api := handlers.New(s, keys)
 	r := mux.NewRouter().StrictSlash(true)
 
-	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/authenticate"), handlers.Login).Methods("POST")
-	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/logout"), handlers.Logout)
+	r.HandleFunc("/.well-known/jwks.json", api.GetJWKS).Methods("GET")
+	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/authenticate"), api.Login).Methods("POST")
+	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/authenticate/2fa"), api.LoginTwoFactor).Methods("POST")
+	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/token/refresh"), api.RefreshToken).Methods("POST")
+	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/logout"), api.Logout).Methods("POST")
+	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/me"), api.GetMe).Methods("GET")
+	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/me/2fa"), api.PostTwoFactor).Methods("POST")
+	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/me/2fa"), api.DeleteTwoFactor).Methods("DELETE")
+	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/me/2fa/verify"), api.VerifyTwoFactor).Methods("POST")
+	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/me/2fa/recovery_codes"), api.PostRecoveryCodes).Methods("POST")
+	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/password/forgot"), api.ForgotPassword).Methods("POST")
+	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/password/reset"), api.ResetPassword).Methods("POST")
+	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/email/verify"), api.VerifyEmail).Methods("POST")
+	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/email/verify/resend"), api.ResendVerification).Methods("POST")
+	r.HandleFunc(fmt.Sprintf("%s%s", V1URLBase, "/signup"), api.PostSignup).Methods("POST")
 
 	// Status endpoints
 	statuses := fmt.Sprintf("%s/statuses", V1URLBase)
 
-	r.HandleFunc(statuses, handlers.GetStatuses).
+	r.HandleFunc(statuses, api.GetStatuses).
 		Methods("GET")
-	r.HandleFunc(fmt.Sprintf("%s/{status_id}", statuses), handlers.GetStatus).
+	r.HandleFunc(fmt.Sprintf("%s/{status_id}", statuses), api.GetStatus).
 		Methods("GET")
-	r.HandleFunc(statuses, handlers.PostStatus).
+	r.HandleFunc(statuses, api.Authorize(api.PostStatus, admin)).
 		Methods("POST")
-	r.HandleFunc(fmt.Sprintf("%s/{status_id}", statuses), handlers.PutStatus).
+	r.HandleFunc(fmt.Sprintf("%s/{status_id}", statuses), api.Authorize(api.PutStatus, admin)).
 		Methods("PUT")
-	r.HandleFunc(fmt.Sprintf("%s/{status_id}", statuses), handlers.DeleteStatus).
+	r.HandleFunc(fmt.Sprintf("%s/{status_id}", statuses), api.Authorize(api.PatchStatus, admin)).
+		Methods("PATCH")
+	r.HandleFunc(fmt.Sprintf("%s/{status_id}", statuses), api.Authorize(api.DeleteStatus, admin)).
 		Methods("DELETE")
 
 	// Visit endpoints
 	visits := fmt.Sprintf("%s/visits", V1URLBase)
 
-	r.HandleFunc(visits, handlers.GetVisits).
+	r.HandleFunc(visits, api.Authorize(api.GetVisits, admin, employee, gym, location, member)).
 		Methods("GET")
-	r.HandleFunc(fmt.Sprintf("%s/{visit_id}", visits), handlers.GetVisit).
+	r.HandleFunc(fmt.Sprintf("%s/{visit_id}", visits), api.Authorize(api.GetVisit, admin, employee, gym, location, member)).
 		Methods("GET")
-	r.HandleFunc(visits, handlers.PostVisit).
+	r.HandleFunc(visits, api.Authorize(api.PostVisit, admin, employee, member)).
 		Methods("POST")
-	r.HandleFunc(fmt.Sprintf("%s/{visit_id}", visits), handlers.PutVisit).
+	r.HandleFunc(fmt.Sprintf("%s/{visit_id}", visits), api.Authorize(api.PutVisit, admin, employee, location)).
 		Methods("PUT")
-	r.HandleFunc(fmt.Sprintf("%s/{visit_id}", visits), handlers.DeleteVisit).
+	r.HandleFunc(fmt.Sprintf("%s/{visit_id}", visits), api.Authorize(api.PatchVisit, admin, employee, location)).
+		Methods("PATCH")
+	r.HandleFunc(fmt.Sprintf("%s/{visit_id}", visits), api.Authorize(api.DeleteVisit, admin)).
 		Methods("DELETE")
 
 	// Member endpoints
 	members := fmt.Sprintf("%s/members", V1URLBase)
 
-	r.HandleFunc(members, handlers.GetMembers).
+	r.HandleFunc(members, api.Authorize(api.GetMembers, admin, employee, location, member)).
 		Methods("GET")
-	r.HandleFunc(fmt.Sprintf("%s/{member_id}", members), handlers.GetMember).
+	r.HandleFunc(fmt.Sprintf("%s/{member_id}", members), api.Authorize(api.GetMember, admin, employee, location, member)).
 		Methods("GET")
-	r.HandleFunc(members, handlers.PostMember).
+	r.HandleFunc(members, api.Authorize(api.PostMember, admin, employee, member)).
 		Methods("POST")
-	r.HandleFunc(fmt.Sprintf("%s/{member_id}", members), handlers.PutMember).
+	r.HandleFunc(fmt.Sprintf("%s/{member_id}", members), api.Authorize(api.PutMember, admin, employee, member)).
 		Methods("PUT")
-	r.HandleFunc(fmt.Sprintf("%s/{member_id}", members), handlers.DeleteMember).
+	r.HandleFunc(fmt.Sprintf("%s/{member_id}", members), api.Authorize(api.PatchMember, admin, employee, member)).
+		Methods("PATCH")
+	r.HandleFunc(fmt.Sprintf("%s/{member_id}", members), api.Authorize(api.DeleteMember, admin)).
 		Methods("DELETE")
+	r.HandleFunc(fmt.Sprintf("%s/{member_id}/restore", members), api.Authorize(api.RestoreMember, admin)).
+		Methods("POST")
 
 	// GymLocation endpoints
 	gymLocations := fmt.Sprintf("%s/gym_locations", V1URLBase)
 
-	r.HandleFunc(gymLocations, handlers.GetGymLocations).
+	r.HandleFunc(gymLocations, api.GetGymLocations).
 		Methods("GET")
-	r.HandleFunc(fmt.Sprintf("%s/{gym_location_id}", gymLocations), handlers.GetGymLocation).
+	r.HandleFunc(fmt.Sprintf("%s/{gym_location_id}", gymLocations), api.GetGymLocation).
 		Methods("GET")
-	r.HandleFunc(gymLocations, handlers.PostGymLocation).
+	r.HandleFunc(gymLocations, api.Authorize(api.PostGymLocation, admin, gym)).
 		Methods("POST")
-	r.HandleFunc(fmt.Sprintf("%s/{gym_location_id}", gymLocations), handlers.PutGymLocation).
+	r.HandleFunc(fmt.Sprintf("%s/{gym_location_id}", gymLocations), api.Authorize(api.PutGymLocation, admin, gym, location)).
 		Methods("PUT")
-	r.HandleFunc(fmt.Sprintf("%s/{gym_location_id}", gymLocations), handlers.DeleteGymLocation).
+	r.HandleFunc(fmt.Sprintf("%s/{gym_location_id}", gymLocations), api.Authorize(api.PatchGymLocation, admin, gym, location)).
+		Methods("PATCH")
+	r.HandleFunc(fmt.Sprintf("%s/{gym_location_id}", gymLocations), api.Authorize(api.DeleteGymLocation, admin)).
+		Methods("DELETE")
+	r.HandleFunc(fmt.Sprintf("%s/{gym_location_id}/restore", gymLocations), api.Authorize(api.RestoreGymLocation, admin)).
+		Methods("POST")
+
+	// Gym endpoints
+	gyms := fmt.Sprintf("%s/gyms", V1URLBase)
+
+	r.HandleFunc(fmt.Sprintf("%s/{gym_id}", gyms), api.Authorize(api.DeleteGym, admin)).
 		Methods("DELETE")
+	r.HandleFunc(fmt.Sprintf("%s/{gym_id}/restore", gyms), api.Authorize(api.RestoreGym, admin)).
+		Methods("POST")
 
 	// User endpoints
 	users := fmt.Sprintf("%s/users", V1URLBase)
 
-	r.HandleFunc(users, handlers.GetUsers).
+	r.HandleFunc(users, api.Authorize(api.GetUsers, admin, employee)).
 		Methods("GET")
-	r.HandleFunc(fmt.Sprintf("%s/{user_id}", users), handlers.GetUser).
+	r.HandleFunc(fmt.Sprintf("%s/{user_id}", users), api.Authorize(api.GetUser, admin, employee)).
 		Methods("GET")
-	r.HandleFunc(users, handlers.PostUser).
+	r.HandleFunc(users, api.PostUser).
 		Methods("POST")
-	r.HandleFunc(fmt.Sprintf("%s/{user_id}", users), handlers.PutUser).
+	r.HandleFunc(fmt.Sprintf("%s/{user_id}", users), api.Authorize(api.PutUser, admin)).
 		Methods("PUT")
-	r.HandleFunc(fmt.Sprintf("%s/{user_id}", users), handlers.DeleteUser).
+	r.HandleFunc(fmt.Sprintf("%s/{user_id}", users), api.Authorize(api.PatchUser, admin)).
+		Methods("PATCH")
+	r.HandleFunc(fmt.Sprintf("%s/{user_id}", users), api.Authorize(api.DeleteUser, admin)).
 		Methods("DELETE")
+	r.HandleFunc(fmt.Sprintf("%s/{user_id}/unlock", users), api.Authorize(api.UnlockUser, admin)).
+		Methods("POST")
 
-	router := ghandlers.LoggingHandler(os.Stdout, r)
-	router = handlers.CORS(router)
-	router = handlers.VerifyToken(router)
+	// API key endpoints
+	apiKeys := fmt.Sprintf("%s/gyms/{gym_id}/api_keys", V1URLBase)
 
-	return router
+	r.HandleFunc(apiKeys, api.Authorize(api.GetAPIKeys, admin, gym)).
+		Methods("GET")
+	r.HandleFunc(apiKeys, api.Authorize(api.PostAPIKey, admin, gym)).
+		Methods("POST")
+	r.HandleFunc(fmt.Sprintf("%s/{api_key_id}", apiKeys), api.Authorize(api.DeleteAPIKey, admin, gym)).
+		Methods("DELETE")
+
+	// Batch endpoint
+	r.HandleFunc(fmt.Sprintf("%s/batch", V1URLBase), api.Authorize(api.Batch(func(tx *store.Store) http.Handler {
+		return routes(tx, keys)
+	}), admin)).
+		Methods("POST")
+
+	return r
 }
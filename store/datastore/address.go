@@ -1,20 +1,21 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetAddressList(where string) ([]models.Address, error) {
+func GetAddressList(ctx context.Context, db DB, q Query) ([]models.Address, error) {
 	var (
 		addresses []models.Address
 		address   models.Address
 	)
 
-	query := fmt.Sprintf("%s %s", getAddressListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getAddressListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -37,14 +38,15 @@ func GetAddressList(where string) ([]models.Address, error) {
 	}
 	defer rows.Close()
 
-	return addresses, nil
+	return addresses, rows.Err()
 }
 
-func GetAddressCount(where string) (*int, error) {
+func GetAddressCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getAddressCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getAddressCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 
 	if err != nil {
@@ -54,10 +56,10 @@ func GetAddressCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetAddress(addressID int64) (*models.Address, error) {
+func GetAddress(ctx context.Context, db DB, addressID int64) (*models.Address, error) {
 	var address models.Address
 
-	row := store.DB.QueryRow(getAddressQuery, addressID)
+	row := db.QueryRowContext(ctx, getAddressQuery, addressID)
 	err := row.Scan(
 		&address.AddressID,
 		&address.Country,
@@ -76,10 +78,11 @@ func GetAddress(addressID int64) (*models.Address, error) {
 	return &address, nil
 }
 
-func CreateAddress(address models.Address) (*models.Address, error) {
+func CreateAddress(ctx context.Context, db DB, address models.Address) (*models.Address, error) {
 	var created models.Address
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		createAddressQuery,
 		address.Country,
 		address.StateRegion,
@@ -106,10 +109,11 @@ func CreateAddress(address models.Address) (*models.Address, error) {
 	return &created, nil
 }
 
-func UpdateAddress(addressID int64, address models.Address) (*models.Address, error) {
+func UpdateAddress(ctx context.Context, db DB, addressID int64, address models.Address) (*models.Address, error) {
 	var updated models.Address
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		updateAddressQuery,
 		address.Country,
 		address.StateRegion,
@@ -137,18 +141,8 @@ func UpdateAddress(addressID int64, address models.Address) (*models.Address, er
 	return &updated, nil
 }
 
-func DeleteAddress(addressID int64) error {
-	stmt, err := store.DB.Prepare(deleteAddressQuery)
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(addressID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func DeleteAddress(ctx context.Context, db DB, addressID int64) error {
+	return execOne(ctx, db, deleteAddressQuery, addressID)
 }
 
 const getAddressListQuery = `
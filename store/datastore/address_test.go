@@ -11,17 +11,17 @@ var _ = Describe("Address db interactions", func() {
 	var one, two, three, four *models.Address
 
 	BeforeEach(func() {
-		one, _ = datastore.CreateAddress(models.Address{StreetAddress: "Testing"})
-		two, _ = datastore.CreateAddress(models.Address{StreetAddress: "Testing Two"})
-		three, _ = datastore.CreateAddress(models.Address{StreetAddress: "Testing Three"})
-		four, _ = datastore.CreateAddress(models.Address{StreetAddress: "Testing Four"})
+		one, _ = datastore.CreateAddress(ctx, db, models.Address{StreetAddress: "Testing"})
+		two, _ = datastore.CreateAddress(ctx, db, models.Address{StreetAddress: "Testing Two"})
+		three, _ = datastore.CreateAddress(ctx, db, models.Address{StreetAddress: "Testing Three"})
+		four, _ = datastore.CreateAddress(ctx, db, models.Address{StreetAddress: "Testing Four"})
 	})
 
 	AfterEach(func() {
-		datastore.DeleteAddress(one.AddressID)
-		datastore.DeleteAddress(two.AddressID)
-		datastore.DeleteAddress(three.AddressID)
-		datastore.DeleteAddress(four.AddressID)
+		datastore.DeleteAddress(ctx, db, one.AddressID)
+		datastore.DeleteAddress(ctx, db, two.AddressID)
+		datastore.DeleteAddress(ctx, db, three.AddressID)
+		datastore.DeleteAddress(ctx, db, four.AddressID)
 	})
 
 	Describe("GetAddressList", func() {
@@ -29,7 +29,7 @@ var _ = Describe("Address db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				addresses, _ = datastore.GetAddressList("")
+				addresses, _ = datastore.GetAddressList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of addresses", func() {
@@ -43,7 +43,7 @@ var _ = Describe("Address db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct address", func() {
-				address, _ = datastore.GetAddress(one.AddressID)
+				address, _ = datastore.GetAddress(ctx, db, one.AddressID)
 				Expect(address.AddressID).To(Equal(one.AddressID))
 			})
 		})
@@ -55,7 +55,7 @@ var _ = Describe("Address db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				address, err = datastore.GetAddress(nonExistentID)
+				address, err = datastore.GetAddress(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -73,7 +73,7 @@ var _ = Describe("Address db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetAddressCount("")
+				count, _ = datastore.GetAddressCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -92,11 +92,11 @@ var _ = Describe("Address db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				address = models.Address{StreetAddress: streetAddress}
-				created, _ = datastore.CreateAddress(address)
+				created, _ = datastore.CreateAddress(ctx, db, address)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteAddress(created.AddressID)
+				datastore.DeleteAddress(ctx, db, created.AddressID)
 			})
 
 			It("should return the created address", func() {
@@ -104,7 +104,7 @@ var _ = Describe("Address db interactions", func() {
 			})
 
 			It("should add a address to the db", func() {
-				newAddress, _ := datastore.GetAddress(created.AddressID)
+				newAddress, _ := datastore.GetAddress(ctx, db, created.AddressID)
 				Expect(newAddress.StreetAddress).To(Equal(streetAddress))
 			})
 		})
@@ -113,14 +113,14 @@ var _ = Describe("Address db interactions", func() {
 			var created *models.Address
 
 			AfterEach(func() {
-				datastore.DeleteAddress(created.AddressID)
+				datastore.DeleteAddress(ctx, db, created.AddressID)
 			})
 
 			It("should return an error object if address is not unique", func() {
 				street := "Test Street"
 				addr := models.Address{StreetAddress: street}
-				created, _ = datastore.CreateAddress(addr)
-				_, err := datastore.CreateAddress(addr)
+				created, _ = datastore.CreateAddress(ctx, db, addr)
+				_, err := datastore.CreateAddress(ctx, db, addr)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -138,12 +138,12 @@ var _ = Describe("Address db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				address = models.Address{StreetAddress: streetAddress}
-				created, _ = datastore.CreateAddress(models.Address{StreetAddress: "456 Test Ave."})
-				updated, _ = datastore.UpdateAddress(created.AddressID, address)
+				created, _ = datastore.CreateAddress(ctx, db, models.Address{StreetAddress: "456 Test Ave."})
+				updated, _ = datastore.UpdateAddress(ctx, db, created.AddressID, address)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteAddress(updated.AddressID)
+				datastore.DeleteAddress(ctx, db, updated.AddressID)
 			})
 
 			It("should return the updated address", func() {
@@ -154,7 +154,7 @@ var _ = Describe("Address db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				address = models.Address{StreetAddress: "456 Test Ave."}
-				updated, err = datastore.UpdateAddress(5000, address)
+				updated, err = datastore.UpdateAddress(ctx, db, 5000, address)
 			})
 
 			It("should return an error object if address to update doesn't exist", func() {
@@ -170,7 +170,7 @@ var _ = Describe("Address db interactions", func() {
 	Describe("DeleteAddress", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				err := datastore.DeleteAddress(one.AddressID)
+				err := datastore.DeleteAddress(ctx, db, one.AddressID)
 				Expect(err).To(BeNil())
 			})
 		})
@@ -0,0 +1,201 @@
+package datastore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+func GetAPIKeyList(ctx context.Context, db DB, q Query) ([]models.APIKey, error) {
+	var (
+		apiKeys []models.APIKey
+		apiKey  models.APIKey
+	)
+
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getAPIKeyListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.Scan(
+			&apiKey.APIKeyID,
+			&apiKey.GymID,
+			&apiKey.KeyName,
+			&apiKey.KeyPrefix,
+			&apiKey.KeyHash,
+			pq.Array(&apiKey.Scopes),
+			&apiKey.CreatedOn,
+			&apiKey.ExpiresOn,
+			&apiKey.RevokedOn,
+			&apiKey.LastUsedOn,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	return apiKeys, rows.Err()
+}
+
+func GetAPIKeyCount(ctx context.Context, db DB, q Query) (*int, error) {
+	var count int
+
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getAPIKeyCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
+	err := row.Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+
+	return &count, nil
+}
+
+func GetAPIKey(ctx context.Context, db DB, apiKeyID int64) (*models.APIKey, error) {
+	var apiKey models.APIKey
+
+	row := db.QueryRowContext(ctx, getAPIKeyQuery, apiKeyID)
+	err := row.Scan(
+		&apiKey.APIKeyID,
+		&apiKey.GymID,
+		&apiKey.KeyName,
+		&apiKey.KeyPrefix,
+		&apiKey.KeyHash,
+		pq.Array(&apiKey.Scopes),
+		&apiKey.CreatedOn,
+		&apiKey.ExpiresOn,
+		&apiKey.RevokedOn,
+		&apiKey.LastUsedOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func GetAPIKeyByHash(ctx context.Context, db DB, keyHash string) (*models.APIKey, error) {
+	var apiKey models.APIKey
+
+	row := db.QueryRowContext(ctx, getAPIKeyByHashQuery, keyHash)
+	err := row.Scan(
+		&apiKey.APIKeyID,
+		&apiKey.GymID,
+		&apiKey.KeyName,
+		&apiKey.KeyPrefix,
+		&apiKey.KeyHash,
+		pq.Array(&apiKey.Scopes),
+		&apiKey.CreatedOn,
+		&apiKey.ExpiresOn,
+		&apiKey.RevokedOn,
+		&apiKey.LastUsedOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+func CreateAPIKey(ctx context.Context, db DB, apiKey models.APIKey) (*models.APIKey, error) {
+	var created models.APIKey
+
+	row := db.QueryRowContext(
+		ctx,
+		createAPIKeyQuery,
+		apiKey.GymID,
+		apiKey.KeyName,
+		apiKey.KeyPrefix,
+		apiKey.KeyHash,
+		pq.Array(apiKey.Scopes),
+		apiKey.ExpiresOn,
+	)
+	err := row.Scan(
+		&created.APIKeyID,
+		&created.GymID,
+		&created.KeyName,
+		&created.KeyPrefix,
+		&created.KeyHash,
+		pq.Array(&created.Scopes),
+		&created.CreatedOn,
+		&created.ExpiresOn,
+		&created.RevokedOn,
+		&created.LastUsedOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// RevokeAPIKey stops the key from being accepted. It returns sql.ErrNoRows
+// if the key doesn't exist or was already revoked.
+func RevokeAPIKey(ctx context.Context, db DB, apiKeyID int64) error {
+	return execOne(ctx, db, revokeAPIKeyQuery, time.Now(), apiKeyID)
+}
+
+func TouchAPIKey(ctx context.Context, db DB, apiKeyID int64) error {
+	_, err := db.ExecContext(ctx, touchAPIKeyQuery, time.Now(), apiKeyID)
+	return err
+}
+
+func DeleteAPIKey(ctx context.Context, db DB, apiKeyID int64) error {
+	return execOne(ctx, db, deleteAPIKeyQuery, apiKeyID)
+}
+
+const getAPIKeyListQuery = `
+SELECT api_key_id, gym_id, key_name, key_prefix, key_hash, scopes, created_on, expires_on, revoked_on, last_used_on
+FROM api_keys
+`
+
+const getAPIKeyQuery = `
+SELECT api_key_id, gym_id, key_name, key_prefix, key_hash, scopes, created_on, expires_on, revoked_on, last_used_on
+FROM api_keys
+WHERE api_key_id = $1
+`
+
+const getAPIKeyByHashQuery = `
+SELECT api_key_id, gym_id, key_name, key_prefix, key_hash, scopes, created_on, expires_on, revoked_on, last_used_on
+FROM api_keys
+WHERE key_hash = $1
+`
+
+const createAPIKeyQuery = `
+INSERT INTO api_keys (gym_id, key_name, key_prefix, key_hash, scopes, expires_on)
+VALUES ($1, $2, $3, $4, COALESCE($5, '{}'), $6)
+RETURNING api_key_id, gym_id, key_name, key_prefix, key_hash, scopes, created_on, expires_on, revoked_on, last_used_on
+`
+
+const revokeAPIKeyQuery = `
+UPDATE api_keys
+SET revoked_on = $1
+WHERE api_key_id = $2 AND revoked_on IS NULL
+`
+
+const touchAPIKeyQuery = `
+UPDATE api_keys
+SET last_used_on = $1
+WHERE api_key_id = $2
+`
+
+const deleteAPIKeyQuery = `
+DELETE
+FROM api_keys
+WHERE api_key_id = $1
+`
+
+const getAPIKeyCountQuery = `
+SELECT count(*)
+FROM api_keys
+`
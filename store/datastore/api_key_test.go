@@ -0,0 +1,132 @@
+package datastore_test
+
+import (
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("APIKey db interactions", func() {
+	var (
+		gymID  int64 = 1
+		apiKey *models.APIKey
+	)
+
+	BeforeEach(func() {
+		apiKey, _ = datastore.CreateAPIKey(ctx, db, models.APIKey{
+			GymID:     gymID,
+			KeyName:   "Front desk",
+			KeyPrefix: "agk_abcdefgh",
+			KeyHash:   "apikeyhash",
+			Scopes:    []string{"visits:read"},
+		})
+	})
+
+	AfterEach(func() {
+		datastore.DeleteAPIKey(ctx, db, apiKey.APIKeyID)
+	})
+
+	Describe("GetAPIKeyList", func() {
+		Describe("Successful call", func() {
+			It("should return the gym's keys", func() {
+				apiKeys, _ := datastore.GetAPIKeyList(ctx, db, datastore.Where("gym_id", gymID))
+				Expect(len(apiKeys)).To(Equal(1))
+				Expect(apiKeys[0].Scopes).To(Equal([]string{"visits:read"}))
+			})
+		})
+	})
+
+	Describe("GetAPIKey", func() {
+		Describe("Successful call", func() {
+			It("should return the correct key", func() {
+				found, _ := datastore.GetAPIKey(ctx, db, apiKey.APIKeyID)
+				Expect(found.KeyName).To(Equal("Front desk"))
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return an error", func() {
+				found, err := datastore.GetAPIKey(ctx, db, 99999)
+				Expect(err).ToNot(BeNil())
+				Expect(found).To(BeNil())
+			})
+		})
+	})
+
+	Describe("GetAPIKeyByHash", func() {
+		Describe("Successful call", func() {
+			It("should return the correct key", func() {
+				found, _ := datastore.GetAPIKeyByHash(ctx, db, "apikeyhash")
+				Expect(found.APIKeyID).To(Equal(apiKey.APIKeyID))
+			})
+		})
+	})
+
+	Describe("CreateAPIKey", func() {
+		Describe("Successful call", func() {
+			It("should default to no scopes", func() {
+				expires := time.Now().Add(time.Hour)
+				created, err := datastore.CreateAPIKey(ctx, db, models.APIKey{
+					GymID:     gymID,
+					KeyPrefix: "agk_ijklmnop",
+					KeyHash:   "otherhash",
+					ExpiresOn: &expires,
+				})
+				defer datastore.DeleteAPIKey(ctx, db, created.APIKeyID)
+
+				Expect(err).To(BeNil())
+				Expect(created.Scopes).To(BeEmpty())
+				Expect(created.ExpiresOn).ToNot(BeNil())
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return an error for a non existent gym", func() {
+				_, err := datastore.CreateAPIKey(ctx, db, models.APIKey{GymID: 99999, KeyPrefix: "agk_", KeyHash: "nogym"})
+				Expect(err).ToNot(BeNil())
+			})
+		})
+	})
+
+	Describe("RevokeAPIKey", func() {
+		Describe("Successful call", func() {
+			It("should set revoked_on", func() {
+				err := datastore.RevokeAPIKey(ctx, db, apiKey.APIKeyID)
+				Expect(err).To(BeNil())
+
+				revoked, _ := datastore.GetAPIKey(ctx, db, apiKey.APIKeyID)
+				Expect(revoked.RevokedOn).ToNot(BeNil())
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return an error if already revoked", func() {
+				datastore.RevokeAPIKey(ctx, db, apiKey.APIKeyID)
+				err := datastore.RevokeAPIKey(ctx, db, apiKey.APIKeyID)
+				Expect(err).ToNot(BeNil())
+			})
+		})
+	})
+
+	Describe("TouchAPIKey", func() {
+		Describe("Successful call", func() {
+			It("should set last_used_on", func() {
+				datastore.TouchAPIKey(ctx, db, apiKey.APIKeyID)
+				touched, _ := datastore.GetAPIKey(ctx, db, apiKey.APIKeyID)
+				Expect(touched.LastUsedOn).ToNot(BeNil())
+			})
+		})
+	})
+
+	Describe("DeleteAPIKey", func() {
+		Describe("Successful call", func() {
+			It("should return nil", func() {
+				err := datastore.DeleteAPIKey(ctx, db, apiKey.APIKeyID)
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+})
@@ -1,20 +1,23 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/lib/pq"
+
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetBusinessHourList(where string) ([]models.BusinessHour, error) {
+func GetBusinessHourList(ctx context.Context, db DB, q Query) ([]models.BusinessHour, error) {
 	var (
 		businessHours []models.BusinessHour
 		businessHour  models.BusinessHour
 	)
 
-	query := fmt.Sprintf("%s %s", getBusinessHourListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getBusinessHourListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -37,14 +40,46 @@ func GetBusinessHourList(where string) ([]models.BusinessHour, error) {
 	}
 	defer rows.Close()
 
-	return businessHours, nil
+	return businessHours, rows.Err()
 }
 
-func GetBusinessHourCount(where string) (*int, error) {
+// getBusinessHoursByLocation loads the business hours of all of the gym
+// locations with one query.
+func getBusinessHoursByLocation(ctx context.Context, db DB, gymLocationIDs []int64) (map[int64][]models.BusinessHour, error) {
+	businessHours := map[int64][]models.BusinessHour{}
+
+	rows, err := db.QueryContext(ctx, getBusinessHoursByLocationQuery, pq.Array(gymLocationIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var businessHour models.BusinessHour
+		err = rows.Scan(
+			&businessHour.BusinessHourID,
+			&businessHour.GymLocationID,
+			&businessHour.HolidayID,
+			&businessHour.DayID,
+			&businessHour.OpenTime,
+			&businessHour.CloseTime,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		businessHours[businessHour.GymLocationID] = append(businessHours[businessHour.GymLocationID], businessHour)
+	}
+
+	return businessHours, rows.Err()
+}
+
+func GetBusinessHourCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getBusinessHourCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getBusinessHourCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 
 	if err != nil {
@@ -54,10 +89,10 @@ func GetBusinessHourCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetBusinessHour(businessHourID int64) (*models.BusinessHour, error) {
+func GetBusinessHour(ctx context.Context, db DB, businessHourID int64) (*models.BusinessHour, error) {
 	var businessHour models.BusinessHour
 
-	row := store.DB.QueryRow(getBusinessHourQuery, businessHourID)
+	row := db.QueryRowContext(ctx, getBusinessHourQuery, businessHourID)
 	err := row.Scan(
 		&businessHour.BusinessHourID,
 		&businessHour.GymLocationID,
@@ -74,10 +109,11 @@ func GetBusinessHour(businessHourID int64) (*models.BusinessHour, error) {
 	return &businessHour, nil
 }
 
-func CreateBusinessHour(businessHour models.BusinessHour) (*models.BusinessHour, error) {
+func CreateBusinessHour(ctx context.Context, db DB, businessHour models.BusinessHour) (*models.BusinessHour, error) {
 	var created models.BusinessHour
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		createBusinessHourQuery,
 		businessHour.GymLocationID,
 		businessHour.HolidayID,
@@ -100,10 +136,11 @@ func CreateBusinessHour(businessHour models.BusinessHour) (*models.BusinessHour,
 	return &created, nil
 }
 
-func UpdateBusinessHour(businessHourID int64, businessHour models.BusinessHour) (*models.BusinessHour, error) {
+func UpdateBusinessHour(ctx context.Context, db DB, businessHourID int64, businessHour models.BusinessHour) (*models.BusinessHour, error) {
 	var updated models.BusinessHour
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		updateBusinessHourQuery,
 		businessHour.GymLocationID,
 		businessHour.HolidayID,
@@ -127,18 +164,8 @@ func UpdateBusinessHour(businessHourID int64, businessHour models.BusinessHour)
 	return &updated, nil
 }
 
-func DeleteBusinessHour(businessHourID int64) error {
-	stmt, err := store.DB.Prepare(deleteBusinessHourQuery)
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(businessHourID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func DeleteBusinessHour(ctx context.Context, db DB, businessHourID int64) error {
+	return execOne(ctx, db, deleteBusinessHourQuery, businessHourID)
 }
 
 const getBusinessHourListQuery = `
@@ -146,6 +173,13 @@ SELECT *
 FROM business_hours
 `
 
+const getBusinessHoursByLocationQuery = `
+SELECT business_hour_id, gym_location_id, holiday_id, day_id, open_time, close_time
+FROM business_hours
+WHERE gym_location_id = ANY($1)
+ORDER BY business_hour_id
+`
+
 const getBusinessHourQuery = `
 SELECT *
 FROM business_hours
@@ -16,27 +16,27 @@ var _ = Describe("BusinessHour db interactions", func() {
 	)
 
 	BeforeEach(func() {
-		addr, _ = datastore.CreateAddress(models.Address{StreetAddress: "Testing"})
-		gymLocation, _ = datastore.CreateGymLocation(models.GymLocation{
+		addr, _ = datastore.CreateAddress(ctx, db, models.Address{StreetAddress: "Testing"})
+		gymLocation, _ = datastore.CreateGymLocation(ctx, db, models.GymLocation{
 			GymID:        gymID,
 			AddressID:    addr.AddressID,
 			LocationName: "Testing",
 		})
-		businessHourOne, _ = datastore.CreateBusinessHour(models.BusinessHour{
+		businessHourOne, _ = datastore.CreateBusinessHour(ctx, db, models.BusinessHour{
 			GymLocationID: gymLocation.GymLocationID,
 			DayID:         &mondayID,
 		})
-		businessHourTwo, _ = datastore.CreateBusinessHour(models.BusinessHour{
+		businessHourTwo, _ = datastore.CreateBusinessHour(ctx, db, models.BusinessHour{
 			GymLocationID: gymLocation.GymLocationID,
 			DayID:         &tuesdayID,
 		})
 	})
 
 	AfterEach(func() {
-		datastore.DeleteBusinessHour(businessHourOne.BusinessHourID)
-		datastore.DeleteBusinessHour(businessHourTwo.BusinessHourID)
-		datastore.DeleteGymLocation(gymLocation.GymLocationID)
-		datastore.DeleteAddress(addr.AddressID)
+		datastore.DeleteBusinessHour(ctx, db, businessHourOne.BusinessHourID)
+		datastore.DeleteBusinessHour(ctx, db, businessHourTwo.BusinessHourID)
+		purge("gym_locations", "gym_location_id", gymLocation.GymLocationID)
+		datastore.DeleteAddress(ctx, db, addr.AddressID)
 	})
 
 	Describe("GetBusinessHourList", func() {
@@ -44,7 +44,7 @@ var _ = Describe("BusinessHour db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				businessHours, _ = datastore.GetBusinessHourList("")
+				businessHours, _ = datastore.GetBusinessHourList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of businessHours", func() {
@@ -58,7 +58,7 @@ var _ = Describe("BusinessHour db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct businessHour", func() {
-				businessHour, _ = datastore.GetBusinessHour(businessHourOne.BusinessHourID)
+				businessHour, _ = datastore.GetBusinessHour(ctx, db, businessHourOne.BusinessHourID)
 				Expect(businessHour.BusinessHourID).To(Equal(businessHourOne.BusinessHourID))
 			})
 		})
@@ -70,7 +70,7 @@ var _ = Describe("BusinessHour db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				businessHour, err = datastore.GetBusinessHour(nonExistentID)
+				businessHour, err = datastore.GetBusinessHour(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -88,7 +88,7 @@ var _ = Describe("BusinessHour db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetBusinessHourCount("")
+				count, _ = datastore.GetBusinessHourCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -110,11 +110,11 @@ var _ = Describe("BusinessHour db interactions", func() {
 					GymLocationID: gymLocation.GymLocationID,
 					DayID:         &wednesdayID,
 				}
-				created, _ = datastore.CreateBusinessHour(businessHour)
+				created, _ = datastore.CreateBusinessHour(ctx, db, businessHour)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteBusinessHour(created.BusinessHourID)
+				datastore.DeleteBusinessHour(ctx, db, created.BusinessHourID)
 			})
 
 			It("should return the created businessHour", func() {
@@ -122,7 +122,7 @@ var _ = Describe("BusinessHour db interactions", func() {
 			})
 
 			It("should add a businessHour to the db", func() {
-				newMember, _ := datastore.GetBusinessHour(created.BusinessHourID)
+				newMember, _ := datastore.GetBusinessHour(ctx, db, created.BusinessHourID)
 				Expect(newMember.DayID).To(Equal(&wednesdayID))
 			})
 		})
@@ -130,7 +130,7 @@ var _ = Describe("BusinessHour db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			It("should return an error object if no day_id or holiday_id", func() {
 				mbr := models.BusinessHour{}
-				_, err := datastore.CreateBusinessHour(mbr)
+				_, err := datastore.CreateBusinessHour(ctx, db, mbr)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -147,16 +147,16 @@ var _ = Describe("BusinessHour db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				created, _ = datastore.CreateBusinessHour(models.BusinessHour{
+				created, _ = datastore.CreateBusinessHour(ctx, db, models.BusinessHour{
 					GymLocationID: gymLocation.GymLocationID,
 					DayID:         &wednesdayID,
 				})
 				created.DayID = &thursdayID
-				updated, _ = datastore.UpdateBusinessHour(created.BusinessHourID, *created)
+				updated, _ = datastore.UpdateBusinessHour(ctx, db, created.BusinessHourID, *created)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteBusinessHour(updated.BusinessHourID)
+				datastore.DeleteBusinessHour(ctx, db, updated.BusinessHourID)
 			})
 
 			It("should return the updated businessHour", func() {
@@ -170,7 +170,7 @@ var _ = Describe("BusinessHour db interactions", func() {
 					GymLocationID: gymLocation.GymLocationID,
 					DayID:         &wednesdayID,
 				}
-				updated, err = datastore.UpdateBusinessHour(5000, businessHour)
+				updated, err = datastore.UpdateBusinessHour(ctx, db, 5000, businessHour)
 			})
 
 			It("should return an error object if businessHour to update doesn't exist", func() {
@@ -187,11 +187,11 @@ var _ = Describe("BusinessHour db interactions", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
 				var wednesdayID int64 = 3
-				created, _ := datastore.CreateBusinessHour(models.BusinessHour{
+				created, _ := datastore.CreateBusinessHour(ctx, db, models.BusinessHour{
 					GymLocationID: gymLocation.GymLocationID,
 					DayID:         &wednesdayID,
 				})
-				err := datastore.DeleteBusinessHour(created.BusinessHourID)
+				err := datastore.DeleteBusinessHour(ctx, db, created.BusinessHourID)
 				Expect(err).To(BeNil())
 			})
 		})
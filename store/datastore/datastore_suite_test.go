@@ -1,13 +1,39 @@
 package datastore_test
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/lukashambsch/anygym.api/store"
+
 	"testing"
 )
 
+var (
+	db  *sql.DB
+	ctx = context.Background()
+)
+
 func TestDatastore(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Datastore Suite")
 }
+
+var _ = BeforeSuite(func() {
+	var err error
+	db, err = store.Open()
+	Expect(err).To(BeNil())
+})
+
+var _ = AfterSuite(func() {
+	db.Close()
+})
+
+// purge removes a row for good, deletes of soft deleted tables only mark it.
+func purge(table string, idColumn string, id int64) {
+	db.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = $1", table, idColumn), id)
+}
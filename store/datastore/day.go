@@ -1,20 +1,21 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetDayList(where string) ([]models.Day, error) {
+func GetDayList(ctx context.Context, db DB, q Query) ([]models.Day, error) {
 	var (
 		days []models.Day
 		day  models.Day
 	)
 
-	query := fmt.Sprintf("%s %s", getDayListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getDayListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -28,14 +29,15 @@ func GetDayList(where string) ([]models.Day, error) {
 	}
 	defer rows.Close()
 
-	return days, nil
+	return days, rows.Err()
 }
 
-func GetDayCount(where string) (*int, error) {
+func GetDayCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getDayCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getDayCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 	if err != nil {
 		return nil, err
@@ -44,10 +46,10 @@ func GetDayCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetDay(dayID int64) (*models.Day, error) {
+func GetDay(ctx context.Context, db DB, dayID int64) (*models.Day, error) {
 	var day models.Day
 
-	row := store.DB.QueryRow(getDayQuery, dayID)
+	row := db.QueryRowContext(ctx, getDayQuery, dayID)
 	err := row.Scan(&day.DayID, &day.DayName)
 	if err != nil {
 		return nil, err
@@ -56,10 +58,10 @@ func GetDay(dayID int64) (*models.Day, error) {
 	return &day, nil
 }
 
-func CreateDay(day models.Day) (*models.Day, error) {
+func CreateDay(ctx context.Context, db DB, day models.Day) (*models.Day, error) {
 	var created models.Day
 
-	row := store.DB.QueryRow(createDayQuery, day.DayName)
+	row := db.QueryRowContext(ctx, createDayQuery, day.DayName)
 	err := row.Scan(&created.DayID, &created.DayName)
 	if err != nil {
 		return nil, err
@@ -68,10 +70,10 @@ func CreateDay(day models.Day) (*models.Day, error) {
 	return &created, nil
 }
 
-func UpdateDay(dayID int64, day models.Day) (*models.Day, error) {
+func UpdateDay(ctx context.Context, db DB, dayID int64, day models.Day) (*models.Day, error) {
 	var updated models.Day
 
-	row := store.DB.QueryRow(updateDayQuery, day.DayName, dayID)
+	row := db.QueryRowContext(ctx, updateDayQuery, day.DayName, dayID)
 	err := row.Scan(&updated.DayID, &updated.DayName)
 	if err != nil {
 		return nil, err
@@ -80,18 +82,8 @@ func UpdateDay(dayID int64, day models.Day) (*models.Day, error) {
 	return &updated, nil
 }
 
-func DeleteDay(dayID int64) error {
-	stmt, err := store.DB.Prepare(deleteDayQuery)
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(dayID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func DeleteDay(ctx context.Context, db DB, dayID int64) error {
+	return execOne(ctx, db, deleteDayQuery, dayID)
 }
 
 const getDayListQuery = `
@@ -15,7 +15,7 @@ var _ = Describe("Day db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				days, _ = datastore.GetDayList("")
+				days, _ = datastore.GetDayList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of days", func() {
@@ -29,7 +29,7 @@ var _ = Describe("Day db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct day", func() {
-				day, _ = datastore.GetDay(dayID)
+				day, _ = datastore.GetDay(ctx, db, dayID)
 				Expect(day.DayID).To(Equal(dayID))
 			})
 		})
@@ -41,7 +41,7 @@ var _ = Describe("Day db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				day, err = datastore.GetDay(nonExistentID)
+				day, err = datastore.GetDay(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -59,7 +59,7 @@ var _ = Describe("Day db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetDayCount("")
+				count, _ = datastore.GetDayCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -78,11 +78,11 @@ var _ = Describe("Day db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				day = models.Day{DayName: dayName}
-				created, _ = datastore.CreateDay(day)
+				created, _ = datastore.CreateDay(ctx, db, day)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteDay(created.DayID)
+				datastore.DeleteDay(ctx, db, created.DayID)
 			})
 
 			It("should return the created day", func() {
@@ -90,7 +90,7 @@ var _ = Describe("Day db interactions", func() {
 			})
 
 			It("should add a day to the db", func() {
-				newDay, _ := datastore.GetDay(created.DayID)
+				newDay, _ := datastore.GetDay(ctx, db, created.DayID)
 				Expect(newDay.DayName).To(Equal(dayName))
 			})
 		})
@@ -99,14 +99,14 @@ var _ = Describe("Day db interactions", func() {
 			var created *models.Day
 
 			AfterEach(func() {
-				datastore.DeleteDay(created.DayID)
+				datastore.DeleteDay(ctx, db, created.DayID)
 			})
 
 			It("should return an error object if day is not unique", func() {
 				name := "Test Name"
 				pln := models.Day{DayName: name}
-				created, _ = datastore.CreateDay(pln)
-				_, err := datastore.CreateDay(pln)
+				created, _ = datastore.CreateDay(ctx, db, pln)
+				_, err := datastore.CreateDay(ctx, db, pln)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -124,12 +124,12 @@ var _ = Describe("Day db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				day = models.Day{DayName: dayName}
-				created, _ = datastore.CreateDay(models.Day{DayName: "Daily"})
-				updated, _ = datastore.UpdateDay(created.DayID, day)
+				created, _ = datastore.CreateDay(ctx, db, models.Day{DayName: "Daily"})
+				updated, _ = datastore.UpdateDay(ctx, db, created.DayID, day)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteDay(updated.DayID)
+				datastore.DeleteDay(ctx, db, updated.DayID)
 			})
 
 			It("should return the updated day", func() {
@@ -140,7 +140,7 @@ var _ = Describe("Day db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				day = models.Day{DayName: "Daily"}
-				updated, err = datastore.UpdateDay(10000, day)
+				updated, err = datastore.UpdateDay(ctx, db, 10000, day)
 			})
 
 			It("should return an error object if day to update doesn't exist", func() {
@@ -156,8 +156,8 @@ var _ = Describe("Day db interactions", func() {
 	Describe("DeleteDay", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				created, _ := datastore.CreateDay(models.Day{DayName: "Testing"})
-				err := datastore.DeleteDay(created.DayID)
+				created, _ := datastore.CreateDay(ctx, db, models.Day{DayName: "Testing"})
+				err := datastore.DeleteDay(ctx, db, created.DayID)
 				Expect(err).To(BeNil())
 			})
 		})
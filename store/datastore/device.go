@@ -1,20 +1,21 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetDeviceList(where string) ([]models.Device, error) {
+func GetDeviceList(ctx context.Context, db DB, q Query) ([]models.Device, error) {
 	var (
 		devices []models.Device
 		device  models.Device
 	)
 
-	query := fmt.Sprintf("%s %s", getDeviceListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getDeviceListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -28,14 +29,15 @@ func GetDeviceList(where string) ([]models.Device, error) {
 	}
 	defer rows.Close()
 
-	return devices, nil
+	return devices, rows.Err()
 }
 
-func GetDeviceCount(where string) (*int, error) {
+func GetDeviceCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getDeviceCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getDeviceCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 	if err != nil {
 		return nil, err
@@ -44,10 +46,10 @@ func GetDeviceCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetDevice(deviceID int64) (*models.Device, error) {
+func GetDevice(ctx context.Context, db DB, deviceID int64) (*models.Device, error) {
 	var device models.Device
 
-	row := store.DB.QueryRow(getDeviceQuery, deviceID)
+	row := db.QueryRowContext(ctx, getDeviceQuery, deviceID)
 	err := row.Scan(&device.DeviceID, &device.UserID, &device.DeviceToken)
 	if err != nil {
 		return nil, err
@@ -56,10 +58,10 @@ func GetDevice(deviceID int64) (*models.Device, error) {
 	return &device, nil
 }
 
-func CreateDevice(device models.Device) (*models.Device, error) {
+func CreateDevice(ctx context.Context, db DB, device models.Device) (*models.Device, error) {
 	var created models.Device
 
-	row := store.DB.QueryRow(createDeviceQuery, device.UserID, device.DeviceToken)
+	row := db.QueryRowContext(ctx, createDeviceQuery, device.UserID, device.DeviceToken)
 	err := row.Scan(&created.DeviceID, &created.UserID, &created.DeviceToken)
 	if err != nil {
 		return nil, err
@@ -68,10 +70,10 @@ func CreateDevice(device models.Device) (*models.Device, error) {
 	return &created, nil
 }
 
-func UpdateDevice(deviceID int64, device models.Device) (*models.Device, error) {
+func UpdateDevice(ctx context.Context, db DB, deviceID int64, device models.Device) (*models.Device, error) {
 	var updated models.Device
 
-	row := store.DB.QueryRow(updateDeviceQuery, device.UserID, device.DeviceToken, deviceID)
+	row := db.QueryRowContext(ctx, updateDeviceQuery, device.UserID, device.DeviceToken, deviceID)
 	err := row.Scan(&updated.DeviceID, &updated.UserID, &updated.DeviceToken)
 	if err != nil {
 		return nil, err
@@ -80,18 +82,8 @@ func UpdateDevice(deviceID int64, device models.Device) (*models.Device, error)
 	return &updated, nil
 }
 
-func DeleteDevice(deviceID int64) error {
-	stmt, err := store.DB.Prepare(deleteDeviceQuery)
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(deviceID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func DeleteDevice(ctx context.Context, db DB, deviceID int64) error {
+	return execOne(ctx, db, deleteDeviceQuery, deviceID)
 }
 
 const getDeviceListQuery = `
@@ -11,17 +11,17 @@ var _ = Describe("Device db interactions", func() {
 	var one, two, three, four *models.Device
 
 	BeforeEach(func() {
-		one, _ = datastore.CreateDevice(models.Device{UserID: 1, DeviceToken: "Testing"})
-		two, _ = datastore.CreateDevice(models.Device{UserID: 1, DeviceToken: "Testing Two"})
-		three, _ = datastore.CreateDevice(models.Device{UserID: 1, DeviceToken: "Testing Three"})
-		four, _ = datastore.CreateDevice(models.Device{UserID: 1, DeviceToken: "Testing Four"})
+		one, _ = datastore.CreateDevice(ctx, db, models.Device{UserID: 1, DeviceToken: "Testing"})
+		two, _ = datastore.CreateDevice(ctx, db, models.Device{UserID: 1, DeviceToken: "Testing Two"})
+		three, _ = datastore.CreateDevice(ctx, db, models.Device{UserID: 1, DeviceToken: "Testing Three"})
+		four, _ = datastore.CreateDevice(ctx, db, models.Device{UserID: 1, DeviceToken: "Testing Four"})
 	})
 
 	AfterEach(func() {
-		datastore.DeleteDevice(one.DeviceID)
-		datastore.DeleteDevice(two.DeviceID)
-		datastore.DeleteDevice(three.DeviceID)
-		datastore.DeleteDevice(four.DeviceID)
+		datastore.DeleteDevice(ctx, db, one.DeviceID)
+		datastore.DeleteDevice(ctx, db, two.DeviceID)
+		datastore.DeleteDevice(ctx, db, three.DeviceID)
+		datastore.DeleteDevice(ctx, db, four.DeviceID)
 	})
 
 	Describe("GetDeviceList", func() {
@@ -29,7 +29,7 @@ var _ = Describe("Device db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				devices, _ = datastore.GetDeviceList("")
+				devices, _ = datastore.GetDeviceList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of devices", func() {
@@ -43,7 +43,7 @@ var _ = Describe("Device db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct device", func() {
-				device, _ = datastore.GetDevice(one.DeviceID)
+				device, _ = datastore.GetDevice(ctx, db, one.DeviceID)
 				Expect(device.DeviceID).To(Equal(one.DeviceID))
 			})
 		})
@@ -55,7 +55,7 @@ var _ = Describe("Device db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				device, err = datastore.GetDevice(nonExistentID)
+				device, err = datastore.GetDevice(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -73,7 +73,7 @@ var _ = Describe("Device db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetDeviceCount("")
+				count, _ = datastore.GetDeviceCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -92,11 +92,11 @@ var _ = Describe("Device db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				device = models.Device{UserID: 1, DeviceToken: deviceToken}
-				created, _ = datastore.CreateDevice(device)
+				created, _ = datastore.CreateDevice(ctx, db, device)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteDevice(created.DeviceID)
+				datastore.DeleteDevice(ctx, db, created.DeviceID)
 			})
 
 			It("should return the created device", func() {
@@ -104,7 +104,7 @@ var _ = Describe("Device db interactions", func() {
 			})
 
 			It("should add a device to the db", func() {
-				newDevice, _ := datastore.GetDevice(created.DeviceID)
+				newDevice, _ := datastore.GetDevice(ctx, db, created.DeviceID)
 				Expect(newDevice.DeviceToken).To(Equal(deviceToken))
 			})
 		})
@@ -113,8 +113,8 @@ var _ = Describe("Device db interactions", func() {
 			It("should return an error object if device is not unique", func() {
 				name := "Test Name"
 				pln := models.Device{DeviceToken: name}
-				datastore.CreateDevice(pln)
-				_, err := datastore.CreateDevice(pln)
+				datastore.CreateDevice(ctx, db, pln)
+				_, err := datastore.CreateDevice(ctx, db, pln)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -132,12 +132,12 @@ var _ = Describe("Device db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				device = models.Device{UserID: 2, DeviceToken: deviceToken}
-				created, _ = datastore.CreateDevice(models.Device{UserID: 1, DeviceToken: "Daily"})
-				updated, _ = datastore.UpdateDevice(created.DeviceID, device)
+				created, _ = datastore.CreateDevice(ctx, db, models.Device{UserID: 1, DeviceToken: "Daily"})
+				updated, _ = datastore.UpdateDevice(ctx, db, created.DeviceID, device)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteDevice(updated.DeviceID)
+				datastore.DeleteDevice(ctx, db, updated.DeviceID)
 			})
 
 			It("should return the updated device", func() {
@@ -148,7 +148,7 @@ var _ = Describe("Device db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				device = models.Device{UserID: 1, DeviceToken: "Daily"}
-				updated, err = datastore.UpdateDevice(10000, device)
+				updated, err = datastore.UpdateDevice(ctx, db, 10000, device)
 			})
 
 			It("should return an error object if device to update doesn't exist", func() {
@@ -164,7 +164,7 @@ var _ = Describe("Device db interactions", func() {
 	Describe("DeleteDevice", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				err := datastore.DeleteDevice(one.DeviceID)
+				err := datastore.DeleteDevice(ctx, db, one.DeviceID)
 				Expect(err).To(BeNil())
 			})
 		})
@@ -1,20 +1,21 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetFeatureList(where string) ([]models.Feature, error) {
+func GetFeatureList(ctx context.Context, db DB, q Query) ([]models.Feature, error) {
 	var (
 		features []models.Feature
 		feature  models.Feature
 	)
 
-	query := fmt.Sprintf("%s %s", getFeatureListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getFeatureListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -28,14 +29,15 @@ func GetFeatureList(where string) ([]models.Feature, error) {
 	}
 	defer rows.Close()
 
-	return features, nil
+	return features, rows.Err()
 }
 
-func GetFeatureCount(where string) (*int, error) {
+func GetFeatureCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getFeatureCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getFeatureCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 	if err != nil {
 		return nil, err
@@ -44,10 +46,10 @@ func GetFeatureCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetFeature(featureID int64) (*models.Feature, error) {
+func GetFeature(ctx context.Context, db DB, featureID int64) (*models.Feature, error) {
 	var feature models.Feature
 
-	row := store.DB.QueryRow(getFeatureQuery, featureID)
+	row := db.QueryRowContext(ctx, getFeatureQuery, featureID)
 	err := row.Scan(&feature.FeatureID, &feature.FeatureName, &feature.FeatureDescription)
 	if err != nil {
 		return nil, err
@@ -56,10 +58,10 @@ func GetFeature(featureID int64) (*models.Feature, error) {
 	return &feature, nil
 }
 
-func CreateFeature(feature models.Feature) (*models.Feature, error) {
+func CreateFeature(ctx context.Context, db DB, feature models.Feature) (*models.Feature, error) {
 	var created models.Feature
 
-	row := store.DB.QueryRow(createFeatureQuery, feature.FeatureName, feature.FeatureDescription)
+	row := db.QueryRowContext(ctx, createFeatureQuery, feature.FeatureName, feature.FeatureDescription)
 	err := row.Scan(&created.FeatureID, &created.FeatureName, &created.FeatureDescription)
 	if err != nil {
 		return nil, err
@@ -68,10 +70,10 @@ func CreateFeature(feature models.Feature) (*models.Feature, error) {
 	return &created, nil
 }
 
-func UpdateFeature(featureID int64, feature models.Feature) (*models.Feature, error) {
+func UpdateFeature(ctx context.Context, db DB, featureID int64, feature models.Feature) (*models.Feature, error) {
 	var updated models.Feature
 
-	row := store.DB.QueryRow(updateFeatureQuery, feature.FeatureName, feature.FeatureDescription, featureID)
+	row := db.QueryRowContext(ctx, updateFeatureQuery, feature.FeatureName, feature.FeatureDescription, featureID)
 	err := row.Scan(&updated.FeatureID, &updated.FeatureName, &updated.FeatureDescription)
 	if err != nil {
 		return nil, err
@@ -80,18 +82,8 @@ func UpdateFeature(featureID int64, feature models.Feature) (*models.Feature, er
 	return &updated, nil
 }
 
-func DeleteFeature(featureID int64) error {
-	stmt, err := store.DB.Prepare(deleteFeatureQuery)
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(featureID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func DeleteFeature(ctx context.Context, db DB, featureID int64) error {
+	return execOne(ctx, db, deleteFeatureQuery, featureID)
 }
 
 const getFeatureListQuery = `
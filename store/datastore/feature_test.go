@@ -15,7 +15,7 @@ var _ = Describe("Feature db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				features, _ = datastore.GetFeatureList("")
+				features, _ = datastore.GetFeatureList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of features", func() {
@@ -29,7 +29,7 @@ var _ = Describe("Feature db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct feature", func() {
-				feature, _ = datastore.GetFeature(featureID)
+				feature, _ = datastore.GetFeature(ctx, db, featureID)
 				Expect(feature.FeatureID).To(Equal(featureID))
 			})
 		})
@@ -41,7 +41,7 @@ var _ = Describe("Feature db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				feature, err = datastore.GetFeature(nonExistentID)
+				feature, err = datastore.GetFeature(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -59,7 +59,7 @@ var _ = Describe("Feature db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetFeatureCount("")
+				count, _ = datastore.GetFeatureCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -78,11 +78,11 @@ var _ = Describe("Feature db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				feature = models.Feature{FeatureName: featureName, FeatureDescription: "Test Description"}
-				created, _ = datastore.CreateFeature(feature)
+				created, _ = datastore.CreateFeature(ctx, db, feature)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteFeature(created.FeatureID)
+				datastore.DeleteFeature(ctx, db, created.FeatureID)
 			})
 
 			It("should return the created feature", func() {
@@ -90,7 +90,7 @@ var _ = Describe("Feature db interactions", func() {
 			})
 
 			It("should add a feature to the db", func() {
-				newFeature, _ := datastore.GetFeature(created.FeatureID)
+				newFeature, _ := datastore.GetFeature(ctx, db, created.FeatureID)
 				Expect(newFeature.FeatureName).To(Equal(featureName))
 			})
 		})
@@ -99,13 +99,13 @@ var _ = Describe("Feature db interactions", func() {
 			var created *models.Feature
 
 			AfterEach(func() {
-				datastore.DeleteFeature(created.FeatureID)
+				datastore.DeleteFeature(ctx, db, created.FeatureID)
 			})
 
 			It("should return an error object if feature is not unique", func() {
 				usrRole := models.Feature{FeatureName: featureName, FeatureDescription: "Test Description"}
-				created, _ = datastore.CreateFeature(usrRole)
-				_, err := datastore.CreateFeature(usrRole)
+				created, _ = datastore.CreateFeature(ctx, db, usrRole)
+				_, err := datastore.CreateFeature(ctx, db, usrRole)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -123,12 +123,12 @@ var _ = Describe("Feature db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				feature = models.Feature{FeatureName: featureName, FeatureDescription: "Test Description"}
-				created, _ = datastore.CreateFeature(models.Feature{FeatureName: "Test"})
-				updated, _ = datastore.UpdateFeature(created.FeatureID, feature)
+				created, _ = datastore.CreateFeature(ctx, db, models.Feature{FeatureName: "Test"})
+				updated, _ = datastore.UpdateFeature(ctx, db, created.FeatureID, feature)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteFeature(updated.FeatureID)
+				datastore.DeleteFeature(ctx, db, updated.FeatureID)
 			})
 
 			It("should return the updated feature", func() {
@@ -139,7 +139,7 @@ var _ = Describe("Feature db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				feature = models.Feature{}
-				updated, err = datastore.UpdateFeature(10000, feature)
+				updated, err = datastore.UpdateFeature(ctx, db, 10000, feature)
 			})
 
 			It("should return an error object if feature to update doesn't exist", func() {
@@ -155,8 +155,8 @@ var _ = Describe("Feature db interactions", func() {
 	Describe("DeleteFeature", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				created, _ := datastore.CreateFeature(models.Feature{FeatureName: "Test"})
-				err := datastore.DeleteFeature(created.FeatureID)
+				created, _ := datastore.CreateFeature(ctx, db, models.Feature{FeatureName: "Test"})
+				err := datastore.DeleteFeature(ctx, db, created.FeatureID)
 				Expect(err).To(BeNil())
 			})
 		})
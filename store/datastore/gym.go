@@ -1,26 +1,27 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetGymList(where string) ([]models.Gym, error) {
+func GetGymList(ctx context.Context, db DB, q Query) ([]models.Gym, error) {
 	var (
 		gyms []models.Gym
 		gym  models.Gym
 	)
 
-	query := fmt.Sprintf("%s %s", getGymListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getGymListQuery, b.list(q, "deleted_on IS NULL"))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
 
 	for rows.Next() {
-		err = rows.Scan(&gym.GymID, &gym.UserID, &gym.GymName, &gym.MonthlyMemberFee)
+		err = rows.Scan(&gym.GymID, &gym.UserID, &gym.GymName, &gym.MonthlyMemberFee, &gym.DeletedOn)
 		gyms = append(gyms, gym)
 		if err != nil {
 			return nil, err
@@ -28,14 +29,15 @@ func GetGymList(where string) ([]models.Gym, error) {
 	}
 	defer rows.Close()
 
-	return gyms, nil
+	return gyms, rows.Err()
 }
 
-func GetGymCount(where string) (*int, error) {
+func GetGymCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getGymCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getGymCountQuery, b.where(q, "deleted_on IS NULL"))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 
 	if err != nil {
@@ -45,11 +47,11 @@ func GetGymCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetGym(gymID int64) (*models.Gym, error) {
+func GetGym(ctx context.Context, db DB, gymID int64) (*models.Gym, error) {
 	var gym models.Gym
 
-	row := store.DB.QueryRow(getGymQuery, gymID)
-	err := row.Scan(&gym.GymID, &gym.UserID, &gym.GymName, &gym.MonthlyMemberFee)
+	row := db.QueryRowContext(ctx, getGymQuery, gymID)
+	err := row.Scan(&gym.GymID, &gym.UserID, &gym.GymName, &gym.MonthlyMemberFee, &gym.DeletedOn)
 
 	if err != nil {
 		return nil, err
@@ -58,11 +60,11 @@ func GetGym(gymID int64) (*models.Gym, error) {
 	return &gym, nil
 }
 
-func CreateGym(gym models.Gym) (*models.Gym, error) {
+func CreateGym(ctx context.Context, db DB, gym models.Gym) (*models.Gym, error) {
 	var created models.Gym
 
-	row := store.DB.QueryRow(createGymQuery, gym.UserID, gym.GymName, gym.MonthlyMemberFee)
-	err := row.Scan(&created.GymID, &created.UserID, &created.GymName, &created.MonthlyMemberFee)
+	row := db.QueryRowContext(ctx, createGymQuery, gym.UserID, gym.GymName, gym.MonthlyMemberFee)
+	err := row.Scan(&created.GymID, &created.UserID, &created.GymName, &created.MonthlyMemberFee, &created.DeletedOn)
 	if err != nil {
 		return nil, err
 	}
@@ -70,11 +72,11 @@ func CreateGym(gym models.Gym) (*models.Gym, error) {
 	return &created, nil
 }
 
-func UpdateGym(gymID int64, gym models.Gym) (*models.Gym, error) {
+func UpdateGym(ctx context.Context, db DB, gymID int64, gym models.Gym) (*models.Gym, error) {
 	var updated models.Gym
 
-	row := store.DB.QueryRow(updateGymQuery, gym.UserID, gym.GymName, gym.MonthlyMemberFee, gymID)
-	err := row.Scan(&updated.GymID, &updated.UserID, &updated.GymName, &updated.MonthlyMemberFee)
+	row := db.QueryRowContext(ctx, updateGymQuery, gym.UserID, gym.GymName, gym.MonthlyMemberFee, gymID)
+	err := row.Scan(&updated.GymID, &updated.UserID, &updated.GymName, &updated.MonthlyMemberFee, &updated.DeletedOn)
 	if err != nil {
 		return nil, err
 	}
@@ -82,18 +84,24 @@ func UpdateGym(gymID int64, gym models.Gym) (*models.Gym, error) {
 	return &updated, nil
 }
 
-func DeleteGym(gymID int64) error {
-	stmt, err := store.DB.Prepare(deleteGymQuery)
-	if err != nil {
-		return err
-	}
+// DeleteGym marks the gym deleted. Its locations stay, but its API keys stop
+// working.
+func DeleteGym(ctx context.Context, db DB, gymID int64) error {
+	return execOne(ctx, db, deleteGymQuery, gymID)
+}
 
-	_, err = stmt.Exec(gymID)
+// RestoreGym undoes DeleteGym. Restoring a gym that isn't deleted leaves it as
+// it is.
+func RestoreGym(ctx context.Context, db DB, gymID int64) (*models.Gym, error) {
+	var restored models.Gym
+
+	row := db.QueryRowContext(ctx, restoreGymQuery, gymID)
+	err := row.Scan(&restored.GymID, &restored.UserID, &restored.GymName, &restored.MonthlyMemberFee, &restored.DeletedOn)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return &restored, nil
 }
 
 const getGymListQuery = `
@@ -104,26 +112,33 @@ FROM gyms
 const getGymQuery = `
 SELECT *
 FROM gyms
-WHERE gym_id = $1
+WHERE gym_id = $1 AND deleted_on IS NULL
 `
 
 const createGymQuery = `
 INSERT INTO gyms (user_id, gym_name, monthly_member_fee)
 VALUES ($1, $2, $3)
-RETURNING gym_id, user_id, gym_name, monthly_member_fee
+RETURNING gym_id, user_id, gym_name, monthly_member_fee, deleted_on
 `
 
 const updateGymQuery = `
 UPDATE gyms
 SET user_id = $1, gym_name = $2, monthly_member_fee = $3
-WHERE gym_id = $4
-RETURNING gym_id, user_id, gym_name, monthly_member_fee
+WHERE gym_id = $4 AND deleted_on IS NULL
+RETURNING gym_id, user_id, gym_name, monthly_member_fee, deleted_on
 `
 
 const deleteGymQuery = `
-DELETE
-FROM gyms
+UPDATE gyms
+SET deleted_on = CURRENT_TIMESTAMP
+WHERE gym_id = $1 AND deleted_on IS NULL
+`
+
+const restoreGymQuery = `
+UPDATE gyms
+SET deleted_on = NULL
 WHERE gym_id = $1
+RETURNING gym_id, user_id, gym_name, monthly_member_fee, deleted_on
 `
 
 const getGymCountQuery = `
@@ -1,20 +1,21 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetGymFeatureList(where string) ([]models.GymFeature, error) {
+func GetGymFeatureList(ctx context.Context, db DB, q Query) ([]models.GymFeature, error) {
 	var (
 		gymFeatures []models.GymFeature
 		gymFeature  models.GymFeature
 	)
 
-	query := fmt.Sprintf("%s %s", getGymFeatureListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getGymFeatureListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -28,14 +29,15 @@ func GetGymFeatureList(where string) ([]models.GymFeature, error) {
 	}
 	defer rows.Close()
 
-	return gymFeatures, nil
+	return gymFeatures, rows.Err()
 }
 
-func GetGymFeatureCount(where string) (*int, error) {
+func GetGymFeatureCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getGymFeatureCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getGymFeatureCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 	if err != nil {
 		return nil, err
@@ -44,10 +46,10 @@ func GetGymFeatureCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetGymFeature(gymFeatureID int64) (*models.GymFeature, error) {
+func GetGymFeature(ctx context.Context, db DB, gymFeatureID int64) (*models.GymFeature, error) {
 	var gymFeature models.GymFeature
 
-	row := store.DB.QueryRow(getGymFeatureQuery, gymFeatureID)
+	row := db.QueryRowContext(ctx, getGymFeatureQuery, gymFeatureID)
 	err := row.Scan(&gymFeature.GymFeatureID, &gymFeature.GymID, &gymFeature.FeatureID)
 	if err != nil {
 		return nil, err
@@ -56,10 +58,10 @@ func GetGymFeature(gymFeatureID int64) (*models.GymFeature, error) {
 	return &gymFeature, nil
 }
 
-func CreateGymFeature(gymFeature models.GymFeature) (*models.GymFeature, error) {
+func CreateGymFeature(ctx context.Context, db DB, gymFeature models.GymFeature) (*models.GymFeature, error) {
 	var created models.GymFeature
 
-	row := store.DB.QueryRow(createGymFeatureQuery, gymFeature.GymID, gymFeature.FeatureID)
+	row := db.QueryRowContext(ctx, createGymFeatureQuery, gymFeature.GymID, gymFeature.FeatureID)
 	err := row.Scan(&created.GymFeatureID, &created.GymID, &created.FeatureID)
 	if err != nil {
 		return nil, err
@@ -68,10 +70,10 @@ func CreateGymFeature(gymFeature models.GymFeature) (*models.GymFeature, error)
 	return &created, nil
 }
 
-func UpdateGymFeature(gymFeatureID int64, gymFeature models.GymFeature) (*models.GymFeature, error) {
+func UpdateGymFeature(ctx context.Context, db DB, gymFeatureID int64, gymFeature models.GymFeature) (*models.GymFeature, error) {
 	var updated models.GymFeature
 
-	row := store.DB.QueryRow(updateGymFeatureQuery, gymFeature.GymID, gymFeature.FeatureID, gymFeatureID)
+	row := db.QueryRowContext(ctx, updateGymFeatureQuery, gymFeature.GymID, gymFeature.FeatureID, gymFeatureID)
 	err := row.Scan(&updated.GymFeatureID, &updated.GymID, &updated.FeatureID)
 	if err != nil {
 		return nil, err
@@ -80,18 +82,8 @@ func UpdateGymFeature(gymFeatureID int64, gymFeature models.GymFeature) (*models
 	return &updated, nil
 }
 
-func DeleteGymFeature(gymFeatureID int64) error {
-	stmt, err := store.DB.Prepare(deleteGymFeatureQuery)
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(gymFeatureID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func DeleteGymFeature(ctx context.Context, db DB, gymFeatureID int64) error {
+	return execOne(ctx, db, deleteGymFeatureQuery, gymFeatureID)
 }
 
 const getGymFeatureListQuery = `
@@ -14,15 +14,15 @@ var _ = Describe("GymFeature db interactions", func() {
 	)
 
 	BeforeEach(func() {
-		gym, _ = datastore.CreateGym(models.Gym{GymName: "Test Gym Name"})
-		one, _ = datastore.CreateGymFeature(models.GymFeature{GymID: gym.GymID, FeatureID: 1})
-		two, _ = datastore.CreateGymFeature(models.GymFeature{GymID: gym.GymID, FeatureID: 2})
+		gym, _ = datastore.CreateGym(ctx, db, models.Gym{GymName: "Test Gym Name"})
+		one, _ = datastore.CreateGymFeature(ctx, db, models.GymFeature{GymID: gym.GymID, FeatureID: 1})
+		two, _ = datastore.CreateGymFeature(ctx, db, models.GymFeature{GymID: gym.GymID, FeatureID: 2})
 	})
 
 	AfterEach(func() {
-		datastore.DeleteGymFeature(one.GymFeatureID)
-		datastore.DeleteGymFeature(two.GymFeatureID)
-		datastore.DeleteGym(gym.GymID)
+		datastore.DeleteGymFeature(ctx, db, one.GymFeatureID)
+		datastore.DeleteGymFeature(ctx, db, two.GymFeatureID)
+		purge("gyms", "gym_id", gym.GymID)
 	})
 
 	Describe("GetGymFeatureList", func() {
@@ -30,7 +30,7 @@ var _ = Describe("GymFeature db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				gymFeatures, _ = datastore.GetGymFeatureList("")
+				gymFeatures, _ = datastore.GetGymFeatureList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of gymFeatures", func() {
@@ -44,7 +44,7 @@ var _ = Describe("GymFeature db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct gymFeature", func() {
-				gymFeature, _ = datastore.GetGymFeature(one.GymFeatureID)
+				gymFeature, _ = datastore.GetGymFeature(ctx, db, one.GymFeatureID)
 				Expect(gymFeature.GymFeatureID).To(Equal(one.GymFeatureID))
 			})
 		})
@@ -56,7 +56,7 @@ var _ = Describe("GymFeature db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				gymFeature, err = datastore.GetGymFeature(nonExistentID)
+				gymFeature, err = datastore.GetGymFeature(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -74,7 +74,7 @@ var _ = Describe("GymFeature db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetGymFeatureCount("")
+				count, _ = datastore.GetGymFeatureCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -93,11 +93,11 @@ var _ = Describe("GymFeature db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				gymFeature = models.GymFeature{GymID: gym.GymID, FeatureID: featureID}
-				created, _ = datastore.CreateGymFeature(gymFeature)
+				created, _ = datastore.CreateGymFeature(ctx, db, gymFeature)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteGymFeature(created.GymFeatureID)
+				datastore.DeleteGymFeature(ctx, db, created.GymFeatureID)
 			})
 
 			It("should return the created gymFeature", func() {
@@ -105,7 +105,7 @@ var _ = Describe("GymFeature db interactions", func() {
 			})
 
 			It("should add a gymFeature to the db", func() {
-				newGymFeature, _ := datastore.GetGymFeature(created.GymFeatureID)
+				newGymFeature, _ := datastore.GetGymFeature(ctx, db, created.GymFeatureID)
 				Expect(newGymFeature.FeatureID).To(Equal(featureID))
 			})
 		})
@@ -114,13 +114,13 @@ var _ = Describe("GymFeature db interactions", func() {
 			var created *models.GymFeature
 
 			AfterEach(func() {
-				datastore.DeleteGymFeature(created.GymFeatureID)
+				datastore.DeleteGymFeature(ctx, db, created.GymFeatureID)
 			})
 
 			It("should return an error object if gymFeature is not unique", func() {
 				gymFtr := models.GymFeature{GymID: gym.GymID, FeatureID: featureID}
-				created, _ = datastore.CreateGymFeature(gymFtr)
-				_, err := datastore.CreateGymFeature(gymFtr)
+				created, _ = datastore.CreateGymFeature(ctx, db, gymFtr)
+				_, err := datastore.CreateGymFeature(ctx, db, gymFtr)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -138,12 +138,12 @@ var _ = Describe("GymFeature db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				gymFeature = models.GymFeature{GymID: gym.GymID, FeatureID: featureID}
-				created, _ = datastore.CreateGymFeature(models.GymFeature{GymID: gym.GymID, FeatureID: featureID})
-				updated, _ = datastore.UpdateGymFeature(created.GymFeatureID, gymFeature)
+				created, _ = datastore.CreateGymFeature(ctx, db, models.GymFeature{GymID: gym.GymID, FeatureID: featureID})
+				updated, _ = datastore.UpdateGymFeature(ctx, db, created.GymFeatureID, gymFeature)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteGymFeature(updated.GymFeatureID)
+				datastore.DeleteGymFeature(ctx, db, updated.GymFeatureID)
 			})
 
 			It("should return the updated gymFeature", func() {
@@ -154,7 +154,7 @@ var _ = Describe("GymFeature db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				gymFeature = models.GymFeature{}
-				updated, err = datastore.UpdateGymFeature(10000, gymFeature)
+				updated, err = datastore.UpdateGymFeature(ctx, db, 10000, gymFeature)
 			})
 
 			It("should return an error object if gymFeature to update doesn't exist", func() {
@@ -170,8 +170,8 @@ var _ = Describe("GymFeature db interactions", func() {
 	Describe("DeleteGymFeature", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				created, _ := datastore.CreateGymFeature(models.GymFeature{GymID: gym.GymID, FeatureID: 10})
-				err := datastore.DeleteGymFeature(created.GymFeatureID)
+				created, _ := datastore.CreateGymFeature(ctx, db, models.GymFeature{GymID: gym.GymID, FeatureID: 10})
+				err := datastore.DeleteGymFeature(ctx, db, created.GymFeatureID)
 				Expect(err).To(BeNil())
 			})
 		})
@@ -1,25 +1,30 @@
 package datastore
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetGymLocationList(where string) ([]models.GymLocation, error) {
+func GetGymLocationList(ctx context.Context, db DB, scope Scope, q Query) ([]models.GymLocation, error) {
 	var (
-		gymLocations []models.GymLocation
-		gymLocation  models.GymLocation
+		gymLocations   []models.GymLocation
+		gymLocation    models.GymLocation
+		gymLocationIDs []int64
 	)
 
-	query := fmt.Sprintf("%s %s", getGymLocationListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getGymLocationListQuery, b.list(q, scope.gymLocations(b)))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
+		gymLocation.Address = &models.Address{}
 		err = rows.Scan(
 			&gymLocation.GymLocationID,
 			&gymLocation.GymID,
@@ -29,6 +34,9 @@ func GetGymLocationList(where string) ([]models.GymLocation, error) {
 			&gymLocation.WebsiteUrl,
 			&gymLocation.InNetwork,
 			&gymLocation.MonthlyMemberFee,
+			&gymLocation.UserID,
+			&gymLocation.Version,
+			&gymLocation.DeletedOn,
 			&gymLocation.Address.AddressID,
 			&gymLocation.Address.Country,
 			&gymLocation.Address.StateRegion,
@@ -43,27 +51,37 @@ func GetGymLocationList(where string) ([]models.GymLocation, error) {
 			return nil, err
 		}
 
-		gymLocation.BusinessHours, err = GetBusinessHourList(fmt.Sprintf(
-			"WHERE gym_location_id = %v",
-			gymLocation.GymLocationID,
-		))
+		gymLocations = append(gymLocations, gymLocation)
+		gymLocationIDs = append(gymLocationIDs, gymLocation.GymLocationID)
+	}
 
-		if err != nil {
-			return nil, err
-		}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
 
-		gymLocations = append(gymLocations, gymLocation)
+	if len(gymLocations) == 0 {
+		return gymLocations, nil
+	}
+
+	businessHours, err := getBusinessHoursByLocation(ctx, db, gymLocationIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range gymLocations {
+		gymLocations[i].BusinessHours = businessHours[gymLocations[i].GymLocationID]
 	}
-	defer rows.Close()
 
 	return gymLocations, nil
 }
 
-func GetGymLocationCount(where string) (*int, error) {
+func GetGymLocationCount(ctx context.Context, db DB, scope Scope, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getGymLocationCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getGymLocationCountQuery, b.where(q, scope.gymLocations(b)))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 
 	if err != nil {
@@ -73,10 +91,11 @@ func GetGymLocationCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetGymLocation(addressID int64) (*models.GymLocation, error) {
+func GetGymLocation(ctx context.Context, db DB, scope Scope, gymLocationID int64) (*models.GymLocation, error) {
 	var gymLocation models.GymLocation
 
-	row := store.DB.QueryRow(getGymLocationQuery, addressID)
+	b := &builder{args: []interface{}{gymLocationID}}
+	row := db.QueryRowContext(ctx, fmt.Sprintf("%s AND %s", getGymLocationQuery, scope.gymLocations(b)), b.args...)
 	err := row.Scan(
 		&gymLocation.GymLocationID,
 		&gymLocation.GymID,
@@ -86,6 +105,9 @@ func GetGymLocation(addressID int64) (*models.GymLocation, error) {
 		&gymLocation.WebsiteUrl,
 		&gymLocation.InNetwork,
 		&gymLocation.MonthlyMemberFee,
+		&gymLocation.UserID,
+		&gymLocation.Version,
+		&gymLocation.DeletedOn,
 	)
 
 	if err != nil {
@@ -95,10 +117,11 @@ func GetGymLocation(addressID int64) (*models.GymLocation, error) {
 	return &gymLocation, nil
 }
 
-func CreateGymLocation(gymLocation models.GymLocation) (*models.GymLocation, error) {
+func CreateGymLocation(ctx context.Context, db DB, gymLocation models.GymLocation) (*models.GymLocation, error) {
 	var created models.GymLocation
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		createGymLocationQuery,
 		gymLocation.GymID,
 		gymLocation.AddressID,
@@ -107,6 +130,7 @@ func CreateGymLocation(gymLocation models.GymLocation) (*models.GymLocation, err
 		gymLocation.WebsiteUrl,
 		gymLocation.InNetwork,
 		gymLocation.MonthlyMemberFee,
+		gymLocation.UserID,
 	)
 	err := row.Scan(
 		&created.GymLocationID,
@@ -117,6 +141,9 @@ func CreateGymLocation(gymLocation models.GymLocation) (*models.GymLocation, err
 		&created.WebsiteUrl,
 		&created.InNetwork,
 		&created.MonthlyMemberFee,
+		&created.UserID,
+		&created.Version,
+		&created.DeletedOn,
 	)
 	if err != nil {
 		return nil, err
@@ -125,10 +152,13 @@ func CreateGymLocation(gymLocation models.GymLocation) (*models.GymLocation, err
 	return &created, nil
 }
 
-func UpdateGymLocation(addressID int64, gymLocation models.GymLocation) (*models.GymLocation, error) {
+// UpdateGymLocation updates the gym location if it's at gymLocation.Version,
+// or whatever version it's at if that's 0.
+func UpdateGymLocation(ctx context.Context, db DB, gymLocationID int64, gymLocation models.GymLocation) (*models.GymLocation, error) {
 	var updated models.GymLocation
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		updateGymLocationQuery,
 		gymLocation.GymID,
 		gymLocation.AddressID,
@@ -137,7 +167,9 @@ func UpdateGymLocation(addressID int64, gymLocation models.GymLocation) (*models
 		gymLocation.WebsiteUrl,
 		gymLocation.InNetwork,
 		gymLocation.MonthlyMemberFee,
-		addressID,
+		gymLocation.UserID,
+		gymLocationID,
+		gymLocation.Version,
 	)
 	err := row.Scan(
 		&updated.GymLocationID,
@@ -148,7 +180,13 @@ func UpdateGymLocation(addressID int64, gymLocation models.GymLocation) (*models
 		&updated.WebsiteUrl,
 		&updated.InNetwork,
 		&updated.MonthlyMemberFee,
+		&updated.UserID,
+		&updated.Version,
+		&updated.DeletedOn,
 	)
+	if err == sql.ErrNoRows {
+		return nil, checkVersion(ctx, db, "gym_locations", "gym_location_id", gymLocationID, gymLocation.Version)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -156,18 +194,87 @@ func UpdateGymLocation(addressID int64, gymLocation models.GymLocation) (*models
 	return &updated, nil
 }
 
-func DeleteGymLocation(addressID int64) error {
-	stmt, err := store.DB.Prepare(deleteGymLocationQuery)
+// PatchGymLocation only updates the fields of gymLocation that are listed in
+// fields.
+func PatchGymLocation(ctx context.Context, db DB, gymLocationID int64, gymLocation models.GymLocation, fields []string) (*models.GymLocation, error) {
+	var patched models.GymLocation
+
+	b := &builder{}
+	set, err := b.set(map[string]interface{}{
+		"gym_id":             gymLocation.GymID,
+		"address_id":         gymLocation.AddressID,
+		"location_name":      gymLocation.LocationName,
+		"phone_number":       gymLocation.PhoneNumber,
+		"website_url":        gymLocation.WebsiteUrl,
+		"in_network":         gymLocation.InNetwork,
+		"monthly_member_fee": gymLocation.MonthlyMemberFee,
+		"user_id":            gymLocation.UserID,
+	}, fields)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = stmt.Exec(addressID)
+	query := fmt.Sprintf(patchGymLocationQuery, set, b.arg(gymLocationID), b.arg(gymLocation.Version))
+	row := db.QueryRowContext(ctx, query, b.args...)
+	err = row.Scan(
+		&patched.GymLocationID,
+		&patched.GymID,
+		&patched.AddressID,
+		&patched.LocationName,
+		&patched.PhoneNumber,
+		&patched.WebsiteUrl,
+		&patched.InNetwork,
+		&patched.MonthlyMemberFee,
+		&patched.UserID,
+		&patched.Version,
+		&patched.DeletedOn,
+	)
+	if err == sql.ErrNoRows {
+		return nil, checkVersion(ctx, db, "gym_locations", "gym_location_id", gymLocationID, gymLocation.Version)
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return &patched, nil
+}
+
+// DeleteGymLocation marks the gym location deleted if it's at version, or
+// whatever version it's at if that's 0. Its visits, business hours and images
+// stay.
+func DeleteGymLocation(ctx context.Context, db DB, gymLocationID int64, version int64) error {
+	err := execOne(ctx, db, deleteGymLocationQuery, gymLocationID, version)
+	if err == sql.ErrNoRows {
+		return checkVersion(ctx, db, "gym_locations", "gym_location_id", gymLocationID, version)
+	}
+
+	return err
+}
+
+// RestoreGymLocation undoes DeleteGymLocation. Restoring a gym location that
+// isn't deleted leaves it as it is.
+func RestoreGymLocation(ctx context.Context, db DB, gymLocationID int64) (*models.GymLocation, error) {
+	var restored models.GymLocation
+
+	row := db.QueryRowContext(ctx, restoreGymLocationQuery, gymLocationID)
+	err := row.Scan(
+		&restored.GymLocationID,
+		&restored.GymID,
+		&restored.AddressID,
+		&restored.LocationName,
+		&restored.PhoneNumber,
+		&restored.WebsiteUrl,
+		&restored.InNetwork,
+		&restored.MonthlyMemberFee,
+		&restored.UserID,
+		&restored.Version,
+		&restored.DeletedOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restored, nil
 }
 
 const getGymLocationListQuery = `
@@ -180,6 +287,9 @@ SELECT
     gl.website_url,
     gl.in_network,
     gl.monthly_member_fee,
+    gl.user_id,
+    gl.version,
+    gl.deleted_on,
     a.address_id,
     a.country,
     a.state_region,
@@ -189,33 +299,46 @@ SELECT
     a.latitude,
     a.longitude
 FROM gym_locations AS gl
-JOIN addresses AS a
-ON gl.address_id = a.address_id
+JOIN addresses AS a USING (address_id)
 `
 
 const getGymLocationQuery = `
-SELECT *
+SELECT gym_location_id, gym_id, address_id, location_name, phone_number, website_url, in_network, monthly_member_fee, user_id, version, deleted_on
 FROM gym_locations
 WHERE gym_location_id = $1
 `
 
 const createGymLocationQuery = `
-INSERT INTO gym_locations (gym_id, address_id, location_name, phone_number, website_url, in_network, monthly_member_fee)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING gym_location_id, gym_id, address_id, location_name, phone_number, website_url, in_network, monthly_member_fee
+INSERT INTO gym_locations (gym_id, address_id, location_name, phone_number, website_url, in_network, monthly_member_fee, user_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING gym_location_id, gym_id, address_id, location_name, phone_number, website_url, in_network, monthly_member_fee, user_id, version, deleted_on
 `
 
 const updateGymLocationQuery = `
 UPDATE gym_locations
-SET gym_id = $1, address_id = $2, location_name = $3, phone_number = $4, website_url = $5, in_network = $6, monthly_member_fee = $7
-WHERE gym_location_id = $8
-RETURNING gym_location_id, gym_id, address_id, location_name, phone_number, website_url, in_network, monthly_member_fee
+SET gym_id = $1, address_id = $2, location_name = $3, phone_number = $4, website_url = $5, in_network = $6, monthly_member_fee = $7, user_id = $8, version = version + 1
+WHERE gym_location_id = $9 AND deleted_on IS NULL AND ($10 = 0 OR version = $10)
+RETURNING gym_location_id, gym_id, address_id, location_name, phone_number, website_url, in_network, monthly_member_fee, user_id, version, deleted_on
+`
+
+const patchGymLocationQuery = `
+UPDATE gym_locations
+SET %s
+WHERE gym_location_id = %s AND deleted_on IS NULL AND (%[3]s = 0 OR version = %[3]s)
+RETURNING gym_location_id, gym_id, address_id, location_name, phone_number, website_url, in_network, monthly_member_fee, user_id, version, deleted_on
 `
 
 const deleteGymLocationQuery = `
-DELETE
-FROM gym_locations
+UPDATE gym_locations
+SET deleted_on = CURRENT_TIMESTAMP, version = version + 1
+WHERE gym_location_id = $1 AND deleted_on IS NULL AND ($2 = 0 OR version = $2)
+`
+
+const restoreGymLocationQuery = `
+UPDATE gym_locations
+SET deleted_on = NULL, version = version + CASE WHEN deleted_on IS NULL THEN 0 ELSE 1 END
 WHERE gym_location_id = $1
+RETURNING gym_location_id, gym_id, address_id, location_name, phone_number, website_url, in_network, monthly_member_fee, user_id, version, deleted_on
 `
 
 const getGymLocationCountQuery = `
@@ -1,6 +1,8 @@
 package datastore_test
 
 import (
+	"database/sql"
+
 	"github.com/lukashambsch/anygym.api/models"
 	"github.com/lukashambsch/anygym.api/store/datastore"
 	. "github.com/onsi/ginkgo"
@@ -15,14 +17,14 @@ var _ = Describe("GymLocation db interactions", func() {
 	)
 
 	BeforeEach(func() {
-		addr, _ = datastore.CreateAddress(models.Address{StreetAddress: "Testing"})
-		address, _ = datastore.CreateAddress(models.Address{StreetAddress: "Testing Two"})
-		one, _ = datastore.CreateGymLocation(models.GymLocation{
+		addr, _ = datastore.CreateAddress(ctx, db, models.Address{StreetAddress: "Testing"})
+		address, _ = datastore.CreateAddress(ctx, db, models.Address{StreetAddress: "Testing Two"})
+		one, _ = datastore.CreateGymLocation(ctx, db, models.GymLocation{
 			GymID:        gymID,
 			AddressID:    addr.AddressID,
 			LocationName: "Testing",
 		})
-		two, _ = datastore.CreateGymLocation(models.GymLocation{
+		two, _ = datastore.CreateGymLocation(ctx, db, models.GymLocation{
 			GymID:        gymID,
 			AddressID:    address.AddressID,
 			LocationName: "Testing Two",
@@ -30,10 +32,10 @@ var _ = Describe("GymLocation db interactions", func() {
 	})
 
 	AfterEach(func() {
-		datastore.DeleteGymLocation(one.GymLocationID)
-		datastore.DeleteGymLocation(two.GymLocationID)
-		datastore.DeleteAddress(addr.AddressID)
-		datastore.DeleteAddress(address.AddressID)
+		purge("gym_locations", "gym_location_id", one.GymLocationID)
+		purge("gym_locations", "gym_location_id", two.GymLocationID)
+		datastore.DeleteAddress(ctx, db, addr.AddressID)
+		datastore.DeleteAddress(ctx, db, address.AddressID)
 	})
 
 	Describe("GetGymLocationList", func() {
@@ -41,12 +43,23 @@ var _ = Describe("GymLocation db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				gymLocations, _ = datastore.GetGymLocationList("")
+				gymLocations, _ = datastore.GetGymLocationList(ctx, db, datastore.Unscoped, datastore.Query{})
 			})
 
 			It("should return a list of gymLocations", func() {
 				Expect(len(gymLocations)).To(Equal(4))
 			})
+
+			It("should load each location's own business hours", func() {
+				var total int
+				for _, gymLocation := range gymLocations {
+					for _, businessHour := range gymLocation.BusinessHours {
+						Expect(businessHour.GymLocationID).To(Equal(gymLocation.GymLocationID))
+					}
+					total += len(gymLocation.BusinessHours)
+				}
+				Expect(total).ToNot(BeZero())
+			})
 		})
 	})
 
@@ -55,7 +68,7 @@ var _ = Describe("GymLocation db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct gymLocation", func() {
-				gymLocation, _ = datastore.GetGymLocation(one.GymLocationID)
+				gymLocation, _ = datastore.GetGymLocation(ctx, db, datastore.Unscoped, one.GymLocationID)
 				Expect(gymLocation.GymLocationID).To(Equal(one.GymLocationID))
 			})
 		})
@@ -67,7 +80,7 @@ var _ = Describe("GymLocation db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				gymLocation, err = datastore.GetGymLocation(nonExistentID)
+				gymLocation, err = datastore.GetGymLocation(ctx, db, datastore.Unscoped, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -85,7 +98,7 @@ var _ = Describe("GymLocation db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetGymLocationCount("")
+				count, _ = datastore.GetGymLocationCount(ctx, db, datastore.Unscoped, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -104,14 +117,14 @@ var _ = Describe("GymLocation db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				newAddr, _ = datastore.CreateAddress(models.Address{StreetAddress: "New One"})
+				newAddr, _ = datastore.CreateAddress(ctx, db, models.Address{StreetAddress: "New One"})
 				gymLocation = models.GymLocation{GymID: gymID, AddressID: newAddr.AddressID, LocationName: locationName}
-				created, _ = datastore.CreateGymLocation(gymLocation)
+				created, _ = datastore.CreateGymLocation(ctx, db, gymLocation)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteGymLocation(created.GymLocationID)
-				datastore.DeleteAddress(newAddr.AddressID)
+				purge("gym_locations", "gym_location_id", created.GymLocationID)
+				datastore.DeleteAddress(ctx, db, newAddr.AddressID)
 			})
 
 			It("should return the created gymLocation", func() {
@@ -119,7 +132,7 @@ var _ = Describe("GymLocation db interactions", func() {
 			})
 
 			It("should add a gymLocation to the db", func() {
-				newGymLocation, _ := datastore.GetGymLocation(created.GymLocationID)
+				newGymLocation, _ := datastore.GetGymLocation(ctx, db, datastore.Unscoped, created.GymLocationID)
 				Expect(newGymLocation.LocationName).To(Equal(locationName))
 			})
 		})
@@ -128,7 +141,7 @@ var _ = Describe("GymLocation db interactions", func() {
 			It("should return an error object if gymLocation is not unique", func() {
 				street := "Test Street"
 				loc := models.GymLocation{GymID: gymID, AddressID: addr.AddressID, LocationName: street}
-				_, err := datastore.CreateGymLocation(loc)
+				_, err := datastore.CreateGymLocation(ctx, db, loc)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -146,19 +159,19 @@ var _ = Describe("GymLocation db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				newAddr, _ = datastore.CreateAddress(models.Address{StreetAddress: "Another Test Street"})
+				newAddr, _ = datastore.CreateAddress(ctx, db, models.Address{StreetAddress: "Another Test Street"})
 				gymLocation = models.GymLocation{GymID: gymID, AddressID: newAddr.AddressID, LocationName: locationName}
-				created, _ = datastore.CreateGymLocation(models.GymLocation{
+				created, _ = datastore.CreateGymLocation(ctx, db, models.GymLocation{
 					GymID:        gymID,
 					AddressID:    newAddr.AddressID,
 					LocationName: "Test Name",
 				})
-				updated, _ = datastore.UpdateGymLocation(created.GymLocationID, gymLocation)
+				updated, _ = datastore.UpdateGymLocation(ctx, db, created.GymLocationID, gymLocation)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteGymLocation(updated.GymLocationID)
-				datastore.DeleteAddress(newAddr.AddressID)
+				purge("gym_locations", "gym_location_id", updated.GymLocationID)
+				datastore.DeleteAddress(ctx, db, newAddr.AddressID)
 			})
 
 			It("should return the updated gymLocation", func() {
@@ -169,7 +182,7 @@ var _ = Describe("GymLocation db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				gymLocation = models.GymLocation{LocationName: "Test Name"}
-				updated, err = datastore.UpdateGymLocation(2, gymLocation)
+				updated, err = datastore.UpdateGymLocation(ctx, db, 2, gymLocation)
 			})
 
 			It("should return an error object if gymLocation to update doesn't exist", func() {
@@ -185,9 +198,44 @@ var _ = Describe("GymLocation db interactions", func() {
 	Describe("DeleteGymLocation", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				err := datastore.DeleteGymLocation(one.GymLocationID)
+				err := datastore.DeleteGymLocation(ctx, db, one.GymLocationID, 0)
 				Expect(err).To(BeNil())
 			})
+
+			It("should hide the gym location unless the scope asks for deleted rows", func() {
+				datastore.DeleteGymLocation(ctx, db, one.GymLocationID, 0)
+
+				_, err := datastore.GetGymLocation(ctx, db, datastore.Unscoped, one.GymLocationID)
+				Expect(err).To(Equal(sql.ErrNoRows))
+
+				deleted, err := datastore.GetGymLocation(ctx, db, datastore.Scope{All: true, Deleted: true}, one.GymLocationID)
+				Expect(err).To(BeNil())
+				Expect(deleted.DeletedOn).NotTo(BeNil())
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return sql.ErrNoRows if the gym location is already deleted", func() {
+				datastore.DeleteGymLocation(ctx, db, one.GymLocationID, 0)
+				err := datastore.DeleteGymLocation(ctx, db, one.GymLocationID, 0)
+				Expect(err).To(Equal(sql.ErrNoRows))
+			})
+		})
+	})
+
+	Describe("RestoreGymLocation", func() {
+		It("should bring a deleted gym location back at a new version", func() {
+			datastore.DeleteGymLocation(ctx, db, one.GymLocationID, 0)
+			restored, err := datastore.RestoreGymLocation(ctx, db, one.GymLocationID)
+			Expect(err).To(BeNil())
+			Expect(restored.DeletedOn).To(BeNil())
+			Expect(restored.Version).To(Equal(one.Version + 2))
+		})
+
+		It("should leave a gym location that isn't deleted as it is", func() {
+			restored, err := datastore.RestoreGymLocation(ctx, db, one.GymLocationID)
+			Expect(err).To(BeNil())
+			Expect(restored.Version).To(Equal(one.Version))
 		})
 	})
 })
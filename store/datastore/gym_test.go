@@ -1,6 +1,8 @@
 package datastore_test
 
 import (
+	"database/sql"
+
 	"github.com/lukashambsch/anygym.api/models"
 	"github.com/lukashambsch/anygym.api/store/datastore"
 	. "github.com/onsi/ginkgo"
@@ -15,7 +17,7 @@ var _ = Describe("Gym db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				gyms, _ = datastore.GetGymList("")
+				gyms, _ = datastore.GetGymList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of gyms", func() {
@@ -29,7 +31,7 @@ var _ = Describe("Gym db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct gym", func() {
-				gym, _ = datastore.GetGym(gymID)
+				gym, _ = datastore.GetGym(ctx, db, gymID)
 				Expect(gym.GymID).To(Equal(gymID))
 			})
 		})
@@ -41,7 +43,7 @@ var _ = Describe("Gym db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				gym, err = datastore.GetGym(nonExistentID)
+				gym, err = datastore.GetGym(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -59,7 +61,7 @@ var _ = Describe("Gym db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetGymCount("")
+				count, _ = datastore.GetGymCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -78,11 +80,11 @@ var _ = Describe("Gym db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				gym = models.Gym{GymName: gymName}
-				created, _ = datastore.CreateGym(gym)
+				created, _ = datastore.CreateGym(ctx, db, gym)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteGym(created.GymID)
+				purge("gyms", "gym_id", created.GymID)
 			})
 
 			It("should return the created gym", func() {
@@ -90,7 +92,7 @@ var _ = Describe("Gym db interactions", func() {
 			})
 
 			It("should add a gym to the db", func() {
-				newGym, _ := datastore.GetGym(created.GymID)
+				newGym, _ := datastore.GetGym(ctx, db, created.GymID)
 				Expect(newGym.GymName).To(Equal(gymName))
 			})
 		})
@@ -111,12 +113,12 @@ var _ = Describe("Gym db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				gym = models.Gym{GymName: gymName}
-				created, _ = datastore.CreateGym(models.Gym{GymName: "Gym"})
-				updated, _ = datastore.UpdateGym(created.GymID, gym)
+				created, _ = datastore.CreateGym(ctx, db, models.Gym{GymName: "Gym"})
+				updated, _ = datastore.UpdateGym(ctx, db, created.GymID, gym)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteGym(updated.GymID)
+				purge("gyms", "gym_id", updated.GymID)
 			})
 
 			It("should return the updated gym", func() {
@@ -127,7 +129,7 @@ var _ = Describe("Gym db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				gym = models.Gym{GymName: "Pending"}
-				updated, err = datastore.UpdateGym(2000, gym)
+				updated, err = datastore.UpdateGym(ctx, db, 2000, gym)
 			})
 
 			It("should return an error object", func() {
@@ -141,12 +143,50 @@ var _ = Describe("Gym db interactions", func() {
 	})
 
 	Describe("DeleteGym", func() {
+		var created *models.Gym
+
+		BeforeEach(func() {
+			created, _ = datastore.CreateGym(ctx, db, models.Gym{GymName: "Test"})
+		})
+
+		AfterEach(func() {
+			purge("gyms", "gym_id", created.GymID)
+		})
+
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				created, _ := datastore.CreateGym(models.Gym{GymName: "Test"})
-				err := datastore.DeleteGym(created.GymID)
+				err := datastore.DeleteGym(ctx, db, created.GymID)
 				Expect(err).To(BeNil())
 			})
+
+			It("should hide the gym", func() {
+				datastore.DeleteGym(ctx, db, created.GymID)
+				_, err := datastore.GetGym(ctx, db, created.GymID)
+				Expect(err).To(Equal(sql.ErrNoRows))
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return sql.ErrNoRows if the gym is already deleted", func() {
+				datastore.DeleteGym(ctx, db, created.GymID)
+				err := datastore.DeleteGym(ctx, db, created.GymID)
+				Expect(err).To(Equal(sql.ErrNoRows))
+			})
+		})
+	})
+
+	Describe("RestoreGym", func() {
+		It("should bring a deleted gym back", func() {
+			created, _ := datastore.CreateGym(ctx, db, models.Gym{GymName: "Test"})
+			defer purge("gyms", "gym_id", created.GymID)
+
+			datastore.DeleteGym(ctx, db, created.GymID)
+			restored, err := datastore.RestoreGym(ctx, db, created.GymID)
+			Expect(err).To(BeNil())
+			Expect(restored.DeletedOn).To(BeNil())
+
+			_, err = datastore.GetGym(ctx, db, created.GymID)
+			Expect(err).To(BeNil())
 		})
 	})
 })
@@ -1,20 +1,21 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetHolidayList(where string) ([]models.Holiday, error) {
+func GetHolidayList(ctx context.Context, db DB, q Query) ([]models.Holiday, error) {
 	var (
 		holidays []models.Holiday
 		holiday  models.Holiday
 	)
 
-	query := fmt.Sprintf("%s %s", getHolidayListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getHolidayListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -28,14 +29,15 @@ func GetHolidayList(where string) ([]models.Holiday, error) {
 	}
 	defer rows.Close()
 
-	return holidays, nil
+	return holidays, rows.Err()
 }
 
-func GetHolidayCount(where string) (*int, error) {
+func GetHolidayCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getHolidayCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getHolidayCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 	if err != nil {
 		return nil, err
@@ -44,10 +46,10 @@ func GetHolidayCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetHoliday(holidayID int64) (*models.Holiday, error) {
+func GetHoliday(ctx context.Context, db DB, holidayID int64) (*models.Holiday, error) {
 	var holiday models.Holiday
 
-	row := store.DB.QueryRow(getHolidayQuery, holidayID)
+	row := db.QueryRowContext(ctx, getHolidayQuery, holidayID)
 	err := row.Scan(&holiday.HolidayID, &holiday.HolidayName)
 	if err != nil {
 		return nil, err
@@ -56,10 +58,10 @@ func GetHoliday(holidayID int64) (*models.Holiday, error) {
 	return &holiday, nil
 }
 
-func CreateHoliday(holiday models.Holiday) (*models.Holiday, error) {
+func CreateHoliday(ctx context.Context, db DB, holiday models.Holiday) (*models.Holiday, error) {
 	var created models.Holiday
 
-	row := store.DB.QueryRow(createHolidayQuery, holiday.HolidayName)
+	row := db.QueryRowContext(ctx, createHolidayQuery, holiday.HolidayName)
 	err := row.Scan(&created.HolidayID, &created.HolidayName)
 	if err != nil {
 		return nil, err
@@ -68,10 +70,10 @@ func CreateHoliday(holiday models.Holiday) (*models.Holiday, error) {
 	return &created, nil
 }
 
-func UpdateHoliday(holidayID int64, holiday models.Holiday) (*models.Holiday, error) {
+func UpdateHoliday(ctx context.Context, db DB, holidayID int64, holiday models.Holiday) (*models.Holiday, error) {
 	var updated models.Holiday
 
-	row := store.DB.QueryRow(updateHolidayQuery, holiday.HolidayName, holidayID)
+	row := db.QueryRowContext(ctx, updateHolidayQuery, holiday.HolidayName, holidayID)
 	err := row.Scan(&updated.HolidayID, &updated.HolidayName)
 	if err != nil {
 		return nil, err
@@ -80,18 +82,8 @@ func UpdateHoliday(holidayID int64, holiday models.Holiday) (*models.Holiday, er
 	return &updated, nil
 }
 
-func DeleteHoliday(holidayID int64) error {
-	stmt, err := store.DB.Prepare(deleteHolidayQuery)
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(holidayID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func DeleteHoliday(ctx context.Context, db DB, holidayID int64) error {
+	return execOne(ctx, db, deleteHolidayQuery, holidayID)
 }
 
 const getHolidayListQuery = `
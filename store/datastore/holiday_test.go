@@ -15,7 +15,7 @@ var _ = Describe("Holiday db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				holidays, _ = datastore.GetHolidayList("")
+				holidays, _ = datastore.GetHolidayList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of holidays", func() {
@@ -29,7 +29,7 @@ var _ = Describe("Holiday db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct holiday", func() {
-				holiday, _ = datastore.GetHoliday(holidayID)
+				holiday, _ = datastore.GetHoliday(ctx, db, holidayID)
 				Expect(holiday.HolidayID).To(Equal(holidayID))
 			})
 		})
@@ -41,7 +41,7 @@ var _ = Describe("Holiday db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				holiday, err = datastore.GetHoliday(nonExistentID)
+				holiday, err = datastore.GetHoliday(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -59,7 +59,7 @@ var _ = Describe("Holiday db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetHolidayCount("")
+				count, _ = datastore.GetHolidayCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -78,11 +78,11 @@ var _ = Describe("Holiday db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				holiday = models.Holiday{HolidayName: holidayName}
-				created, _ = datastore.CreateHoliday(holiday)
+				created, _ = datastore.CreateHoliday(ctx, db, holiday)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteHoliday(created.HolidayID)
+				datastore.DeleteHoliday(ctx, db, created.HolidayID)
 			})
 
 			It("should return the created holiday", func() {
@@ -90,7 +90,7 @@ var _ = Describe("Holiday db interactions", func() {
 			})
 
 			It("should add a holiday to the db", func() {
-				newHoliday, _ := datastore.GetHoliday(created.HolidayID)
+				newHoliday, _ := datastore.GetHoliday(ctx, db, created.HolidayID)
 				Expect(newHoliday.HolidayName).To(Equal(holidayName))
 			})
 		})
@@ -99,14 +99,14 @@ var _ = Describe("Holiday db interactions", func() {
 			var created *models.Holiday
 
 			AfterEach(func() {
-				datastore.DeleteHoliday(created.HolidayID)
+				datastore.DeleteHoliday(ctx, db, created.HolidayID)
 			})
 
 			It("should return an error object if holiday is not unique", func() {
 				name := "Test Name"
 				pln := models.Holiday{HolidayName: name}
-				created, _ = datastore.CreateHoliday(pln)
-				_, err := datastore.CreateHoliday(pln)
+				created, _ = datastore.CreateHoliday(ctx, db, pln)
+				_, err := datastore.CreateHoliday(ctx, db, pln)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -124,12 +124,12 @@ var _ = Describe("Holiday db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				holiday = models.Holiday{HolidayName: holidayName}
-				created, _ = datastore.CreateHoliday(models.Holiday{HolidayName: "Daily"})
-				updated, _ = datastore.UpdateHoliday(created.HolidayID, holiday)
+				created, _ = datastore.CreateHoliday(ctx, db, models.Holiday{HolidayName: "Daily"})
+				updated, _ = datastore.UpdateHoliday(ctx, db, created.HolidayID, holiday)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteHoliday(updated.HolidayID)
+				datastore.DeleteHoliday(ctx, db, updated.HolidayID)
 			})
 
 			It("should return the updated holiday", func() {
@@ -140,7 +140,7 @@ var _ = Describe("Holiday db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				holiday = models.Holiday{HolidayName: "Daily"}
-				updated, err = datastore.UpdateHoliday(10000, holiday)
+				updated, err = datastore.UpdateHoliday(ctx, db, 10000, holiday)
 			})
 
 			It("should return an error object if holiday to update doesn't exist", func() {
@@ -156,8 +156,8 @@ var _ = Describe("Holiday db interactions", func() {
 	Describe("DeleteHoliday", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				created, _ := datastore.CreateHoliday(models.Holiday{HolidayName: "Testing"})
-				err := datastore.DeleteHoliday(created.HolidayID)
+				created, _ := datastore.CreateHoliday(ctx, db, models.Holiday{HolidayName: "Testing"})
+				err := datastore.DeleteHoliday(ctx, db, created.HolidayID)
 				Expect(err).To(BeNil())
 			})
 		})
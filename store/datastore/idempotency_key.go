@@ -0,0 +1,114 @@
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+func GetIdempotencyKey(ctx context.Context, db DB, owner string, key string) (*models.IdempotencyKey, error) {
+	return scanIdempotencyKey(db.QueryRowContext(ctx, getIdempotencyKeyQuery, owner, key))
+}
+
+// ClaimIdempotencyKey stores key for window, taking over an expired one of
+// the same owner and name. It returns false, and the stored key, if there's
+// one that hasn't expired yet.
+func ClaimIdempotencyKey(ctx context.Context, db DB, key models.IdempotencyKey, window time.Duration) (*models.IdempotencyKey, bool, error) {
+	row := db.QueryRowContext(ctx, claimIdempotencyKeyQuery, key.Owner, key.Key, key.Fingerprint, window.Seconds())
+	claimed, err := scanIdempotencyKey(row)
+	if err == nil {
+		return claimed, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	stored, err := GetIdempotencyKey(ctx, db, key.Owner, key.Key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return stored, false, nil
+}
+
+// SaveIdempotentResponse stores the response to the request key was claimed
+// for.
+func SaveIdempotentResponse(ctx context.Context, db DB, key models.IdempotencyKey) error {
+	headers, err := json.Marshal(key.ResponseHeaders)
+	if err != nil {
+		return err
+	}
+
+	return execOne(ctx, db, saveIdempotentResponseQuery, key.Owner, key.Key, key.ResponseStatus, string(headers), key.ResponseBody)
+}
+
+func DeleteIdempotencyKey(ctx context.Context, db DB, owner string, key string) error {
+	return execOne(ctx, db, deleteIdempotencyKeyQuery, owner, key)
+}
+
+func scanIdempotencyKey(row *sql.Row) (*models.IdempotencyKey, error) {
+	var (
+		key     models.IdempotencyKey
+		status  sql.NullInt64
+		headers sql.NullString
+	)
+
+	err := row.Scan(
+		&key.Owner,
+		&key.Key,
+		&key.Fingerprint,
+		&status,
+		&headers,
+		&key.ResponseBody,
+		&key.CreatedOn,
+		&key.ExpiresOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	key.ResponseStatus = int(status.Int64)
+	if headers.Valid {
+		err = json.Unmarshal([]byte(headers.String), &key.ResponseHeaders)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &key, nil
+}
+
+const getIdempotencyKeyQuery = `
+SELECT owner, idempotency_key, fingerprint, response_status, response_headers, response_body, created_on, expires_on
+FROM idempotency_keys
+WHERE owner = $1 AND idempotency_key = $2 AND expires_on > CURRENT_TIMESTAMP
+`
+
+const claimIdempotencyKeyQuery = `
+INSERT INTO idempotency_keys (owner, idempotency_key, fingerprint, expires_on)
+VALUES ($1, $2, $3, CURRENT_TIMESTAMP + $4::float8 * interval '1 second')
+ON CONFLICT (owner, idempotency_key) DO UPDATE
+SET fingerprint = EXCLUDED.fingerprint,
+    response_status = NULL,
+    response_headers = NULL,
+    response_body = NULL,
+    created_on = CURRENT_TIMESTAMP,
+    expires_on = EXCLUDED.expires_on
+WHERE idempotency_keys.expires_on <= CURRENT_TIMESTAMP
+RETURNING owner, idempotency_key, fingerprint, response_status, response_headers, response_body, created_on, expires_on
+`
+
+const saveIdempotentResponseQuery = `
+UPDATE idempotency_keys
+SET response_status = $3, response_headers = $4, response_body = $5
+WHERE owner = $1 AND idempotency_key = $2
+`
+
+const deleteIdempotencyKeyQuery = `
+DELETE
+FROM idempotency_keys
+WHERE owner = $1 AND idempotency_key = $2
+`
@@ -0,0 +1,88 @@
+package datastore_test
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("IdempotencyKey db interactions", func() {
+	var (
+		key = models.IdempotencyKey{Owner: "user:1", Key: "retry-me", Fingerprint: "abc"}
+	)
+
+	AfterEach(func() {
+		datastore.DeleteIdempotencyKey(ctx, db, key.Owner, key.Key)
+	})
+
+	Describe("ClaimIdempotencyKey", func() {
+		It("should claim a new key", func() {
+			claimed, ok, err := datastore.ClaimIdempotencyKey(ctx, db, key, time.Hour)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeTrue())
+			Expect(claimed.Fingerprint).To(Equal("abc"))
+			Expect(claimed.ResponseStatus).To(Equal(0))
+		})
+
+		It("should return the stored key if it's taken", func() {
+			datastore.ClaimIdempotencyKey(ctx, db, key, time.Hour)
+
+			other := key
+			other.Fingerprint = "def"
+			stored, ok, err := datastore.ClaimIdempotencyKey(ctx, db, other, time.Hour)
+			Expect(err).To(BeNil())
+			Expect(ok).To(BeFalse())
+			Expect(stored.Fingerprint).To(Equal("abc"))
+		})
+
+		It("should take over an expired key", func() {
+			datastore.ClaimIdempotencyKey(ctx, db, key, time.Millisecond)
+			time.Sleep(10 * time.Millisecond)
+
+			other := key
+			other.Fingerprint = "def"
+			claimed, ok, _ := datastore.ClaimIdempotencyKey(ctx, db, other, time.Hour)
+			Expect(ok).To(BeTrue())
+			Expect(claimed.Fingerprint).To(Equal("def"))
+		})
+
+		It("should keep keys of different owners apart", func() {
+			datastore.ClaimIdempotencyKey(ctx, db, key, time.Hour)
+
+			other := key
+			other.Owner = "user:2"
+			defer datastore.DeleteIdempotencyKey(ctx, db, other.Owner, other.Key)
+			_, ok, _ := datastore.ClaimIdempotencyKey(ctx, db, other, time.Hour)
+			Expect(ok).To(BeTrue())
+		})
+	})
+
+	Describe("SaveIdempotentResponse", func() {
+		It("should store the response", func() {
+			datastore.ClaimIdempotencyKey(ctx, db, key, time.Hour)
+
+			done := key
+			done.ResponseStatus = 201
+			done.ResponseHeaders = map[string][]string{"Content-Type": {"application/json"}}
+			done.ResponseBody = []byte(`{"visit_id": 1}`)
+			err := datastore.SaveIdempotentResponse(ctx, db, done)
+			Expect(err).To(BeNil())
+
+			stored, _ := datastore.GetIdempotencyKey(ctx, db, key.Owner, key.Key)
+			Expect(stored.ResponseStatus).To(Equal(201))
+			Expect(stored.ResponseHeaders).To(Equal(done.ResponseHeaders))
+			Expect(stored.ResponseBody).To(Equal(done.ResponseBody))
+		})
+	})
+
+	Describe("DeleteIdempotencyKey", func() {
+		It("should return sql.ErrNoRows for a missing key", func() {
+			err := datastore.DeleteIdempotencyKey(ctx, db, key.Owner, key.Key)
+			Expect(err).To(Equal(sql.ErrNoRows))
+		})
+	})
+})
@@ -1,20 +1,21 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetImageList(where string) ([]models.Image, error) {
+func GetImageList(ctx context.Context, db DB, q Query) ([]models.Image, error) {
 	var (
 		images []models.Image
 		image  models.Image
 	)
 
-	query := fmt.Sprintf("%s %s", getImageListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getImageListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -28,14 +29,15 @@ func GetImageList(where string) ([]models.Image, error) {
 	}
 	defer rows.Close()
 
-	return images, nil
+	return images, rows.Err()
 }
 
-func GetImageCount(where string) (*int, error) {
+func GetImageCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getImageCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getImageCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 	if err != nil {
 		return nil, err
@@ -44,10 +46,10 @@ func GetImageCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetImage(imageID int64) (*models.Image, error) {
+func GetImage(ctx context.Context, db DB, imageID int64) (*models.Image, error) {
 	var image models.Image
 
-	row := store.DB.QueryRow(getImageQuery, imageID)
+	row := db.QueryRowContext(ctx, getImageQuery, imageID)
 	err := row.Scan(&image.ImageID, &image.GymID, &image.GymLocationID, &image.UserID, &image.ImagePath)
 	if err != nil {
 		return nil, err
@@ -56,10 +58,10 @@ func GetImage(imageID int64) (*models.Image, error) {
 	return &image, nil
 }
 
-func CreateImage(image models.Image) (*models.Image, error) {
+func CreateImage(ctx context.Context, db DB, image models.Image) (*models.Image, error) {
 	var created models.Image
 
-	row := store.DB.QueryRow(createImageQuery, image.GymID, image.GymLocationID, image.UserID, image.ImagePath)
+	row := db.QueryRowContext(ctx, createImageQuery, image.GymID, image.GymLocationID, image.UserID, image.ImagePath)
 	err := row.Scan(&created.ImageID, &created.GymID, &created.GymLocationID, &created.UserID, &created.ImagePath)
 	if err != nil {
 		return nil, err
@@ -68,10 +70,10 @@ func CreateImage(image models.Image) (*models.Image, error) {
 	return &created, nil
 }
 
-func UpdateImage(imageID int64, image models.Image) (*models.Image, error) {
+func UpdateImage(ctx context.Context, db DB, imageID int64, image models.Image) (*models.Image, error) {
 	var updated models.Image
 
-	row := store.DB.QueryRow(updateImageQuery, image.GymID, image.GymLocationID, image.UserID, image.ImagePath, imageID)
+	row := db.QueryRowContext(ctx, updateImageQuery, image.GymID, image.GymLocationID, image.UserID, image.ImagePath, imageID)
 	err := row.Scan(&updated.ImageID, &updated.GymID, &updated.GymLocationID, &updated.UserID, &updated.ImagePath)
 	if err != nil {
 		return nil, err
@@ -80,18 +82,8 @@ func UpdateImage(imageID int64, image models.Image) (*models.Image, error) {
 	return &updated, nil
 }
 
-func DeleteImage(imageID int64) error {
-	stmt, err := store.DB.Prepare(deleteImageQuery)
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(imageID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func DeleteImage(ctx context.Context, db DB, imageID int64) error {
+	return execOne(ctx, db, deleteImageQuery, imageID)
 }
 
 const getImageListQuery = `
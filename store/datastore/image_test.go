@@ -14,17 +14,17 @@ var _ = Describe("Image db interactions", func() {
 	)
 
 	BeforeEach(func() {
-		one, _ = datastore.CreateImage(models.Image{GymID: &gymID, ImagePath: "/tst/img/path"})
-		two, _ = datastore.CreateImage(models.Image{GymID: &gymID, ImagePath: "/tst/img/path/two"})
-		three, _ = datastore.CreateImage(models.Image{GymID: &gymID, ImagePath: "/tst/img/path/three"})
-		four, _ = datastore.CreateImage(models.Image{GymID: &gymID, ImagePath: "/tst/img/path/four"})
+		one, _ = datastore.CreateImage(ctx, db, models.Image{GymID: &gymID, ImagePath: "/tst/img/path"})
+		two, _ = datastore.CreateImage(ctx, db, models.Image{GymID: &gymID, ImagePath: "/tst/img/path/two"})
+		three, _ = datastore.CreateImage(ctx, db, models.Image{GymID: &gymID, ImagePath: "/tst/img/path/three"})
+		four, _ = datastore.CreateImage(ctx, db, models.Image{GymID: &gymID, ImagePath: "/tst/img/path/four"})
 	})
 
 	AfterEach(func() {
-		datastore.DeleteImage(one.ImageID)
-		datastore.DeleteImage(two.ImageID)
-		datastore.DeleteImage(three.ImageID)
-		datastore.DeleteImage(four.ImageID)
+		datastore.DeleteImage(ctx, db, one.ImageID)
+		datastore.DeleteImage(ctx, db, two.ImageID)
+		datastore.DeleteImage(ctx, db, three.ImageID)
+		datastore.DeleteImage(ctx, db, four.ImageID)
 	})
 
 	Describe("GetImageList", func() {
@@ -32,7 +32,7 @@ var _ = Describe("Image db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				images, _ = datastore.GetImageList("")
+				images, _ = datastore.GetImageList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of images", func() {
@@ -46,7 +46,7 @@ var _ = Describe("Image db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct image", func() {
-				image, _ = datastore.GetImage(one.ImageID)
+				image, _ = datastore.GetImage(ctx, db, one.ImageID)
 				Expect(image.ImageID).To(Equal(one.ImageID))
 			})
 		})
@@ -58,7 +58,7 @@ var _ = Describe("Image db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				image, err = datastore.GetImage(nonExistentID)
+				image, err = datastore.GetImage(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -76,7 +76,7 @@ var _ = Describe("Image db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetImageCount("")
+				count, _ = datastore.GetImageCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -95,11 +95,11 @@ var _ = Describe("Image db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				image = models.Image{GymID: &gymID, ImagePath: imagePath}
-				created, _ = datastore.CreateImage(image)
+				created, _ = datastore.CreateImage(ctx, db, image)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteImage(created.ImageID)
+				datastore.DeleteImage(ctx, db, created.ImageID)
 			})
 
 			It("should return the created image", func() {
@@ -107,7 +107,7 @@ var _ = Describe("Image db interactions", func() {
 			})
 
 			It("should add a image to the db", func() {
-				newImage, _ := datastore.GetImage(created.ImageID)
+				newImage, _ := datastore.GetImage(ctx, db, created.ImageID)
 				Expect(newImage.ImagePath).To(Equal(imagePath))
 			})
 		})
@@ -119,14 +119,14 @@ var _ = Describe("Image db interactions", func() {
 			)
 
 			AfterEach(func() {
-				datastore.DeleteImage(created.ImageID)
+				datastore.DeleteImage(ctx, db, created.ImageID)
 			})
 
 			It("should return an error object if user_id is not unique", func() {
 				var userID int64 = int64(1)
 				img := models.Image{UserID: &userID, ImagePath: "/tst/path"}
-				created, _ = datastore.CreateImage(img)
-				_, err = datastore.CreateImage(img)
+				created, _ = datastore.CreateImage(ctx, db, img)
+				_, err = datastore.CreateImage(ctx, db, img)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -144,12 +144,12 @@ var _ = Describe("Image db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				image = models.Image{GymID: &gymID, ImagePath: imagePath}
-				created, _ = datastore.CreateImage(models.Image{GymID: &gymID, ImagePath: "/old/path"})
-				updated, _ = datastore.UpdateImage(created.ImageID, image)
+				created, _ = datastore.CreateImage(ctx, db, models.Image{GymID: &gymID, ImagePath: "/old/path"})
+				updated, _ = datastore.UpdateImage(ctx, db, created.ImageID, image)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteImage(updated.ImageID)
+				datastore.DeleteImage(ctx, db, updated.ImageID)
 			})
 
 			It("should return the updated image", func() {
@@ -160,7 +160,7 @@ var _ = Describe("Image db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				image = models.Image{ImagePath: "Daily"}
-				updated, err = datastore.UpdateImage(10000, image)
+				updated, err = datastore.UpdateImage(ctx, db, 10000, image)
 			})
 
 			It("should return an error object if image to update doesn't exist", func() {
@@ -176,7 +176,7 @@ var _ = Describe("Image db interactions", func() {
 	Describe("DeleteImage", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				err := datastore.DeleteImage(one.ImageID)
+				err := datastore.DeleteImage(ctx, db, one.ImageID)
 				Expect(err).To(BeNil())
 			})
 		})
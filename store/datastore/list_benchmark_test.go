@@ -0,0 +1,201 @@
+package datastore_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lib/pq"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+// queryCount is the number of statements sent through the counting driver.
+var queryCount int64
+
+func init() {
+	sql.Register("postgres-counting", countingDriver{&pq.Driver{}})
+}
+
+type countingDriver struct {
+	driver.Driver
+}
+
+func (d countingDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return countingConn{conn}, nil
+}
+
+type countingConn struct {
+	driver.Conn
+}
+
+func (c countingConn) Prepare(query string) (driver.Stmt, error) {
+	atomic.AddInt64(&queryCount, 1)
+	return c.Conn.Prepare(query)
+}
+
+func (c countingConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+	atomic.AddInt64(&queryCount, 1)
+	return c.Conn.(driver.Queryer).Query(query, args)
+}
+
+func (c countingConn) Exec(query string, args []driver.Value) (driver.Result, error) {
+	atomic.AddInt64(&queryCount, 1)
+	return c.Conn.(driver.Execer).Exec(query, args)
+}
+
+// countingDB opens a connection to the test database that counts the
+// statements sent through it.
+func countingDB(b *testing.B) *sql.DB {
+	db, err := sql.Open("postgres-counting", store.DataSourceName())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return db
+}
+
+// benchmarkQueries runs list and fails if a call sends any other number of
+// statements than queries.
+func benchmarkQueries(b *testing.B, queries int64, list func() error) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		atomic.StoreInt64(&queryCount, 0)
+		err := list()
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if count := atomic.LoadInt64(&queryCount); count != queries {
+			b.Fatalf("%d queries per call, want %d", count, queries)
+		}
+	}
+}
+
+// listSizes are the numbers of extra rows each list is benchmarked with. The
+// query count has to be the same for all of them.
+var listSizes = []int{10, 100}
+
+func createMembers(b *testing.B, db *sql.DB, n int) []int64 {
+	role, err := datastore.GetRoleByName(ctx, db, models.MemberRole)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	var userIDs []int64
+	for i := 0; i < n; i++ {
+		user, err := datastore.CreateUser(ctx, db, models.User{Email: fmt.Sprintf("benchmark%d@example.com", i)})
+		if err != nil {
+			b.Fatal(err)
+		}
+		userIDs = append(userIDs, user.UserID)
+
+		_, err = datastore.CreateUserRole(ctx, db, models.UserRole{UserID: user.UserID, RoleID: role.RoleID})
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		_, err = datastore.CreateMember(ctx, db, models.Member{UserID: user.UserID, FirstName: "Benchmark"})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	return userIDs
+}
+
+func deleteUsers(db *sql.DB, userIDs []int64) {
+	for _, userID := range userIDs {
+		datastore.DeleteUser(ctx, db, userID, 0)
+	}
+}
+
+func BenchmarkGetMemberList(b *testing.B) {
+	db := countingDB(b)
+	defer db.Close()
+	for _, size := range listSizes {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			userIDs := createMembers(b, db, size)
+			defer deleteUsers(db, userIDs)
+
+			// members, their users and the users' roles
+			benchmarkQueries(b, 3, func() error {
+				_, err := datastore.GetMemberList(ctx, db, datastore.Unscoped, datastore.Query{})
+				return err
+			})
+		})
+	}
+}
+
+func BenchmarkGetUserList(b *testing.B) {
+	db := countingDB(b)
+	defer db.Close()
+	for _, size := range listSizes {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			userIDs := createMembers(b, db, size)
+			defer deleteUsers(db, userIDs)
+
+			// users and their roles
+			benchmarkQueries(b, 2, func() error {
+				_, err := datastore.GetUserList(ctx, db, datastore.Unscoped, datastore.Query{})
+				return err
+			})
+		})
+	}
+}
+
+func BenchmarkGetGymLocationList(b *testing.B) {
+	var mondayID int64 = 1
+
+	db := countingDB(b)
+	defer db.Close()
+
+	for _, size := range listSizes {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			var addressIDs []int64
+			defer func() {
+				for _, addressID := range addressIDs {
+					datastore.DeleteAddress(ctx, db, addressID)
+				}
+			}()
+
+			for i := 0; i < size; i++ {
+				address, err := datastore.CreateAddress(ctx, db, models.Address{StreetAddress: fmt.Sprintf("Benchmark %d", i)})
+				if err != nil {
+					b.Fatal(err)
+				}
+				addressIDs = append(addressIDs, address.AddressID)
+
+				gymLocation, err := datastore.CreateGymLocation(ctx, db, models.GymLocation{
+					GymID:        1,
+					AddressID:    address.AddressID,
+					LocationName: "Benchmark",
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+				defer purge("gym_locations", "gym_location_id", gymLocation.GymLocationID)
+
+				_, err = datastore.CreateBusinessHour(ctx, db, models.BusinessHour{GymLocationID: gymLocation.GymLocationID, DayID: &mondayID})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			// locations with their addresses and their business hours
+			benchmarkQueries(b, 2, func() error {
+				_, err := datastore.GetGymLocationList(ctx, db, datastore.Unscoped, datastore.Query{})
+				return err
+			})
+		})
+	}
+}
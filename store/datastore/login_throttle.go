@@ -0,0 +1,100 @@
+package datastore
+
+import (
+	"context"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+func GetLoginThrottle(ctx context.Context, db DB, throttleKey string) (*models.LoginThrottle, error) {
+	var throttle models.LoginThrottle
+
+	row := db.QueryRowContext(ctx, getLoginThrottleQuery, throttleKey)
+	err := row.Scan(
+		&throttle.ThrottleKey,
+		&throttle.FailedAttempts,
+		&throttle.LockedUntil,
+		&throttle.UpdatedOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &throttle, nil
+}
+
+// RecordLoginFailure adds a failed attempt to throttleKey. The count starts
+// over if the last failure was longer than window ago.
+func RecordLoginFailure(ctx context.Context, db DB, throttleKey string, window time.Duration) (*models.LoginThrottle, error) {
+	var throttle models.LoginThrottle
+
+	row := db.QueryRowContext(ctx, recordLoginFailureQuery, throttleKey, window.Seconds())
+	err := row.Scan(
+		&throttle.ThrottleKey,
+		&throttle.FailedAttempts,
+		&throttle.LockedUntil,
+		&throttle.UpdatedOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &throttle, nil
+}
+
+// LockLogin locks throttleKey for lockFor. An existing lock that runs longer
+// is kept.
+func LockLogin(ctx context.Context, db DB, throttleKey string, lockFor time.Duration) (*models.LoginThrottle, error) {
+	var throttle models.LoginThrottle
+
+	row := db.QueryRowContext(ctx, lockLoginQuery, throttleKey, lockFor.Seconds())
+	err := row.Scan(
+		&throttle.ThrottleKey,
+		&throttle.FailedAttempts,
+		&throttle.LockedUntil,
+		&throttle.UpdatedOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &throttle, nil
+}
+
+func DeleteLoginThrottle(ctx context.Context, db DB, throttleKey string) error {
+	_, err := db.ExecContext(ctx, deleteLoginThrottleQuery, throttleKey)
+	return err
+}
+
+const getLoginThrottleQuery = `
+SELECT throttle_key, failed_attempts, locked_until, updated_on
+FROM login_throttles
+WHERE throttle_key = $1
+`
+
+const recordLoginFailureQuery = `
+INSERT INTO login_throttles (throttle_key, failed_attempts, updated_on)
+VALUES ($1, 1, CURRENT_TIMESTAMP)
+ON CONFLICT (throttle_key) DO UPDATE
+SET failed_attempts = CASE
+      WHEN login_throttles.updated_on > CURRENT_TIMESTAMP - $2::float8 * interval '1 second'
+      THEN login_throttles.failed_attempts + 1
+      ELSE 1
+    END,
+    updated_on = CURRENT_TIMESTAMP
+RETURNING throttle_key, failed_attempts, locked_until, updated_on
+`
+
+const lockLoginQuery = `
+UPDATE login_throttles
+SET locked_until = GREATEST(locked_until, CURRENT_TIMESTAMP + $2::float8 * interval '1 second')
+WHERE throttle_key = $1
+RETURNING throttle_key, failed_attempts, locked_until, updated_on
+`
+
+const deleteLoginThrottleQuery = `
+DELETE
+FROM login_throttles
+WHERE throttle_key = $1
+`
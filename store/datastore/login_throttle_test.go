@@ -0,0 +1,89 @@
+package datastore_test
+
+import (
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LoginThrottle db interactions", func() {
+	var (
+		throttleKey string = "email:throttled@email.com"
+		throttle    *models.LoginThrottle
+	)
+
+	BeforeEach(func() {
+		throttle, _ = datastore.RecordLoginFailure(ctx, db, throttleKey, time.Hour)
+	})
+
+	AfterEach(func() {
+		datastore.DeleteLoginThrottle(ctx, db, throttleKey)
+	})
+
+	Describe("RecordLoginFailure", func() {
+		Describe("Successful call", func() {
+			It("should start counting at one", func() {
+				Expect(throttle.FailedAttempts).To(Equal(1))
+				Expect(throttle.LockedUntil).To(BeNil())
+			})
+
+			It("should add to the count within the window", func() {
+				throttle, _ = datastore.RecordLoginFailure(ctx, db, throttleKey, time.Hour)
+				Expect(throttle.FailedAttempts).To(Equal(2))
+			})
+
+			It("should start over outside the window", func() {
+				time.Sleep(10 * time.Millisecond)
+				throttle, _ = datastore.RecordLoginFailure(ctx, db, throttleKey, time.Millisecond)
+				Expect(throttle.FailedAttempts).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("LockLogin", func() {
+		Describe("Successful call", func() {
+			It("should set locked_until", func() {
+				throttle, _ = datastore.LockLogin(ctx, db, throttleKey, time.Minute)
+				Expect(throttle.LockedUntil).ToNot(BeNil())
+				Expect(throttle.LockedUntil.After(time.Now())).To(BeTrue())
+			})
+
+			It("should keep a longer existing lock", func() {
+				locked, _ := datastore.LockLogin(ctx, db, throttleKey, time.Hour)
+				throttle, _ = datastore.LockLogin(ctx, db, throttleKey, time.Minute)
+				Expect(throttle.LockedUntil.Equal(*locked.LockedUntil)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("GetLoginThrottle", func() {
+		Describe("Successful call", func() {
+			It("should return the throttle", func() {
+				found, _ := datastore.GetLoginThrottle(ctx, db, throttleKey)
+				Expect(found.FailedAttempts).To(Equal(1))
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return an error for an unknown key", func() {
+				_, err := datastore.GetLoginThrottle(ctx, db, "email:unknown@email.com")
+				Expect(err).ToNot(BeNil())
+			})
+		})
+	})
+
+	Describe("DeleteLoginThrottle", func() {
+		Describe("Successful call", func() {
+			It("should remove the throttle", func() {
+				err := datastore.DeleteLoginThrottle(ctx, db, throttleKey)
+				Expect(err).To(BeNil())
+
+				_, err = datastore.GetLoginThrottle(ctx, db, throttleKey)
+				Expect(err).ToNot(BeNil())
+			})
+		})
+	})
+})
@@ -1,25 +1,29 @@
 package datastore
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetMemberList(where string) ([]models.Member, error) {
+func GetMemberList(ctx context.Context, db DB, scope Scope, q Query) ([]models.Member, error) {
 	var (
 		members []models.Member
 		member  models.Member
 	)
 
-	query := fmt.Sprintf("%s %s", getMemberListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getMemberListQuery, b.list(q, scope.members(b)))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
 
+	defer rows.Close()
+
+	var userIDs []int64
 	for rows.Next() {
 		err = rows.Scan(
 			&member.MemberID,
@@ -28,28 +32,44 @@ func GetMemberList(where string) ([]models.Member, error) {
 			&member.AddressID,
 			&member.FirstName,
 			&member.LastName,
+			&member.Version,
+			&member.DeletedOn,
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		member.User, err = GetUser(member.UserID)
-		if err != nil {
-			return nil, err
-		}
-
 		members = append(members, member)
+		userIDs = append(userIDs, member.UserID)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(members) == 0 {
+		return members, nil
+	}
+
+	users, err := getUsersByID(ctx, db, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range members {
+		members[i].User = users[members[i].UserID]
 	}
-	defer rows.Close()
 
 	return members, nil
 }
 
-func GetMemberCount(where string) (*int, error) {
+func GetMemberCount(ctx context.Context, db DB, scope Scope, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getMemberCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getMemberCountQuery, b.where(q, scope.members(b)))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 
 	if err != nil {
@@ -59,15 +79,16 @@ func GetMemberCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetMember(memberID int64) (*models.Member, error) {
-	row := store.DB.QueryRow(getMemberQuery, memberID)
+func GetMember(ctx context.Context, db DB, scope Scope, memberID int64) (*models.Member, error) {
+	b := &builder{args: []interface{}{memberID}}
+	row := db.QueryRowContext(ctx, fmt.Sprintf("%s AND %s", getMemberQuery, scope.members(b)), b.args...)
 	member, err := ScanMember(row)
 
 	if err != nil {
 		return nil, err
 	}
 
-	member.User, err = GetUser(member.UserID)
+	member.User, err = GetUser(ctx, db, member.UserID)
 
 	if err != nil {
 		return nil, err
@@ -76,15 +97,27 @@ func GetMember(memberID int64) (*models.Member, error) {
 	return &member, nil
 }
 
-func GetMemberByEmail(email string) (*models.Member, error) {
-	row := store.DB.QueryRow(getMemberByEmailQuery, email)
+func GetMemberByEmail(ctx context.Context, db DB, scope Scope, email string) (*models.Member, error) {
+	b := &builder{args: []interface{}{email}}
+	row := db.QueryRowContext(ctx, fmt.Sprintf("%s AND %s", getMemberByEmailQuery, scope.members(b)), b.args...)
 	member, err := ScanMember(row)
 
 	if err != nil {
 		return nil, err
 	}
 
-	member.User, err = GetUser(member.UserID)
+	member.User, err = GetUser(ctx, db, member.UserID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+func GetMemberByUserID(ctx context.Context, db DB, userID int64) (*models.Member, error) {
+	row := db.QueryRowContext(ctx, getMemberByUserIDQuery, userID)
+	member, err := ScanMember(row)
 
 	if err != nil {
 		return nil, err
@@ -103,15 +136,18 @@ func ScanMember(row *sql.Row) (models.Member, error) {
 		&member.AddressID,
 		&member.FirstName,
 		&member.LastName,
+		&member.Version,
+		&member.DeletedOn,
 	)
 
 	return member, err
 }
 
-func CreateMember(member models.Member) (*models.Member, error) {
+func CreateMember(ctx context.Context, db DB, member models.Member) (*models.Member, error) {
 	var created models.Member
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		createMemberQuery,
 		member.UserID,
 		member.ImageID,
@@ -126,6 +162,8 @@ func CreateMember(member models.Member) (*models.Member, error) {
 		&created.AddressID,
 		&created.FirstName,
 		&created.LastName,
+		&created.Version,
+		&created.DeletedOn,
 	)
 	if err != nil {
 		return nil, err
@@ -134,10 +172,13 @@ func CreateMember(member models.Member) (*models.Member, error) {
 	return &created, nil
 }
 
-func UpdateMember(memberID int64, member models.Member) (*models.Member, error) {
+// UpdateMember updates the member if it's at member.Version, or whatever
+// version it's at if that's 0.
+func UpdateMember(ctx context.Context, db DB, memberID int64, member models.Member) (*models.Member, error) {
 	var updated models.Member
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		updateMemberQuery,
 		member.UserID,
 		member.ImageID,
@@ -145,6 +186,7 @@ func UpdateMember(memberID int64, member models.Member) (*models.Member, error)
 		member.FirstName,
 		member.LastName,
 		memberID,
+		member.Version,
 	)
 	err := row.Scan(
 		&updated.MemberID,
@@ -153,7 +195,12 @@ func UpdateMember(memberID int64, member models.Member) (*models.Member, error)
 		&updated.AddressID,
 		&updated.FirstName,
 		&updated.LastName,
+		&updated.Version,
+		&updated.DeletedOn,
 	)
+	if err == sql.ErrNoRows {
+		return nil, checkVersion(ctx, db, "members", "member_id", memberID, member.Version)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -161,18 +208,53 @@ func UpdateMember(memberID int64, member models.Member) (*models.Member, error)
 	return &updated, nil
 }
 
-func DeleteMember(memberID int64) error {
-	stmt, err := store.DB.Prepare(deleteMemberQuery)
+// PatchMember only updates the fields of member that are listed in fields.
+func PatchMember(ctx context.Context, db DB, memberID int64, member models.Member, fields []string) (*models.Member, error) {
+	b := &builder{}
+	set, err := b.set(map[string]interface{}{
+		"user_id":    member.UserID,
+		"image_id":   member.ImageID,
+		"address_id": member.AddressID,
+		"first_name": member.FirstName,
+		"last_name":  member.LastName,
+	}, fields)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = stmt.Exec(memberID)
+	query := fmt.Sprintf(patchMemberQuery, set, b.arg(memberID), b.arg(member.Version))
+	patched, err := ScanMember(db.QueryRowContext(ctx, query, b.args...))
+	if err == sql.ErrNoRows {
+		return nil, checkVersion(ctx, db, "members", "member_id", memberID, member.Version)
+	}
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return &patched, nil
+}
+
+// DeleteMember marks the member deleted if it's at version, or whatever
+// version it's at if that's 0. Their visits and memberships stay.
+func DeleteMember(ctx context.Context, db DB, memberID int64, version int64) error {
+	err := execOne(ctx, db, deleteMemberQuery, memberID, version)
+	if err == sql.ErrNoRows {
+		return checkVersion(ctx, db, "members", "member_id", memberID, version)
 	}
 
-	return nil
+	return err
+}
+
+// RestoreMember undoes DeleteMember. Restoring a member that isn't deleted
+// leaves it as it is.
+func RestoreMember(ctx context.Context, db DB, memberID int64) (*models.Member, error) {
+	row := db.QueryRowContext(ctx, restoreMemberQuery, memberID)
+	member, err := ScanMember(row)
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
 }
 
 const getMemberListQuery = `
@@ -181,11 +263,15 @@ FROM members
 `
 
 const getMemberByEmailQuery = `
-SELECT m.*
-FROM members m
-JOIN users u
-ON m.user_id = u.user_id
-WHERE u.email = $1
+SELECT *
+FROM members
+WHERE user_id = (SELECT user_id FROM users WHERE email = $1)
+`
+
+const getMemberByUserIDQuery = `
+SELECT *
+FROM members
+WHERE user_id = $1 AND deleted_on IS NULL
 `
 
 const getMemberQuery = `
@@ -197,20 +283,34 @@ WHERE member_id = $1
 const createMemberQuery = `
 INSERT INTO members (user_id, image_id, address_id, first_name, last_name)
 VALUES ($1, $2, $3, $4, $5)
-RETURNING member_id, user_id, image_id, address_id, first_name, last_name
+RETURNING member_id, user_id, image_id, address_id, first_name, last_name, version, deleted_on
 `
 
 const updateMemberQuery = `
 UPDATE members
-SET user_id = $1, image_id = $2, address_id = $3, first_name = $4, last_name = $5
-WHERE member_id = $6
-RETURNING member_id, user_id, image_id, address_id, first_name, last_name
+SET user_id = $1, image_id = $2, address_id = $3, first_name = $4, last_name = $5, version = version + 1
+WHERE member_id = $6 AND deleted_on IS NULL AND ($7 = 0 OR version = $7)
+RETURNING member_id, user_id, image_id, address_id, first_name, last_name, version, deleted_on
+`
+
+const patchMemberQuery = `
+UPDATE members
+SET %s
+WHERE member_id = %s AND deleted_on IS NULL AND (%[3]s = 0 OR version = %[3]s)
+RETURNING member_id, user_id, image_id, address_id, first_name, last_name, version, deleted_on
 `
 
 const deleteMemberQuery = `
-DELETE
-FROM members
+UPDATE members
+SET deleted_on = CURRENT_TIMESTAMP, version = version + 1
+WHERE member_id = $1 AND deleted_on IS NULL AND ($2 = 0 OR version = $2)
+`
+
+const restoreMemberQuery = `
+UPDATE members
+SET deleted_on = NULL, version = version + CASE WHEN deleted_on IS NULL THEN 0 ELSE 1 END
 WHERE member_id = $1
+RETURNING member_id, user_id, image_id, address_id, first_name, last_name, version, deleted_on
 `
 
 const getMemberCountQuery = `
@@ -1,6 +1,8 @@
 package datastore_test
 
 import (
+	"database/sql"
+
 	"github.com/lukashambsch/anygym.api/models"
 	"github.com/lukashambsch/anygym.api/store/datastore"
 	. "github.com/onsi/ginkgo"
@@ -15,13 +17,13 @@ var _ = Describe("Member db interactions", func() {
 	)
 
 	BeforeEach(func() {
-		user, _ = datastore.CreateUser(models.User{Email: "testemail@gmail.com"})
-		member, _ = datastore.CreateMember(models.Member{FirstName: "Test First", UserID: user.UserID})
+		user, _ = datastore.CreateUser(ctx, db, models.User{Email: "testemail@gmail.com"})
+		member, _ = datastore.CreateMember(ctx, db, models.Member{FirstName: "Test First", UserID: user.UserID})
 	})
 
 	AfterEach(func() {
-		datastore.DeleteMember(member.MemberID)
-		datastore.DeleteUser(user.UserID)
+		datastore.DeleteMember(ctx, db, member.MemberID, 0)
+		datastore.DeleteUser(ctx, db, user.UserID, 0)
 	})
 
 	Describe("GetMemberList", func() {
@@ -29,24 +31,30 @@ var _ = Describe("Member db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				members, _ = datastore.GetMemberList("")
+				members, _ = datastore.GetMemberList(ctx, db, datastore.Unscoped, datastore.Query{})
 			})
 
 			It("should return a list of members", func() {
 				Expect(len(members)).To(Equal(3))
 			})
+
+			It("should load each member's user", func() {
+				for _, member := range members {
+					Expect(member.User.UserID).To(Equal(member.UserID))
+				}
+			})
 		})
 	})
 
 	Describe("GetMember", func() {
 		Describe("Successful call", func() {
 			It("should return the correct member", func() {
-				mbr, _ := datastore.GetMember(memberID)
+				mbr, _ := datastore.GetMember(ctx, db, datastore.Unscoped, memberID)
 				Expect(mbr.MemberID).To(Equal(memberID))
 			})
 
 			It("should return the correct user", func() {
-				mbr, _ := datastore.GetMember(memberID)
+				mbr, _ := datastore.GetMember(ctx, db, datastore.Unscoped, memberID)
 				Expect(mbr.User.UserID).To(Equal(mbr.UserID))
 			})
 		})
@@ -59,7 +67,7 @@ var _ = Describe("Member db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				mbr, err = datastore.GetMember(nonExistentID)
+				mbr, err = datastore.GetMember(ctx, db, datastore.Unscoped, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -75,7 +83,7 @@ var _ = Describe("Member db interactions", func() {
 	Describe("GetMemberByEmail", func() {
 		Describe("Successful call", func() {
 			It("should return the correct member", func() {
-				mbr, _ := datastore.GetMemberByEmail(user.Email)
+				mbr, _ := datastore.GetMemberByEmail(ctx, db, datastore.Unscoped, user.Email)
 				Expect(mbr.MemberID).To(Equal(member.MemberID))
 			})
 		})
@@ -88,7 +96,36 @@ var _ = Describe("Member db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				mbr, err = datastore.GetMemberByEmail(nonExistentEmail)
+				mbr, err = datastore.GetMemberByEmail(ctx, db, datastore.Unscoped, nonExistentEmail)
+			})
+
+			It("should return an error", func() {
+				Expect(err).ToNot(BeNil())
+			})
+
+			It("should return a nil member", func() {
+				Expect(mbr).To(BeNil())
+			})
+		})
+	})
+
+	Describe("GetMemberByUserID", func() {
+		Describe("Successful call", func() {
+			It("should return the correct member", func() {
+				mbr, _ := datastore.GetMemberByUserID(ctx, db, user.UserID)
+				Expect(mbr.MemberID).To(Equal(member.MemberID))
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			var (
+				nonExistentID int64 = 99999
+				err           error
+				mbr           *models.Member
+			)
+
+			BeforeEach(func() {
+				mbr, err = datastore.GetMemberByUserID(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -106,7 +143,7 @@ var _ = Describe("Member db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetMemberCount("")
+				count, _ = datastore.GetMemberCount(ctx, db, datastore.Unscoped, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -124,14 +161,14 @@ var _ = Describe("Member db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				usr, _ = datastore.CreateUser(models.User{Email: "Another Test"})
+				usr, _ = datastore.CreateUser(ctx, db, models.User{Email: "Another Test"})
 				mbr := models.Member{UserID: usr.UserID, FirstName: firstName}
-				created, _ = datastore.CreateMember(mbr)
+				created, _ = datastore.CreateMember(ctx, db, mbr)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteMember(created.MemberID)
-				datastore.DeleteUser(usr.UserID)
+				datastore.DeleteMember(ctx, db, created.MemberID, 0)
+				datastore.DeleteUser(ctx, db, usr.UserID, 0)
 			})
 
 			It("should return the created member", func() {
@@ -139,7 +176,7 @@ var _ = Describe("Member db interactions", func() {
 			})
 
 			It("should add a member to the db", func() {
-				newMember, _ := datastore.GetMember(created.MemberID)
+				newMember, _ := datastore.GetMember(ctx, db, datastore.Unscoped, created.MemberID)
 				Expect(newMember.FirstName).To(Equal(firstName))
 			})
 		})
@@ -147,7 +184,7 @@ var _ = Describe("Member db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			It("should return an error object if no names", func() {
 				mbr := models.Member{}
-				_, err := datastore.CreateMember(mbr)
+				_, err := datastore.CreateMember(ctx, db, mbr)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -165,15 +202,15 @@ var _ = Describe("Member db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				usr, _ = datastore.CreateUser(models.User{Email: "Email"})
-				created, _ = datastore.CreateMember(models.Member{FirstName: "First Name", UserID: usr.UserID})
+				usr, _ = datastore.CreateUser(ctx, db, models.User{Email: "Email"})
+				created, _ = datastore.CreateMember(ctx, db, models.Member{FirstName: "First Name", UserID: usr.UserID})
 				created.FirstName = firstName
-				updated, err = datastore.UpdateMember(created.MemberID, *created)
+				updated, err = datastore.UpdateMember(ctx, db, created.MemberID, *created)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteMember(updated.MemberID)
-				datastore.DeleteUser(usr.UserID)
+				datastore.DeleteMember(ctx, db, updated.MemberID, 0)
+				datastore.DeleteUser(ctx, db, usr.UserID, 0)
 			})
 
 			It("should return the updated member", func() {
@@ -184,7 +221,7 @@ var _ = Describe("Member db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				mbr = models.Member{FirstName: "First Name"}
-				updated, err = datastore.UpdateMember(2, mbr)
+				updated, err = datastore.UpdateMember(ctx, db, 2, mbr)
 			})
 
 			It("should return an error object if member to update doesn't exist", func() {
@@ -200,9 +237,27 @@ var _ = Describe("Member db interactions", func() {
 	Describe("DeleteMember", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				err := datastore.DeleteMember(member.MemberID)
+				err := datastore.DeleteMember(ctx, db, member.MemberID, 0)
 				Expect(err).To(BeNil())
 			})
+
+			It("should hide the member", func() {
+				datastore.DeleteMember(ctx, db, member.MemberID, 0)
+				_, err := datastore.GetMember(ctx, db, datastore.Unscoped, member.MemberID)
+				Expect(err).To(Equal(sql.ErrNoRows))
+			})
+		})
+	})
+
+	Describe("RestoreMember", func() {
+		It("should bring a deleted member back", func() {
+			datastore.DeleteMember(ctx, db, member.MemberID, 0)
+			restored, err := datastore.RestoreMember(ctx, db, member.MemberID)
+			Expect(err).To(BeNil())
+			Expect(restored.DeletedOn).To(BeNil())
+
+			_, err = datastore.GetMember(ctx, db, datastore.Unscoped, member.MemberID)
+			Expect(err).To(BeNil())
 		})
 	})
 })
@@ -1,20 +1,21 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetMembershipList(where string) ([]models.Membership, error) {
+func GetMembershipList(ctx context.Context, db DB, q Query) ([]models.Membership, error) {
 	var (
 		memberships []models.Membership
 		membership  models.Membership
 	)
 
-	query := fmt.Sprintf("%s %s", getMembershipListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getMembershipListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -36,14 +37,15 @@ func GetMembershipList(where string) ([]models.Membership, error) {
 	}
 	defer rows.Close()
 
-	return memberships, nil
+	return memberships, rows.Err()
 }
 
-func GetMembershipCount(where string) (*int, error) {
+func GetMembershipCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getMembershipCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getMembershipCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 
 	if err != nil {
@@ -53,10 +55,10 @@ func GetMembershipCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetMembership(membershipID int64) (*models.Membership, error) {
+func GetMembership(ctx context.Context, db DB, membershipID int64) (*models.Membership, error) {
 	var membership models.Membership
 
-	row := store.DB.QueryRow(getMembershipQuery, membershipID)
+	row := db.QueryRowContext(ctx, getMembershipQuery, membershipID)
 	err := row.Scan(
 		&membership.MembershipID,
 		&membership.PlanID,
@@ -74,10 +76,11 @@ func GetMembership(membershipID int64) (*models.Membership, error) {
 	return &membership, nil
 }
 
-func CreateMembership(membership models.Membership) (*models.Membership, error) {
+func CreateMembership(ctx context.Context, db DB, membership models.Membership) (*models.Membership, error) {
 	var created models.Membership
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		createMembershipQuery,
 		membership.PlanID,
 		membership.MemberID,
@@ -102,10 +105,11 @@ func CreateMembership(membership models.Membership) (*models.Membership, error)
 	return &created, nil
 }
 
-func UpdateMembership(membershipID int64, membership models.Membership) (*models.Membership, error) {
+func UpdateMembership(ctx context.Context, db DB, membershipID int64, membership models.Membership) (*models.Membership, error) {
 	var updated models.Membership
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		updateMembershipQuery,
 		membership.PlanID,
 		membership.MemberID,
@@ -131,18 +135,8 @@ func UpdateMembership(membershipID int64, membership models.Membership) (*models
 	return &updated, nil
 }
 
-func DeleteMembership(membershipID int64) error {
-	stmt, err := store.DB.Prepare(deleteMembershipQuery)
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(membershipID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func DeleteMembership(ctx context.Context, db DB, membershipID int64) error {
+	return execOne(ctx, db, deleteMembershipQuery, membershipID)
 }
 
 const getMembershipListQuery = `
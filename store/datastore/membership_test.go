@@ -15,7 +15,7 @@ var _ = Describe("Membership db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				memberships, _ = datastore.GetMembershipList("")
+				memberships, _ = datastore.GetMembershipList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of memberships", func() {
@@ -29,7 +29,7 @@ var _ = Describe("Membership db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct membership", func() {
-				membership, _ = datastore.GetMembership(membershipID)
+				membership, _ = datastore.GetMembership(ctx, db, membershipID)
 				Expect(membership.MembershipID).To(Equal(membershipID))
 			})
 		})
@@ -41,7 +41,7 @@ var _ = Describe("Membership db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				membership, err = datastore.GetMembership(nonExistentID)
+				membership, err = datastore.GetMembership(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -59,7 +59,7 @@ var _ = Describe("Membership db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetMembershipCount("")
+				count, _ = datastore.GetMembershipCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -78,11 +78,11 @@ var _ = Describe("Membership db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				membership = models.Membership{PlanID: &planID, MemberID: &memberID, Active: active}
-				created, _ = datastore.CreateMembership(membership)
+				created, _ = datastore.CreateMembership(ctx, db, membership)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteMembership(created.MembershipID)
+				datastore.DeleteMembership(ctx, db, created.MembershipID)
 			})
 
 			It("should return the created membership", func() {
@@ -90,7 +90,7 @@ var _ = Describe("Membership db interactions", func() {
 			})
 
 			It("should add a membership to the db", func() {
-				newMember, _ := datastore.GetMembership(created.MembershipID)
+				newMember, _ := datastore.GetMembership(ctx, db, created.MembershipID)
 				Expect(newMember.Active).To(Equal(active))
 			})
 		})
@@ -98,7 +98,7 @@ var _ = Describe("Membership db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			It("should return an error object if no plan_id and member_id", func() {
 				mbr := models.Membership{}
-				_, err := datastore.CreateMembership(mbr)
+				_, err := datastore.CreateMembership(ctx, db, mbr)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -116,14 +116,16 @@ var _ = Describe("Membership db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				created, _ = datastore.CreateMembership(
+					ctx,
+					db,
 					models.Membership{PlanID: &planID, MemberID: &memberID},
 				)
 				created.Active = active
-				updated, _ = datastore.UpdateMembership(created.MembershipID, *created)
+				updated, _ = datastore.UpdateMembership(ctx, db, created.MembershipID, *created)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteMembership(updated.MembershipID)
+				datastore.DeleteMembership(ctx, db, updated.MembershipID)
 			})
 
 			It("should return the updated membership", func() {
@@ -134,7 +136,7 @@ var _ = Describe("Membership db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				membership = models.Membership{PlanID: &planID, MemberID: &memberID}
-				updated, err = datastore.UpdateMembership(3, membership)
+				updated, err = datastore.UpdateMembership(ctx, db, 3, membership)
 			})
 
 			It("should return an error object if membership to update doesn't exist", func() {
@@ -151,9 +153,11 @@ var _ = Describe("Membership db interactions", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
 				created, _ := datastore.CreateMembership(
+					ctx,
+					db,
 					models.Membership{PlanID: &planID, MemberID: &memberID},
 				)
-				err := datastore.DeleteMembership(created.MembershipID)
+				err := datastore.DeleteMembership(ctx, db, created.MembershipID)
 				Expect(err).To(BeNil())
 			})
 		})
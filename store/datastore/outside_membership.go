@@ -1,20 +1,21 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetOutsideMembershipList(where string) ([]models.OutsideMembership, error) {
+func GetOutsideMembershipList(ctx context.Context, db DB, q Query) ([]models.OutsideMembership, error) {
 	var (
 		outsideMemberships []models.OutsideMembership
 		outsideMembership  models.OutsideMembership
 	)
 
-	query := fmt.Sprintf("%s %s", getOutsideMembershipListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getOutsideMembershipListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -33,14 +34,15 @@ func GetOutsideMembershipList(where string) ([]models.OutsideMembership, error)
 	}
 	defer rows.Close()
 
-	return outsideMemberships, nil
+	return outsideMemberships, rows.Err()
 }
 
-func GetOutsideMembershipCount(where string) (*int, error) {
+func GetOutsideMembershipCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getOutsideMembershipCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getOutsideMembershipCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 
 	if err != nil {
@@ -50,10 +52,10 @@ func GetOutsideMembershipCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetOutsideMembership(outsideMembershipID int64) (*models.OutsideMembership, error) {
+func GetOutsideMembership(ctx context.Context, db DB, outsideMembershipID int64) (*models.OutsideMembership, error) {
 	var outsideMembership models.OutsideMembership
 
-	row := store.DB.QueryRow(getOutsideMembershipQuery, outsideMembershipID)
+	row := db.QueryRowContext(ctx, getOutsideMembershipQuery, outsideMembershipID)
 	err := row.Scan(
 		&outsideMembership.OutsideMembershipID,
 		&outsideMembership.MemberID,
@@ -68,10 +70,11 @@ func GetOutsideMembership(outsideMembershipID int64) (*models.OutsideMembership,
 	return &outsideMembership, nil
 }
 
-func CreateOutsideMembership(outsideMembership models.OutsideMembership) (*models.OutsideMembership, error) {
+func CreateOutsideMembership(ctx context.Context, db DB, outsideMembership models.OutsideMembership) (*models.OutsideMembership, error) {
 	var created models.OutsideMembership
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		createOutsideMembershipQuery,
 		outsideMembership.MemberID,
 		outsideMembership.GymLocationID,
@@ -90,10 +93,11 @@ func CreateOutsideMembership(outsideMembership models.OutsideMembership) (*model
 	return &created, nil
 }
 
-func UpdateOutsideMembership(outsideMembershipID int64, outsideMembership models.OutsideMembership) (*models.OutsideMembership, error) {
+func UpdateOutsideMembership(ctx context.Context, db DB, outsideMembershipID int64, outsideMembership models.OutsideMembership) (*models.OutsideMembership, error) {
 	var updated models.OutsideMembership
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		updateOutsideMembershipQuery,
 		outsideMembership.MemberID,
 		outsideMembership.GymLocationID,
@@ -113,18 +117,8 @@ func UpdateOutsideMembership(outsideMembershipID int64, outsideMembership models
 	return &updated, nil
 }
 
-func DeleteOutsideMembership(outsideMembershipID int64) error {
-	stmt, err := store.DB.Prepare(deleteOutsideMembershipQuery)
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(outsideMembershipID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func DeleteOutsideMembership(ctx context.Context, db DB, outsideMembershipID int64) error {
+	return execOne(ctx, db, deleteOutsideMembershipQuery, outsideMembershipID)
 }
 
 const getOutsideMembershipListQuery = `
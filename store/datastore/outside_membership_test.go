@@ -14,19 +14,19 @@ var _ = Describe("OutsideMembership db interactions", func() {
 	)
 
 	BeforeEach(func() {
-		outsideMembershipOne, _ = datastore.CreateOutsideMembership(models.OutsideMembership{
+		outsideMembershipOne, _ = datastore.CreateOutsideMembership(ctx, db, models.OutsideMembership{
 			GymID:    &gymOneID,
 			MemberID: memberID,
 		})
-		outsideMembershipTwo, _ = datastore.CreateOutsideMembership(models.OutsideMembership{
+		outsideMembershipTwo, _ = datastore.CreateOutsideMembership(ctx, db, models.OutsideMembership{
 			GymID:    &gymTwoID,
 			MemberID: memberID,
 		})
 	})
 
 	AfterEach(func() {
-		datastore.DeleteOutsideMembership(outsideMembershipOne.OutsideMembershipID)
-		datastore.DeleteOutsideMembership(outsideMembershipTwo.OutsideMembershipID)
+		datastore.DeleteOutsideMembership(ctx, db, outsideMembershipOne.OutsideMembershipID)
+		datastore.DeleteOutsideMembership(ctx, db, outsideMembershipTwo.OutsideMembershipID)
 	})
 
 	Describe("GetOutsideMembershipList", func() {
@@ -34,7 +34,7 @@ var _ = Describe("OutsideMembership db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				outsideMemberships, _ = datastore.GetOutsideMembershipList("")
+				outsideMemberships, _ = datastore.GetOutsideMembershipList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of outsideMemberships", func() {
@@ -48,7 +48,7 @@ var _ = Describe("OutsideMembership db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct outsideMembership", func() {
-				outsideMembership, _ = datastore.GetOutsideMembership(outsideMembershipOne.OutsideMembershipID)
+				outsideMembership, _ = datastore.GetOutsideMembership(ctx, db, outsideMembershipOne.OutsideMembershipID)
 				Expect(outsideMembership.OutsideMembershipID).To(Equal(outsideMembershipOne.OutsideMembershipID))
 			})
 		})
@@ -60,7 +60,7 @@ var _ = Describe("OutsideMembership db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				outsideMembership, err = datastore.GetOutsideMembership(nonExistentID)
+				outsideMembership, err = datastore.GetOutsideMembership(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -78,7 +78,7 @@ var _ = Describe("OutsideMembership db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetOutsideMembershipCount("")
+				count, _ = datastore.GetOutsideMembershipCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -100,11 +100,11 @@ var _ = Describe("OutsideMembership db interactions", func() {
 					GymID:    &otherGymID,
 					MemberID: memberID,
 				}
-				created, _ = datastore.CreateOutsideMembership(outsideMembership)
+				created, _ = datastore.CreateOutsideMembership(ctx, db, outsideMembership)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteOutsideMembership(created.OutsideMembershipID)
+				datastore.DeleteOutsideMembership(ctx, db, created.OutsideMembershipID)
 			})
 
 			It("should return the created outsideMembership", func() {
@@ -112,7 +112,7 @@ var _ = Describe("OutsideMembership db interactions", func() {
 			})
 
 			It("should add a outsideMembership to the db", func() {
-				newMember, _ := datastore.GetOutsideMembership(created.OutsideMembershipID)
+				newMember, _ := datastore.GetOutsideMembership(ctx, db, created.OutsideMembershipID)
 				Expect(newMember.GymID).To(Equal(&otherGymID))
 			})
 		})
@@ -120,7 +120,7 @@ var _ = Describe("OutsideMembership db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			It("should return an error object if no day_id or holiday_id", func() {
 				mbr := models.OutsideMembership{}
-				_, err := datastore.CreateOutsideMembership(mbr)
+				_, err := datastore.CreateOutsideMembership(ctx, db, mbr)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -137,16 +137,16 @@ var _ = Describe("OutsideMembership db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				created, _ = datastore.CreateOutsideMembership(models.OutsideMembership{
+				created, _ = datastore.CreateOutsideMembership(ctx, db, models.OutsideMembership{
 					GymID:    &gymOneID,
 					MemberID: memberID,
 				})
 				created.GymID = &gymTwoID
-				updated, _ = datastore.UpdateOutsideMembership(created.OutsideMembershipID, *created)
+				updated, _ = datastore.UpdateOutsideMembership(ctx, db, created.OutsideMembershipID, *created)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteOutsideMembership(updated.OutsideMembershipID)
+				datastore.DeleteOutsideMembership(ctx, db, updated.OutsideMembershipID)
 			})
 
 			It("should return the updated outsideMembership", func() {
@@ -160,7 +160,7 @@ var _ = Describe("OutsideMembership db interactions", func() {
 					GymID:    &gymOneID,
 					MemberID: memberID,
 				}
-				updated, err = datastore.UpdateOutsideMembership(5000, outsideMembership)
+				updated, err = datastore.UpdateOutsideMembership(ctx, db, 5000, outsideMembership)
 			})
 
 			It("should return an error object if outsideMembership to update doesn't exist", func() {
@@ -177,11 +177,11 @@ var _ = Describe("OutsideMembership db interactions", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
 				var otherGymID int64 = 3
-				created, _ := datastore.CreateOutsideMembership(models.OutsideMembership{
+				created, _ := datastore.CreateOutsideMembership(ctx, db, models.OutsideMembership{
 					GymID:    &otherGymID,
 					MemberID: memberID,
 				})
-				err := datastore.DeleteOutsideMembership(created.OutsideMembershipID)
+				err := datastore.DeleteOutsideMembership(ctx, db, created.OutsideMembershipID)
 				Expect(err).To(BeNil())
 			})
 		})
@@ -0,0 +1,77 @@
+package datastore
+
+import (
+	"context"
+
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+func CreatePasswordReset(ctx context.Context, db DB, passwordReset models.PasswordReset) (*models.PasswordReset, error) {
+	var created models.PasswordReset
+
+	row := db.QueryRowContext(
+		ctx,
+		createPasswordResetQuery,
+		passwordReset.UserID,
+		passwordReset.TokenHash,
+		passwordReset.ExpiresOn,
+	)
+	err := row.Scan(
+		&created.PasswordResetID,
+		&created.UserID,
+		&created.TokenHash,
+		&created.CreatedOn,
+		&created.ExpiresOn,
+		&created.UsedOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// ConsumePasswordReset marks the reset matching tokenHash as used and returns
+// it. It returns sql.ErrNoRows if there is no such reset, or if it has
+// already been used or has expired.
+func ConsumePasswordReset(ctx context.Context, db DB, tokenHash string) (*models.PasswordReset, error) {
+	var used models.PasswordReset
+
+	row := db.QueryRowContext(ctx, consumePasswordResetQuery, tokenHash)
+	err := row.Scan(
+		&used.PasswordResetID,
+		&used.UserID,
+		&used.TokenHash,
+		&used.CreatedOn,
+		&used.ExpiresOn,
+		&used.UsedOn,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &used, nil
+}
+
+func DeletePasswordReset(ctx context.Context, db DB, passwordResetID int64) error {
+	return execOne(ctx, db, deletePasswordResetQuery, passwordResetID)
+}
+
+const createPasswordResetQuery = `
+INSERT INTO password_resets (user_id, token_hash, expires_on)
+VALUES ($1, $2, $3)
+RETURNING password_reset_id, user_id, token_hash, created_on, expires_on, used_on
+`
+
+const consumePasswordResetQuery = `
+UPDATE password_resets
+SET used_on = CURRENT_TIMESTAMP
+WHERE token_hash = $1 AND used_on IS NULL AND expires_on > CURRENT_TIMESTAMP
+RETURNING password_reset_id, user_id, token_hash, created_on, expires_on, used_on
+`
+
+const deletePasswordResetQuery = `
+DELETE
+FROM password_resets
+WHERE password_reset_id = $1
+`
@@ -0,0 +1,90 @@
+package datastore_test
+
+import (
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PasswordReset db interactions", func() {
+	var (
+		userID        int64 = 1
+		passwordReset *models.PasswordReset
+	)
+
+	BeforeEach(func() {
+		passwordReset, _ = datastore.CreatePasswordReset(ctx, db, models.PasswordReset{
+			UserID:    userID,
+			TokenHash: "resethash",
+			ExpiresOn: time.Now().Add(time.Hour),
+		})
+	})
+
+	AfterEach(func() {
+		datastore.DeletePasswordReset(ctx, db, passwordReset.PasswordResetID)
+	})
+
+	Describe("CreatePasswordReset", func() {
+		Describe("Successful call", func() {
+			It("should return the created reset", func() {
+				Expect(passwordReset.UserID).To(Equal(userID))
+				Expect(passwordReset.UsedOn).To(BeNil())
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return an error if the token hash is not unique", func() {
+				_, err := datastore.CreatePasswordReset(ctx, db, models.PasswordReset{
+					UserID:    userID,
+					TokenHash: "resethash",
+					ExpiresOn: time.Now().Add(time.Hour),
+				})
+				Expect(err).ToNot(BeNil())
+			})
+		})
+	})
+
+	Describe("ConsumePasswordReset", func() {
+		Describe("Successful call", func() {
+			It("should mark the reset as used", func() {
+				used, err := datastore.ConsumePasswordReset(ctx, db, "resethash")
+				Expect(err).To(BeNil())
+				Expect(used.PasswordResetID).To(Equal(passwordReset.PasswordResetID))
+				Expect(used.UsedOn).ToNot(BeNil())
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return an error the second time", func() {
+				datastore.ConsumePasswordReset(ctx, db, "resethash")
+				used, err := datastore.ConsumePasswordReset(ctx, db, "resethash")
+				Expect(err).ToNot(BeNil())
+				Expect(used).To(BeNil())
+			})
+
+			It("should return an error once expired", func() {
+				expired, _ := datastore.CreatePasswordReset(ctx, db, models.PasswordReset{
+					UserID:    userID,
+					TokenHash: "expiredhash",
+					ExpiresOn: time.Now().Add(-time.Hour),
+				})
+				defer datastore.DeletePasswordReset(ctx, db, expired.PasswordResetID)
+
+				_, err := datastore.ConsumePasswordReset(ctx, db, "expiredhash")
+				Expect(err).ToNot(BeNil())
+			})
+		})
+	})
+
+	Describe("DeletePasswordReset", func() {
+		Describe("Successful call", func() {
+			It("should return nil", func() {
+				err := datastore.DeletePasswordReset(ctx, db, passwordReset.PasswordResetID)
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+})
@@ -1,20 +1,21 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetPlanList(where string) ([]models.Plan, error) {
+func GetPlanList(ctx context.Context, db DB, q Query) ([]models.Plan, error) {
 	var (
 		plans []models.Plan
 		plan  models.Plan
 	)
 
-	query := fmt.Sprintf("%s %s", getPlanListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getPlanListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -28,14 +29,15 @@ func GetPlanList(where string) ([]models.Plan, error) {
 	}
 	defer rows.Close()
 
-	return plans, nil
+	return plans, rows.Err()
 }
 
-func GetPlanCount(where string) (*int, error) {
+func GetPlanCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getPlanCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getPlanCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 	if err != nil {
 		return nil, err
@@ -44,10 +46,10 @@ func GetPlanCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetPlan(planID int64) (*models.Plan, error) {
+func GetPlan(ctx context.Context, db DB, planID int64) (*models.Plan, error) {
 	var plan models.Plan
 
-	row := store.DB.QueryRow(getPlanQuery, planID)
+	row := db.QueryRowContext(ctx, getPlanQuery, planID)
 	err := row.Scan(&plan.PlanID, &plan.PlanName, &plan.Price)
 	if err != nil {
 		return nil, err
@@ -56,10 +58,10 @@ func GetPlan(planID int64) (*models.Plan, error) {
 	return &plan, nil
 }
 
-func CreatePlan(plan models.Plan) (*models.Plan, error) {
+func CreatePlan(ctx context.Context, db DB, plan models.Plan) (*models.Plan, error) {
 	var created models.Plan
 
-	row := store.DB.QueryRow(createPlanQuery, plan.PlanName, plan.Price)
+	row := db.QueryRowContext(ctx, createPlanQuery, plan.PlanName, plan.Price)
 	err := row.Scan(&created.PlanID, &created.PlanName, &created.Price)
 	if err != nil {
 		return nil, err
@@ -68,10 +70,10 @@ func CreatePlan(plan models.Plan) (*models.Plan, error) {
 	return &created, nil
 }
 
-func UpdatePlan(planID int64, plan models.Plan) (*models.Plan, error) {
+func UpdatePlan(ctx context.Context, db DB, planID int64, plan models.Plan) (*models.Plan, error) {
 	var updated models.Plan
 
-	row := store.DB.QueryRow(updatePlanQuery, plan.PlanName, plan.Price, planID)
+	row := db.QueryRowContext(ctx, updatePlanQuery, plan.PlanName, plan.Price, planID)
 	err := row.Scan(&updated.PlanID, &updated.PlanName, &updated.Price)
 	if err != nil {
 		return nil, err
@@ -80,18 +82,8 @@ func UpdatePlan(planID int64, plan models.Plan) (*models.Plan, error) {
 	return &updated, nil
 }
 
-func DeletePlan(planID int64) error {
-	stmt, err := store.DB.Prepare(deletePlanQuery)
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(planID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func DeletePlan(ctx context.Context, db DB, planID int64) error {
+	return execOne(ctx, db, deletePlanQuery, planID)
 }
 
 const getPlanListQuery = `
@@ -11,17 +11,17 @@ var _ = Describe("Plan db interactions", func() {
 	var one, two, three, four *models.Plan
 
 	BeforeEach(func() {
-		one, _ = datastore.CreatePlan(models.Plan{PlanName: "Testing"})
-		two, _ = datastore.CreatePlan(models.Plan{PlanName: "Testing Two"})
-		three, _ = datastore.CreatePlan(models.Plan{PlanName: "Testing Three"})
-		four, _ = datastore.CreatePlan(models.Plan{PlanName: "Testing Four"})
+		one, _ = datastore.CreatePlan(ctx, db, models.Plan{PlanName: "Testing"})
+		two, _ = datastore.CreatePlan(ctx, db, models.Plan{PlanName: "Testing Two"})
+		three, _ = datastore.CreatePlan(ctx, db, models.Plan{PlanName: "Testing Three"})
+		four, _ = datastore.CreatePlan(ctx, db, models.Plan{PlanName: "Testing Four"})
 	})
 
 	AfterEach(func() {
-		datastore.DeletePlan(one.PlanID)
-		datastore.DeletePlan(two.PlanID)
-		datastore.DeletePlan(three.PlanID)
-		datastore.DeletePlan(four.PlanID)
+		datastore.DeletePlan(ctx, db, one.PlanID)
+		datastore.DeletePlan(ctx, db, two.PlanID)
+		datastore.DeletePlan(ctx, db, three.PlanID)
+		datastore.DeletePlan(ctx, db, four.PlanID)
 	})
 
 	Describe("GetPlanList", func() {
@@ -29,7 +29,7 @@ var _ = Describe("Plan db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				plans, _ = datastore.GetPlanList("")
+				plans, _ = datastore.GetPlanList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of plans", func() {
@@ -43,7 +43,7 @@ var _ = Describe("Plan db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct plan", func() {
-				plan, _ = datastore.GetPlan(one.PlanID)
+				plan, _ = datastore.GetPlan(ctx, db, one.PlanID)
 				Expect(plan.PlanID).To(Equal(one.PlanID))
 			})
 		})
@@ -55,7 +55,7 @@ var _ = Describe("Plan db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				plan, err = datastore.GetPlan(nonExistentID)
+				plan, err = datastore.GetPlan(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -73,7 +73,7 @@ var _ = Describe("Plan db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetPlanCount("")
+				count, _ = datastore.GetPlanCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -92,11 +92,11 @@ var _ = Describe("Plan db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				plan = models.Plan{PlanName: planName}
-				created, _ = datastore.CreatePlan(plan)
+				created, _ = datastore.CreatePlan(ctx, db, plan)
 			})
 
 			AfterEach(func() {
-				datastore.DeletePlan(created.PlanID)
+				datastore.DeletePlan(ctx, db, created.PlanID)
 			})
 
 			It("should return the created plan", func() {
@@ -104,7 +104,7 @@ var _ = Describe("Plan db interactions", func() {
 			})
 
 			It("should add a plan to the db", func() {
-				newPlan, _ := datastore.GetPlan(created.PlanID)
+				newPlan, _ := datastore.GetPlan(ctx, db, created.PlanID)
 				Expect(newPlan.PlanName).To(Equal(planName))
 			})
 		})
@@ -113,14 +113,14 @@ var _ = Describe("Plan db interactions", func() {
 			var created *models.Plan
 
 			AfterEach(func() {
-				datastore.DeletePlan(created.PlanID)
+				datastore.DeletePlan(ctx, db, created.PlanID)
 			})
 
 			It("should return an error object if plan is not unique", func() {
 				name := "Test Name"
 				pln := models.Plan{PlanName: name}
-				created, _ = datastore.CreatePlan(pln)
-				_, err := datastore.CreatePlan(pln)
+				created, _ = datastore.CreatePlan(ctx, db, pln)
+				_, err := datastore.CreatePlan(ctx, db, pln)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -138,12 +138,12 @@ var _ = Describe("Plan db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				plan = models.Plan{PlanName: planName}
-				created, _ = datastore.CreatePlan(models.Plan{PlanName: "Daily"})
-				updated, _ = datastore.UpdatePlan(created.PlanID, plan)
+				created, _ = datastore.CreatePlan(ctx, db, models.Plan{PlanName: "Daily"})
+				updated, _ = datastore.UpdatePlan(ctx, db, created.PlanID, plan)
 			})
 
 			AfterEach(func() {
-				datastore.DeletePlan(updated.PlanID)
+				datastore.DeletePlan(ctx, db, updated.PlanID)
 			})
 
 			It("should return the updated plan", func() {
@@ -154,7 +154,7 @@ var _ = Describe("Plan db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				plan = models.Plan{PlanName: "Daily"}
-				updated, err = datastore.UpdatePlan(10000, plan)
+				updated, err = datastore.UpdatePlan(ctx, db, 10000, plan)
 			})
 
 			It("should return an error object if plan to update doesn't exist", func() {
@@ -170,7 +170,7 @@ var _ = Describe("Plan db interactions", func() {
 	Describe("DeletePlan", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				err := datastore.DeletePlan(one.PlanID)
+				err := datastore.DeletePlan(ctx, db, one.PlanID)
 				Expect(err).To(BeNil())
 			})
 		})
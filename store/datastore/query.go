@@ -0,0 +1,211 @@
+package datastore
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Query narrows down and orders a list. Fields are column names, which the
+// handlers check against the columns they allow. Values are only ever sent
+// to the database as query arguments, never pasted into the SQL.
+type Query struct {
+	Filters []Filter
+	Sort    []Sort
+
+	// Limit of 0 returns every row.
+	Limit  int
+	Offset int
+
+	// Cursor holds the sort values of a row to continue the list from,
+	// one for each of Sort. The sort has to end in a unique column for the
+	// pages not to skip or repeat rows.
+	Cursor []interface{}
+	// Backward returns the rows before Cursor instead, closest first.
+	Backward bool
+}
+
+// Operator compares a column with a filter's value.
+type Operator string
+
+const (
+	Equal          Operator = "="
+	GreaterThan    Operator = ">"
+	GreaterOrEqual Operator = ">="
+	LessThan       Operator = "<"
+	LessOrEqual    Operator = "<="
+	// Contains matches strings that have the value anywhere in them.
+	Contains Operator = "LIKE"
+	// In matches any of the values in a slice.
+	In Operator = "IN"
+	// IsNull matches nulls if the value is true, everything else if it's
+	// false.
+	IsNull Operator = "IS NULL"
+)
+
+type Filter struct {
+	Field    string
+	Operator Operator
+	Value    interface{}
+}
+
+type Sort struct {
+	Field      string
+	Descending bool
+}
+
+// Where returns a Query for the rows whose field equals value.
+func Where(field string, value interface{}) Query {
+	return Query{Filters: []Filter{{Field: field, Operator: Equal, Value: value}}}
+}
+
+// builder collects the arguments of a query as its clauses are rendered.
+type builder struct {
+	args []interface{}
+}
+
+// arg adds value to the query's arguments and returns its placeholder.
+func (b *builder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// set renders the SET list of a patch, the fields picked out of columns,
+// and bumps the version. A field that isn't one of the columns is an error
+// rather than set to NULL.
+func (b *builder) set(columns map[string]interface{}, fields []string) (string, error) {
+	var set []string
+	for _, field := range fields {
+		value, ok := columns[field]
+		if !ok {
+			return "", fmt.Errorf("%s can't be patched", field)
+		}
+		set = append(set, fmt.Sprintf("%s = %s", pq.QuoteIdentifier(field), b.arg(value)))
+	}
+
+	return strings.Join(append(set, "version = version + 1"), ", "), nil
+}
+
+// where renders q's filters and any extra conditions, like the caller's
+// scope, ANDed together.
+func (b *builder) where(q Query, conditions ...string) string {
+	for _, filter := range q.Filters {
+		conditions = append(conditions, b.filter(filter))
+	}
+
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
+
+func (b *builder) filter(filter Filter) string {
+	field := pq.QuoteIdentifier(filter.Field)
+
+	switch filter.Operator {
+	case Contains:
+		return fmt.Sprintf("%s LIKE %s", field, b.arg("%"+escapeLike(fmt.Sprint(filter.Value))+"%"))
+	case In:
+		values := reflect.ValueOf(filter.Value)
+		if values.Kind() != reflect.Slice || values.Len() == 0 {
+			return "FALSE"
+		}
+
+		var placeholders []string
+		for i := 0; i < values.Len(); i++ {
+			placeholders = append(placeholders, b.arg(values.Index(i).Interface()))
+		}
+		return fmt.Sprintf("%s IN (%s)", field, strings.Join(placeholders, ", "))
+	case IsNull:
+		if isNull, _ := filter.Value.(bool); !isNull {
+			return fmt.Sprintf("%s IS NOT NULL", field)
+		}
+		return fmt.Sprintf("%s IS NULL", field)
+	case GreaterThan, GreaterOrEqual, LessThan, LessOrEqual:
+		return fmt.Sprintf("%s %s %s", field, filter.Operator, b.arg(filter.Value))
+	default:
+		return fmt.Sprintf("%s = %s", field, b.arg(filter.Value))
+	}
+}
+
+// list renders everything after the FROM of a list query: the filters and
+// extra conditions, the cursor, the sort and the page.
+func (b *builder) list(q Query, conditions ...string) string {
+	if len(q.Cursor) > 0 {
+		conditions = append(conditions, b.cursor(q))
+	}
+
+	clauses := []string{b.where(q, conditions...), orderBy(q)}
+	if q.Limit > 0 {
+		clauses = append(clauses, "LIMIT "+b.arg(q.Limit))
+	}
+	if q.Offset > 0 {
+		clauses = append(clauses, "OFFSET "+b.arg(q.Offset))
+	}
+
+	return strings.Join(clauses, " ")
+}
+
+// cursor matches the rows that come after q.Cursor in the sort order, e.g.
+// for a sort on a, b: a > $1 OR (a = $1 AND b > $2). Nulls sort last, the
+// same as postgres does by default.
+func (b *builder) cursor(q Query) string {
+	var (
+		alternatives []string
+		equal        []string
+	)
+
+	for i, sort := range q.Sort {
+		field := pq.QuoteIdentifier(sort.Field)
+		value := q.Cursor[i]
+		ascending := sort.Descending == q.Backward
+
+		var after string
+		switch {
+		case value == nil && ascending:
+			after = "FALSE"
+		case value == nil:
+			after = fmt.Sprintf("%s IS NOT NULL", field)
+		case ascending:
+			after = fmt.Sprintf("(%s > %s OR %s IS NULL)", field, b.arg(value), field)
+		default:
+			after = fmt.Sprintf("%s < %s", field, b.arg(value))
+		}
+		alternatives = append(alternatives, strings.Join(append(equal, after), " AND "))
+
+		if value == nil {
+			equal = append(equal, fmt.Sprintf("%s IS NULL", field))
+		} else {
+			equal = append(equal, fmt.Sprintf("%s = %s", field, b.arg(value)))
+		}
+	}
+
+	return fmt.Sprintf("(%s)", strings.Join(alternatives, " OR "))
+}
+
+// orderBy renders q's sort, reversed when paging backward. It doesn't take
+// any arguments.
+func orderBy(q Query) string {
+	var fields []string
+	for _, sort := range q.Sort {
+		field := pq.QuoteIdentifier(sort.Field)
+		if sort.Descending != q.Backward {
+			field += " DESC"
+		}
+		fields = append(fields, field)
+	}
+
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return "ORDER BY " + strings.Join(fields, ", ")
+}
+
+// escapeLike stops wildcards in value from matching anything.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
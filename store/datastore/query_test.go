@@ -0,0 +1,142 @@
+package datastore_test
+
+import (
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Query", func() {
+	var statuses []models.Status
+
+	Describe("Where", func() {
+		It("should only return rows with the value", func() {
+			statuses, _ = datastore.GetStatusList(ctx, db, datastore.Where("status_name", "Pending"))
+			Expect(len(statuses)).To(Equal(1))
+			Expect(statuses[0].StatusName).To(Equal("Pending"))
+		})
+	})
+
+	Describe("Filters", func() {
+		It("should AND filters together", func() {
+			statuses, _ = datastore.GetStatusList(ctx, db, datastore.Query{
+				Filters: []datastore.Filter{
+					{Field: "status_name", Operator: datastore.Contains, Value: "Denied"},
+					{Field: "status_id", Operator: datastore.Equal, Value: 1},
+				},
+			})
+			Expect(len(statuses)).To(Equal(0))
+		})
+
+		It("should compare with the operator", func() {
+			statuses, _ = datastore.GetStatusList(ctx, db, datastore.Query{
+				Filters: []datastore.Filter{
+					{Field: "status_id", Operator: datastore.GreaterThan, Value: 2},
+					{Field: "status_id", Operator: datastore.LessOrEqual, Value: 4},
+				},
+			})
+			Expect(len(statuses)).To(Equal(2))
+		})
+
+		It("should match any of the values with in", func() {
+			statuses, _ = datastore.GetStatusList(ctx, db, datastore.Query{
+				Filters: []datastore.Filter{{Field: "status_id", Operator: datastore.In, Value: []interface{}{1, 3, 5}}},
+			})
+			Expect(len(statuses)).To(Equal(3))
+		})
+
+		It("should match nothing with an empty in", func() {
+			statuses, _ = datastore.GetStatusList(ctx, db, datastore.Query{
+				Filters: []datastore.Filter{{Field: "status_id", Operator: datastore.In, Value: []interface{}{}}},
+			})
+			Expect(len(statuses)).To(Equal(0))
+		})
+
+		It("should match nulls with is_null", func() {
+			visits, _ := datastore.GetVisitList(ctx, db, datastore.Unscoped, datastore.Query{
+				Filters: []datastore.Filter{{Field: "modified_on", Operator: datastore.IsNull, Value: true}},
+			})
+			Expect(len(visits)).To(Equal(5))
+
+			visits, _ = datastore.GetVisitList(ctx, db, datastore.Unscoped, datastore.Query{
+				Filters: []datastore.Filter{{Field: "modified_on", Operator: datastore.IsNull, Value: false}},
+			})
+			Expect(len(visits)).To(Equal(0))
+		})
+
+		It("should return an error for an unknown field", func() {
+			_, err := datastore.GetStatusList(ctx, db, datastore.Where("status_name; DROP TABLE statuses", "Pending"))
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Describe("Sort", func() {
+		It("should order the rows", func() {
+			statuses, _ = datastore.GetStatusList(ctx, db, datastore.Query{
+				Sort: []datastore.Sort{{Field: "status_id", Descending: true}},
+			})
+			Expect(statuses[0].StatusID).To(Equal(int64(6)))
+			Expect(statuses[len(statuses)-1].StatusID).To(Equal(int64(1)))
+		})
+
+		It("should only count filtered rows", func() {
+			count, _ := datastore.GetStatusCount(ctx, db, datastore.Query{
+				Filters: []datastore.Filter{{Field: "status_name", Operator: datastore.Contains, Value: "Denied"}},
+				Sort:    []datastore.Sort{{Field: "status_id"}},
+			})
+			Expect(*count).To(Equal(2))
+		})
+	})
+
+	Describe("Paging", func() {
+		var byID = []datastore.Sort{{Field: "status_id"}}
+
+		It("should limit the rows", func() {
+			statuses, _ = datastore.GetStatusList(ctx, db, datastore.Query{Sort: byID, Limit: 2})
+			Expect(len(statuses)).To(Equal(2))
+			Expect(statuses[0].StatusID).To(Equal(int64(1)))
+		})
+
+		It("should skip offset rows", func() {
+			statuses, _ = datastore.GetStatusList(ctx, db, datastore.Query{Sort: byID, Limit: 2, Offset: 2})
+			Expect(statuses[0].StatusID).To(Equal(int64(3)))
+			Expect(statuses[1].StatusID).To(Equal(int64(4)))
+		})
+
+		It("should continue after the cursor", func() {
+			statuses, _ = datastore.GetStatusList(ctx, db, datastore.Query{Sort: byID, Limit: 2, Cursor: []interface{}{2}})
+			Expect(statuses[0].StatusID).To(Equal(int64(3)))
+			Expect(statuses[1].StatusID).To(Equal(int64(4)))
+		})
+
+		It("should return the rows before the cursor closest first", func() {
+			statuses, _ = datastore.GetStatusList(ctx, db, datastore.Query{Sort: byID, Limit: 2, Cursor: []interface{}{5}, Backward: true})
+			Expect(statuses[0].StatusID).To(Equal(int64(4)))
+			Expect(statuses[1].StatusID).To(Equal(int64(3)))
+		})
+
+		It("should continue after the cursor when sorting descending", func() {
+			statuses, _ = datastore.GetStatusList(ctx, db, datastore.Query{
+				Sort:   []datastore.Sort{{Field: "status_id", Descending: true}},
+				Cursor: []interface{}{3},
+			})
+			Expect(len(statuses)).To(Equal(2))
+			Expect(statuses[0].StatusID).To(Equal(int64(2)))
+		})
+
+		It("should page through null sort values", func() {
+			visits, _ := datastore.GetVisitList(ctx, db, datastore.Unscoped, datastore.Query{
+				Sort:   []datastore.Sort{{Field: "modified_on"}, {Field: "visit_id"}},
+				Cursor: []interface{}{nil, 2},
+			})
+			Expect(len(visits)).To(Equal(3))
+			Expect(visits[0].VisitID).To(Equal(int64(3)))
+		})
+
+		It("should not limit the count", func() {
+			count, _ := datastore.GetStatusCount(ctx, db, datastore.Query{Sort: byID, Limit: 2, Offset: 2, Cursor: []interface{}{2}})
+			Expect(*count).To(Equal(6))
+		})
+	})
+})
@@ -1,20 +1,21 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetRoleList(where string) ([]models.Role, error) {
+func GetRoleList(ctx context.Context, db DB, q Query) ([]models.Role, error) {
 	var (
 		roles []models.Role
 		role  models.Role
 	)
 
-	query := fmt.Sprintf("%s %s", getRoleListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getRoleListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -28,14 +29,15 @@ func GetRoleList(where string) ([]models.Role, error) {
 	}
 	defer rows.Close()
 
-	return roles, nil
+	return roles, rows.Err()
 }
 
-func GetRoleCount(where string) (*int, error) {
+func GetRoleCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getRoleCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getRoleCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 	if err != nil {
 		return nil, err
@@ -44,10 +46,10 @@ func GetRoleCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetRole(roleID int64) (*models.Role, error) {
+func GetRole(ctx context.Context, db DB, roleID int64) (*models.Role, error) {
 	var role models.Role
 
-	row := store.DB.QueryRow(getRoleQuery, roleID)
+	row := db.QueryRowContext(ctx, getRoleQuery, roleID)
 	err := row.Scan(&role.RoleID, &role.RoleName)
 	if err != nil {
 		return nil, err
@@ -56,10 +58,22 @@ func GetRole(roleID int64) (*models.Role, error) {
 	return &role, nil
 }
 
-func CreateRole(role models.Role) (*models.Role, error) {
+func GetRoleByName(ctx context.Context, db DB, roleName string) (*models.Role, error) {
+	var role models.Role
+
+	row := db.QueryRowContext(ctx, getRoleByNameQuery, roleName)
+	err := row.Scan(&role.RoleID, &role.RoleName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+func CreateRole(ctx context.Context, db DB, role models.Role) (*models.Role, error) {
 	var created models.Role
 
-	row := store.DB.QueryRow(createRoleQuery, role.RoleName)
+	row := db.QueryRowContext(ctx, createRoleQuery, role.RoleName)
 	err := row.Scan(&created.RoleID, &created.RoleName)
 	if err != nil {
 		return nil, err
@@ -68,10 +82,10 @@ func CreateRole(role models.Role) (*models.Role, error) {
 	return &created, nil
 }
 
-func UpdateRole(roleID int64, role models.Role) (*models.Role, error) {
+func UpdateRole(ctx context.Context, db DB, roleID int64, role models.Role) (*models.Role, error) {
 	var updated models.Role
 
-	row := store.DB.QueryRow(updateRoleQuery, role.RoleName, roleID)
+	row := db.QueryRowContext(ctx, updateRoleQuery, role.RoleName, roleID)
 	err := row.Scan(&updated.RoleID, &updated.RoleName)
 	if err != nil {
 		return nil, err
@@ -80,18 +94,8 @@ func UpdateRole(roleID int64, role models.Role) (*models.Role, error) {
 	return &updated, nil
 }
 
-func DeleteRole(roleID int64) error {
-	stmt, err := store.DB.Prepare(deleteRoleQuery)
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(roleID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func DeleteRole(ctx context.Context, db DB, roleID int64) error {
+	return execOne(ctx, db, deleteRoleQuery, roleID)
 }
 
 const getRoleListQuery = `
@@ -105,6 +109,12 @@ FROM roles
 WHERE role_id = $1
 `
 
+const getRoleByNameQuery = `
+SELECT *
+FROM roles
+WHERE role_name = $1
+`
+
 const createRoleQuery = `
 INSERT INTO roles (role_name)
 VALUES ($1)
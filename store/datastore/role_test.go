@@ -15,7 +15,7 @@ var _ = Describe("Role db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				roles, _ = datastore.GetRoleList("")
+				roles, _ = datastore.GetRoleList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of roles", func() {
@@ -29,7 +29,7 @@ var _ = Describe("Role db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct role", func() {
-				role, _ = datastore.GetRole(roleID)
+				role, _ = datastore.GetRole(ctx, db, roleID)
 				Expect(role.RoleID).To(Equal(roleID))
 			})
 		})
@@ -41,7 +41,34 @@ var _ = Describe("Role db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				role, err = datastore.GetRole(nonExistentID)
+				role, err = datastore.GetRole(ctx, db, nonExistentID)
+			})
+
+			It("should return an error", func() {
+				Expect(err).ToNot(BeNil())
+			})
+
+			It("should return a nil role", func() {
+				Expect(role).To(BeNil())
+			})
+		})
+	})
+
+	Describe("GetRoleByName", func() {
+		var role *models.Role
+
+		Describe("Successful call", func() {
+			It("should return the correct role", func() {
+				role, _ = datastore.GetRoleByName(ctx, db, models.MemberRole)
+				Expect(role.RoleName).To(Equal(models.MemberRole))
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			var err error
+
+			BeforeEach(func() {
+				role, err = datastore.GetRoleByName(ctx, db, "nonexistent")
 			})
 
 			It("should return an error", func() {
@@ -59,7 +86,7 @@ var _ = Describe("Role db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetRoleCount("")
+				count, _ = datastore.GetRoleCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -78,11 +105,11 @@ var _ = Describe("Role db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				role = models.Role{RoleName: roleName}
-				created, _ = datastore.CreateRole(role)
+				created, _ = datastore.CreateRole(ctx, db, role)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteRole(created.RoleID)
+				datastore.DeleteRole(ctx, db, created.RoleID)
 			})
 
 			It("should return the created role", func() {
@@ -90,7 +117,7 @@ var _ = Describe("Role db interactions", func() {
 			})
 
 			It("should add a role to the db", func() {
-				newRole, _ := datastore.GetRole(created.RoleID)
+				newRole, _ := datastore.GetRole(ctx, db, created.RoleID)
 				Expect(newRole.RoleName).To(Equal(roleName))
 			})
 		})
@@ -99,14 +126,14 @@ var _ = Describe("Role db interactions", func() {
 			var created *models.Role
 
 			AfterEach(func() {
-				datastore.DeleteRole(created.RoleID)
+				datastore.DeleteRole(ctx, db, created.RoleID)
 			})
 
 			It("should return an error object if role is not unique", func() {
 				name := "Test Name"
 				pln := models.Role{RoleName: name}
-				created, _ = datastore.CreateRole(pln)
-				_, err := datastore.CreateRole(pln)
+				created, _ = datastore.CreateRole(ctx, db, pln)
+				_, err := datastore.CreateRole(ctx, db, pln)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -124,12 +151,12 @@ var _ = Describe("Role db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				role = models.Role{RoleName: roleName}
-				created, _ = datastore.CreateRole(models.Role{RoleName: "Daily"})
-				updated, _ = datastore.UpdateRole(created.RoleID, role)
+				created, _ = datastore.CreateRole(ctx, db, models.Role{RoleName: "Daily"})
+				updated, _ = datastore.UpdateRole(ctx, db, created.RoleID, role)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteRole(updated.RoleID)
+				datastore.DeleteRole(ctx, db, updated.RoleID)
 			})
 
 			It("should return the updated role", func() {
@@ -140,7 +167,7 @@ var _ = Describe("Role db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				role = models.Role{RoleName: "Daily"}
-				updated, err = datastore.UpdateRole(10000, role)
+				updated, err = datastore.UpdateRole(ctx, db, 10000, role)
 			})
 
 			It("should return an error object if role to update doesn't exist", func() {
@@ -156,8 +183,8 @@ var _ = Describe("Role db interactions", func() {
 	Describe("DeleteRole", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				created, _ := datastore.CreateRole(models.Role{RoleName: "Testing"})
-				err := datastore.DeleteRole(created.RoleID)
+				created, _ := datastore.CreateRole(ctx, db, models.Role{RoleName: "Testing"})
+				err := datastore.DeleteRole(ctx, db, created.RoleID)
 				Expect(err).To(BeNil())
 			})
 		})
@@ -0,0 +1,167 @@
+package datastore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+// Scope limits queries to the rows a caller may see, based on who they are.
+// A caller with several roles sees everything any of their roles can.
+// Admins and employees see everything.
+type Scope struct {
+	// All turns scoping off, for lookups the API makes on its own behalf.
+	All    bool
+	UserID int64
+	// GymID is set when the caller is one of the gym's API keys rather
+	// than a user.
+	GymID int64
+	Roles []string
+	// Deleted also sees the members and gym locations that have been
+	// deleted. Only admins get to set it.
+	Deleted bool
+	// Write narrows the scope to the rows the caller may change. Locations
+	// and gyms see the members that visited them and everyone sees every
+	// gym location, but they can only change their own.
+	Write bool
+}
+
+// Unscoped sees every row.
+var Unscoped = Scope{All: true}
+
+func (s Scope) has(role string) bool {
+	for _, r := range s.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// condition ORs together the conditions of the caller's roles. Roles that
+// aren't in byRole don't see anything. The conditions are only rendered for
+// roles the caller has, so b doesn't get arguments the query never uses.
+func (s Scope) condition(byRole map[string]func() string) string {
+	if s.All || s.has(models.AdminRole) || s.has(models.EmployeeRole) {
+		return "TRUE"
+	}
+
+	var conditions []string
+	for _, role := range s.Roles {
+		render, ok := byRole[role]
+		if !ok {
+			continue
+		}
+		condition := render()
+		if condition == "TRUE" {
+			return "TRUE"
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if len(conditions) == 0 {
+		return "FALSE"
+	}
+
+	return fmt.Sprintf("(%s)", strings.Join(conditions, " OR "))
+}
+
+// live keeps deleted rows out, unless the scope asks for them.
+func (s Scope) live(condition string) string {
+	if s.Deleted {
+		return condition
+	}
+
+	return fmt.Sprintf("deleted_on IS NULL AND %s", condition)
+}
+
+func always() string {
+	return "TRUE"
+}
+
+// ownedGyms selects the gyms a gym user or API key belongs to.
+func (s Scope) ownedGyms(b *builder) string {
+	if s.GymID != 0 {
+		return fmt.Sprintf("SELECT %s::bigint", b.arg(s.GymID))
+	}
+
+	return fmt.Sprintf("SELECT gym_id FROM gyms WHERE user_id = %s", b.arg(s.UserID))
+}
+
+// managedLocations selects the locations a location user manages.
+func (s Scope) managedLocations(b *builder) string {
+	return fmt.Sprintf("SELECT gym_location_id FROM gym_locations WHERE user_id = %s", b.arg(s.UserID))
+}
+
+// ownedLocations selects every location of the gyms a gym user owns.
+func (s Scope) ownedLocations(b *builder) string {
+	return fmt.Sprintf("SELECT gym_location_id FROM gym_locations WHERE gym_id IN (%s)", s.ownedGyms(b))
+}
+
+func (s Scope) visits(b *builder) string {
+	return s.condition(map[string]func() string{
+		models.MemberRole: func() string {
+			return fmt.Sprintf("member_id IN (SELECT member_id FROM members WHERE user_id = %s)", b.arg(s.UserID))
+		},
+		models.LocationRole: func() string {
+			return fmt.Sprintf("gym_location_id IN (%s)", s.managedLocations(b))
+		},
+		models.GymRole: func() string {
+			return fmt.Sprintf("gym_location_id IN (%s)", s.ownedLocations(b))
+		},
+	})
+}
+
+// members lets locations and gyms see the members that have visited them,
+// but only members can change themselves.
+func (s Scope) members(b *builder) string {
+	byRole := map[string]func() string{
+		models.MemberRole: func() string {
+			return fmt.Sprintf("user_id = %s", b.arg(s.UserID))
+		},
+	}
+	if !s.Write {
+		byRole[models.LocationRole] = func() string {
+			return fmt.Sprintf("member_id IN (SELECT member_id FROM visits WHERE gym_location_id IN (%s))", s.managedLocations(b))
+		}
+		byRole[models.GymRole] = func() string {
+			return fmt.Sprintf("member_id IN (SELECT member_id FROM visits WHERE gym_location_id IN (%s))", s.ownedLocations(b))
+		}
+	}
+
+	return s.live(s.condition(byRole))
+}
+
+func (s Scope) users(b *builder) string {
+	self := func() string {
+		return fmt.Sprintf("user_id = %s", b.arg(s.UserID))
+	}
+
+	return s.condition(map[string]func() string{
+		models.MemberRole:   self,
+		models.LocationRole: self,
+		models.GymRole:      self,
+	})
+}
+
+// gymLocations are a public directory for members, only gyms are limited to
+// their own locations. Changing one takes managing it or owning its gym.
+func (s Scope) gymLocations(b *builder) string {
+	byRole := map[string]func() string{
+		models.MemberRole:   always,
+		models.LocationRole: always,
+		models.GymRole: func() string {
+			return fmt.Sprintf("gym_id IN (%s)", s.ownedGyms(b))
+		},
+	}
+	if s.Write {
+		delete(byRole, models.MemberRole)
+		byRole[models.LocationRole] = func() string {
+			return fmt.Sprintf("gym_location_id IN (%s)", s.managedLocations(b))
+		}
+	}
+
+	return s.live(s.condition(byRole))
+}
@@ -0,0 +1,136 @@
+package datastore_test
+
+import (
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scope", func() {
+	var (
+		visits       []models.Visit
+		members      []models.Member
+		users        []models.User
+		gymLocations []models.GymLocation
+	)
+
+	Describe("Unscoped", func() {
+		It("should see every visit", func() {
+			visits, _ = datastore.GetVisitList(ctx, db, datastore.Unscoped, datastore.Query{})
+			Expect(len(visits)).To(Equal(5))
+		})
+	})
+
+	Describe("Admin", func() {
+		It("should see every visit", func() {
+			scope := datastore.Scope{UserID: 1, Roles: []string{models.AdminRole}}
+			visits, _ = datastore.GetVisitList(ctx, db, scope, datastore.Query{})
+			Expect(len(visits)).To(Equal(5))
+		})
+	})
+
+	Describe("Member", func() {
+		var scope = datastore.Scope{UserID: 2, Roles: []string{models.MemberRole}}
+
+		It("should only see their own visits", func() {
+			visits, _ = datastore.GetVisitList(ctx, db, scope, datastore.Query{})
+			Expect(len(visits)).To(Equal(2))
+			for _, visit := range visits {
+				Expect(visit.MemberID).To(Equal(int64(2)))
+			}
+		})
+
+		It("should combine with the query's own conditions", func() {
+			visits, _ = datastore.GetVisitList(ctx, db, scope, datastore.Query{
+				Filters: []datastore.Filter{{Field: "gym_location_id", Operator: datastore.Equal, Value: 1}},
+				Sort:    []datastore.Sort{{Field: "visit_id"}},
+			})
+			Expect(len(visits)).To(Equal(1))
+		})
+
+		It("should only count their own visits", func() {
+			count, _ := datastore.GetVisitCount(ctx, db, scope, datastore.Query{})
+			Expect(*count).To(Equal(2))
+		})
+
+		It("should only see their own member record", func() {
+			members, _ = datastore.GetMemberList(ctx, db, scope, datastore.Query{})
+			Expect(len(members)).To(Equal(1))
+			Expect(members[0].UserID).To(Equal(int64(2)))
+		})
+
+		It("should not get another member by id", func() {
+			member, err := datastore.GetMember(ctx, db, scope, 1)
+			Expect(err).ToNot(BeNil())
+			Expect(member).To(BeNil())
+		})
+
+		It("should only see their own user", func() {
+			users, _ = datastore.GetUserList(ctx, db, scope, datastore.Query{})
+			Expect(len(users)).To(Equal(1))
+		})
+
+		It("should see every gym location", func() {
+			gymLocations, _ = datastore.GetGymLocationList(ctx, db, scope, datastore.Query{})
+			Expect(len(gymLocations)).To(Equal(2))
+		})
+	})
+
+	Describe("Location", func() {
+		var scope = datastore.Scope{UserID: 2, Roles: []string{models.LocationRole}}
+
+		BeforeEach(func() {
+			db.Exec("UPDATE gym_locations SET user_id = 2 WHERE gym_location_id = 2")
+		})
+
+		AfterEach(func() {
+			db.Exec("UPDATE gym_locations SET user_id = NULL WHERE gym_location_id = 2")
+		})
+
+		It("should only see visits at their location", func() {
+			visits, _ = datastore.GetVisitList(ctx, db, scope, datastore.Query{})
+			Expect(len(visits)).To(Equal(2))
+			for _, visit := range visits {
+				Expect(visit.GymLocationID).To(Equal(int64(2)))
+			}
+		})
+
+		It("should see the members that visited their location", func() {
+			members, _ = datastore.GetMemberList(ctx, db, scope, datastore.Query{})
+			Expect(len(members)).To(Equal(2))
+		})
+
+		It("should only change their own location", func() {
+			write := scope
+			write.Write = true
+			gymLocations, _ = datastore.GetGymLocationList(ctx, db, write, datastore.Query{})
+			Expect(len(gymLocations)).To(Equal(1))
+			Expect(gymLocations[0].GymLocationID).To(Equal(int64(2)))
+		})
+	})
+
+	Describe("Gym", func() {
+		It("should see visits at all of its locations", func() {
+			visits, _ = datastore.GetVisitList(ctx, db, datastore.Scope{GymID: 1, Roles: []string{models.GymRole}}, datastore.Query{})
+			Expect(len(visits)).To(Equal(5))
+		})
+
+		It("should only see its own locations", func() {
+			gymLocations, _ = datastore.GetGymLocationList(ctx, db, datastore.Scope{GymID: 2, Roles: []string{models.GymRole}}, datastore.Query{})
+			Expect(len(gymLocations)).To(Equal(0))
+		})
+
+		It("should not see anything without a gym", func() {
+			visits, _ = datastore.GetVisitList(ctx, db, datastore.Scope{UserID: 2, Roles: []string{models.GymRole}}, datastore.Query{})
+			Expect(len(visits)).To(Equal(0))
+		})
+	})
+
+	Describe("No roles", func() {
+		It("should not see anything", func() {
+			visits, _ = datastore.GetVisitList(ctx, db, datastore.Scope{UserID: 1}, datastore.Query{})
+			Expect(len(visits)).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,211 @@
+package datastore
+
+import (
+	"context"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+func GetSession(ctx context.Context, db DB, sessionID int64) (*models.Session, error) {
+	var session models.Session
+
+	row := db.QueryRowContext(ctx, getSessionQuery, sessionID)
+	err := row.Scan(
+		&session.SessionID,
+		&session.UserID,
+		&session.RefreshTokenHash,
+		&session.CreatedOn,
+		&session.ExpiresOn,
+		&session.RevokedOn,
+		&session.TwoFactor,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func GetSessionByTokenHash(ctx context.Context, db DB, refreshTokenHash string) (*models.Session, error) {
+	var session models.Session
+
+	row := db.QueryRowContext(ctx, getSessionByTokenHashQuery, refreshTokenHash)
+	err := row.Scan(
+		&session.SessionID,
+		&session.UserID,
+		&session.RefreshTokenHash,
+		&session.CreatedOn,
+		&session.ExpiresOn,
+		&session.RevokedOn,
+		&session.TwoFactor,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func CreateSession(ctx context.Context, db DB, session models.Session) (*models.Session, error) {
+	var created models.Session
+
+	row := db.QueryRowContext(
+		ctx,
+		createSessionQuery,
+		session.UserID,
+		session.RefreshTokenHash,
+		session.ExpiresOn,
+		session.TwoFactor,
+	)
+	err := row.Scan(
+		&created.SessionID,
+		&created.UserID,
+		&created.RefreshTokenHash,
+		&created.CreatedOn,
+		&created.ExpiresOn,
+		&created.RevokedOn,
+		&created.TwoFactor,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func UpdateSession(ctx context.Context, db DB, sessionID int64, session models.Session) (*models.Session, error) {
+	var updated models.Session
+
+	row := db.QueryRowContext(
+		ctx,
+		updateSessionQuery,
+		session.RefreshTokenHash,
+		session.ExpiresOn,
+		session.RevokedOn,
+		session.TwoFactor,
+		sessionID,
+	)
+	err := row.Scan(
+		&updated.SessionID,
+		&updated.UserID,
+		&updated.RefreshTokenHash,
+		&updated.CreatedOn,
+		&updated.ExpiresOn,
+		&updated.RevokedOn,
+		&updated.TwoFactor,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// RotateSession updates the session like UpdateSession, but only if it's
+// still open and its refresh token hash is still refreshTokenHash. It
+// returns sql.ErrNoRows if not, which means the old refresh token was
+// already used.
+func RotateSession(ctx context.Context, db DB, sessionID int64, refreshTokenHash string, session models.Session) (*models.Session, error) {
+	var rotated models.Session
+
+	row := db.QueryRowContext(
+		ctx,
+		rotateSessionQuery,
+		session.RefreshTokenHash,
+		session.ExpiresOn,
+		sessionID,
+		refreshTokenHash,
+	)
+	err := row.Scan(
+		&rotated.SessionID,
+		&rotated.UserID,
+		&rotated.RefreshTokenHash,
+		&rotated.CreatedOn,
+		&rotated.ExpiresOn,
+		&rotated.RevokedOn,
+		&rotated.TwoFactor,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rotated, nil
+}
+
+func RevokeSession(ctx context.Context, db DB, sessionID int64) error {
+	return execOne(ctx, db, revokeSessionQuery, time.Now(), sessionID)
+}
+
+// RevokeUserSessions revokes every open session belonging to userID.
+func RevokeUserSessions(ctx context.Context, db DB, userID int64) error {
+	_, err := db.ExecContext(ctx, revokeUserSessionsQuery, time.Now(), userID)
+	return err
+}
+
+// RevokeOtherUserSessions revokes every open session belonging to userID
+// but sessionID.
+func RevokeOtherUserSessions(ctx context.Context, db DB, userID int64, sessionID int64) error {
+	_, err := db.ExecContext(ctx, revokeOtherUserSessionsQuery, time.Now(), userID, sessionID)
+	return err
+}
+
+func DeleteSession(ctx context.Context, db DB, sessionID int64) error {
+	return execOne(ctx, db, deleteSessionQuery, sessionID)
+}
+
+const getSessionQuery = `
+SELECT session_id, user_id, refresh_token_hash, created_on, expires_on, revoked_on, two_factor
+FROM sessions
+WHERE session_id = $1
+`
+
+const getSessionByTokenHashQuery = `
+SELECT session_id, user_id, refresh_token_hash, created_on, expires_on, revoked_on, two_factor
+FROM sessions
+WHERE refresh_token_hash = $1
+`
+
+const createSessionQuery = `
+INSERT INTO sessions (user_id, refresh_token_hash, expires_on, two_factor)
+VALUES ($1, $2, $3, $4)
+RETURNING session_id, user_id, refresh_token_hash, created_on, expires_on, revoked_on, two_factor
+`
+
+const updateSessionQuery = `
+UPDATE sessions
+SET refresh_token_hash = $1, expires_on = $2, revoked_on = $3, two_factor = $4
+WHERE session_id = $5
+RETURNING session_id, user_id, refresh_token_hash, created_on, expires_on, revoked_on, two_factor
+`
+
+const rotateSessionQuery = `
+UPDATE sessions
+SET refresh_token_hash = $1, expires_on = $2
+WHERE session_id = $3 AND refresh_token_hash = $4 AND revoked_on IS NULL
+RETURNING session_id, user_id, refresh_token_hash, created_on, expires_on, revoked_on, two_factor
+`
+
+const revokeSessionQuery = `
+UPDATE sessions
+SET revoked_on = $1
+WHERE session_id = $2 AND revoked_on IS NULL
+`
+
+const revokeUserSessionsQuery = `
+UPDATE sessions
+SET revoked_on = $1
+WHERE user_id = $2 AND revoked_on IS NULL
+`
+
+const revokeOtherUserSessionsQuery = `
+UPDATE sessions
+SET revoked_on = $1
+WHERE user_id = $2 AND session_id <> $3 AND revoked_on IS NULL
+`
+
+const deleteSessionQuery = `
+DELETE
+FROM sessions
+WHERE session_id = $1
+`
@@ -0,0 +1,173 @@
+package datastore_test
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Session db interactions", func() {
+	var (
+		userID  int64 = 1
+		session *models.Session
+	)
+
+	BeforeEach(func() {
+		session, _ = datastore.CreateSession(ctx, db, models.Session{
+			UserID:           userID,
+			RefreshTokenHash: "testhash",
+			ExpiresOn:        time.Now().Add(time.Hour),
+		})
+	})
+
+	AfterEach(func() {
+		datastore.DeleteSession(ctx, db, session.SessionID)
+	})
+
+	Describe("GetSession", func() {
+		Describe("Successful call", func() {
+			It("should return the correct session", func() {
+				sess, _ := datastore.GetSession(ctx, db, session.SessionID)
+				Expect(sess.UserID).To(Equal(userID))
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			var (
+				nonExistentID int64 = 99999
+				sess          *models.Session
+				err           error
+			)
+
+			BeforeEach(func() {
+				sess, err = datastore.GetSession(ctx, db, nonExistentID)
+			})
+
+			It("should return an error", func() {
+				Expect(err).ToNot(BeNil())
+			})
+
+			It("should return a nil session", func() {
+				Expect(sess).To(BeNil())
+			})
+		})
+	})
+
+	Describe("GetSessionByTokenHash", func() {
+		Describe("Successful call", func() {
+			It("should return the correct session", func() {
+				sess, _ := datastore.GetSessionByTokenHash(ctx, db, "testhash")
+				Expect(sess.SessionID).To(Equal(session.SessionID))
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return an error", func() {
+				_, err := datastore.GetSessionByTokenHash(ctx, db, "nonexistent")
+				Expect(err).ToNot(BeNil())
+			})
+		})
+	})
+
+	Describe("CreateSession", func() {
+		Describe("Successful call", func() {
+			It("should return the created session", func() {
+				Expect(session.SessionID).ToNot(Equal(int64(0)))
+				Expect(session.RevokedOn).To(BeNil())
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return an error if the token hash is not unique", func() {
+				_, err := datastore.CreateSession(ctx, db, models.Session{
+					UserID:           userID,
+					RefreshTokenHash: "testhash",
+					ExpiresOn:        time.Now().Add(time.Hour),
+				})
+				Expect(err).ToNot(BeNil())
+			})
+		})
+	})
+
+	Describe("UpdateSession", func() {
+		Describe("Successful call", func() {
+			It("should return the updated session", func() {
+				session.RefreshTokenHash = "rotatedhash"
+				updated, _ := datastore.UpdateSession(ctx, db, session.SessionID, *session)
+				Expect(updated.RefreshTokenHash).To(Equal("rotatedhash"))
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return an error if the session doesn't exist", func() {
+				updated, err := datastore.UpdateSession(ctx, db, 99999, *session)
+				Expect(err).ToNot(BeNil())
+				Expect(updated).To(BeNil())
+			})
+		})
+	})
+
+	Describe("RotateSession", func() {
+		var rotated models.Session
+
+		BeforeEach(func() {
+			rotated = *session
+			rotated.RefreshTokenHash = "rotatedhash"
+		})
+
+		Describe("Successful call", func() {
+			It("should return the rotated session", func() {
+				updated, err := datastore.RotateSession(ctx, db, session.SessionID, "testhash", rotated)
+				Expect(err).To(BeNil())
+				Expect(updated.RefreshTokenHash).To(Equal("rotatedhash"))
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return sql.ErrNoRows if the token was already rotated", func() {
+				datastore.RotateSession(ctx, db, session.SessionID, "testhash", rotated)
+				rotated.RefreshTokenHash = "otherhash"
+				updated, err := datastore.RotateSession(ctx, db, session.SessionID, "testhash", rotated)
+				Expect(err).To(Equal(sql.ErrNoRows))
+				Expect(updated).To(BeNil())
+			})
+		})
+	})
+
+	Describe("RevokeSession", func() {
+		Describe("Successful call", func() {
+			It("should mark the session as revoked", func() {
+				err := datastore.RevokeSession(ctx, db, session.SessionID)
+				Expect(err).To(BeNil())
+
+				revoked, _ := datastore.GetSession(ctx, db, session.SessionID)
+				Expect(revoked.RevokedOn).ToNot(BeNil())
+			})
+		})
+	})
+
+	Describe("RevokeUserSessions", func() {
+		Describe("Successful call", func() {
+			It("should revoke the user's sessions", func() {
+				err := datastore.RevokeUserSessions(ctx, db, session.UserID)
+				Expect(err).To(BeNil())
+
+				revoked, _ := datastore.GetSession(ctx, db, session.SessionID)
+				Expect(revoked.RevokedOn).ToNot(BeNil())
+			})
+		})
+	})
+
+	Describe("DeleteSession", func() {
+		Describe("Successful call", func() {
+			It("should return nil", func() {
+				err := datastore.DeleteSession(ctx, db, session.SessionID)
+				Expect(err).To(BeNil())
+			})
+		})
+	})
+})
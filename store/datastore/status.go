@@ -1,26 +1,28 @@
 package datastore
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetStatusList(where string) ([]models.Status, error) {
+func GetStatusList(ctx context.Context, db DB, q Query) ([]models.Status, error) {
 	var (
 		statuses []models.Status
 		status   models.Status
 	)
 
-	query := fmt.Sprintf("%s %s", getStatusListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getStatusListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
 
 	for rows.Next() {
-		err = rows.Scan(&status.StatusID, &status.StatusName)
+		err = rows.Scan(&status.StatusID, &status.StatusName, &status.Version)
 		statuses = append(statuses, status)
 		if err != nil {
 			return nil, err
@@ -28,14 +30,15 @@ func GetStatusList(where string) ([]models.Status, error) {
 	}
 	defer rows.Close()
 
-	return statuses, nil
+	return statuses, rows.Err()
 }
 
-func GetStatusCount(where string) (*int, error) {
+func GetStatusCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getStatusCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getStatusCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 
 	if err != nil {
@@ -45,11 +48,11 @@ func GetStatusCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetStatus(statusID int64) (*models.Status, error) {
+func GetStatus(ctx context.Context, db DB, statusID int64) (*models.Status, error) {
 	var status models.Status
 
-	row := store.DB.QueryRow(getStatusQuery, statusID)
-	err := row.Scan(&status.StatusID, &status.StatusName)
+	row := db.QueryRowContext(ctx, getStatusQuery, statusID)
+	err := row.Scan(&status.StatusID, &status.StatusName, &status.Version)
 
 	if err != nil {
 		return nil, err
@@ -58,11 +61,11 @@ func GetStatus(statusID int64) (*models.Status, error) {
 	return &status, nil
 }
 
-func CreateStatus(status models.Status) (*models.Status, error) {
+func CreateStatus(ctx context.Context, db DB, status models.Status) (*models.Status, error) {
 	var created models.Status
 
-	row := store.DB.QueryRow(createStatusQuery, status.StatusName)
-	err := row.Scan(&created.StatusID, &created.StatusName)
+	row := db.QueryRowContext(ctx, createStatusQuery, status.StatusName)
+	err := row.Scan(&created.StatusID, &created.StatusName, &created.Version)
 	if err != nil {
 		return nil, err
 	}
@@ -70,11 +73,16 @@ func CreateStatus(status models.Status) (*models.Status, error) {
 	return &created, nil
 }
 
-func UpdateStatus(statusID int64, status models.Status) (*models.Status, error) {
+// UpdateStatus updates the status if it's at status.Version, or whatever
+// version it's at if that's 0.
+func UpdateStatus(ctx context.Context, db DB, statusID int64, status models.Status) (*models.Status, error) {
 	var updated models.Status
 
-	row := store.DB.QueryRow(updateStatusQuery, status.StatusName, statusID)
-	err := row.Scan(&updated.StatusID, &updated.StatusName)
+	row := db.QueryRowContext(ctx, updateStatusQuery, status.StatusName, statusID, status.Version)
+	err := row.Scan(&updated.StatusID, &updated.StatusName, &updated.Version)
+	if err == sql.ErrNoRows {
+		return nil, checkVersion(ctx, db, "statuses", "status_id", statusID, status.Version)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -82,18 +90,40 @@ func UpdateStatus(statusID int64, status models.Status) (*models.Status, error)
 	return &updated, nil
 }
 
-func DeleteStatus(statusID int64) error {
-	stmt, err := store.DB.Prepare(deleteStatusQuery)
+// PatchStatus only updates the fields of status that are listed in fields.
+func PatchStatus(ctx context.Context, db DB, statusID int64, status models.Status, fields []string) (*models.Status, error) {
+	var patched models.Status
+
+	b := &builder{}
+	set, err := b.set(map[string]interface{}{
+		"status_name": status.StatusName,
+	}, fields)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = stmt.Exec(statusID)
+	query := fmt.Sprintf(patchStatusQuery, set, b.arg(statusID), b.arg(status.Version))
+	row := db.QueryRowContext(ctx, query, b.args...)
+	err = row.Scan(&patched.StatusID, &patched.StatusName, &patched.Version)
+	if err == sql.ErrNoRows {
+		return nil, checkVersion(ctx, db, "statuses", "status_id", statusID, status.Version)
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return &patched, nil
+}
+
+// DeleteStatus deletes the status if it's at version, or whatever version
+// it's at if that's 0.
+func DeleteStatus(ctx context.Context, db DB, statusID int64, version int64) error {
+	err := execOne(ctx, db, deleteStatusQuery, statusID, version)
+	if err == sql.ErrNoRows {
+		return checkVersion(ctx, db, "statuses", "status_id", statusID, version)
+	}
+
+	return err
 }
 
 const getStatusListQuery = `
@@ -110,20 +140,27 @@ WHERE status_id = $1
 const createStatusQuery = `
 INSERT INTO statuses (status_name)
 VALUES ($1)
-RETURNING status_id, status_name
+RETURNING status_id, status_name, version
 `
 
 const updateStatusQuery = `
 UPDATE statuses
-SET status_name = $1
-WHERE status_id = $2
-RETURNING status_id, status_name
+SET status_name = $1, version = version + 1
+WHERE status_id = $2 AND ($3 = 0 OR version = $3)
+RETURNING status_id, status_name, version
+`
+
+const patchStatusQuery = `
+UPDATE statuses
+SET %s
+WHERE status_id = %s AND (%[3]s = 0 OR version = %[3]s)
+RETURNING status_id, status_name, version
 `
 
 const deleteStatusQuery = `
 DELETE
 FROM statuses
-WHERE status_id = $1
+WHERE status_id = $1 AND ($2 = 0 OR version = $2)
 `
 
 const getStatusCountQuery = `
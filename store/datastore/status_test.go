@@ -1,13 +1,18 @@
 package datastore_test
 
 import (
+	"database/sql"
+
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 	"github.com/lukashambsch/anygym.api/store/datastore"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+func contains(field string, value string) datastore.Query {
+	return datastore.Query{Filters: []datastore.Filter{{Field: field, Operator: datastore.Contains, Value: value}}}
+}
+
 var _ = Describe("Status db interactions", func() {
 
 	Describe("GetStatusList", func() {
@@ -15,27 +20,37 @@ var _ = Describe("Status db interactions", func() {
 
 		Describe("Successful calls", func() {
 			It("should return a list of all statuses", func() {
-				statuses, _ = datastore.GetStatusList("")
+				statuses, _ = datastore.GetStatusList(ctx, db, datastore.Query{})
 				Expect(len(statuses)).To(Equal(6))
 			})
 
 			It("should return a list of one partially matching status", func() {
-				statuses, _ = datastore.GetStatusList("WHERE status_name LIKE '%Pend%'")
+				statuses, _ = datastore.GetStatusList(ctx, db, contains("status_name", "Pend"))
 				Expect(len(statuses)).To(Equal(1))
 			})
 
 			It("should return a list of one exact match status", func() {
-				statuses, _ = datastore.GetStatusList("WHERE status_name LIKE '%Pending%'")
+				statuses, _ = datastore.GetStatusList(ctx, db, contains("status_name", "Pending"))
 				Expect(len(statuses)).To(Equal(1))
 			})
 
 			It("should return a list of multiple matching statuses", func() {
-				statuses, _ = datastore.GetStatusList("WHERE status_name LIKE '%Denied%'")
+				statuses, _ = datastore.GetStatusList(ctx, db, contains("status_name", "Denied"))
 				Expect(len(statuses)).To(Equal(2))
 			})
 
+			It("should not treat wildcards in the value as wildcards", func() {
+				statuses, _ = datastore.GetStatusList(ctx, db, contains("status_name", "%"))
+				Expect(len(statuses)).To(Equal(0))
+			})
+
+			It("should not run sql in the value", func() {
+				statuses, _ = datastore.GetStatusList(ctx, db, contains("status_name", "' OR '1'='1"))
+				Expect(len(statuses)).To(Equal(0))
+			})
+
 			It("should match status_id", func() {
-				statuses, _ = datastore.GetStatusList("WHERE status_id = '1'")
+				statuses, _ = datastore.GetStatusList(ctx, db, datastore.Where("status_id", 1))
 				Expect(len(statuses)).To(Equal(1))
 			})
 		})
@@ -48,7 +63,7 @@ var _ = Describe("Status db interactions", func() {
 			var statusID int64 = 1
 
 			It("should return the correct status", func() {
-				status, _ = datastore.GetStatus(statusID)
+				status, _ = datastore.GetStatus(ctx, db, statusID)
 				Expect(status.StatusID).To(Equal(statusID))
 			})
 		})
@@ -60,7 +75,7 @@ var _ = Describe("Status db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				status, err = datastore.GetStatus(nonExistentID)
+				status, err = datastore.GetStatus(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -78,7 +93,7 @@ var _ = Describe("Status db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetStatusCount("")
+				count, _ = datastore.GetStatusCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -98,11 +113,11 @@ var _ = Describe("Status db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				status = models.Status{StatusName: statusName}
-				created, _ = datastore.CreateStatus(status)
+				created, _ = datastore.CreateStatus(ctx, db, status)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteStatus(created.StatusID)
+				datastore.DeleteStatus(ctx, db, created.StatusID, 0)
 			})
 
 			It("should return the created status", func() {
@@ -110,7 +125,7 @@ var _ = Describe("Status db interactions", func() {
 			})
 
 			It("should add a status to the db", func() {
-				newStatus, _ := datastore.GetStatus(created.StatusID)
+				newStatus, _ := datastore.GetStatus(ctx, db, created.StatusID)
 				Expect(newStatus.StatusName).To(Equal(statusName))
 			})
 		})
@@ -118,7 +133,7 @@ var _ = Describe("Status db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				status = models.Status{StatusName: "Pending"}
-				created, err = datastore.CreateStatus(status)
+				created, err = datastore.CreateStatus(ctx, db, status)
 			})
 
 			It("should return an error", func() {
@@ -143,23 +158,48 @@ var _ = Describe("Status db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				status = models.Status{StatusName: statusName}
-				created, _ = datastore.CreateStatus(models.Status{StatusName: "Created"})
-				updated, _ = datastore.UpdateStatus(created.StatusID, status)
+				created, _ = datastore.CreateStatus(ctx, db, models.Status{StatusName: "Created"})
+				updated, _ = datastore.UpdateStatus(ctx, db, created.StatusID, status)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteStatus(updated.StatusID)
+				datastore.DeleteStatus(ctx, db, updated.StatusID, 0)
 			})
 
 			It("should return the updated status", func() {
 				Expect(updated.StatusName).To(Equal(statusName))
 			})
+
+			It("should bump the version", func() {
+				Expect(updated.Version).To(Equal(created.Version + 1))
+			})
+		})
+
+		Describe("Version mismatch", func() {
+			BeforeEach(func() {
+				created, _ = datastore.CreateStatus(ctx, db, models.Status{StatusName: "Created"})
+				status = models.Status{StatusName: statusName, Version: created.Version + 1}
+				updated, err = datastore.UpdateStatus(ctx, db, created.StatusID, status)
+			})
+
+			AfterEach(func() {
+				datastore.DeleteStatus(ctx, db, created.StatusID, 0)
+			})
+
+			It("should return ErrVersionMismatch", func() {
+				Expect(err).To(Equal(datastore.ErrVersionMismatch))
+			})
+
+			It("should leave the status as it was", func() {
+				status, _ := datastore.GetStatus(ctx, db, created.StatusID)
+				Expect(status.StatusName).To(Equal("Created"))
+			})
 		})
 
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				status = models.Status{StatusName: "Pending"}
-				updated, err = datastore.UpdateStatus(2, status)
+				updated, err = datastore.UpdateStatus(ctx, db, 2, status)
 			})
 
 			It("should return an error object", func() {
@@ -172,6 +212,41 @@ var _ = Describe("Status db interactions", func() {
 		})
 	})
 
+	Describe("PatchStatus", func() {
+		var created *models.Status
+
+		BeforeEach(func() {
+			created, _ = datastore.CreateStatus(ctx, db, models.Status{StatusName: "Created"})
+		})
+
+		AfterEach(func() {
+			datastore.DeleteStatus(ctx, db, created.StatusID, 0)
+		})
+
+		It("should update the fields listed", func() {
+			patched, err := datastore.PatchStatus(ctx, db, created.StatusID, models.Status{StatusName: "Patched"}, []string{"status_name"})
+			Expect(err).To(BeNil())
+			Expect(patched.StatusName).To(Equal("Patched"))
+			Expect(patched.Version).To(Equal(created.Version + 1))
+		})
+
+		It("should leave the fields that aren't listed", func() {
+			patched, _ := datastore.PatchStatus(ctx, db, created.StatusID, models.Status{StatusName: "Patched"}, nil)
+			Expect(patched.StatusName).To(Equal("Created"))
+		})
+
+		It("should return an error for a field that isn't a column", func() {
+			_, err := datastore.PatchStatus(ctx, db, created.StatusID, models.Status{}, []string{"status_id"})
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should return ErrVersionMismatch", func() {
+			status := models.Status{StatusName: "Patched", Version: created.Version + 1}
+			_, err := datastore.PatchStatus(ctx, db, created.StatusID, status, []string{"status_name"})
+			Expect(err).To(Equal(datastore.ErrVersionMismatch))
+		})
+	})
+
 	Describe("DeleteStatus", func() {
 		var (
 			statusID int64 = 3
@@ -180,11 +255,11 @@ var _ = Describe("Status db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				status, _ = datastore.GetStatus(statusID)
+				status, _ = datastore.GetStatus(ctx, db, statusID)
 			})
 
 			AfterEach(func() {
-				store.DB.QueryRow(
+				db.QueryRow(
 					"INSERT INTO statuses (status_id, status_name) VALUES ($1, $2)",
 					status.StatusID,
 					status.StatusName,
@@ -192,9 +267,21 @@ var _ = Describe("Status db interactions", func() {
 			})
 
 			It("should return nil", func() {
-				err := datastore.DeleteStatus(statusID)
+				err := datastore.DeleteStatus(ctx, db, statusID, 0)
 				Expect(err).To(BeNil())
 			})
 		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return sql.ErrNoRows for a missing id", func() {
+				err := datastore.DeleteStatus(ctx, db, 5000, 0)
+				Expect(err).To(Equal(sql.ErrNoRows))
+			})
+
+			It("should return ErrVersionMismatch for another version", func() {
+				err := datastore.DeleteStatus(ctx, db, statusID, 5000)
+				Expect(err).To(Equal(datastore.ErrVersionMismatch))
+			})
+		})
 	})
 })
@@ -1,20 +1,21 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetSupportRequestList(where string) ([]models.SupportRequest, error) {
+func GetSupportRequestList(ctx context.Context, db DB, q Query) ([]models.SupportRequest, error) {
 	var (
 		supportRequests []models.SupportRequest
 		supportRequest  models.SupportRequest
 	)
 
-	query := fmt.Sprintf("%s %s", getSupportRequestListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getSupportRequestListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -36,14 +37,15 @@ func GetSupportRequestList(where string) ([]models.SupportRequest, error) {
 	}
 	defer rows.Close()
 
-	return supportRequests, nil
+	return supportRequests, rows.Err()
 }
 
-func GetSupportRequestCount(where string) (*int, error) {
+func GetSupportRequestCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getSupportRequestCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getSupportRequestCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 
 	if err != nil {
@@ -53,10 +55,10 @@ func GetSupportRequestCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetSupportRequest(supportRequestID int64) (*models.SupportRequest, error) {
+func GetSupportRequest(ctx context.Context, db DB, supportRequestID int64) (*models.SupportRequest, error) {
 	var supportRequest models.SupportRequest
 
-	row := store.DB.QueryRow(getSupportRequestQuery, supportRequestID)
+	row := db.QueryRowContext(ctx, getSupportRequestQuery, supportRequestID)
 	err := row.Scan(
 		&supportRequest.SupportRequestID,
 		&supportRequest.UserID,
@@ -74,10 +76,11 @@ func GetSupportRequest(supportRequestID int64) (*models.SupportRequest, error) {
 	return &supportRequest, nil
 }
 
-func CreateSupportRequest(supportRequest models.SupportRequest) (*models.SupportRequest, error) {
+func CreateSupportRequest(ctx context.Context, db DB, supportRequest models.SupportRequest) (*models.SupportRequest, error) {
 	var created models.SupportRequest
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		createSupportRequestQuery,
 		supportRequest.UserID,
 		supportRequest.SupportSourceID,
@@ -102,10 +105,11 @@ func CreateSupportRequest(supportRequest models.SupportRequest) (*models.Support
 	return &created, nil
 }
 
-func UpdateSupportRequest(supportRequestID int64, supportRequest models.SupportRequest) (*models.SupportRequest, error) {
+func UpdateSupportRequest(ctx context.Context, db DB, supportRequestID int64, supportRequest models.SupportRequest) (*models.SupportRequest, error) {
 	var updated models.SupportRequest
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		updateSupportRequestQuery,
 		supportRequest.UserID,
 		supportRequest.SupportSourceID,
@@ -131,18 +135,8 @@ func UpdateSupportRequest(supportRequestID int64, supportRequest models.SupportR
 	return &updated, nil
 }
 
-func DeleteSupportRequest(supportRequestID int64) error {
-	stmt, err := store.DB.Prepare(deleteSupportRequestQuery)
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(supportRequestID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func DeleteSupportRequest(ctx context.Context, db DB, supportRequestID int64) error {
+	return execOne(ctx, db, deleteSupportRequestQuery, supportRequestID)
 }
 
 const getSupportRequestListQuery = `
@@ -11,13 +11,13 @@ var _ = Describe("SupportRequest db interactions", func() {
 	var reqOne, reqTwo *models.SupportRequest
 
 	BeforeEach(func() {
-		reqOne, _ = datastore.CreateSupportRequest(models.SupportRequest{Content: "Test Content One"})
-		reqTwo, _ = datastore.CreateSupportRequest(models.SupportRequest{Content: "Test Content Two"})
+		reqOne, _ = datastore.CreateSupportRequest(ctx, db, models.SupportRequest{Content: "Test Content One"})
+		reqTwo, _ = datastore.CreateSupportRequest(ctx, db, models.SupportRequest{Content: "Test Content Two"})
 	})
 
 	AfterEach(func() {
-		datastore.DeleteSupportRequest(reqOne.SupportRequestID)
-		datastore.DeleteSupportRequest(reqTwo.SupportRequestID)
+		datastore.DeleteSupportRequest(ctx, db, reqOne.SupportRequestID)
+		datastore.DeleteSupportRequest(ctx, db, reqTwo.SupportRequestID)
 	})
 
 	Describe("GetSupportRequestList", func() {
@@ -25,7 +25,7 @@ var _ = Describe("SupportRequest db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				supportRequests, _ = datastore.GetSupportRequestList("")
+				supportRequests, _ = datastore.GetSupportRequestList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of supportRequests", func() {
@@ -39,7 +39,7 @@ var _ = Describe("SupportRequest db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct supportRequest", func() {
-				supportRequest, _ = datastore.GetSupportRequest(reqOne.SupportRequestID)
+				supportRequest, _ = datastore.GetSupportRequest(ctx, db, reqOne.SupportRequestID)
 				Expect(supportRequest.SupportRequestID).To(Equal(reqOne.SupportRequestID))
 			})
 		})
@@ -51,7 +51,7 @@ var _ = Describe("SupportRequest db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				supportRequest, err = datastore.GetSupportRequest(nonExistentID)
+				supportRequest, err = datastore.GetSupportRequest(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -69,7 +69,7 @@ var _ = Describe("SupportRequest db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetSupportRequestCount("")
+				count, _ = datastore.GetSupportRequestCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -89,11 +89,11 @@ var _ = Describe("SupportRequest db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				supportRequest = models.SupportRequest{Content: content}
-				created, _ = datastore.CreateSupportRequest(supportRequest)
+				created, _ = datastore.CreateSupportRequest(ctx, db, supportRequest)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteSupportRequest(created.SupportRequestID)
+				datastore.DeleteSupportRequest(ctx, db, created.SupportRequestID)
 			})
 
 			It("should return the created supportRequest", func() {
@@ -101,7 +101,7 @@ var _ = Describe("SupportRequest db interactions", func() {
 			})
 
 			It("should add a supportRequest to the db", func() {
-				newSupportRequest, _ := datastore.GetSupportRequest(created.SupportRequestID)
+				newSupportRequest, _ := datastore.GetSupportRequest(ctx, db, created.SupportRequestID)
 				Expect(newSupportRequest.Content).To(Equal(content))
 			})
 		})
@@ -109,11 +109,11 @@ var _ = Describe("SupportRequest db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				supportRequest = models.SupportRequest{}
-				_, err = datastore.CreateSupportRequest(supportRequest)
+				_, err = datastore.CreateSupportRequest(ctx, db, supportRequest)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteSupportRequest(created.SupportRequestID)
+				datastore.DeleteSupportRequest(ctx, db, created.SupportRequestID)
 			})
 
 			It("should return an error object", func() {
@@ -134,12 +134,12 @@ var _ = Describe("SupportRequest db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				supportRequest = models.SupportRequest{Content: content}
-				created, _ = datastore.CreateSupportRequest(models.SupportRequest{Content: "SupportRequest"})
-				updated, _ = datastore.UpdateSupportRequest(created.SupportRequestID, supportRequest)
+				created, _ = datastore.CreateSupportRequest(ctx, db, models.SupportRequest{Content: "SupportRequest"})
+				updated, _ = datastore.UpdateSupportRequest(ctx, db, created.SupportRequestID, supportRequest)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteSupportRequest(updated.SupportRequestID)
+				datastore.DeleteSupportRequest(ctx, db, updated.SupportRequestID)
 			})
 
 			It("should return the updated supportRequest", func() {
@@ -150,7 +150,7 @@ var _ = Describe("SupportRequest db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				supportRequest = models.SupportRequest{}
-				updated, err = datastore.UpdateSupportRequest(2000, supportRequest)
+				updated, err = datastore.UpdateSupportRequest(ctx, db, 2000, supportRequest)
 			})
 
 			It("should return an error object", func() {
@@ -166,8 +166,8 @@ var _ = Describe("SupportRequest db interactions", func() {
 	Describe("DeleteSupportRequest", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				created, _ := datastore.CreateSupportRequest(models.SupportRequest{Content: "Test"})
-				err := datastore.DeleteSupportRequest(created.SupportRequestID)
+				created, _ := datastore.CreateSupportRequest(ctx, db, models.SupportRequest{Content: "Test"})
+				err := datastore.DeleteSupportRequest(ctx, db, created.SupportRequestID)
 				Expect(err).To(BeNil())
 			})
 		})
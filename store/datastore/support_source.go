@@ -1,20 +1,21 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetSupportSourceList(where string) ([]models.SupportSource, error) {
+func GetSupportSourceList(ctx context.Context, db DB, q Query) ([]models.SupportSource, error) {
 	var (
 		supportSources []models.SupportSource
 		supportSource  models.SupportSource
 	)
 
-	query := fmt.Sprintf("%s %s", getSupportSourceListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getSupportSourceListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -28,14 +29,15 @@ func GetSupportSourceList(where string) ([]models.SupportSource, error) {
 	}
 	defer rows.Close()
 
-	return supportSources, nil
+	return supportSources, rows.Err()
 }
 
-func GetSupportSourceCount(where string) (*int, error) {
+func GetSupportSourceCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getSupportSourceCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getSupportSourceCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 	if err != nil {
 		return nil, err
@@ -44,10 +46,10 @@ func GetSupportSourceCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetSupportSource(supportSourceID int64) (*models.SupportSource, error) {
+func GetSupportSource(ctx context.Context, db DB, supportSourceID int64) (*models.SupportSource, error) {
 	var supportSource models.SupportSource
 
-	row := store.DB.QueryRow(getSupportSourceQuery, supportSourceID)
+	row := db.QueryRowContext(ctx, getSupportSourceQuery, supportSourceID)
 	err := row.Scan(&supportSource.SupportSourceID, &supportSource.SupportSourceName)
 	if err != nil {
 		return nil, err
@@ -56,10 +58,10 @@ func GetSupportSource(supportSourceID int64) (*models.SupportSource, error) {
 	return &supportSource, nil
 }
 
-func CreateSupportSource(supportSource models.SupportSource) (*models.SupportSource, error) {
+func CreateSupportSource(ctx context.Context, db DB, supportSource models.SupportSource) (*models.SupportSource, error) {
 	var created models.SupportSource
 
-	row := store.DB.QueryRow(createSupportSourceQuery, supportSource.SupportSourceName)
+	row := db.QueryRowContext(ctx, createSupportSourceQuery, supportSource.SupportSourceName)
 	err := row.Scan(&created.SupportSourceID, &created.SupportSourceName)
 	if err != nil {
 		return nil, err
@@ -68,10 +70,10 @@ func CreateSupportSource(supportSource models.SupportSource) (*models.SupportSou
 	return &created, nil
 }
 
-func UpdateSupportSource(supportSourceID int64, supportSource models.SupportSource) (*models.SupportSource, error) {
+func UpdateSupportSource(ctx context.Context, db DB, supportSourceID int64, supportSource models.SupportSource) (*models.SupportSource, error) {
 	var updated models.SupportSource
 
-	row := store.DB.QueryRow(updateSupportSourceQuery, supportSource.SupportSourceName, supportSourceID)
+	row := db.QueryRowContext(ctx, updateSupportSourceQuery, supportSource.SupportSourceName, supportSourceID)
 	err := row.Scan(&updated.SupportSourceID, &updated.SupportSourceName)
 	if err != nil {
 		return nil, err
@@ -80,18 +82,8 @@ func UpdateSupportSource(supportSourceID int64, supportSource models.SupportSour
 	return &updated, nil
 }
 
-func DeleteSupportSource(supportSourceID int64) error {
-	stmt, err := store.DB.Prepare(deleteSupportSourceQuery)
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(supportSourceID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func DeleteSupportSource(ctx context.Context, db DB, supportSourceID int64) error {
+	return execOne(ctx, db, deleteSupportSourceQuery, supportSourceID)
 }
 
 const getSupportSourceListQuery = `
@@ -15,7 +15,7 @@ var _ = Describe("SupportSource db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				supportSources, _ = datastore.GetSupportSourceList("")
+				supportSources, _ = datastore.GetSupportSourceList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of supportSources", func() {
@@ -29,7 +29,7 @@ var _ = Describe("SupportSource db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct supportSource", func() {
-				supportSource, _ = datastore.GetSupportSource(supportSourceID)
+				supportSource, _ = datastore.GetSupportSource(ctx, db, supportSourceID)
 				Expect(supportSource.SupportSourceID).To(Equal(supportSourceID))
 			})
 		})
@@ -41,7 +41,7 @@ var _ = Describe("SupportSource db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				supportSource, err = datastore.GetSupportSource(nonExistentID)
+				supportSource, err = datastore.GetSupportSource(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -59,7 +59,7 @@ var _ = Describe("SupportSource db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetSupportSourceCount("")
+				count, _ = datastore.GetSupportSourceCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -78,11 +78,11 @@ var _ = Describe("SupportSource db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				supportSource = models.SupportSource{SupportSourceName: supportSourceName}
-				created, _ = datastore.CreateSupportSource(supportSource)
+				created, _ = datastore.CreateSupportSource(ctx, db, supportSource)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteSupportSource(created.SupportSourceID)
+				datastore.DeleteSupportSource(ctx, db, created.SupportSourceID)
 			})
 
 			It("should return the created supportSource", func() {
@@ -90,7 +90,7 @@ var _ = Describe("SupportSource db interactions", func() {
 			})
 
 			It("should add a supportSource to the db", func() {
-				newSupportSource, _ := datastore.GetSupportSource(created.SupportSourceID)
+				newSupportSource, _ := datastore.GetSupportSource(ctx, db, created.SupportSourceID)
 				Expect(newSupportSource.SupportSourceName).To(Equal(supportSourceName))
 			})
 		})
@@ -99,14 +99,14 @@ var _ = Describe("SupportSource db interactions", func() {
 			var created *models.SupportSource
 
 			AfterEach(func() {
-				datastore.DeleteSupportSource(created.SupportSourceID)
+				datastore.DeleteSupportSource(ctx, db, created.SupportSourceID)
 			})
 
 			It("should return an error object if supportSource is not unique", func() {
 				name := "Test Name"
 				pln := models.SupportSource{SupportSourceName: name}
-				created, _ = datastore.CreateSupportSource(pln)
-				_, err := datastore.CreateSupportSource(pln)
+				created, _ = datastore.CreateSupportSource(ctx, db, pln)
+				_, err := datastore.CreateSupportSource(ctx, db, pln)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -124,12 +124,12 @@ var _ = Describe("SupportSource db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				supportSource = models.SupportSource{SupportSourceName: supportSourceName}
-				created, _ = datastore.CreateSupportSource(models.SupportSource{SupportSourceName: "Daily"})
-				updated, _ = datastore.UpdateSupportSource(created.SupportSourceID, supportSource)
+				created, _ = datastore.CreateSupportSource(ctx, db, models.SupportSource{SupportSourceName: "Daily"})
+				updated, _ = datastore.UpdateSupportSource(ctx, db, created.SupportSourceID, supportSource)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteSupportSource(updated.SupportSourceID)
+				datastore.DeleteSupportSource(ctx, db, updated.SupportSourceID)
 			})
 
 			It("should return the updated supportSource", func() {
@@ -140,7 +140,7 @@ var _ = Describe("SupportSource db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				supportSource = models.SupportSource{SupportSourceName: "Daily"}
-				updated, err = datastore.UpdateSupportSource(10000, supportSource)
+				updated, err = datastore.UpdateSupportSource(ctx, db, 10000, supportSource)
 			})
 
 			It("should return an error object if supportSource to update doesn't exist", func() {
@@ -156,8 +156,8 @@ var _ = Describe("SupportSource db interactions", func() {
 	Describe("DeleteSupportSource", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				created, _ := datastore.CreateSupportSource(models.SupportSource{SupportSourceName: "Testing"})
-				err := datastore.DeleteSupportSource(created.SupportSourceID)
+				created, _ := datastore.CreateSupportSource(ctx, db, models.SupportSource{SupportSourceName: "Testing"})
+				err := datastore.DeleteSupportSource(ctx, db, created.SupportSourceID)
 				Expect(err).To(BeNil())
 			})
 		})
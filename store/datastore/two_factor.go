@@ -0,0 +1,138 @@
+package datastore
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+func GetTwoFactor(ctx context.Context, db DB, userID int64) (*models.TwoFactor, error) {
+	var twoFactor models.TwoFactor
+
+	row := db.QueryRowContext(ctx, getTwoFactorQuery, userID)
+	err := row.Scan(
+		&twoFactor.UserID,
+		&twoFactor.Secret,
+		&twoFactor.CreatedOn,
+		&twoFactor.EnabledOn,
+		&twoFactor.LastStep,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &twoFactor, nil
+}
+
+// SaveTwoFactor starts a new enrolment for the user, replacing any earlier
+// one. It isn't enabled until EnableTwoFactor is called.
+func SaveTwoFactor(ctx context.Context, db DB, twoFactor models.TwoFactor) (*models.TwoFactor, error) {
+	var saved models.TwoFactor
+
+	row := db.QueryRowContext(ctx, saveTwoFactorQuery, twoFactor.UserID, twoFactor.Secret)
+	err := row.Scan(
+		&saved.UserID,
+		&saved.Secret,
+		&saved.CreatedOn,
+		&saved.EnabledOn,
+		&saved.LastStep,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &saved, nil
+}
+
+func EnableTwoFactor(ctx context.Context, db DB, userID int64) error {
+	return execOne(ctx, db, enableTwoFactorQuery, userID)
+}
+
+// UseTwoFactorStep records that a code for step was accepted. It returns
+// sql.ErrNoRows if a code for that step or a later one was already used.
+func UseTwoFactorStep(ctx context.Context, db DB, userID int64, step int64) error {
+	return execOne(ctx, db, useTwoFactorStepQuery, userID, step)
+}
+
+func DeleteTwoFactor(ctx context.Context, db DB, userID int64) error {
+	return execOne(ctx, db, deleteTwoFactorQuery, userID)
+}
+
+// ReplaceRecoveryCodes swaps all of the user's recovery codes for the new
+// hashes.
+func ReplaceRecoveryCodes(ctx context.Context, db DB, userID int64, codeHashes []string) error {
+	_, err := db.ExecContext(ctx, replaceRecoveryCodesQuery, userID, pq.Array(codeHashes))
+	return err
+}
+
+// UseRecoveryCode marks one of the user's recovery codes as used. It returns
+// sql.ErrNoRows if there is no such unused code.
+func UseRecoveryCode(ctx context.Context, db DB, userID int64, codeHash string) error {
+	return execOne(ctx, db, useRecoveryCodeQuery, userID, codeHash)
+}
+
+func GetRecoveryCodeCount(ctx context.Context, db DB, userID int64) (*int, error) {
+	var count int
+
+	row := db.QueryRowContext(ctx, getRecoveryCodeCountQuery, userID)
+	err := row.Scan(&count)
+	if err != nil {
+		return nil, err
+	}
+
+	return &count, nil
+}
+
+const getTwoFactorQuery = `
+SELECT user_id, secret, created_on, enabled_on, last_step
+FROM two_factors
+WHERE user_id = $1
+`
+
+const saveTwoFactorQuery = `
+INSERT INTO two_factors (user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, created_on = CURRENT_TIMESTAMP, enabled_on = NULL, last_step = NULL
+RETURNING user_id, secret, created_on, enabled_on, last_step
+`
+
+const enableTwoFactorQuery = `
+UPDATE two_factors
+SET enabled_on = CURRENT_TIMESTAMP
+WHERE user_id = $1
+`
+
+const useTwoFactorStepQuery = `
+UPDATE two_factors
+SET last_step = $2
+WHERE user_id = $1 AND (last_step IS NULL OR last_step < $2)
+`
+
+const deleteTwoFactorQuery = `
+DELETE
+FROM two_factors
+WHERE user_id = $1
+`
+
+const replaceRecoveryCodesQuery = `
+WITH deleted AS (
+    DELETE FROM recovery_codes WHERE user_id = $1
+)
+INSERT INTO recovery_codes (user_id, code_hash)
+SELECT $1, unnest($2::text[])
+`
+
+const useRecoveryCodeQuery = `
+UPDATE recovery_codes
+SET used_on = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND code_hash = $2 AND used_on IS NULL
+`
+
+const getRecoveryCodeCountQuery = `
+SELECT count(*)
+FROM recovery_codes
+WHERE user_id = $1 AND used_on IS NULL
+`
@@ -0,0 +1,90 @@
+package datastore_test
+
+import (
+	"database/sql"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TwoFactor db interactions", func() {
+	var (
+		userID    int64 = 2
+		twoFactor *models.TwoFactor
+	)
+
+	BeforeEach(func() {
+		twoFactor, _ = datastore.SaveTwoFactor(ctx, db, models.TwoFactor{UserID: userID, Secret: "JBSWY3DPEHPK3PXP"})
+	})
+
+	AfterEach(func() {
+		datastore.ReplaceRecoveryCodes(ctx, db, userID, nil)
+		datastore.DeleteTwoFactor(ctx, db, userID)
+	})
+
+	Describe("SaveTwoFactor", func() {
+		It("should not be enabled yet", func() {
+			Expect(twoFactor.Secret).To(Equal("JBSWY3DPEHPK3PXP"))
+			Expect(twoFactor.EnabledOn).To(BeNil())
+		})
+
+		It("should start over when saved again", func() {
+			datastore.EnableTwoFactor(ctx, db, userID)
+			twoFactor, _ = datastore.SaveTwoFactor(ctx, db, models.TwoFactor{UserID: userID, Secret: "KRSXG5CTMVRXEZLU"})
+			Expect(twoFactor.Secret).To(Equal("KRSXG5CTMVRXEZLU"))
+			Expect(twoFactor.EnabledOn).To(BeNil())
+		})
+	})
+
+	Describe("EnableTwoFactor", func() {
+		It("should set enabled_on", func() {
+			err := datastore.EnableTwoFactor(ctx, db, userID)
+			Expect(err).To(BeNil())
+			twoFactor, _ = datastore.GetTwoFactor(ctx, db, userID)
+			Expect(twoFactor.EnabledOn).ToNot(BeNil())
+		})
+	})
+
+	Describe("UseTwoFactorStep", func() {
+		It("should only accept a step once", func() {
+			Expect(datastore.UseTwoFactorStep(ctx, db, userID, 100)).To(BeNil())
+			Expect(datastore.UseTwoFactorStep(ctx, db, userID, 100)).To(Equal(sql.ErrNoRows))
+		})
+
+		It("should not accept an earlier step", func() {
+			datastore.UseTwoFactorStep(ctx, db, userID, 100)
+			Expect(datastore.UseTwoFactorStep(ctx, db, userID, 99)).To(Equal(sql.ErrNoRows))
+			Expect(datastore.UseTwoFactorStep(ctx, db, userID, 101)).To(BeNil())
+		})
+	})
+
+	Describe("DeleteTwoFactor", func() {
+		It("should remove the enrolment", func() {
+			datastore.DeleteTwoFactor(ctx, db, userID)
+			_, err := datastore.GetTwoFactor(ctx, db, userID)
+			Expect(err).To(Equal(sql.ErrNoRows))
+		})
+	})
+
+	Describe("Recovery codes", func() {
+		BeforeEach(func() {
+			datastore.ReplaceRecoveryCodes(ctx, db, userID, []string{"hash1", "hash2"})
+		})
+
+		It("should replace the old codes", func() {
+			datastore.ReplaceRecoveryCodes(ctx, db, userID, []string{"hash3"})
+			count, _ := datastore.GetRecoveryCodeCount(ctx, db, userID)
+			Expect(*count).To(Equal(1))
+			Expect(datastore.UseRecoveryCode(ctx, db, userID, "hash1")).To(Equal(sql.ErrNoRows))
+		})
+
+		It("should only accept a code once", func() {
+			Expect(datastore.UseRecoveryCode(ctx, db, userID, "hash1")).To(BeNil())
+			Expect(datastore.UseRecoveryCode(ctx, db, userID, "hash1")).To(Equal(sql.ErrNoRows))
+			count, _ := datastore.GetRecoveryCodeCount(ctx, db, userID)
+			Expect(*count).To(Equal(1))
+		})
+	})
+})
@@ -1,26 +1,31 @@
 package datastore
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetUserList(where string) ([]models.User, error) {
+func GetUserList(ctx context.Context, db DB, scope Scope, q Query) ([]models.User, error) {
 	var (
 		users []models.User
 		user  models.User
 	)
 
-	query := fmt.Sprintf("%s %s", getUserListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getUserListQuery, b.list(q, scope.users(b)))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 		err = rows.Scan(
 			&user.UserID,
@@ -28,28 +33,35 @@ func GetUserList(where string) ([]models.User, error) {
 			&user.Token,
 			&user.PasswordHash,
 			&user.CreatedOn,
+			&user.EmailVerifiedOn,
+			&user.Version,
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		user.Roles, err = GetUserRoles(user.UserID)
-		if err != nil {
-			return nil, err
-		}
-
 		users = append(users, user)
 	}
-	defer rows.Close()
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	err = withRoles(ctx, db, users)
+	if err != nil {
+		return nil, err
+	}
 
 	return users, nil
 }
 
-func GetUserCount(where string) (*int, error) {
+func GetUserCount(ctx context.Context, db DB, scope Scope, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getUserCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getUserCountQuery, b.where(q, scope.users(b)))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 
 	if err != nil {
@@ -59,22 +71,49 @@ func GetUserCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetUser(userID int64) (*models.User, error) {
+func GetUser(ctx context.Context, db DB, userID int64) (*models.User, error) {
+	var user models.User
+
+	row := db.QueryRowContext(ctx, getUserQuery, userID)
+	err := row.Scan(
+		&user.UserID,
+		&user.Email,
+		&user.Token,
+		&user.PasswordHash,
+		&user.CreatedOn,
+		&user.EmailVerifiedOn,
+		&user.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Roles, err = GetUserRoles(ctx, db, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func GetUserByEmail(ctx context.Context, db DB, email string) (*models.User, error) {
 	var user models.User
 
-	row := store.DB.QueryRow(getUserQuery, userID)
+	row := db.QueryRowContext(ctx, getUserByEmailQuery, email)
 	err := row.Scan(
 		&user.UserID,
 		&user.Email,
 		&user.Token,
 		&user.PasswordHash,
 		&user.CreatedOn,
+		&user.EmailVerifiedOn,
+		&user.Version,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	user.Roles, err = GetUserRoles(user.UserID)
+	user.Roles, err = GetUserRoles(ctx, db, user.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -82,23 +121,111 @@ func GetUser(userID int64) (*models.User, error) {
 	return &user, nil
 }
 
-func GetUserRoles(userID int64) ([]*models.Role, error) {
-	var roles []*models.Role
+func GetUserRoles(ctx context.Context, db DB, userID int64) ([]*models.Role, error) {
+	roles, err := getRolesByUser(ctx, db, []int64{userID})
+	if err != nil {
+		return nil, err
+	}
+
+	return roles[userID], nil
+}
+
+// getUsersByID loads the users with the given ids, and their roles, with two
+// queries however many ids there are.
+func getUsersByID(ctx context.Context, db DB, userIDs []int64) (map[int64]*models.User, error) {
+	var users []models.User
+
+	rows, err := db.QueryContext(ctx, getUsersByIDQuery, pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.User
+		err = rows.Scan(
+			&user.UserID,
+			&user.Email,
+			&user.Token,
+			&user.PasswordHash,
+			&user.CreatedOn,
+			&user.EmailVerifiedOn,
+			&user.Version,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	err = withRoles(ctx, db, users)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[int64]*models.User{}
+	for i := range users {
+		byID[users[i].UserID] = &users[i]
+	}
+
+	return byID, nil
+}
+
+// withRoles fills in the roles of every user with a single query.
+func withRoles(ctx context.Context, db DB, users []models.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	var userIDs []int64
+	for _, user := range users {
+		userIDs = append(userIDs, user.UserID)
+	}
+
+	roles, err := getRolesByUser(ctx, db, userIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range users {
+		users[i].Roles = roles[users[i].UserID]
+	}
+
+	return nil
+}
+
+func getRolesByUser(ctx context.Context, db DB, userIDs []int64) (map[int64][]*models.Role, error) {
+	roles := map[int64][]*models.Role{}
 
-	userRoles, err := GetUserRoleList(fmt.Sprintf("WHERE user_id = %v", userID))
+	rows, err := db.QueryContext(ctx, getRolesByUserQuery, pq.Array(userIDs))
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	for _, userRole := range userRoles {
-		roles = append(roles, userRole.Role)
+	for rows.Next() {
+		var (
+			userID int64
+			role   models.Role
+		)
+		err = rows.Scan(&userID, &role.RoleID, &role.RoleName)
+		if err != nil {
+			return nil, err
+		}
+
+		roles[userID] = append(roles[userID], &role)
 	}
 
-	fmt.Print(roles)
-	return roles, nil
+	return roles, rows.Err()
 }
 
-func CreateUser(user models.User) (*models.User, error) {
+func CreateUser(ctx context.Context, db DB, user models.User) (*models.User, error) {
 	var created models.User
 	var err error
 
@@ -107,11 +234,13 @@ func CreateUser(user models.User) (*models.User, error) {
 		return nil, err
 	}
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		createUserQuery,
 		user.Email,
 		user.Token,
 		user.PasswordHash,
+		user.EmailVerifiedOn,
 	)
 	err = row.Scan(
 		&created.UserID,
@@ -119,6 +248,8 @@ func CreateUser(user models.User) (*models.User, error) {
 		&created.Token,
 		&created.PasswordHash,
 		&created.CreatedOn,
+		&created.EmailVerifiedOn,
+		&created.Version,
 	)
 	if err != nil {
 		return nil, err
@@ -127,23 +258,26 @@ func CreateUser(user models.User) (*models.User, error) {
 	return &created, nil
 }
 
-func UpdateUser(userID int64, user models.User) (*models.User, error) {
+// UpdateUser updates the user if they're at user.Version, or whatever version
+// they're at if that's 0. Only the email is updated, the password hash is
+// only ever changed by UpdateUserPassword. A new email address has to be
+// verified again, so changing it clears email_verified_on.
+func UpdateUser(ctx context.Context, db DB, userID int64, user models.User) (*models.User, error) {
 	var updated models.User
 
-	row := store.DB.QueryRow(
-		updateUserQuery,
-		user.Email,
-		user.Token,
-		user.PasswordHash,
-		userID,
-	)
+	row := db.QueryRowContext(ctx, updateUserQuery, user.Email, userID, user.Version)
 	err := row.Scan(
 		&updated.UserID,
 		&updated.Email,
 		&updated.Token,
 		&updated.PasswordHash,
 		&updated.CreatedOn,
+		&updated.EmailVerifiedOn,
+		&updated.Version,
 	)
+	if err == sql.ErrNoRows {
+		return nil, checkVersion(ctx, db, "users", "user_id", userID, user.Version)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -151,48 +285,140 @@ func UpdateUser(userID int64, user models.User) (*models.User, error) {
 	return &updated, nil
 }
 
-func DeleteUser(userID int64) error {
-	stmt, err := store.DB.Prepare(deleteUserQuery)
+// PatchUser only updates the fields of user that are listed in fields.
+// Changing the email address clears email_verified_on, as UpdateUser does.
+func PatchUser(ctx context.Context, db DB, userID int64, user models.User, fields []string) (*models.User, error) {
+	var patched models.User
+
+	b := &builder{}
+	set, err := b.set(map[string]interface{}{
+		"email": user.Email,
+	}, fields)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for _, field := range fields {
+		if field == "email" {
+			set += fmt.Sprintf(", email_verified_on = CASE WHEN email = %s THEN email_verified_on END", b.arg(user.Email))
+		}
 	}
 
-	_, err = stmt.Exec(userID)
+	query := fmt.Sprintf(patchUserQuery, set, b.arg(userID), b.arg(user.Version))
+	row := db.QueryRowContext(ctx, query, b.args...)
+	err = row.Scan(
+		&patched.UserID,
+		&patched.Email,
+		&patched.Token,
+		&patched.PasswordHash,
+		&patched.CreatedOn,
+		&patched.EmailVerifiedOn,
+		&patched.Version,
+	)
+	if err == sql.ErrNoRows {
+		return nil, checkVersion(ctx, db, "users", "user_id", userID, user.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &patched, nil
+}
+
+// UpdateUserPassword hashes password and stores it as the user's new
+// password.
+func UpdateUserPassword(ctx context.Context, db DB, userID int64, password string) error {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return execOne(ctx, db, updateUserPasswordQuery, passwordHash, userID)
+}
+
+// VerifyUserEmail marks email as verified for the user, as long as it is
+// still their email address. It returns sql.ErrNoRows otherwise.
+func VerifyUserEmail(ctx context.Context, db DB, userID int64, email string) error {
+	return execOne(ctx, db, verifyUserEmailQuery, userID, email)
+}
+
+// DeleteUser deletes the user if they're at version, or whatever version
+// they're at if that's 0.
+func DeleteUser(ctx context.Context, db DB, userID int64, version int64) error {
+	err := execOne(ctx, db, deleteUserQuery, userID, version)
+	if err == sql.ErrNoRows {
+		return checkVersion(ctx, db, "users", "user_id", userID, version)
+	}
+
+	return err
 }
 
 const getUserListQuery = `
-SELECT *
+SELECT user_id, email, token, password_hash, created_on, email_verified_on, version
 FROM users
 `
 
 const getUserQuery = `
-SELECT *
+SELECT user_id, email, token, password_hash, created_on, email_verified_on, version
 FROM users
 WHERE user_id = $1
 `
 
+const getUsersByIDQuery = `
+SELECT user_id, email, token, password_hash, created_on, email_verified_on, version
+FROM users
+WHERE user_id = ANY($1)
+`
+
+const getRolesByUserQuery = `
+SELECT user_roles.user_id, roles.role_id, roles.role_name
+FROM user_roles
+JOIN roles USING (role_id)
+WHERE user_roles.user_id = ANY($1)
+ORDER BY user_roles.user_role_id
+`
+
+const getUserByEmailQuery = `
+SELECT user_id, email, token, password_hash, created_on, email_verified_on, version
+FROM users
+WHERE email = $1
+`
+
 const createUserQuery = `
-INSERT INTO users (email, token, password_hash)
-VALUES ($1, $2, $3)
-RETURNING user_id, email, token, password_hash, created_on
+INSERT INTO users (email, token, password_hash, email_verified_on)
+VALUES ($1, $2, $3, $4)
+RETURNING user_id, email, token, password_hash, created_on, email_verified_on, version
 `
 
 const updateUserQuery = `
 UPDATE users
-SET email = $1, token = $2, password_hash = $3
-WHERE user_id = $4
-RETURNING user_id, email, token, password_hash, created_on
+SET email = $1, email_verified_on = CASE WHEN email = $1 THEN email_verified_on END, version = version + 1
+WHERE user_id = $2 AND ($3 = 0 OR version = $3)
+RETURNING user_id, email, token, password_hash, created_on, email_verified_on, version
+`
+
+const patchUserQuery = `
+UPDATE users
+SET %s
+WHERE user_id = %s AND (%[3]s = 0 OR version = %[3]s)
+RETURNING user_id, email, token, password_hash, created_on, email_verified_on, version
+`
+
+const updateUserPasswordQuery = `
+UPDATE users
+SET password_hash = $1, version = version + 1
+WHERE user_id = $2
+`
+
+const verifyUserEmailQuery = `
+UPDATE users
+SET email_verified_on = COALESCE(email_verified_on, CURRENT_TIMESTAMP), version = version + 1
+WHERE user_id = $1 AND email = $2
 `
 
 const deleteUserQuery = `
 DELETE
 FROM users
-WHERE user_id = $1
+WHERE user_id = $1 AND ($2 = 0 OR version = $2)
 `
 
 const getUserCountQuery = `
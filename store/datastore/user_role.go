@@ -1,49 +1,47 @@
 package datastore
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetUserRoleList(where string) ([]models.UserRole, error) {
+func GetUserRoleList(ctx context.Context, db DB, q Query) ([]models.UserRole, error) {
 	var (
 		userRoles []models.UserRole
 		userRole  models.UserRole
 	)
 
-	query := fmt.Sprintf("%s %s", getUserRoleListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getUserRoleListQuery, b.list(q))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
 
 	for rows.Next() {
-		err = rows.Scan(&userRole.UserRoleID, &userRole.UserID, &userRole.RoleID)
-
-		if err != nil {
-			return nil, err
-		}
-
-		userRole.Role, err = GetRole(userRole.RoleID)
+		userRole.Role = &models.Role{}
+		err = rows.Scan(&userRole.UserRoleID, &userRole.UserID, &userRole.RoleID, &userRole.Role.RoleName)
 
 		if err != nil {
 			return nil, err
 		}
+		userRole.Role.RoleID = userRole.RoleID
 
 		userRoles = append(userRoles, userRole)
 	}
 	defer rows.Close()
 
-	return userRoles, nil
+	return userRoles, rows.Err()
 }
 
-func GetUserRoleCount(where string) (*int, error) {
+func GetUserRoleCount(ctx context.Context, db DB, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getUserRoleCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getUserRoleCountQuery, b.where(q))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 	if err != nil {
 		return nil, err
@@ -52,27 +50,24 @@ func GetUserRoleCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetUserRole(userRoleID int64) (*models.UserRole, error) {
+func GetUserRole(ctx context.Context, db DB, userRoleID int64) (*models.UserRole, error) {
 	var userRole models.UserRole
 
-	row := store.DB.QueryRow(getUserRoleQuery, userRoleID)
-	err := row.Scan(&userRole.UserRoleID, &userRole.UserID, &userRole.RoleID)
-	if err != nil {
-		return nil, err
-	}
-
-	userRole.Role, err = GetRole(userRole.RoleID)
+	userRole.Role = &models.Role{}
+	row := db.QueryRowContext(ctx, getUserRoleQuery, userRoleID)
+	err := row.Scan(&userRole.UserRoleID, &userRole.UserID, &userRole.RoleID, &userRole.Role.RoleName)
 	if err != nil {
 		return nil, err
 	}
+	userRole.Role.RoleID = userRole.RoleID
 
 	return &userRole, nil
 }
 
-func CreateUserRole(userRole models.UserRole) (*models.UserRole, error) {
+func CreateUserRole(ctx context.Context, db DB, userRole models.UserRole) (*models.UserRole, error) {
 	var created models.UserRole
 
-	row := store.DB.QueryRow(createUserRoleQuery, userRole.UserID, userRole.RoleID)
+	row := db.QueryRowContext(ctx, createUserRoleQuery, userRole.UserID, userRole.RoleID)
 	err := row.Scan(&created.UserRoleID, &created.UserID, &created.RoleID)
 	if err != nil {
 		return nil, err
@@ -81,10 +76,10 @@ func CreateUserRole(userRole models.UserRole) (*models.UserRole, error) {
 	return &created, nil
 }
 
-func UpdateUserRole(userRoleID int64, userRole models.UserRole) (*models.UserRole, error) {
+func UpdateUserRole(ctx context.Context, db DB, userRoleID int64, userRole models.UserRole) (*models.UserRole, error) {
 	var updated models.UserRole
 
-	row := store.DB.QueryRow(updateUserRoleQuery, userRole.UserID, userRole.RoleID, userRoleID)
+	row := db.QueryRowContext(ctx, updateUserRoleQuery, userRole.UserID, userRole.RoleID, userRoleID)
 	err := row.Scan(&updated.UserRoleID, &updated.UserID, &updated.RoleID)
 	if err != nil {
 		return nil, err
@@ -93,28 +88,20 @@ func UpdateUserRole(userRoleID int64, userRole models.UserRole) (*models.UserRol
 	return &updated, nil
 }
 
-func DeleteUserRole(userRoleID int64) error {
-	stmt, err := store.DB.Prepare(deleteUserRoleQuery)
-	if err != nil {
-		return err
-	}
-
-	_, err = stmt.Exec(userRoleID)
-	if err != nil {
-		return err
-	}
-
-	return nil
+func DeleteUserRole(ctx context.Context, db DB, userRoleID int64) error {
+	return execOne(ctx, db, deleteUserRoleQuery, userRoleID)
 }
 
 const getUserRoleListQuery = `
-SELECT *
+SELECT user_role_id, user_id, role_id, role_name
 FROM user_roles
+JOIN roles USING (role_id)
 `
 
 const getUserRoleQuery = `
-SELECT *
+SELECT user_role_id, user_id, role_id, role_name
 FROM user_roles
+JOIN roles USING (role_id)
 WHERE user_role_id = $1
 `
 
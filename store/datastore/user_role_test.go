@@ -15,12 +15,19 @@ var _ = Describe("UserRole db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				userRoles, _ = datastore.GetUserRoleList("")
+				userRoles, _ = datastore.GetUserRoleList(ctx, db, datastore.Query{})
 			})
 
 			It("should return a list of userRoles", func() {
 				Expect(len(userRoles)).To(Equal(2))
 			})
+
+			It("should include each role", func() {
+				for _, userRole := range userRoles {
+					Expect(userRole.Role.RoleID).To(Equal(userRole.RoleID))
+					Expect(userRole.Role.RoleName).ToNot(BeEmpty())
+				}
+			})
 		})
 	})
 
@@ -29,7 +36,7 @@ var _ = Describe("UserRole db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct userRole", func() {
-				userRole, _ = datastore.GetUserRole(userRoleID)
+				userRole, _ = datastore.GetUserRole(ctx, db, userRoleID)
 				Expect(userRole.UserRoleID).To(Equal(userRoleID))
 			})
 		})
@@ -41,7 +48,7 @@ var _ = Describe("UserRole db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				userRole, err = datastore.GetUserRole(nonExistentID)
+				userRole, err = datastore.GetUserRole(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -59,7 +66,7 @@ var _ = Describe("UserRole db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetUserRoleCount("")
+				count, _ = datastore.GetUserRoleCount(ctx, db, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -78,11 +85,11 @@ var _ = Describe("UserRole db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				userRole = models.UserRole{UserID: userID, RoleID: 5}
-				created, _ = datastore.CreateUserRole(userRole)
+				created, _ = datastore.CreateUserRole(ctx, db, userRole)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteUserRole(created.UserRoleID)
+				datastore.DeleteUserRole(ctx, db, created.UserRoleID)
 			})
 
 			It("should return the created userRole", func() {
@@ -90,7 +97,7 @@ var _ = Describe("UserRole db interactions", func() {
 			})
 
 			It("should add a userRole to the db", func() {
-				newUserRole, _ := datastore.GetUserRole(created.UserRoleID)
+				newUserRole, _ := datastore.GetUserRole(ctx, db, created.UserRoleID)
 				Expect(newUserRole.UserID).To(Equal(userID))
 			})
 		})
@@ -98,7 +105,7 @@ var _ = Describe("UserRole db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			It("should return an error object if userRole is not unique", func() {
 				usrRole := models.UserRole{UserID: userID, RoleID: 1}
-				_, err := datastore.CreateUserRole(usrRole)
+				_, err := datastore.CreateUserRole(ctx, db, usrRole)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -116,12 +123,12 @@ var _ = Describe("UserRole db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				userRole = models.UserRole{UserID: userID, RoleID: 5}
-				created, _ = datastore.CreateUserRole(models.UserRole{UserID: 1, RoleID: 5})
-				updated, _ = datastore.UpdateUserRole(created.UserRoleID, userRole)
+				created, _ = datastore.CreateUserRole(ctx, db, models.UserRole{UserID: 1, RoleID: 5})
+				updated, _ = datastore.UpdateUserRole(ctx, db, created.UserRoleID, userRole)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteUserRole(updated.UserRoleID)
+				datastore.DeleteUserRole(ctx, db, updated.UserRoleID)
 			})
 
 			It("should return the updated userRole", func() {
@@ -132,7 +139,7 @@ var _ = Describe("UserRole db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				userRole = models.UserRole{}
-				updated, err = datastore.UpdateUserRole(10000, userRole)
+				updated, err = datastore.UpdateUserRole(ctx, db, 10000, userRole)
 			})
 
 			It("should return an error object if userRole to update doesn't exist", func() {
@@ -148,8 +155,8 @@ var _ = Describe("UserRole db interactions", func() {
 	Describe("DeleteUserRole", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				created, _ := datastore.CreateUserRole(models.UserRole{UserID: 1, RoleID: 5})
-				err := datastore.DeleteUserRole(created.UserRoleID)
+				created, _ := datastore.CreateUserRole(ctx, db, models.UserRole{UserID: 1, RoleID: 5})
+				err := datastore.DeleteUserRole(ctx, db, created.UserRoleID)
 				Expect(err).To(BeNil())
 			})
 		})
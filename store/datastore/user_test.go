@@ -1,6 +1,8 @@
 package datastore_test
 
 import (
+	"golang.org/x/crypto/bcrypt"
+
 	"github.com/lukashambsch/anygym.api/models"
 	"github.com/lukashambsch/anygym.api/store/datastore"
 	. "github.com/onsi/ginkgo"
@@ -15,12 +17,19 @@ var _ = Describe("User db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				users, _ = datastore.GetUserList("")
+				users, _ = datastore.GetUserList(ctx, db, datastore.Unscoped, datastore.Query{})
 			})
 
 			It("should return a list of users", func() {
 				Expect(len(users)).To(Equal(2))
 			})
+
+			It("should load each user's own roles", func() {
+				for _, user := range users {
+					roles, _ := datastore.GetUserRoles(ctx, db, user.UserID)
+					Expect(user.Roles).To(Equal(roles))
+				}
+			})
 		})
 	})
 
@@ -29,7 +38,7 @@ var _ = Describe("User db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct user", func() {
-				user, _ = datastore.GetUser(userID)
+				user, _ = datastore.GetUser(ctx, db, userID)
 				Expect(user.UserID).To(Equal(userID))
 			})
 		})
@@ -41,7 +50,7 @@ var _ = Describe("User db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				user, err = datastore.GetUser(nonExistentID)
+				user, err = datastore.GetUser(ctx, db, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -54,13 +63,30 @@ var _ = Describe("User db interactions", func() {
 		})
 	})
 
+	Describe("GetUserByEmail", func() {
+		Describe("Successful call", func() {
+			It("should return the correct user", func() {
+				user, _ := datastore.GetUserByEmail(ctx, db, "lukas.hambsch@gmail.com")
+				Expect(user.UserID).To(Equal(userID))
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return an error", func() {
+				user, err := datastore.GetUserByEmail(ctx, db, "nobody@email.com")
+				Expect(err).ToNot(BeNil())
+				Expect(user).To(BeNil())
+			})
+		})
+	})
+
 	Describe("GetUserRoles", func() {
 		var user *models.User
 
 		Describe("Successful call", func() {
 			It("should return the list of the user's roles", func() {
-				user, _ = datastore.GetUser(userID)
-				roles, _ := datastore.GetUserRoles(user.UserID)
+				user, _ = datastore.GetUser(ctx, db, userID)
+				roles, _ := datastore.GetUserRoles(ctx, db, user.UserID)
 				Expect(len(roles)).To(Equal(1))
 			})
 		})
@@ -71,7 +97,7 @@ var _ = Describe("User db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetUserCount("")
+				count, _ = datastore.GetUserCount(ctx, db, datastore.Unscoped, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -90,11 +116,11 @@ var _ = Describe("User db interactions", func() {
 		Describe("Successful call", func() {
 			BeforeEach(func() {
 				user = models.User{Email: email}
-				created, _ = datastore.CreateUser(user)
+				created, _ = datastore.CreateUser(ctx, db, user)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteUser(created.UserID)
+				datastore.DeleteUser(ctx, db, created.UserID, 0)
 			})
 
 			It("should return the created user", func() {
@@ -102,7 +128,7 @@ var _ = Describe("User db interactions", func() {
 			})
 
 			It("should add a user to the db", func() {
-				newMember, _ := datastore.GetUser(created.UserID)
+				newMember, _ := datastore.GetUser(ctx, db, created.UserID)
 				Expect(newMember.Email).To(Equal(email))
 			})
 		})
@@ -110,7 +136,7 @@ var _ = Describe("User db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			It("should return an error object if no email", func() {
 				mbr := models.User{}
-				_, err := datastore.CreateUser(mbr)
+				_, err := datastore.CreateUser(ctx, db, mbr)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -127,24 +153,32 @@ var _ = Describe("User db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				created, _ = datastore.CreateUser(models.User{Email: "different"})
+				created, _ = datastore.CreateUser(ctx, db, models.User{Email: "different"})
 				created.Email = email
-				updated, _ = datastore.UpdateUser(created.UserID, *created)
+				updated, _ = datastore.UpdateUser(ctx, db, created.UserID, *created)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteUser(updated.UserID)
+				datastore.DeleteUser(ctx, db, updated.UserID, 0)
 			})
 
 			It("should return the updated user", func() {
 				Expect(updated.Email).To(Equal(email))
 			})
+
+			It("should keep the password hash", func() {
+				datastore.UpdateUserPassword(ctx, db, created.UserID, "testing")
+				before, _ := datastore.GetUser(ctx, db, created.UserID)
+				updated, _ = datastore.UpdateUser(ctx, db, created.UserID, models.User{Email: email})
+				Expect(updated.PasswordHash).NotTo(BeEmpty())
+				Expect(updated.PasswordHash).To(Equal(before.PasswordHash))
+			})
 		})
 
 		Describe("Unsuccessful call", func() {
 			BeforeEach(func() {
 				user = models.User{Email: email}
-				updated, err = datastore.UpdateUser(3, user)
+				updated, err = datastore.UpdateUser(ctx, db, 3, user)
 			})
 
 			It("should return an error object if user to update doesn't exist", func() {
@@ -157,11 +191,76 @@ var _ = Describe("User db interactions", func() {
 		})
 	})
 
+	Describe("PatchUser", func() {
+		It("should update the email and keep the password", func() {
+			created, _ := datastore.CreateUser(ctx, db, models.User{Email: "patch@email.com", Password: "testing"})
+			defer datastore.DeleteUser(ctx, db, created.UserID, 0)
+
+			patched, err := datastore.PatchUser(ctx, db, created.UserID, models.User{Email: "patched@email.com"}, []string{"email"})
+			Expect(err).To(BeNil())
+			Expect(patched.Email).To(Equal("patched@email.com"))
+			Expect(patched.PasswordHash).To(Equal(created.PasswordHash))
+		})
+	})
+
+	Describe("UpdateUserPassword", func() {
+		Describe("Successful call", func() {
+			It("should store a hash of the new password", func() {
+				created, _ := datastore.CreateUser(ctx, db, models.User{Email: "password@email.com", Password: "old"})
+				defer datastore.DeleteUser(ctx, db, created.UserID, 0)
+
+				err := datastore.UpdateUserPassword(ctx, db, created.UserID, "new")
+				Expect(err).To(BeNil())
+
+				updated, _ := datastore.GetUser(ctx, db, created.UserID)
+				Expect(bcrypt.CompareHashAndPassword(updated.PasswordHash, []byte("new"))).To(BeNil())
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return an error if the user doesn't exist", func() {
+				err := datastore.UpdateUserPassword(ctx, db, 99999, "new")
+				Expect(err).ToNot(BeNil())
+			})
+		})
+	})
+
+	Describe("VerifyUserEmail", func() {
+		var created *models.User
+
+		BeforeEach(func() {
+			created, _ = datastore.CreateUser(ctx, db, models.User{Email: "verify@email.com", Password: "testing"})
+		})
+
+		AfterEach(func() {
+			datastore.DeleteUser(ctx, db, created.UserID, 0)
+		})
+
+		Describe("Successful call", func() {
+			It("should mark the email as verified", func() {
+				Expect(created.EmailVerifiedOn).To(BeNil())
+
+				err := datastore.VerifyUserEmail(ctx, db, created.UserID, "verify@email.com")
+				Expect(err).To(BeNil())
+
+				verified, _ := datastore.GetUser(ctx, db, created.UserID)
+				Expect(verified.EmailVerifiedOn).ToNot(BeNil())
+			})
+		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return an error if the email has changed", func() {
+				err := datastore.VerifyUserEmail(ctx, db, created.UserID, "other@email.com")
+				Expect(err).ToNot(BeNil())
+			})
+		})
+	})
+
 	Describe("DeleteUser", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				created, _ := datastore.CreateUser(models.User{Email: "email"})
-				err := datastore.DeleteUser(created.UserID)
+				created, _ := datastore.CreateUser(ctx, db, models.User{Email: "email"})
+				err := datastore.DeleteUser(ctx, db, created.UserID, 0)
 				Expect(err).To(BeNil())
 			})
 		})
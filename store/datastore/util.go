@@ -0,0 +1,94 @@
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrVersionMismatch is returned by updates and deletes that were only to go
+// ahead if the row was still at the version they were given, when it has been
+// changed since.
+var ErrVersionMismatch = errors.New("the row was changed by someone else")
+
+// DB is what the queries run on: a *sql.DB, or a *sql.Tx to run several of
+// them atomically.
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// execOne runs a statement that is expected to change a row and returns
+// sql.ErrNoRows if it didn't change any.
+func execOne(ctx context.Context, db DB, query string, args ...interface{}) error {
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// softDeleted are the tables rows are only marked deleted in. Writes don't
+// see the deleted rows.
+var softDeleted = map[string]bool{
+	"gyms":          true,
+	"gym_locations": true,
+	"members":       true,
+}
+
+// checkVersion finds out why a write of the row in table with idColumn id,
+// conditional on version, didn't match: either there's no such row
+// (sql.ErrNoRows) or it's at another version (ErrVersionMismatch).
+func checkVersion(ctx context.Context, db DB, table string, idColumn string, id int64, version int64) error {
+	if version == 0 {
+		return sql.ErrNoRows
+	}
+
+	var current int64
+	query := fmt.Sprintf("SELECT version FROM %s WHERE %s = $1", table, idColumn)
+	if softDeleted[table] {
+		query += " AND deleted_on IS NULL"
+	}
+	err := db.QueryRowContext(ctx, query, id).Scan(&current)
+	if err != nil {
+		return err
+	}
+
+	return ErrVersionMismatch
+}
+
+// Transaction runs fn in a transaction on db. The transaction is committed if
+// fn returns nil and rolled back otherwise, so either all of the queries fn
+// runs on tx take effect or none of them do.
+func Transaction(ctx context.Context, db *sql.DB, fn func(tx DB) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
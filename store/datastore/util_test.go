@@ -0,0 +1,59 @@
+package datastore_test
+
+import (
+	"errors"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transaction", func() {
+	var (
+		created *models.Status
+		err     error
+	)
+
+	AfterEach(func() {
+		if created != nil {
+			datastore.DeleteStatus(ctx, db, created.StatusID, 0)
+		}
+	})
+
+	Describe("Successful call", func() {
+		BeforeEach(func() {
+			err = datastore.Transaction(ctx, db, func(tx datastore.DB) error {
+				created, err = datastore.CreateStatus(ctx, tx, models.Status{StatusName: "Transaction"})
+				return err
+			})
+		})
+
+		It("should commit the queries", func() {
+			Expect(err).To(BeNil())
+			status, err := datastore.GetStatus(ctx, db, created.StatusID)
+			Expect(err).To(BeNil())
+			Expect(status.StatusName).To(Equal("Transaction"))
+		})
+	})
+
+	Describe("Unsuccessful call", func() {
+		failed := errors.New("failed")
+
+		BeforeEach(func() {
+			err = datastore.Transaction(ctx, db, func(tx datastore.DB) error {
+				created, _ = datastore.CreateStatus(ctx, tx, models.Status{StatusName: "Transaction"})
+				return failed
+			})
+		})
+
+		It("should return fn's error", func() {
+			Expect(err).To(Equal(failed))
+		})
+
+		It("should roll the queries back", func() {
+			_, err := datastore.GetStatus(ctx, db, created.StatusID)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+})
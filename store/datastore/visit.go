@@ -1,21 +1,23 @@
 package datastore
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
 	"github.com/lukashambsch/anygym.api/models"
-	"github.com/lukashambsch/anygym.api/store"
 )
 
-func GetVisitList(where string) ([]models.Visit, error) {
+func GetVisitList(ctx context.Context, db DB, scope Scope, q Query) ([]models.Visit, error) {
 	var (
 		visits []models.Visit
 		visit  models.Visit
 	)
 
-	query := fmt.Sprintf("%s %s", getVisitListQuery, where)
-	rows, err := store.DB.Query(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getVisitListQuery, b.list(q, scope.visits(b)))
+	rows, err := db.QueryContext(ctx, query, b.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -28,6 +30,7 @@ func GetVisitList(where string) ([]models.Visit, error) {
 			&visit.StatusID,
 			&visit.CreatedOn,
 			&visit.ModifiedOn,
+			&visit.Version,
 		)
 		visits = append(visits, visit)
 		if err != nil {
@@ -36,14 +39,15 @@ func GetVisitList(where string) ([]models.Visit, error) {
 	}
 	defer rows.Close()
 
-	return visits, nil
+	return visits, rows.Err()
 }
 
-func GetVisitCount(where string) (*int, error) {
+func GetVisitCount(ctx context.Context, db DB, scope Scope, q Query) (*int, error) {
 	var count int
 
-	query := fmt.Sprintf("%s %s", getVisitCountQuery, where)
-	row := store.DB.QueryRow(query)
+	b := &builder{}
+	query := fmt.Sprintf("%s %s", getVisitCountQuery, b.where(q, scope.visits(b)))
+	row := db.QueryRowContext(ctx, query, b.args...)
 	err := row.Scan(&count)
 
 	if err != nil {
@@ -53,10 +57,11 @@ func GetVisitCount(where string) (*int, error) {
 	return &count, nil
 }
 
-func GetVisit(visitID int64) (*models.Visit, error) {
+func GetVisit(ctx context.Context, db DB, scope Scope, visitID int64) (*models.Visit, error) {
 	var visit models.Visit
 
-	row := store.DB.QueryRow(getVisitQuery, visitID)
+	b := &builder{args: []interface{}{visitID}}
+	row := db.QueryRowContext(ctx, fmt.Sprintf("%s AND %s", getVisitQuery, scope.visits(b)), b.args...)
 	err := row.Scan(
 		&visit.VisitID,
 		&visit.MemberID,
@@ -64,6 +69,7 @@ func GetVisit(visitID int64) (*models.Visit, error) {
 		&visit.StatusID,
 		&visit.CreatedOn,
 		&visit.ModifiedOn,
+		&visit.Version,
 	)
 
 	if err != nil {
@@ -73,10 +79,11 @@ func GetVisit(visitID int64) (*models.Visit, error) {
 	return &visit, nil
 }
 
-func CreateVisit(visit models.Visit) (*models.Visit, error) {
+func CreateVisit(ctx context.Context, db DB, visit models.Visit) (*models.Visit, error) {
 	var created models.Visit
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		createVisitQuery,
 		visit.MemberID,
 		visit.GymLocationID,
@@ -89,6 +96,7 @@ func CreateVisit(visit models.Visit) (*models.Visit, error) {
 		&created.StatusID,
 		&created.CreatedOn,
 		&created.ModifiedOn,
+		&created.Version,
 	)
 	if err != nil {
 		return nil, err
@@ -97,16 +105,20 @@ func CreateVisit(visit models.Visit) (*models.Visit, error) {
 	return &created, nil
 }
 
-func UpdateVisit(visitID int64, visit models.Visit) (*models.Visit, error) {
+// UpdateVisit updates the visit if it's at visit.Version, or whatever version
+// it's at if that's 0.
+func UpdateVisit(ctx context.Context, db DB, visitID int64, visit models.Visit) (*models.Visit, error) {
 	var updated models.Visit
 
-	row := store.DB.QueryRow(
+	row := db.QueryRowContext(
+		ctx,
 		updateVisitQuery,
 		visit.MemberID,
 		visit.GymLocationID,
 		visit.StatusID,
 		time.Now(),
 		visitID,
+		visit.Version,
 	)
 	err := row.Scan(
 		&updated.VisitID,
@@ -115,7 +127,11 @@ func UpdateVisit(visitID int64, visit models.Visit) (*models.Visit, error) {
 		&updated.StatusID,
 		&updated.CreatedOn,
 		&updated.ModifiedOn,
+		&updated.Version,
 	)
+	if err == sql.ErrNoRows {
+		return nil, checkVersion(ctx, db, "visits", "visit_id", visitID, visit.Version)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -123,18 +139,50 @@ func UpdateVisit(visitID int64, visit models.Visit) (*models.Visit, error) {
 	return &updated, nil
 }
 
-func DeleteVisit(visitID int64) error {
-	stmt, err := store.DB.Prepare(deleteVisitQuery)
+// PatchVisit only updates the fields of visit that are listed in fields.
+func PatchVisit(ctx context.Context, db DB, visitID int64, visit models.Visit, fields []string) (*models.Visit, error) {
+	var patched models.Visit
+
+	b := &builder{}
+	set, err := b.set(map[string]interface{}{
+		"member_id":       visit.MemberID,
+		"gym_location_id": visit.GymLocationID,
+		"status_id":       visit.StatusID,
+	}, fields)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = stmt.Exec(visitID)
+	query := fmt.Sprintf(patchVisitQuery, set, b.arg(time.Now()), b.arg(visitID), b.arg(visit.Version))
+	row := db.QueryRowContext(ctx, query, b.args...)
+	err = row.Scan(
+		&patched.VisitID,
+		&patched.MemberID,
+		&patched.GymLocationID,
+		&patched.StatusID,
+		&patched.CreatedOn,
+		&patched.ModifiedOn,
+		&patched.Version,
+	)
+	if err == sql.ErrNoRows {
+		return nil, checkVersion(ctx, db, "visits", "visit_id", visitID, visit.Version)
+	}
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return &patched, nil
+}
+
+// DeleteVisit deletes the visit if it's at version, or whatever version it's
+// at if that's 0.
+func DeleteVisit(ctx context.Context, db DB, visitID int64, version int64) error {
+	err := execOne(ctx, db, deleteVisitQuery, visitID, version)
+	if err == sql.ErrNoRows {
+		return checkVersion(ctx, db, "visits", "visit_id", visitID, version)
 	}
 
-	return nil
+	return err
 }
 
 const getVisitListQuery = `
@@ -151,20 +199,27 @@ WHERE visit_id = $1
 const createVisitQuery = `
 INSERT INTO visits (member_id, gym_location_id, status_id)
 VALUES ($1, $2, $3)
-RETURNING visit_id, member_id, gym_location_id, status_id, created_on, modified_on
+RETURNING visit_id, member_id, gym_location_id, status_id, created_on, modified_on, version
 `
 
 const updateVisitQuery = `
 UPDATE visits
-SET member_id = $1, gym_location_id = $2, status_id = $3, modified_on = $4
-WHERE visit_id = $5
-RETURNING visit_id, member_id, gym_location_id, status_id, created_on, modified_on
+SET member_id = $1, gym_location_id = $2, status_id = $3, modified_on = $4, version = version + 1
+WHERE visit_id = $5 AND ($6 = 0 OR version = $6)
+RETURNING visit_id, member_id, gym_location_id, status_id, created_on, modified_on, version
+`
+
+const patchVisitQuery = `
+UPDATE visits
+SET %s, modified_on = %s
+WHERE visit_id = %s AND (%[4]s = 0 OR version = %[4]s)
+RETURNING visit_id, member_id, gym_location_id, status_id, created_on, modified_on, version
 `
 
 const deleteVisitQuery = `
 DELETE
 FROM visits
-WHERE visit_id = $1
+WHERE visit_id = $1 AND ($2 = 0 OR version = $2)
 `
 
 const getVisitCountQuery = `
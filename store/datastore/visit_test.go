@@ -1,6 +1,8 @@
 package datastore_test
 
 import (
+	"database/sql"
+
 	"github.com/lukashambsch/anygym.api/models"
 	"github.com/lukashambsch/anygym.api/store/datastore"
 	. "github.com/onsi/ginkgo"
@@ -18,18 +20,18 @@ var _ = Describe("Visit db interactions", func() {
 	)
 
 	BeforeEach(func() {
-		addr, _ = datastore.CreateAddress(models.Address{StreetAddress: "Testing"})
-		gymLocation, _ = datastore.CreateGymLocation(models.GymLocation{
+		addr, _ = datastore.CreateAddress(ctx, db, models.Address{StreetAddress: "Testing"})
+		gymLocation, _ = datastore.CreateGymLocation(ctx, db, models.GymLocation{
 			GymID:        gymID,
 			AddressID:    addr.AddressID,
 			LocationName: "Testing",
 		})
-		visitOne, _ = datastore.CreateVisit(models.Visit{
+		visitOne, _ = datastore.CreateVisit(ctx, db, models.Visit{
 			MemberID:      memberID,
 			GymLocationID: gymLocation.GymLocationID,
 			StatusID:      statusID,
 		})
-		visitTwo, _ = datastore.CreateVisit(models.Visit{
+		visitTwo, _ = datastore.CreateVisit(ctx, db, models.Visit{
 			MemberID:      memberID,
 			GymLocationID: gymLocation.GymLocationID,
 			StatusID:      statusID,
@@ -37,10 +39,10 @@ var _ = Describe("Visit db interactions", func() {
 	})
 
 	AfterEach(func() {
-		datastore.DeleteVisit(visitOne.VisitID)
-		datastore.DeleteVisit(visitTwo.VisitID)
-		datastore.DeleteGymLocation(gymLocation.GymLocationID)
-		datastore.DeleteAddress(addr.AddressID)
+		datastore.DeleteVisit(ctx, db, visitOne.VisitID, 0)
+		datastore.DeleteVisit(ctx, db, visitTwo.VisitID, 0)
+		purge("gym_locations", "gym_location_id", gymLocation.GymLocationID)
+		datastore.DeleteAddress(ctx, db, addr.AddressID)
 	})
 
 	Describe("GetVisitList", func() {
@@ -48,7 +50,7 @@ var _ = Describe("Visit db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				visits, _ = datastore.GetVisitList("")
+				visits, _ = datastore.GetVisitList(ctx, db, datastore.Unscoped, datastore.Query{})
 			})
 
 			It("should return a list of visits", func() {
@@ -62,7 +64,7 @@ var _ = Describe("Visit db interactions", func() {
 
 		Describe("Successful call", func() {
 			It("should return the correct visit", func() {
-				visit, _ = datastore.GetVisit(visitOne.VisitID)
+				visit, _ = datastore.GetVisit(ctx, db, datastore.Unscoped, visitOne.VisitID)
 				Expect(visit.VisitID).To(Equal(visitOne.VisitID))
 			})
 		})
@@ -74,7 +76,7 @@ var _ = Describe("Visit db interactions", func() {
 			)
 
 			BeforeEach(func() {
-				visit, err = datastore.GetVisit(nonExistentID)
+				visit, err = datastore.GetVisit(ctx, db, datastore.Unscoped, nonExistentID)
 			})
 
 			It("should return an error", func() {
@@ -92,7 +94,7 @@ var _ = Describe("Visit db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				count, _ = datastore.GetVisitCount("")
+				count, _ = datastore.GetVisitCount(ctx, db, datastore.Unscoped, datastore.Query{})
 			})
 
 			It("should return the correct count", func() {
@@ -114,11 +116,11 @@ var _ = Describe("Visit db interactions", func() {
 					GymLocationID: gymLocation.GymLocationID,
 					StatusID:      statusID,
 				}
-				created, _ = datastore.CreateVisit(visit)
+				created, _ = datastore.CreateVisit(ctx, db, visit)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteVisit(created.VisitID)
+				datastore.DeleteVisit(ctx, db, created.VisitID, 0)
 			})
 
 			It("should return the created visit", func() {
@@ -126,7 +128,7 @@ var _ = Describe("Visit db interactions", func() {
 			})
 
 			It("should add a visit to the db", func() {
-				newMember, _ := datastore.GetVisit(created.VisitID)
+				newMember, _ := datastore.GetVisit(ctx, db, datastore.Unscoped, created.VisitID)
 				Expect(newMember.StatusID).To(Equal(statusID))
 			})
 		})
@@ -134,7 +136,7 @@ var _ = Describe("Visit db interactions", func() {
 		Describe("Unsuccessful call", func() {
 			It("should return an error object if no fk ids", func() {
 				v := models.Visit{}
-				_, err := datastore.CreateVisit(v)
+				_, err := datastore.CreateVisit(ctx, db, v)
 				Expect(err).ToNot(BeNil())
 			})
 		})
@@ -151,17 +153,17 @@ var _ = Describe("Visit db interactions", func() {
 
 		Describe("Successful call", func() {
 			BeforeEach(func() {
-				created, _ = datastore.CreateVisit(models.Visit{
+				created, _ = datastore.CreateVisit(ctx, db, models.Visit{
 					MemberID:      memberID,
 					GymLocationID: gymLocation.GymLocationID,
 					StatusID:      statusID,
 				})
 				created.StatusID = otherID
-				updated, _ = datastore.UpdateVisit(created.VisitID, *created)
+				updated, _ = datastore.UpdateVisit(ctx, db, created.VisitID, *created)
 			})
 
 			AfterEach(func() {
-				datastore.DeleteVisit(updated.VisitID)
+				datastore.DeleteVisit(ctx, db, updated.VisitID, 0)
 			})
 
 			It("should return the updated visit", func() {
@@ -175,7 +177,7 @@ var _ = Describe("Visit db interactions", func() {
 					GymLocationID: gymLocation.GymLocationID,
 					StatusID:      statusID,
 				}
-				updated, err = datastore.UpdateVisit(5000, visit)
+				updated, err = datastore.UpdateVisit(ctx, db, 5000, visit)
 			})
 
 			It("should return an error object if visit to update doesn't exist", func() {
@@ -191,14 +193,21 @@ var _ = Describe("Visit db interactions", func() {
 	Describe("DeleteVisit", func() {
 		Describe("Successful call", func() {
 			It("should return nil", func() {
-				created, _ := datastore.CreateVisit(models.Visit{
+				created, _ := datastore.CreateVisit(ctx, db, models.Visit{
 					MemberID:      memberID,
 					GymLocationID: gymLocation.GymLocationID,
 					StatusID:      statusID,
 				})
-				err := datastore.DeleteVisit(created.VisitID)
+				err := datastore.DeleteVisit(ctx, db, created.VisitID, 0)
 				Expect(err).To(BeNil())
 			})
 		})
+
+		Describe("Unsuccessful call", func() {
+			It("should return sql.ErrNoRows for a missing id", func() {
+				err := datastore.DeleteVisit(ctx, db, 5000, 0)
+				Expect(err).To(Equal(sql.ErrNoRows))
+			})
+		})
 	})
 })
@@ -0,0 +1,33 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+type addresses struct {
+	*data
+}
+
+func (r addresses) List(ctx context.Context, q datastore.Query) ([]models.Address, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return list(r.addresses, q).([]models.Address), nil
+}
+
+func (r addresses) Create(ctx context.Context, address models.Address) (*models.Address, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	address.AddressID = r.nextID("addresses")
+	r.addresses = append(r.addresses, address)
+
+	return &address, nil
+}
@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+type apiKeys struct {
+	*data
+}
+
+func (r apiKeys) List(ctx context.Context, q datastore.Query) ([]models.APIKey, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return list(r.apiKeys, q).([]models.APIKey), nil
+}
+
+func (r apiKeys) Count(ctx context.Context, q datastore.Query) (*int, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return count(r.apiKeys, q), nil
+}
+
+func (r apiKeys) Get(ctx context.Context, apiKeyID int64) (*models.APIKey, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.apiKey(apiKeyID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	apiKey := r.apiKeys[i]
+	return &apiKey, nil
+}
+
+func (r apiKeys) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	for _, apiKey := range r.apiKeys {
+		if apiKey.KeyHash == keyHash {
+			return &apiKey, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (r apiKeys) Create(ctx context.Context, apiKey models.APIKey) (*models.APIKey, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	if r.gym(apiKey.GymID) < 0 {
+		return nil, foreignKeyViolation("api_keys", "gym_id")
+	}
+	for _, other := range r.apiKeys {
+		if other.KeyHash == apiKey.KeyHash {
+			return nil, uniqueViolation("api_keys", "key_hash")
+		}
+	}
+
+	scopes := apiKey.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	created := models.APIKey{
+		APIKeyID:  r.nextID("api_keys"),
+		GymID:     apiKey.GymID,
+		KeyName:   apiKey.KeyName,
+		KeyPrefix: apiKey.KeyPrefix,
+		KeyHash:   apiKey.KeyHash,
+		Scopes:    scopes,
+		CreatedOn: time.Now(),
+		ExpiresOn: apiKey.ExpiresOn,
+	}
+	r.apiKeys = append(r.apiKeys, created)
+
+	return &created, nil
+}
+
+func (r apiKeys) Revoke(ctx context.Context, apiKeyID int64) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.apiKey(apiKeyID)
+	if i < 0 || r.apiKeys[i].RevokedOn != nil {
+		return sql.ErrNoRows
+	}
+
+	now := time.Now()
+	r.apiKeys[i].RevokedOn = &now
+	return nil
+}
+
+func (r apiKeys) Touch(ctx context.Context, apiKeyID int64) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	if i := r.apiKey(apiKeyID); i >= 0 {
+		now := time.Now()
+		r.apiKeys[i].LastUsedOn = &now
+	}
+
+	return nil
+}
+
+func (r apiKeys) Delete(ctx context.Context, apiKeyID int64) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.apiKey(apiKeyID)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+
+	r.apiKeys = append(r.apiKeys[:i], r.apiKeys[i+1:]...)
+	return nil
+}
+
+func (d *data) apiKey(apiKeyID int64) int {
+	for i := range d.apiKeys {
+		if d.apiKeys[i].APIKeyID == apiKeyID {
+			return i
+		}
+	}
+
+	return -1
+}
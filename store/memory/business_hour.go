@@ -0,0 +1,21 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+type businessHours struct {
+	*data
+}
+
+func (r businessHours) List(ctx context.Context, q datastore.Query) ([]models.BusinessHour, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return list(r.businessHours, q).([]models.BusinessHour), nil
+}
@@ -0,0 +1,21 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+type features struct {
+	*data
+}
+
+func (r features) List(ctx context.Context, q datastore.Query) ([]models.Feature, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return list(r.features, q).([]models.Feature), nil
+}
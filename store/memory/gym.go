@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+type gyms struct {
+	*data
+}
+
+func (r gyms) List(ctx context.Context, q datastore.Query) ([]models.Gym, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	var live []models.Gym
+	for _, gym := range r.gyms {
+		if gym.DeletedOn == nil {
+			live = append(live, gym)
+		}
+	}
+
+	return list(live, q).([]models.Gym), nil
+}
+
+func (r gyms) Get(ctx context.Context, gymID int64) (*models.Gym, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.gym(gymID)
+	if i < 0 || r.gyms[i].DeletedOn != nil {
+		return nil, sql.ErrNoRows
+	}
+
+	gym := r.gyms[i]
+	return &gym, nil
+}
+
+func (r gyms) Delete(ctx context.Context, gymID int64) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.gym(gymID)
+	if i < 0 || r.gyms[i].DeletedOn != nil {
+		return sql.ErrNoRows
+	}
+
+	now := time.Now()
+	r.gyms[i].DeletedOn = &now
+	return nil
+}
+
+func (r gyms) Restore(ctx context.Context, gymID int64) (*models.Gym, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.gym(gymID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	r.gyms[i].DeletedOn = nil
+	gym := r.gyms[i]
+	return &gym, nil
+}
+
+func (d *data) gym(gymID int64) int {
+	for i := range d.gyms {
+		if d.gyms[i].GymID == gymID {
+			return i
+		}
+	}
+
+	return -1
+}
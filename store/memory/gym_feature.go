@@ -0,0 +1,21 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+type gymFeatures struct {
+	*data
+}
+
+func (r gymFeatures) List(ctx context.Context, q datastore.Query) ([]models.GymFeature, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return list(r.gymFeatures, q).([]models.GymFeature), nil
+}
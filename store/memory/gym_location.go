@@ -0,0 +1,236 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+type gymLocations struct {
+	*data
+}
+
+// List joins each location's address and business hours, the same as the
+// database list does.
+func (r gymLocations) List(ctx context.Context, scope datastore.Scope, q datastore.Query) ([]models.GymLocation, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	gymLocations := list(r.visibleGymLocations(scope), q).([]models.GymLocation)
+	for i := range gymLocations {
+		if a := r.address(gymLocations[i].AddressID); a >= 0 {
+			address := r.addresses[a]
+			gymLocations[i].Address = &address
+		}
+
+		for _, businessHour := range r.businessHours {
+			if businessHour.GymLocationID == gymLocations[i].GymLocationID {
+				gymLocations[i].BusinessHours = append(gymLocations[i].BusinessHours, businessHour)
+			}
+		}
+	}
+
+	return gymLocations, nil
+}
+
+func (r gymLocations) Count(ctx context.Context, scope datastore.Scope, q datastore.Query) (*int, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return count(r.visibleGymLocations(scope), q), nil
+}
+
+func (r gymLocations) Get(ctx context.Context, scope datastore.Scope, gymLocationID int64) (*models.GymLocation, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.gymLocation(gymLocationID)
+	if i < 0 || !r.seesGymLocation(scope, r.gymLocations[i]) {
+		return nil, sql.ErrNoRows
+	}
+
+	gymLocation := r.gymLocations[i]
+	return &gymLocation, nil
+}
+
+func (r gymLocations) Create(ctx context.Context, gymLocation models.GymLocation) (*models.GymLocation, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	gymLocation.GymLocationID = r.nextID("gym_locations")
+	gymLocation.Version = 1
+	created := columnsOf(gymLocation)
+	err := r.checkGymLocation(created)
+	if err != nil {
+		return nil, err
+	}
+
+	r.gymLocations = append(r.gymLocations, created)
+	return &created, nil
+}
+
+func (r gymLocations) Update(ctx context.Context, gymLocationID int64, gymLocation models.GymLocation) (*models.GymLocation, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return r.updateGymLocation(gymLocationID, gymLocation)
+}
+
+func (r gymLocations) Patch(ctx context.Context, gymLocationID int64, gymLocation models.GymLocation, fields []string) (*models.GymLocation, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.gymLocation(gymLocationID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	patched := r.gymLocations[i]
+	patch(&patched, gymLocation, fields)
+	patched.Version = gymLocation.Version
+	return r.updateGymLocation(gymLocationID, patched)
+}
+
+// updateGymLocation is Update with d locked.
+func (d *data) updateGymLocation(gymLocationID int64, gymLocation models.GymLocation) (*models.GymLocation, error) {
+	i := d.gymLocation(gymLocationID)
+	if i < 0 || d.gymLocations[i].DeletedOn != nil {
+		return nil, sql.ErrNoRows
+	}
+	err := checkVersion(d.gymLocations[i].Version, gymLocation.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	gymLocation.GymLocationID = gymLocationID
+	gymLocation.Version = d.gymLocations[i].Version + 1
+	updated := columnsOf(gymLocation)
+	err = d.checkGymLocation(updated)
+	if err != nil {
+		return nil, err
+	}
+
+	d.gymLocations[i] = updated
+	return &updated, nil
+}
+
+func (r gymLocations) Delete(ctx context.Context, gymLocationID int64, version int64) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.gymLocation(gymLocationID)
+	if i < 0 || r.gymLocations[i].DeletedOn != nil {
+		return sql.ErrNoRows
+	}
+	if err := checkVersion(r.gymLocations[i].Version, version); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	r.gymLocations[i].DeletedOn = &now
+	r.gymLocations[i].Version++
+	return nil
+}
+
+func (r gymLocations) Restore(ctx context.Context, gymLocationID int64) (*models.GymLocation, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.gymLocation(gymLocationID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+	if r.gymLocations[i].DeletedOn != nil {
+		r.gymLocations[i].DeletedOn = nil
+		r.gymLocations[i].Version++
+	}
+
+	restored := r.gymLocations[i]
+	return &restored, nil
+}
+
+func (d *data) gymLocation(gymLocationID int64) int {
+	for i := range d.gymLocations {
+		if d.gymLocations[i].GymLocationID == gymLocationID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (d *data) address(addressID int64) int {
+	for i := range d.addresses {
+		if d.addresses[i].AddressID == addressID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (d *data) visibleGymLocations(scope datastore.Scope) []models.GymLocation {
+	var visible []models.GymLocation
+	for _, gymLocation := range d.gymLocations {
+		if d.seesGymLocation(scope, gymLocation) {
+			visible = append(visible, gymLocation)
+		}
+	}
+
+	return visible
+}
+
+// columnsOf drops everything but the gym_locations columns.
+func columnsOf(gymLocation models.GymLocation) models.GymLocation {
+	return models.GymLocation{
+		GymLocationID:    gymLocation.GymLocationID,
+		GymID:            gymLocation.GymID,
+		AddressID:        gymLocation.AddressID,
+		LocationName:     gymLocation.LocationName,
+		PhoneNumber:      gymLocation.PhoneNumber,
+		WebsiteUrl:       gymLocation.WebsiteUrl,
+		InNetwork:        gymLocation.InNetwork,
+		MonthlyMemberFee: gymLocation.MonthlyMemberFee,
+		UserID:           gymLocation.UserID,
+		Version:          gymLocation.Version,
+	}
+}
+
+// checkGymLocation checks the constraints of the gym_locations table.
+func (d *data) checkGymLocation(gymLocation models.GymLocation) error {
+	switch {
+	case d.gym(gymLocation.GymID) < 0:
+		return foreignKeyViolation("gym_locations", "gym_id")
+	case d.address(gymLocation.AddressID) < 0:
+		return foreignKeyViolation("gym_locations", "address_id")
+	case gymLocation.UserID != nil && d.user(*gymLocation.UserID) < 0:
+		return foreignKeyViolation("gym_locations", "user_id")
+	}
+
+	for _, other := range d.gymLocations {
+		if other.GymLocationID != gymLocation.GymLocationID && other.AddressID == gymLocation.AddressID {
+			return uniqueViolation("gym_locations", "address_id")
+		}
+	}
+
+	return nil
+}
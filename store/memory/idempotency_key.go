@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+type idempotencyKeys struct {
+	*data
+}
+
+func (r idempotencyKeys) Get(ctx context.Context, owner string, key string) (*models.IdempotencyKey, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.idempotencyKey(owner, key)
+	if i < 0 || !r.idempotencyKeys[i].ExpiresOn.After(time.Now()) {
+		return nil, sql.ErrNoRows
+	}
+
+	stored := r.idempotencyKeys[i]
+	return &stored, nil
+}
+
+func (r idempotencyKeys) Claim(ctx context.Context, key models.IdempotencyKey, window time.Duration) (*models.IdempotencyKey, bool, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, false, err
+	}
+	defer r.Unlock()
+
+	now := time.Now()
+	i := r.idempotencyKey(key.Owner, key.Key)
+	if i >= 0 && r.idempotencyKeys[i].ExpiresOn.After(now) {
+		stored := r.idempotencyKeys[i]
+		return &stored, false, nil
+	}
+
+	claimed := models.IdempotencyKey{
+		Owner:       key.Owner,
+		Key:         key.Key,
+		Fingerprint: key.Fingerprint,
+		CreatedOn:   now,
+		ExpiresOn:   now.Add(window),
+	}
+	if i >= 0 {
+		r.idempotencyKeys[i] = claimed
+	} else {
+		r.idempotencyKeys = append(r.idempotencyKeys, claimed)
+	}
+
+	return &claimed, true, nil
+}
+
+func (r idempotencyKeys) SaveResponse(ctx context.Context, key models.IdempotencyKey) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.idempotencyKey(key.Owner, key.Key)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+
+	r.idempotencyKeys[i].ResponseStatus = key.ResponseStatus
+	r.idempotencyKeys[i].ResponseHeaders = key.ResponseHeaders
+	r.idempotencyKeys[i].ResponseBody = key.ResponseBody
+	return nil
+}
+
+func (r idempotencyKeys) Delete(ctx context.Context, owner string, key string) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.idempotencyKey(owner, key)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+
+	r.idempotencyKeys = append(r.idempotencyKeys[:i], r.idempotencyKeys[i+1:]...)
+	return nil
+}
+
+func (d *data) idempotencyKey(owner string, key string) int {
+	for i := range d.idempotencyKeys {
+		if d.idempotencyKeys[i].Owner == owner && d.idempotencyKeys[i].Key == key {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,21 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+type images struct {
+	*data
+}
+
+func (r images) List(ctx context.Context, q datastore.Query) ([]models.Image, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return list(r.images, q).([]models.Image), nil
+}
@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+type loginThrottles struct {
+	*data
+}
+
+func (r loginThrottles) Get(ctx context.Context, throttleKey string) (*models.LoginThrottle, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.data.Unlock()
+
+	i := r.loginThrottle(throttleKey)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	throttle := r.loginThrottles[i]
+	return &throttle, nil
+}
+
+func (r loginThrottles) RecordFailure(ctx context.Context, throttleKey string, window time.Duration) (*models.LoginThrottle, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.data.Unlock()
+
+	now := time.Now()
+	i := r.loginThrottle(throttleKey)
+	if i < 0 {
+		r.loginThrottles = append(r.loginThrottles, models.LoginThrottle{ThrottleKey: throttleKey})
+		i = len(r.loginThrottles) - 1
+	}
+
+	throttle := &r.loginThrottles[i]
+	if throttle.FailedAttempts > 0 && throttle.UpdatedOn.After(now.Add(-window)) {
+		throttle.FailedAttempts++
+	} else {
+		throttle.FailedAttempts = 1
+	}
+	throttle.UpdatedOn = now
+
+	recorded := *throttle
+	return &recorded, nil
+}
+
+func (r loginThrottles) Lock(ctx context.Context, throttleKey string, lockFor time.Duration) (*models.LoginThrottle, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.data.Unlock()
+
+	i := r.loginThrottle(throttleKey)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	throttle := &r.loginThrottles[i]
+	lockedUntil := time.Now().Add(lockFor)
+	if throttle.LockedUntil == nil || throttle.LockedUntil.Before(lockedUntil) {
+		throttle.LockedUntil = &lockedUntil
+	}
+
+	locked := *throttle
+	return &locked, nil
+}
+
+func (r loginThrottles) Delete(ctx context.Context, throttleKey string) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.data.Unlock()
+
+	if i := r.loginThrottle(throttleKey); i >= 0 {
+		r.loginThrottles = append(r.loginThrottles[:i], r.loginThrottles[i+1:]...)
+	}
+
+	return nil
+}
+
+func (d *data) loginThrottle(throttleKey string) int {
+	for i := range d.loginThrottles {
+		if d.loginThrottles[i].ThrottleKey == throttleKey {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,255 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+type members struct {
+	*data
+}
+
+func (r members) List(ctx context.Context, scope datastore.Scope, q datastore.Query) ([]models.Member, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	members := list(r.visibleMembers(scope), q).([]models.Member)
+	for i := range members {
+		members[i].User = r.withRoles(members[i].UserID)
+	}
+
+	return members, nil
+}
+
+func (r members) Count(ctx context.Context, scope datastore.Scope, q datastore.Query) (*int, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return count(r.visibleMembers(scope), q), nil
+}
+
+func (r members) Get(ctx context.Context, scope datastore.Scope, memberID int64) (*models.Member, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.member(memberID)
+	if i < 0 || !r.seesMember(scope, r.members[i]) {
+		return nil, sql.ErrNoRows
+	}
+
+	member := r.members[i]
+	member.User = r.withRoles(member.UserID)
+	return &member, nil
+}
+
+func (r members) GetByEmail(ctx context.Context, scope datastore.Scope, email string) (*models.Member, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	for _, member := range r.members {
+		u := r.user(member.UserID)
+		if u >= 0 && r.users[u].Email == email && r.seesMember(scope, member) {
+			member.User = r.withRoles(member.UserID)
+			return &member, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (r members) GetByUserID(ctx context.Context, userID int64) (*models.Member, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	for _, member := range r.members {
+		if member.UserID == userID && member.DeletedOn == nil {
+			return &member, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (r members) Create(ctx context.Context, member models.Member) (*models.Member, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	err := r.checkMember(member, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	created := models.Member{
+		MemberID:  r.nextID("members"),
+		UserID:    member.UserID,
+		ImageID:   member.ImageID,
+		AddressID: member.AddressID,
+		FirstName: member.FirstName,
+		LastName:  member.LastName,
+		Version:   1,
+	}
+	r.members = append(r.members, created)
+
+	return &created, nil
+}
+
+func (r members) Update(ctx context.Context, memberID int64, member models.Member) (*models.Member, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return r.updateMember(memberID, member)
+}
+
+func (r members) Patch(ctx context.Context, memberID int64, member models.Member, fields []string) (*models.Member, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.member(memberID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	patched := r.members[i]
+	patch(&patched, member, fields)
+	patched.Version = member.Version
+	return r.updateMember(memberID, patched)
+}
+
+// updateMember is Update with d locked.
+func (d *data) updateMember(memberID int64, member models.Member) (*models.Member, error) {
+	i := d.member(memberID)
+	if i < 0 || d.members[i].DeletedOn != nil {
+		return nil, sql.ErrNoRows
+	}
+	err := checkVersion(d.members[i].Version, member.Version)
+	if err != nil {
+		return nil, err
+	}
+	err = d.checkMember(member, memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	d.members[i] = models.Member{
+		MemberID:  memberID,
+		UserID:    member.UserID,
+		ImageID:   member.ImageID,
+		AddressID: member.AddressID,
+		FirstName: member.FirstName,
+		LastName:  member.LastName,
+		Version:   d.members[i].Version + 1,
+	}
+
+	updated := d.members[i]
+	return &updated, nil
+}
+
+func (r members) Delete(ctx context.Context, memberID int64, version int64) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.member(memberID)
+	if i < 0 || r.members[i].DeletedOn != nil {
+		return sql.ErrNoRows
+	}
+	if err := checkVersion(r.members[i].Version, version); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	r.members[i].DeletedOn = &now
+	r.members[i].Version++
+	return nil
+}
+
+func (r members) Restore(ctx context.Context, memberID int64) (*models.Member, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.member(memberID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+	if r.members[i].DeletedOn != nil {
+		r.members[i].DeletedOn = nil
+		r.members[i].Version++
+	}
+
+	restored := r.members[i]
+	return &restored, nil
+}
+
+func (d *data) member(memberID int64) int {
+	for i := range d.members {
+		if d.members[i].MemberID == memberID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (d *data) visibleMembers(scope datastore.Scope) []models.Member {
+	var visible []models.Member
+	for _, member := range d.members {
+		if d.seesMember(scope, member) {
+			visible = append(visible, member)
+		}
+	}
+
+	return visible
+}
+
+// checkMember checks the constraints of the members table, for a member
+// other than except.
+func (d *data) checkMember(member models.Member, except int64) error {
+	if member.FirstName == "" && member.LastName == "" {
+		return checkViolation("members", "valid_name")
+	}
+	if d.user(member.UserID) < 0 {
+		return foreignKeyViolation("members", "user_id")
+	}
+	if member.AddressID != nil && d.address(*member.AddressID) < 0 {
+		return foreignKeyViolation("members", "address_id")
+	}
+
+	for _, other := range d.members {
+		if other.MemberID == except {
+			continue
+		}
+		switch {
+		case other.UserID == member.UserID:
+			return uniqueViolation("members", "user_id")
+		case other.ImageID != nil && member.ImageID != nil && *other.ImageID == *member.ImageID:
+			return uniqueViolation("members", "image_id")
+		case other.AddressID != nil && member.AddressID != nil && *other.AddressID == *member.AddressID:
+			return uniqueViolation("members", "address_id")
+		}
+	}
+
+	return nil
+}
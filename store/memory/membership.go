@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+type memberships struct {
+	*data
+}
+
+func (r memberships) Create(ctx context.Context, membership models.Membership) (*models.Membership, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	if membership.PlanID == nil || r.plan(*membership.PlanID) < 0 {
+		return nil, foreignKeyViolation("memberships", "plan_id")
+	}
+	if membership.MemberID == nil || r.member(*membership.MemberID) < 0 {
+		return nil, foreignKeyViolation("memberships", "member_id")
+	}
+
+	membership.MembershipID = r.nextID("memberships")
+	r.memberships = append(r.memberships, membership)
+
+	return &membership, nil
+}
+
+func (d *data) plan(planID int64) int {
+	for i := range d.plans {
+		if d.plans[i].PlanID == planID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// deleteMemberships deletes the member's memberships, the same as their
+// foreign key cascading.
+func (d *data) deleteMemberships(memberID int64) {
+	var memberships []models.Membership
+	for _, membership := range d.memberships {
+		if *membership.MemberID != memberID {
+			memberships = append(memberships, membership)
+		}
+	}
+	d.memberships = memberships
+}
@@ -0,0 +1,426 @@
+// Package memory keeps the API's data in memory instead of the database, so
+// the handlers can be tested without postgres. A new store starts out with
+// the same rows the static data migration inserts.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+// data holds the rows of every table. The repositories share it and lock it
+// for each call, the same as each query being its own transaction.
+type data struct {
+	sync.Mutex
+
+	// tx is held for the whole of a Transaction, so only one runs at a
+	// time.
+	tx sync.Mutex
+
+	// lastIDs holds the last id handed out for each table, like a serial
+	// column's sequence. Like a sequence it isn't rolled back.
+	lastIDs map[string]int64
+
+	tables
+}
+
+type tables struct {
+	addresses       []models.Address
+	apiKeys         []models.APIKey
+	businessHours   []models.BusinessHour
+	features        []models.Feature
+	gymFeatures     []models.GymFeature
+	gymLocations    []models.GymLocation
+	gyms            []models.Gym
+	idempotencyKeys []models.IdempotencyKey
+	images          []models.Image
+	loginThrottles  []models.LoginThrottle
+	members         []models.Member
+	memberships     []models.Membership
+	passwordResets  []models.PasswordReset
+	plans           []models.Plan
+	recoveryCodes   []recoveryCode
+	roles           []models.Role
+	sessions        []models.Session
+	statuses        []models.Status
+	twoFactors      []models.TwoFactor
+	userRoles       []models.UserRole
+	users           []models.User
+	visits          []models.Visit
+}
+
+// clone copies every table, so changing the rows of one doesn't change the
+// other's.
+func (t tables) clone() tables {
+	return tables{
+		addresses:       append([]models.Address(nil), t.addresses...),
+		apiKeys:         append([]models.APIKey(nil), t.apiKeys...),
+		businessHours:   append([]models.BusinessHour(nil), t.businessHours...),
+		features:        append([]models.Feature(nil), t.features...),
+		gymFeatures:     append([]models.GymFeature(nil), t.gymFeatures...),
+		gymLocations:    append([]models.GymLocation(nil), t.gymLocations...),
+		gyms:            append([]models.Gym(nil), t.gyms...),
+		idempotencyKeys: append([]models.IdempotencyKey(nil), t.idempotencyKeys...),
+		images:          append([]models.Image(nil), t.images...),
+		loginThrottles:  append([]models.LoginThrottle(nil), t.loginThrottles...),
+		members:         append([]models.Member(nil), t.members...),
+		memberships:     append([]models.Membership(nil), t.memberships...),
+		passwordResets:  append([]models.PasswordReset(nil), t.passwordResets...),
+		plans:           append([]models.Plan(nil), t.plans...),
+		recoveryCodes:   append([]recoveryCode(nil), t.recoveryCodes...),
+		roles:           append([]models.Role(nil), t.roles...),
+		sessions:        append([]models.Session(nil), t.sessions...),
+		statuses:        append([]models.Status(nil), t.statuses...),
+		twoFactors:      append([]models.TwoFactor(nil), t.twoFactors...),
+		userRoles:       append([]models.UserRole(nil), t.userRoles...),
+		users:           append([]models.User(nil), t.users...),
+		visits:          append([]models.Visit(nil), t.visits...),
+	}
+}
+
+type recoveryCode struct {
+	userID   int64
+	codeHash string
+	usedOn   *time.Time
+}
+
+// New returns a Store holding the static data.
+func New() *store.Store {
+	d := seed()
+	return d.store(transactor{d})
+}
+
+// store returns repositories on d's tables, whose transactions t runs.
+func (d *data) store(t store.Transactor) *store.Store {
+	return &store.Store{
+		Transactor: t,
+
+		Addresses:       addresses{d},
+		APIKeys:         apiKeys{d},
+		BusinessHours:   businessHours{d},
+		Features:        features{d},
+		GymFeatures:     gymFeatures{d},
+		GymLocations:    gymLocations{d},
+		Gyms:            gyms{d},
+		IdempotencyKeys: idempotencyKeys{d},
+		Images:          images{d},
+		LoginThrottles:  loginThrottles{d},
+		Members:         members{d},
+		Memberships:     memberships{d},
+		PasswordResets:  passwordResets{d},
+		Sessions:        sessions{d},
+		Statuses:        statuses{d},
+		TwoFactors:      twoFactors{d},
+		Users:           users{d},
+		Visits:          visits{d},
+	}
+}
+
+// transactor runs one transaction on d at a time. The calls in it see each
+// other's changes straight away, and if it fails the tables are put back the
+// way they were before it started.
+type transactor struct {
+	*data
+}
+
+func (t transactor) Transaction(ctx context.Context, fn func(tx *store.Store) error) error {
+	t.tx.Lock()
+	defer t.tx.Unlock()
+
+	if err := t.lock(ctx); err != nil {
+		return err
+	}
+	before := t.tables.clone()
+	t.Unlock()
+
+	committed := false
+	defer func() {
+		if !committed {
+			t.Lock()
+			t.tables = before
+			t.Unlock()
+		}
+	}()
+
+	tx := t.store(nil)
+	tx.Transactor = joined{tx}
+
+	err := fn(tx)
+	if err == nil {
+		// like a commit, this fails if the context was cancelled meanwhile
+		err = ctx.Err()
+	}
+	committed = err == nil
+
+	return err
+}
+
+// joined runs transactions started inside another one as part of it.
+type joined struct {
+	tx *store.Store
+}
+
+func (j joined) Transaction(ctx context.Context, fn func(tx *store.Store) error) error {
+	return fn(j.tx)
+}
+
+// lock locks d for a call made with ctx. Like a query, the call fails if ctx
+// is already done.
+func (d *data) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	d.Lock()
+	return nil
+}
+
+// nextID hands out the next id for table.
+func (d *data) nextID(table string) int64 {
+	d.lastIDs[table]++
+	return d.lastIDs[table]
+}
+
+// uniqueViolation, foreignKeyViolation, stillReferenced and checkViolation
+// are the errors postgres returns when a row in table breaks one of the
+// schema's constraints. The constraints are named the way postgres names
+// them by default.
+func uniqueViolation(table string, columns ...string) error {
+	constraint := fmt.Sprintf("%s_%s_key", table, strings.Join(columns, "_"))
+	return &pq.Error{
+		Code:       "23505",
+		Message:    fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		Detail:     fmt.Sprintf("Key (%s) already exists.", strings.Join(columns, ", ")),
+		Table:      table,
+		Constraint: constraint,
+	}
+}
+
+func foreignKeyViolation(table string, column string) error {
+	constraint := fmt.Sprintf("%s_%s_fkey", table, column)
+	return &pq.Error{
+		Code:       "23503",
+		Message:    fmt.Sprintf("insert or update on table %q violates foreign key constraint %q", table, constraint),
+		Detail:     fmt.Sprintf("Key (%s) is not present in the referenced table.", column),
+		Table:      table,
+		Constraint: constraint,
+	}
+}
+
+// stillReferenced is the error for deleting a row that column in table
+// still refers to.
+func stillReferenced(table string, column string) error {
+	constraint := fmt.Sprintf("%s_%s_fkey", table, column)
+	return &pq.Error{
+		Code:       "23503",
+		Message:    fmt.Sprintf("update or delete violates foreign key constraint %q on table %q", constraint, table),
+		Detail:     fmt.Sprintf("Key (%s) is still referenced from table %q.", column, table),
+		Table:      table,
+		Constraint: constraint,
+	}
+}
+
+func checkViolation(table string, constraint string) error {
+	return &pq.Error{
+		Code:       "23514",
+		Message:    fmt.Sprintf("new row for relation %q violates check constraint %q", table, constraint),
+		Table:      table,
+		Constraint: constraint,
+	}
+}
+
+// checkVersion returns the error for a write that was only to go ahead if
+// the row was at version, when it's at current. Version 0 matches any.
+func checkVersion(current int64, version int64) error {
+	if version != 0 && version != current {
+		return datastore.ErrVersionMismatch
+	}
+
+	return nil
+}
+
+var (
+	passwordHash     []byte
+	passwordHashOnce sync.Once
+)
+
+// seedPasswordHash hashes the seeded users' password. It's only done once,
+// bcrypt is too slow to run for every new store.
+func seedPasswordHash() []byte {
+	passwordHashOnce.Do(func() {
+		passwordHash, _ = bcrypt.GenerateFromPassword([]byte("testpass"), bcrypt.DefaultCost)
+	})
+
+	return passwordHash
+}
+
+func seed() *data {
+	now := time.Now()
+	d := &data{lastIDs: map[string]int64{}}
+
+	for _, name := range []string{"Pending", "Approved", "Denied - Identity", "Denied - Banned", "Cancelled", "Expired"} {
+		d.statuses = append(d.statuses, models.Status{StatusID: d.nextID("statuses"), StatusName: name, Version: 1})
+	}
+
+	for _, feature := range [][2]string{
+		{"Towel Service", "Towels are provided at the location at no additional charge."},
+		{"Sauna", "A sauna is provided."},
+		{"Personal Viewing Screens", "Cardio equipment is equipped with a small television."},
+		{"Childcare (Free)", "Childcare is provided at no additional charge."},
+		{"Childcare (Extra Cost)", "Childcare is provided with an additional charge. (Please call this location for details.)"},
+		{"Wifi (Free)", "Wifi service is provided at no additional charge."},
+		{"Wifi (Extra Cost)", "Wifi service is provided with an additional charge. (Please call this location for deatils.)"},
+		{"Lap Pool (Indoor)", "An indoor lap pool is provided."},
+		{"Lap Pool (Outdoor)", "An outdoor lap pool is provided."},
+		{"Group Exercise", "Group Exercise classes are provided."},
+		{"Racquetball Court (Indoor)", "Indoor racquetball courts are provided."},
+		{"Racquetball Court (Outdoor)", "Outdoor racquetball courts are provided."},
+		{"Personal Training (Free)", "Free personal training is provided."},
+		{"Personal Training (Extra Cost)", "Personal traingin is provided at an additional cost. (Please call this location for details.)"},
+		{"Basketball Court (Full Size)", "Full size basketball courts are provided."},
+		{"Basektball Court (Partial)", "A small full court or half court basketball courts are provided."},
+		{"Strength Machines", "Strength training machines are provided."},
+		{"Free Weights (Full)", "A full range of free weights are provided. Including dumbbells, barbells, and squat racks."},
+		{"Free Weights (Partial)", "Some free weights are provided."},
+		{"Whirlpool", "A whirlpool hot tub is provided."},
+		{"Cardio Equipment", "Cardio Equipment is provided."},
+		{"Steam Room", "A steam room is provided."},
+		{"Locker Rooms", "Locker rooms are provided."},
+		{"Group Cycling (Spin)", "Group cycling or spin classes are provided."},
+	} {
+		d.features = append(d.features, models.Feature{
+			FeatureID:          d.nextID("features"),
+			FeatureName:        feature[0],
+			FeatureDescription: feature[1],
+		})
+	}
+
+	for _, email := range []string{"lukas.hambsch@gmail.com", "bugentry@hotmail.com"} {
+		verified := now
+		d.users = append(d.users, models.User{
+			UserID:          d.nextID("users"),
+			Email:           email,
+			PasswordHash:    seedPasswordHash(),
+			CreatedOn:       now,
+			EmailVerifiedOn: &verified,
+			Version:         1,
+		})
+	}
+
+	for _, name := range []string{models.AdminRole, models.EmployeeRole, models.GymRole, models.LocationRole, models.MemberRole} {
+		d.roles = append(d.roles, models.Role{RoleID: d.nextID("roles"), RoleName: name})
+	}
+
+	d.userRoles = []models.UserRole{
+		{UserRoleID: d.nextID("user_roles"), UserID: 1, RoleID: 1},
+		{UserRoleID: d.nextID("user_roles"), UserID: 2, RoleID: 2},
+	}
+
+	for _, path := range []string{"lukas-hambsch-profile.jpg", "mckenzie-hambsch-profile.jpg"} {
+		d.images = append(d.images, models.Image{ImageID: d.nextID("images"), ImagePath: path})
+	}
+
+	for i, name := range [][2]string{{"Lukas", "Hambsch"}, {"McKenzie", "Hambsch"}} {
+		imageID := int64(i + 1)
+		d.members = append(d.members, models.Member{
+			MemberID:  d.nextID("members"),
+			UserID:    int64(i + 1),
+			ImageID:   &imageID,
+			FirstName: name[0],
+			LastName:  name[1],
+			Version:   1,
+		})
+	}
+
+	d.plans = []models.Plan{
+		{PlanID: d.nextID("plans"), PlanName: "All Access", Price: 50},
+		{PlanID: d.nextID("plans"), PlanName: "All Access", Price: 0},
+	}
+	for _, member := range d.members {
+		planID, memberID := int64(2), member.MemberID
+		d.memberships = append(d.memberships, models.Membership{
+			MembershipID: d.nextID("memberships"),
+			PlanID:       &planID,
+			MemberID:     &memberID,
+			StartDate:    now,
+			Active:       true,
+		})
+	}
+
+	for _, name := range []string{"24 Hour Fitness", "LA Fitness", "Crunch Fitness", "YMCA"} {
+		d.gyms = append(d.gyms, models.Gym{GymID: d.nextID("gyms"), GymName: name})
+	}
+
+	d.addresses = []models.Address{
+		{AddressID: d.nextID("addresses"), Country: "USA", StateRegion: "CA", City: "San Diego", PostalArea: "92122", StreetAddress: "4425 La Jolla Village Dr"},
+		{AddressID: d.nextID("addresses"), Country: "USA", StateRegion: "CA", City: "San Diego", PostalArea: "92111", StreetAddress: "7715 Balboa Ave"},
+	}
+
+	d.gymLocations = []models.GymLocation{
+		{
+			GymLocationID: d.nextID("gym_locations"),
+			GymID:         1,
+			AddressID:     1,
+			LocationName:  "Westfield UTC",
+			PhoneNumber:   "858-457-3930",
+			WebsiteUrl:    "https://www.24hourfitness.com/Website/Club/00888",
+			Version:       1,
+		},
+		{
+			GymLocationID: d.nextID("gym_locations"),
+			GymID:         1,
+			AddressID:     2,
+			LocationName:  "Balboa",
+			PhoneNumber:   "858-292-7079",
+			WebsiteUrl:    "https://www.24hourfitness.com/Website/Club/00892",
+			Version:       1,
+		},
+	}
+
+	hour := func(h int) time.Time {
+		return time.Date(0, 1, 1, h, 0, 0, 0, time.UTC)
+	}
+	for _, gymLocation := range d.gymLocations {
+		for day := int64(1); day <= 7; day++ {
+			dayID := day
+			open, close := hour(6), hour(22)
+			switch day {
+			case 1:
+				open, close = hour(8), hour(20)
+			case 7:
+				close = hour(20)
+			}
+
+			d.businessHours = append(d.businessHours, models.BusinessHour{
+				BusinessHourID: d.nextID("business_hours"),
+				GymLocationID:  gymLocation.GymLocationID,
+				DayID:          &dayID,
+				OpenTime:       open,
+				CloseTime:      close,
+			})
+		}
+	}
+
+	for _, visit := range [][2]int64{{1, 1}, {2, 1}, {1, 2}, {2, 2}, {1, 1}} {
+		d.visits = append(d.visits, models.Visit{
+			VisitID:       d.nextID("visits"),
+			MemberID:      visit[0],
+			GymLocationID: visit[1],
+			StatusID:      1,
+			CreatedOn:     now,
+			Version:       1,
+		})
+	}
+
+	return d
+}
@@ -0,0 +1,77 @@
+package memory_test
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store"
+	"github.com/lukashambsch/anygym.api/store/memory"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transactions", func() {
+	var (
+		s       *store.Store
+		created *models.Status
+		err     error
+	)
+
+	BeforeEach(func() {
+		s = memory.New()
+	})
+
+	Describe("Successful transaction", func() {
+		BeforeEach(func() {
+			err = s.Transaction(ctx, func(tx *store.Store) error {
+				created, err = tx.Statuses.Create(ctx, models.Status{StatusName: "Committed"})
+				return err
+			})
+		})
+
+		It("should keep the changes", func() {
+			Expect(err).To(BeNil())
+			status, err := s.Statuses.Get(ctx, created.StatusID)
+			Expect(err).To(BeNil())
+			Expect(status.StatusName).To(Equal("Committed"))
+		})
+	})
+
+	Describe("Failed transaction", func() {
+		failed := errors.New("failed")
+
+		BeforeEach(func() {
+			err = s.Transaction(ctx, func(tx *store.Store) error {
+				created, _ = tx.Statuses.Create(ctx, models.Status{StatusName: "Rolled back"})
+				tx.Statuses.Update(ctx, 1, models.Status{StatusName: "Changed"})
+				return failed
+			})
+		})
+
+		It("should return fn's error", func() {
+			Expect(err).To(Equal(failed))
+		})
+
+		It("should undo the changes", func() {
+			_, err := s.Statuses.Get(ctx, created.StatusID)
+			Expect(err).To(Equal(sql.ErrNoRows))
+
+			status, _ := s.Statuses.Get(ctx, 1)
+			Expect(status.StatusName).To(Equal("Pending"))
+		})
+
+		It("should undo the changes of transactions inside it", func() {
+			s.Transaction(ctx, func(tx *store.Store) error {
+				tx.Transaction(ctx, func(inner *store.Store) error {
+					created, _ = inner.Statuses.Create(ctx, models.Status{StatusName: "Inner"})
+					return nil
+				})
+				return failed
+			})
+
+			_, err := s.Statuses.Get(ctx, created.StatusID)
+			Expect(err).To(Equal(sql.ErrNoRows))
+		})
+	})
+})
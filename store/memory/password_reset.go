@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+type passwordResets struct {
+	*data
+}
+
+func (r passwordResets) Create(ctx context.Context, passwordReset models.PasswordReset) (*models.PasswordReset, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	if r.user(passwordReset.UserID) < 0 {
+		return nil, foreignKeyViolation("password_resets", "user_id")
+	}
+	for _, other := range r.passwordResets {
+		if other.TokenHash == passwordReset.TokenHash {
+			return nil, uniqueViolation("password_resets", "token_hash")
+		}
+	}
+
+	created := models.PasswordReset{
+		PasswordResetID: r.nextID("password_resets"),
+		UserID:          passwordReset.UserID,
+		TokenHash:       passwordReset.TokenHash,
+		CreatedOn:       time.Now(),
+		ExpiresOn:       passwordReset.ExpiresOn,
+	}
+	r.passwordResets = append(r.passwordResets, created)
+
+	return &created, nil
+}
+
+func (r passwordResets) Consume(ctx context.Context, tokenHash string) (*models.PasswordReset, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	now := time.Now()
+	for i := range r.passwordResets {
+		passwordReset := &r.passwordResets[i]
+		if passwordReset.TokenHash == tokenHash && passwordReset.UsedOn == nil && passwordReset.ExpiresOn.After(now) {
+			passwordReset.UsedOn = &now
+			used := *passwordReset
+			return &used, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
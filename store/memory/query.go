@@ -0,0 +1,291 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+// row is a record as its columns, read from its json so the fields have the
+// same names the handlers filter and sort on. Columns that are null are nil.
+type row map[string]interface{}
+
+func columns(record interface{}) row {
+	data, _ := json.Marshal(record)
+
+	r := row{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	decoder.Decode(&r)
+
+	return r
+}
+
+// patch copies the fields of changes onto record, a pointer to the same
+// model, through their json.
+func patch(record interface{}, changes interface{}, fields []string) {
+	r, c := columns(record), columns(changes)
+	for _, field := range fields {
+		r[field] = c[field]
+	}
+
+	data, _ := json.Marshal(r)
+	json.Unmarshal(data, record)
+}
+
+// list returns the records of records, a slice, that match q's filters and
+// cursor, sorted and paged the way postgres would.
+func list(records interface{}, q datastore.Query) interface{} {
+	all := reflect.ValueOf(records)
+	matched := reflect.MakeSlice(all.Type(), 0, all.Len())
+	var rows []row
+
+	for i := 0; i < all.Len(); i++ {
+		r := columns(all.Index(i).Interface())
+		if !r.matches(q.Filters) {
+			continue
+		}
+		if len(q.Cursor) > 0 && !r.after(q) {
+			continue
+		}
+
+		matched = reflect.Append(matched, all.Index(i))
+		rows = append(rows, r)
+	}
+
+	sort.Stable(byQuery{matched, rows, q})
+
+	start, end := q.Offset, matched.Len()
+	if start > end {
+		start = end
+	}
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+	}
+
+	// like scanning rows into a nil slice, an empty list is nil
+	if start == end {
+		return reflect.Zero(all.Type()).Interface()
+	}
+
+	return matched.Slice(start, end).Interface()
+}
+
+// count returns how many of records match q's filters.
+func count(records interface{}, q datastore.Query) *int {
+	all := reflect.ValueOf(records)
+	n := 0
+	for i := 0; i < all.Len(); i++ {
+		if columns(all.Index(i).Interface()).matches(q.Filters) {
+			n++
+		}
+	}
+
+	return &n
+}
+
+func (r row) matches(filters []datastore.Filter) bool {
+	for _, filter := range filters {
+		if !r.match(filter) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r row) match(filter datastore.Filter) bool {
+	value := r[filter.Field]
+
+	switch filter.Operator {
+	case datastore.IsNull:
+		isNull, _ := filter.Value.(bool)
+		return (value == nil) == isNull
+	case datastore.In:
+		values := reflect.ValueOf(filter.Value)
+		if values.Kind() != reflect.Slice {
+			return false
+		}
+		for i := 0; i < values.Len(); i++ {
+			if value != nil && compare(value, values.Index(i).Interface()) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	// comparisons with null are never true
+	if value == nil || filter.Value == nil {
+		return false
+	}
+
+	switch filter.Operator {
+	case datastore.Contains:
+		s, ok := value.(string)
+		return ok && strings.Contains(s, fmt.Sprint(filter.Value))
+	case datastore.GreaterThan:
+		return compare(value, filter.Value) > 0
+	case datastore.GreaterOrEqual:
+		return compare(value, filter.Value) >= 0
+	case datastore.LessThan:
+		return compare(value, filter.Value) < 0
+	case datastore.LessOrEqual:
+		return compare(value, filter.Value) <= 0
+	default:
+		return compare(value, filter.Value) == 0
+	}
+}
+
+// order compares two rows by q's sort, reversed when paging backward. Nulls
+// sort last, the same as postgres does by default.
+func (r row) order(other row, q datastore.Query) int {
+	for i, s := range q.Sort {
+		var cursor interface{}
+		if other == nil {
+			cursor = q.Cursor[i]
+		} else {
+			cursor = other[s.Field]
+		}
+
+		c := compareNullsLast(r[s.Field], cursor)
+		if s.Descending != q.Backward {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// after reports whether the row comes after q's cursor.
+func (r row) after(q datastore.Query) bool {
+	return r.order(nil, q) > 0
+}
+
+type byQuery struct {
+	records reflect.Value
+	rows    []row
+	q       datastore.Query
+}
+
+func (s byQuery) Len() int {
+	return len(s.rows)
+}
+
+func (s byQuery) Less(i, j int) bool {
+	return s.rows[i].order(s.rows[j], s.q) < 0
+}
+
+func (s byQuery) Swap(i, j int) {
+	s.rows[i], s.rows[j] = s.rows[j], s.rows[i]
+
+	record := reflect.New(s.records.Type().Elem()).Elem()
+	record.Set(s.records.Index(i))
+	s.records.Index(i).Set(s.records.Index(j))
+	s.records.Index(j).Set(record)
+}
+
+func compareNullsLast(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	return compare(a, b)
+}
+
+// compare orders two non null values. Numbers compare as numbers and
+// timestamps as times, whichever form they come in.
+func compare(a, b interface{}) int {
+	a, b = normalize(a), normalize(b)
+
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			break
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case bool:
+		y, ok := b.(bool)
+		if !ok {
+			break
+		}
+		switch {
+		case x == y:
+			return 0
+		case y:
+			return -1
+		}
+		return 1
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			break
+		}
+		return strings.Compare(x, y)
+	}
+
+	if x, ok := timestamp(a); ok {
+		if y, ok := timestamp(b); ok {
+			switch {
+			case x.Before(y):
+				return -1
+			case x.After(y):
+				return 1
+			}
+			return 0
+		}
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// normalize turns every number into a float64 and timestamps in json form
+// into times.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return v.String()
+		}
+		return f
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t
+		}
+		return v
+	}
+
+	return value
+}
+
+func timestamp(value interface{}) (time.Time, bool) {
+	t, ok := value.(time.Time)
+	return t, ok
+}
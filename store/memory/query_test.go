@@ -0,0 +1,124 @@
+package memory_test
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	"github.com/lukashambsch/anygym.api/store/memory"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Queries", func() {
+	var (
+		s        *store.Store
+		statuses []models.Status
+	)
+
+	BeforeEach(func() {
+		s = memory.New()
+	})
+
+	names := func(statuses []models.Status) []string {
+		var names []string
+		for _, status := range statuses {
+			names = append(names, status.StatusName)
+		}
+		return names
+	}
+
+	Describe("Filters", func() {
+		It("should match partially with contains", func() {
+			statuses, _ = s.Statuses.List(ctx, datastore.Query{Filters: []datastore.Filter{{Field: "status_name", Operator: datastore.Contains, Value: "Denied"}}})
+			Expect(names(statuses)).To(Equal([]string{"Denied - Identity", "Denied - Banned"}))
+		})
+
+		It("should not treat wildcards in the value as wildcards", func() {
+			statuses, _ = s.Statuses.List(ctx, datastore.Query{Filters: []datastore.Filter{{Field: "status_name", Operator: datastore.Contains, Value: "%"}}})
+			Expect(statuses).To(BeEmpty())
+		})
+
+		It("should compare numbers", func() {
+			statuses, _ = s.Statuses.List(ctx, datastore.Query{Filters: []datastore.Filter{{Field: "status_id", Operator: datastore.GreaterThan, Value: int64(4)}}})
+			Expect(names(statuses)).To(Equal([]string{"Cancelled", "Expired"}))
+		})
+
+		It("should match any of the values with in", func() {
+			statuses, _ = s.Statuses.List(ctx, datastore.Query{Filters: []datastore.Filter{{Field: "status_id", Operator: datastore.In, Value: []interface{}{int64(1), int64(3)}}}})
+			Expect(names(statuses)).To(Equal([]string{"Pending", "Denied - Identity"}))
+		})
+
+		It("should match nulls", func() {
+			visits, _ := s.Visits.List(ctx, datastore.Unscoped, datastore.Query{Filters: []datastore.Filter{{Field: "modified_on", Operator: datastore.IsNull, Value: true}}})
+			Expect(len(visits)).To(Equal(5))
+		})
+
+		It("should count the matching rows", func() {
+			count, _ := s.Statuses.Count(ctx, datastore.Query{Filters: []datastore.Filter{{Field: "status_name", Operator: datastore.Contains, Value: "Denied"}}})
+			Expect(*count).To(Equal(2))
+		})
+	})
+
+	Describe("Sort and pages", func() {
+		sort := []datastore.Sort{{Field: "status_name"}, {Field: "status_id"}}
+
+		It("should sort", func() {
+			statuses, _ = s.Statuses.List(ctx, datastore.Query{Sort: []datastore.Sort{{Field: "status_id", Descending: true}}})
+			Expect(statuses[0].StatusName).To(Equal("Expired"))
+		})
+
+		It("should page with limit and offset", func() {
+			statuses, _ = s.Statuses.List(ctx, datastore.Query{Sort: sort, Limit: 2, Offset: 1})
+			Expect(names(statuses)).To(Equal([]string{"Cancelled", "Denied - Banned"}))
+		})
+
+		It("should continue after a cursor", func() {
+			statuses, _ = s.Statuses.List(ctx, datastore.Query{Sort: sort, Limit: 2, Cursor: []interface{}{"Denied - Identity", json.Number("3")}})
+			Expect(names(statuses)).To(Equal([]string{"Expired", "Pending"}))
+		})
+
+		It("should go back from a cursor, closest first", func() {
+			statuses, _ = s.Statuses.List(ctx, datastore.Query{Sort: sort, Limit: 2, Cursor: []interface{}{"Denied - Identity", json.Number("3")}, Backward: true})
+			Expect(names(statuses)).To(Equal([]string{"Denied - Banned", "Cancelled"}))
+		})
+
+		It("should return nil for an empty page", func() {
+			statuses, _ = s.Statuses.List(ctx, datastore.Query{Offset: 10})
+			Expect(statuses).To(BeNil())
+		})
+	})
+
+	Describe("Constraints", func() {
+		It("should not allow duplicate status names", func() {
+			_, err := s.Statuses.Create(ctx, models.Status{StatusName: "Pending"})
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("should not create a visit for a missing member", func() {
+			_, err := s.Visits.Create(ctx, models.Visit{MemberID: 99999, GymLocationID: 1, StatusID: 1})
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("should delete a user's member along with them", func() {
+			user, _ := s.Users.Create(ctx, models.User{Email: "cascade@email.com"})
+			member, _ := s.Members.Create(ctx, models.Member{UserID: user.UserID, FirstName: "Cascade"})
+
+			Expect(s.Users.Delete(ctx, user.UserID, 0)).To(BeNil())
+			_, err := s.Members.Get(ctx, datastore.Unscoped, member.MemberID)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Describe("Contexts", func() {
+		It("should fail calls made after the context is done", func() {
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+
+			_, err := s.Statuses.List(cancelled, datastore.Query{})
+			Expect(err).To(Equal(context.Canceled))
+		})
+	})
+})
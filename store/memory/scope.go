@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+// The scope checks mirror the conditions of datastore.Scope. They're called
+// with d locked.
+
+// sees reports whether any of the caller's roles lets them see a row. Roles
+// that aren't in byRole don't see anything.
+func sees(s datastore.Scope, byRole map[string]func() bool) bool {
+	if s.All {
+		return true
+	}
+
+	for _, role := range s.Roles {
+		if role == models.AdminRole || role == models.EmployeeRole {
+			return true
+		}
+		if check, ok := byRole[role]; ok && check() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func always() bool {
+	return true
+}
+
+// live mirrors Scope.live, deleted rows are only seen if the scope asks for
+// them.
+func live(s datastore.Scope, deletedOn *time.Time) bool {
+	return deletedOn == nil || s.Deleted
+}
+
+// ownsGym reports whether the gym belongs to a gym user or is the gym of an
+// API key.
+func (d *data) ownsGym(s datastore.Scope, gymID int64) bool {
+	if s.GymID != 0 {
+		return gymID == s.GymID
+	}
+
+	i := d.gym(gymID)
+	return i >= 0 && d.gyms[i].UserID != nil && *d.gyms[i].UserID == s.UserID
+}
+
+func (d *data) managesLocation(s datastore.Scope, gymLocationID int64) bool {
+	i := d.gymLocation(gymLocationID)
+	return i >= 0 && d.gymLocations[i].UserID != nil && *d.gymLocations[i].UserID == s.UserID
+}
+
+func (d *data) ownsLocation(s datastore.Scope, gymLocationID int64) bool {
+	i := d.gymLocation(gymLocationID)
+	return i >= 0 && d.ownsGym(s, d.gymLocations[i].GymID)
+}
+
+func (d *data) seesVisit(s datastore.Scope, visit models.Visit) bool {
+	return sees(s, map[string]func() bool{
+		models.MemberRole: func() bool {
+			i := d.member(visit.MemberID)
+			return i >= 0 && d.members[i].UserID == s.UserID
+		},
+		models.LocationRole: func() bool {
+			return d.managesLocation(s, visit.GymLocationID)
+		},
+		models.GymRole: func() bool {
+			return d.ownsLocation(s, visit.GymLocationID)
+		},
+	})
+}
+
+// seesMember lets locations and gyms see the members that have visited them,
+// but only members can change themselves.
+func (d *data) seesMember(s datastore.Scope, member models.Member) bool {
+	visited := func(at func(s datastore.Scope, gymLocationID int64) bool) func() bool {
+		return func() bool {
+			for _, visit := range d.visits {
+				if visit.MemberID == member.MemberID && at(s, visit.GymLocationID) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	byRole := map[string]func() bool{
+		models.MemberRole: func() bool {
+			return member.UserID == s.UserID
+		},
+	}
+	if !s.Write {
+		byRole[models.LocationRole] = visited(d.managesLocation)
+		byRole[models.GymRole] = visited(d.ownsLocation)
+	}
+
+	return live(s, member.DeletedOn) && sees(s, byRole)
+}
+
+func (d *data) seesUser(s datastore.Scope, user models.User) bool {
+	self := func() bool {
+		return user.UserID == s.UserID
+	}
+
+	return sees(s, map[string]func() bool{
+		models.MemberRole:   self,
+		models.LocationRole: self,
+		models.GymRole:      self,
+	})
+}
+
+func (d *data) seesGymLocation(s datastore.Scope, gymLocation models.GymLocation) bool {
+	byRole := map[string]func() bool{
+		models.MemberRole:   always,
+		models.LocationRole: always,
+		models.GymRole: func() bool {
+			return d.ownsGym(s, gymLocation.GymID)
+		},
+	}
+	if s.Write {
+		delete(byRole, models.MemberRole)
+		byRole[models.LocationRole] = func() bool {
+			return d.managesLocation(s, gymLocation.GymLocationID)
+		}
+	}
+
+	return live(s, gymLocation.DeletedOn) && sees(s, byRole)
+}
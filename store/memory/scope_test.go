@@ -0,0 +1,101 @@
+package memory_test
+
+import (
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+	"github.com/lukashambsch/anygym.api/store/memory"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scope", func() {
+	var s *store.Store
+
+	BeforeEach(func() {
+		s = memory.New()
+	})
+
+	Describe("Admin", func() {
+		It("should see every visit", func() {
+			visits, _ := s.Visits.List(ctx, datastore.Scope{UserID: 1, Roles: []string{models.AdminRole}}, datastore.Query{})
+			Expect(len(visits)).To(Equal(5))
+		})
+	})
+
+	Describe("Member", func() {
+		var scope = datastore.Scope{UserID: 2, Roles: []string{models.MemberRole}}
+
+		It("should only see their own visits", func() {
+			visits, _ := s.Visits.List(ctx, scope, datastore.Query{})
+			Expect(len(visits)).To(Equal(2))
+			count, _ := s.Visits.Count(ctx, scope, datastore.Query{})
+			Expect(*count).To(Equal(2))
+		})
+
+		It("should not get another member by id", func() {
+			member, err := s.Members.Get(ctx, scope, 1)
+			Expect(err).ToNot(BeNil())
+			Expect(member).To(BeNil())
+		})
+
+		It("should see every gym location", func() {
+			gymLocations, _ := s.GymLocations.List(ctx, scope, datastore.Query{})
+			Expect(len(gymLocations)).To(Equal(2))
+		})
+	})
+
+	Describe("Location", func() {
+		var scope = datastore.Scope{UserID: 2, Roles: []string{models.LocationRole}}
+
+		BeforeEach(func() {
+			gymLocation, _ := s.GymLocations.Get(ctx, datastore.Unscoped, 2)
+			gymLocation.UserID = &scope.UserID
+			s.GymLocations.Update(ctx, 2, *gymLocation)
+		})
+
+		It("should only see visits at their location", func() {
+			visits, _ := s.Visits.List(ctx, scope, datastore.Query{})
+			Expect(len(visits)).To(Equal(2))
+		})
+
+		It("should see the members that visited their location", func() {
+			members, _ := s.Members.List(ctx, scope, datastore.Query{})
+			Expect(len(members)).To(Equal(2))
+		})
+
+		It("should only change their own location", func() {
+			write := scope
+			write.Write = true
+			gymLocations, _ := s.GymLocations.List(ctx, write, datastore.Query{})
+			Expect(len(gymLocations)).To(Equal(1))
+			Expect(gymLocations[0].GymLocationID).To(Equal(int64(2)))
+		})
+
+		It("should not change the members that visited their location", func() {
+			write := scope
+			write.Write = true
+			members, _ := s.Members.List(ctx, write, datastore.Query{})
+			Expect(members).To(BeEmpty())
+		})
+	})
+
+	Describe("Gym", func() {
+		It("should see visits at all of its locations", func() {
+			visits, _ := s.Visits.List(ctx, datastore.Scope{GymID: 1, Roles: []string{models.GymRole}}, datastore.Query{})
+			Expect(len(visits)).To(Equal(5))
+		})
+
+		It("should only see its own locations", func() {
+			gymLocations, _ := s.GymLocations.List(ctx, datastore.Scope{GymID: 2, Roles: []string{models.GymRole}}, datastore.Query{})
+			Expect(gymLocations).To(BeEmpty())
+		})
+	})
+
+	Describe("No roles", func() {
+		It("should not see anything", func() {
+			visits, _ := s.Visits.List(ctx, datastore.Scope{UserID: 1}, datastore.Query{})
+			Expect(visits).To(BeEmpty())
+		})
+	})
+})
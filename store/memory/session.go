@@ -0,0 +1,182 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+type sessions struct {
+	*data
+}
+
+func (r sessions) Get(ctx context.Context, sessionID int64) (*models.Session, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.session(sessionID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	session := r.sessions[i]
+	return &session, nil
+}
+
+func (r sessions) GetByTokenHash(ctx context.Context, refreshTokenHash string) (*models.Session, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	for _, session := range r.sessions {
+		if session.RefreshTokenHash == refreshTokenHash {
+			return &session, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (r sessions) Create(ctx context.Context, session models.Session) (*models.Session, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	if r.user(session.UserID) < 0 {
+		return nil, foreignKeyViolation("sessions", "user_id")
+	}
+	if r.tokenHashTaken(session.RefreshTokenHash, 0) {
+		return nil, uniqueViolation("sessions", "refresh_token_hash")
+	}
+
+	created := models.Session{
+		SessionID:        r.nextID("sessions"),
+		UserID:           session.UserID,
+		RefreshTokenHash: session.RefreshTokenHash,
+		CreatedOn:        time.Now(),
+		ExpiresOn:        session.ExpiresOn,
+		TwoFactor:        session.TwoFactor,
+	}
+	r.sessions = append(r.sessions, created)
+
+	return &created, nil
+}
+
+func (r sessions) Update(ctx context.Context, sessionID int64, session models.Session) (*models.Session, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.session(sessionID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+	if r.tokenHashTaken(session.RefreshTokenHash, sessionID) {
+		return nil, uniqueViolation("sessions", "refresh_token_hash")
+	}
+
+	r.sessions[i].RefreshTokenHash = session.RefreshTokenHash
+	r.sessions[i].ExpiresOn = session.ExpiresOn
+	r.sessions[i].RevokedOn = session.RevokedOn
+	r.sessions[i].TwoFactor = session.TwoFactor
+
+	updated := r.sessions[i]
+	return &updated, nil
+}
+
+func (r sessions) Rotate(ctx context.Context, sessionID int64, refreshTokenHash string, session models.Session) (*models.Session, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.session(sessionID)
+	if i < 0 || r.sessions[i].RefreshTokenHash != refreshTokenHash || r.sessions[i].RevokedOn != nil {
+		return nil, sql.ErrNoRows
+	}
+	if r.tokenHashTaken(session.RefreshTokenHash, sessionID) {
+		return nil, uniqueViolation("sessions", "refresh_token_hash")
+	}
+
+	r.sessions[i].RefreshTokenHash = session.RefreshTokenHash
+	r.sessions[i].ExpiresOn = session.ExpiresOn
+
+	rotated := r.sessions[i]
+	return &rotated, nil
+}
+
+func (r sessions) Revoke(ctx context.Context, sessionID int64) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.session(sessionID)
+	if i < 0 || r.sessions[i].RevokedOn != nil {
+		return sql.ErrNoRows
+	}
+
+	now := time.Now()
+	r.sessions[i].RevokedOn = &now
+	return nil
+}
+
+func (r sessions) RevokeUser(ctx context.Context, userID int64) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	now := time.Now()
+	for i := range r.sessions {
+		if r.sessions[i].UserID == userID && r.sessions[i].RevokedOn == nil {
+			r.sessions[i].RevokedOn = &now
+		}
+	}
+
+	return nil
+}
+
+func (r sessions) RevokeOthers(ctx context.Context, userID int64, sessionID int64) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	now := time.Now()
+	for i := range r.sessions {
+		if r.sessions[i].UserID == userID && r.sessions[i].SessionID != sessionID && r.sessions[i].RevokedOn == nil {
+			r.sessions[i].RevokedOn = &now
+		}
+	}
+
+	return nil
+}
+
+func (d *data) session(sessionID int64) int {
+	for i := range d.sessions {
+		if d.sessions[i].SessionID == sessionID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// tokenHashTaken reports whether a session other than except has the hash.
+func (d *data) tokenHashTaken(refreshTokenHash string, except int64) bool {
+	for _, session := range d.sessions {
+		if session.RefreshTokenHash == refreshTokenHash && session.SessionID != except {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+type statuses struct {
+	*data
+}
+
+func (r statuses) List(ctx context.Context, q datastore.Query) ([]models.Status, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return list(r.statuses, q).([]models.Status), nil
+}
+
+func (r statuses) Count(ctx context.Context, q datastore.Query) (*int, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return count(r.statuses, q), nil
+}
+
+func (r statuses) Get(ctx context.Context, statusID int64) (*models.Status, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.status(statusID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	status := r.statuses[i]
+	return &status, nil
+}
+
+func (r statuses) Create(ctx context.Context, status models.Status) (*models.Status, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	if r.statusNamed(status.StatusName, 0) {
+		return nil, uniqueViolation("statuses", "status_name")
+	}
+
+	status.StatusID = r.nextID("statuses")
+	status.Version = 1
+	r.statuses = append(r.statuses, status)
+
+	return &status, nil
+}
+
+func (r statuses) Update(ctx context.Context, statusID int64, status models.Status) (*models.Status, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return r.updateStatus(statusID, status)
+}
+
+func (r statuses) Patch(ctx context.Context, statusID int64, status models.Status, fields []string) (*models.Status, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.status(statusID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	patched := r.statuses[i]
+	patch(&patched, status, fields)
+	patched.Version = status.Version
+	return r.updateStatus(statusID, patched)
+}
+
+// updateStatus is Update with d locked.
+func (d *data) updateStatus(statusID int64, status models.Status) (*models.Status, error) {
+	i := d.status(statusID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+	if err := checkVersion(d.statuses[i].Version, status.Version); err != nil {
+		return nil, err
+	}
+	if d.statusNamed(status.StatusName, statusID) {
+		return nil, uniqueViolation("statuses", "status_name")
+	}
+
+	d.statuses[i].StatusName = status.StatusName
+	d.statuses[i].Version++
+
+	updated := d.statuses[i]
+	return &updated, nil
+}
+
+func (r statuses) Delete(ctx context.Context, statusID int64, version int64) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.status(statusID)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+	if err := checkVersion(r.statuses[i].Version, version); err != nil {
+		return err
+	}
+	for _, visit := range r.visits {
+		if visit.StatusID == statusID {
+			return stillReferenced("visits", "status_id")
+		}
+	}
+
+	r.statuses = append(r.statuses[:i], r.statuses[i+1:]...)
+	return nil
+}
+
+func (d *data) status(statusID int64) int {
+	for i := range d.statuses {
+		if d.statuses[i].StatusID == statusID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// statusNamed reports whether a status other than except has the name.
+func (d *data) statusNamed(name string, except int64) bool {
+	for _, status := range d.statuses {
+		if status.StatusName == name && status.StatusID != except {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+)
+
+type twoFactors struct {
+	*data
+}
+
+func (r twoFactors) Get(ctx context.Context, userID int64) (*models.TwoFactor, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.twoFactor(userID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	twoFactor := r.twoFactors[i]
+	return &twoFactor, nil
+}
+
+func (r twoFactors) Save(ctx context.Context, twoFactor models.TwoFactor) (*models.TwoFactor, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	if r.user(twoFactor.UserID) < 0 {
+		return nil, foreignKeyViolation("two_factors", "user_id")
+	}
+
+	saved := models.TwoFactor{
+		UserID:    twoFactor.UserID,
+		Secret:    twoFactor.Secret,
+		CreatedOn: time.Now(),
+	}
+	if i := r.twoFactor(twoFactor.UserID); i >= 0 {
+		r.twoFactors[i] = saved
+	} else {
+		r.twoFactors = append(r.twoFactors, saved)
+	}
+
+	return &saved, nil
+}
+
+func (r twoFactors) Enable(ctx context.Context, userID int64) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.twoFactor(userID)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+
+	now := time.Now()
+	r.twoFactors[i].EnabledOn = &now
+	return nil
+}
+
+func (r twoFactors) UseStep(ctx context.Context, userID int64, step int64) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.twoFactor(userID)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+
+	lastStep := r.twoFactors[i].LastStep
+	if lastStep != nil && *lastStep >= step {
+		return sql.ErrNoRows
+	}
+
+	r.twoFactors[i].LastStep = &step
+	return nil
+}
+
+func (r twoFactors) Delete(ctx context.Context, userID int64) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.twoFactor(userID)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+
+	r.twoFactors = append(r.twoFactors[:i], r.twoFactors[i+1:]...)
+	return nil
+}
+
+func (r twoFactors) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	if r.user(userID) < 0 && len(codeHashes) > 0 {
+		return foreignKeyViolation("recovery_codes", "user_id")
+	}
+
+	var recoveryCodes []recoveryCode
+	for _, code := range r.recoveryCodes {
+		if code.userID != userID {
+			recoveryCodes = append(recoveryCodes, code)
+		}
+	}
+	for _, codeHash := range codeHashes {
+		recoveryCodes = append(recoveryCodes, recoveryCode{userID: userID, codeHash: codeHash})
+	}
+	r.recoveryCodes = recoveryCodes
+
+	return nil
+}
+
+func (r twoFactors) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	for i := range r.recoveryCodes {
+		code := &r.recoveryCodes[i]
+		if code.userID == userID && code.codeHash == codeHash && code.usedOn == nil {
+			now := time.Now()
+			code.usedOn = &now
+			return nil
+		}
+	}
+
+	return sql.ErrNoRows
+}
+
+func (d *data) twoFactor(userID int64) int {
+	for i := range d.twoFactors {
+		if d.twoFactors[i].UserID == userID {
+			return i
+		}
+	}
+
+	return -1
+}
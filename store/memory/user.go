@@ -0,0 +1,388 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+type users struct {
+	*data
+}
+
+func (r users) List(ctx context.Context, scope datastore.Scope, q datastore.Query) ([]models.User, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	users := list(r.visibleUsers(scope), q).([]models.User)
+	for i := range users {
+		users[i].Roles = r.rolesOf(users[i].UserID)
+	}
+
+	return users, nil
+}
+
+func (r users) Count(ctx context.Context, scope datastore.Scope, q datastore.Query) (*int, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return count(r.visibleUsers(scope), q), nil
+}
+
+func (r users) Get(ctx context.Context, userID int64) (*models.User, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	if r.user(userID) < 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return r.withRoles(userID), nil
+}
+
+func (r users) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			return r.withRoles(user.UserID), nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (r users) Roles(ctx context.Context, userID int64) ([]*models.Role, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return r.rolesOf(userID), nil
+}
+
+func (r users) Create(ctx context.Context, user models.User) (*models.User, error) {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	if user.Email == "" {
+		return nil, checkViolation("users", "valid_name")
+	}
+	if r.emailTaken(user.Email, 0) {
+		return nil, uniqueViolation("users", "email")
+	}
+
+	created := models.User{
+		UserID:          r.nextID("users"),
+		Email:           user.Email,
+		Token:           user.Token,
+		PasswordHash:    passwordHash,
+		CreatedOn:       time.Now(),
+		EmailVerifiedOn: user.EmailVerifiedOn,
+		Version:         1,
+	}
+	r.users = append(r.users, created)
+
+	return &created, nil
+}
+
+func (r users) AddRole(ctx context.Context, userID int64, roleName string) (*models.Role, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	var role *models.Role
+	for i := range r.roles {
+		if r.roles[i].RoleName == roleName {
+			role = &models.Role{RoleID: r.roles[i].RoleID, RoleName: roleName}
+		}
+	}
+	if role == nil {
+		return nil, sql.ErrNoRows
+	}
+	if r.user(userID) < 0 {
+		return nil, foreignKeyViolation("user_roles", "user_id")
+	}
+	for _, userRole := range r.userRoles {
+		if userRole.UserID == userID && userRole.RoleID == role.RoleID {
+			return nil, uniqueViolation("user_roles", "user_id", "role_id")
+		}
+	}
+
+	r.userRoles = append(r.userRoles, models.UserRole{
+		UserRoleID: r.nextID("user_roles"),
+		UserID:     userID,
+		RoleID:     role.RoleID,
+	})
+
+	return role, nil
+}
+
+func (r users) Update(ctx context.Context, userID int64, user models.User) (*models.User, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return r.updateUser(userID, user)
+}
+
+func (r users) Patch(ctx context.Context, userID int64, user models.User, fields []string) (*models.User, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.user(userID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	patched := r.users[i]
+	patch(&patched, user, fields)
+	patched.Version = user.Version
+	return r.updateUser(userID, patched)
+}
+
+// updateUser is Update with d locked.
+func (d *data) updateUser(userID int64, user models.User) (*models.User, error) {
+	i := d.user(userID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+	if err := checkVersion(d.users[i].Version, user.Version); err != nil {
+		return nil, err
+	}
+	if user.Email == "" {
+		return nil, checkViolation("users", "valid_name")
+	}
+	if d.emailTaken(user.Email, userID) {
+		return nil, uniqueViolation("users", "email")
+	}
+
+	if d.users[i].Email != user.Email {
+		d.users[i].EmailVerifiedOn = nil
+	}
+	d.users[i].Email = user.Email
+	d.users[i].Version++
+
+	updated := d.users[i]
+	return &updated, nil
+}
+
+func (r users) UpdatePassword(ctx context.Context, userID int64, password string) error {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.user(userID)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+
+	r.users[i].PasswordHash = passwordHash
+	r.users[i].Version++
+	return nil
+}
+
+func (r users) VerifyEmail(ctx context.Context, userID int64, email string) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.user(userID)
+	if i < 0 || r.users[i].Email != email {
+		return sql.ErrNoRows
+	}
+
+	if r.users[i].EmailVerifiedOn == nil {
+		now := time.Now()
+		r.users[i].EmailVerifiedOn = &now
+	}
+	r.users[i].Version++
+	return nil
+}
+
+// Delete cascades to everything that belongs to the user, the same as the
+// foreign keys do.
+func (r users) Delete(ctx context.Context, userID int64, version int64) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.user(userID)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+	if err := checkVersion(r.users[i].Version, version); err != nil {
+		return err
+	}
+
+	for _, gym := range r.gyms {
+		if gym.UserID != nil && *gym.UserID == userID {
+			return stillReferenced("gyms", "user_id")
+		}
+	}
+
+	var members []models.Member
+	for _, member := range r.members {
+		if member.UserID != userID {
+			members = append(members, member)
+			continue
+		}
+		for _, visit := range r.visits {
+			if visit.MemberID == member.MemberID {
+				return stillReferenced("visits", "member_id")
+			}
+		}
+		r.deleteMemberships(member.MemberID)
+	}
+	r.members = members
+
+	var userRoles []models.UserRole
+	for _, userRole := range r.userRoles {
+		if userRole.UserID != userID {
+			userRoles = append(userRoles, userRole)
+		}
+	}
+	r.userRoles = userRoles
+
+	var sessions []models.Session
+	for _, session := range r.sessions {
+		if session.UserID != userID {
+			sessions = append(sessions, session)
+		}
+	}
+	r.sessions = sessions
+
+	var passwordResets []models.PasswordReset
+	for _, passwordReset := range r.passwordResets {
+		if passwordReset.UserID != userID {
+			passwordResets = append(passwordResets, passwordReset)
+		}
+	}
+	r.passwordResets = passwordResets
+
+	var twoFactors []models.TwoFactor
+	for _, twoFactor := range r.twoFactors {
+		if twoFactor.UserID != userID {
+			twoFactors = append(twoFactors, twoFactor)
+		}
+	}
+	r.twoFactors = twoFactors
+
+	var recoveryCodes []recoveryCode
+	for _, code := range r.recoveryCodes {
+		if code.userID != userID {
+			recoveryCodes = append(recoveryCodes, code)
+		}
+	}
+	r.recoveryCodes = recoveryCodes
+
+	var images []models.Image
+	for _, image := range r.images {
+		if image.UserID == nil || *image.UserID != userID {
+			images = append(images, image)
+		}
+	}
+	r.images = images
+
+	for j := range r.gymLocations {
+		if r.gymLocations[j].UserID != nil && *r.gymLocations[j].UserID == userID {
+			r.gymLocations[j].UserID = nil
+		}
+	}
+
+	r.users = append(r.users[:i], r.users[i+1:]...)
+	return nil
+}
+
+func (d *data) user(userID int64) int {
+	for i := range d.users {
+		if d.users[i].UserID == userID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// withRoles returns a copy of the user with their roles.
+func (d *data) withRoles(userID int64) *models.User {
+	i := d.user(userID)
+	if i < 0 {
+		return nil
+	}
+
+	user := d.users[i]
+	user.Roles = d.rolesOf(userID)
+	return &user
+}
+
+func (d *data) rolesOf(userID int64) []*models.Role {
+	var roles []*models.Role
+	for _, userRole := range d.userRoles {
+		if userRole.UserID != userID {
+			continue
+		}
+		for _, role := range d.roles {
+			if role.RoleID == userRole.RoleID {
+				roles = append(roles, &models.Role{RoleID: role.RoleID, RoleName: role.RoleName})
+			}
+		}
+	}
+
+	return roles
+}
+
+func (d *data) visibleUsers(scope datastore.Scope) []models.User {
+	var visible []models.User
+	for _, user := range d.users {
+		if d.seesUser(scope, user) {
+			visible = append(visible, user)
+		}
+	}
+
+	return visible
+}
+
+// emailTaken reports whether a user other than except has the email.
+func (d *data) emailTaken(email string, except int64) bool {
+	for _, user := range d.users {
+		if user.Email == email && user.UserID != except {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,176 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+type visits struct {
+	*data
+}
+
+func (r visits) List(ctx context.Context, scope datastore.Scope, q datastore.Query) ([]models.Visit, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return list(r.visibleVisits(scope), q).([]models.Visit), nil
+}
+
+func (r visits) Count(ctx context.Context, scope datastore.Scope, q datastore.Query) (*int, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return count(r.visibleVisits(scope), q), nil
+}
+
+func (r visits) Get(ctx context.Context, scope datastore.Scope, visitID int64) (*models.Visit, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.visit(visitID)
+	if i < 0 || !r.seesVisit(scope, r.visits[i]) {
+		return nil, sql.ErrNoRows
+	}
+
+	visit := r.visits[i]
+	return &visit, nil
+}
+
+func (r visits) Create(ctx context.Context, visit models.Visit) (*models.Visit, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	err := r.checkVisit(visit)
+	if err != nil {
+		return nil, err
+	}
+
+	created := models.Visit{
+		VisitID:       r.nextID("visits"),
+		MemberID:      visit.MemberID,
+		GymLocationID: visit.GymLocationID,
+		StatusID:      visit.StatusID,
+		CreatedOn:     time.Now(),
+		Version:       1,
+	}
+	r.visits = append(r.visits, created)
+
+	return &created, nil
+}
+
+func (r visits) Update(ctx context.Context, visitID int64, visit models.Visit) (*models.Visit, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	return r.updateVisit(visitID, visit)
+}
+
+func (r visits) Patch(ctx context.Context, visitID int64, visit models.Visit, fields []string) (*models.Visit, error) {
+	if err := r.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer r.Unlock()
+
+	i := r.visit(visitID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	patched := r.visits[i]
+	patch(&patched, visit, fields)
+	patched.Version = visit.Version
+	return r.updateVisit(visitID, patched)
+}
+
+// updateVisit is Update with d locked.
+func (d *data) updateVisit(visitID int64, visit models.Visit) (*models.Visit, error) {
+	i := d.visit(visitID)
+	if i < 0 {
+		return nil, sql.ErrNoRows
+	}
+	err := checkVersion(d.visits[i].Version, visit.Version)
+	if err != nil {
+		return nil, err
+	}
+	err = d.checkVisit(visit)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	d.visits[i].MemberID = visit.MemberID
+	d.visits[i].GymLocationID = visit.GymLocationID
+	d.visits[i].StatusID = visit.StatusID
+	d.visits[i].ModifiedOn = &now
+	d.visits[i].Version++
+
+	updated := d.visits[i]
+	return &updated, nil
+}
+
+func (r visits) Delete(ctx context.Context, visitID int64, version int64) error {
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.Unlock()
+
+	i := r.visit(visitID)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+	if err := checkVersion(r.visits[i].Version, version); err != nil {
+		return err
+	}
+
+	r.visits = append(r.visits[:i], r.visits[i+1:]...)
+	return nil
+}
+
+func (d *data) visit(visitID int64) int {
+	for i := range d.visits {
+		if d.visits[i].VisitID == visitID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (d *data) visibleVisits(scope datastore.Scope) []models.Visit {
+	var visible []models.Visit
+	for _, visit := range d.visits {
+		if d.seesVisit(scope, visit) {
+			visible = append(visible, visit)
+		}
+	}
+
+	return visible
+}
+
+// checkVisit checks the visit's references the way its foreign keys do.
+func (d *data) checkVisit(visit models.Visit) error {
+	switch {
+	case d.member(visit.MemberID) < 0:
+		return foreignKeyViolation("visits", "member_id")
+	case d.gymLocation(visit.GymLocationID) < 0:
+		return foreignKeyViolation("visits", "gym_location_id")
+	case d.status(visit.StatusID) < 0:
+		return foreignKeyViolation("visits", "status_id")
+	}
+
+	return nil
+}
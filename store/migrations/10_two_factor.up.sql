@@ -0,0 +1,15 @@
+CREATE TABLE two_factors (
+ user_id    INTEGER     PRIMARY KEY REFERENCES users ON DELETE CASCADE
+,secret     VARCHAR(64) NOT NULL
+,created_on TIMESTAMP   WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+,enabled_on TIMESTAMP   WITH TIME ZONE
+,last_step  BIGINT
+);
+
+CREATE TABLE recovery_codes (
+ recovery_code_id SERIAL      PRIMARY KEY
+,user_id          INTEGER     NOT NULL REFERENCES users ON DELETE CASCADE
+,code_hash        VARCHAR(64) NOT NULL
+,used_on          TIMESTAMP   WITH TIME ZONE
+,UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE users DROP COLUMN version;
+ALTER TABLE gym_locations DROP COLUMN version;
+ALTER TABLE members DROP COLUMN version;
+ALTER TABLE visits DROP COLUMN version;
+ALTER TABLE statuses DROP COLUMN version;
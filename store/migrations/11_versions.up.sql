@@ -0,0 +1,7 @@
+-- bumped by every update, so writes can be made conditional on the version
+-- the client last read
+ALTER TABLE statuses ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE visits ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE members ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE gym_locations ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+ALTER TABLE gym_locations DROP COLUMN deleted_on;
+ALTER TABLE gyms DROP COLUMN deleted_on;
+ALTER TABLE members DROP COLUMN deleted_on;
@@ -0,0 +1,5 @@
+-- members, gyms and gym locations have visit and billing history, so
+-- deleting them only marks them deleted
+ALTER TABLE members ADD COLUMN deleted_on TIMESTAMP WITH TIME ZONE;
+ALTER TABLE gyms ADD COLUMN deleted_on TIMESTAMP WITH TIME ZONE;
+ALTER TABLE gym_locations ADD COLUMN deleted_on TIMESTAMP WITH TIME ZONE;
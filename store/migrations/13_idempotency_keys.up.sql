@@ -0,0 +1,11 @@
+CREATE TABLE idempotency_keys (
+ owner            VARCHAR(64)  NOT NULL
+,idempotency_key  VARCHAR(255) NOT NULL
+,fingerprint      VARCHAR(64)  NOT NULL
+,response_status  INTEGER
+,response_headers TEXT
+,response_body    BYTEA
+,created_on       TIMESTAMP    WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+,expires_on       TIMESTAMP    WITH TIME ZONE NOT NULL
+,PRIMARY KEY (owner, idempotency_key)
+);
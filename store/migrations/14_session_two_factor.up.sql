@@ -0,0 +1,2 @@
+-- whether the session was opened with a second factor, admins need one
+ALTER TABLE sessions ADD COLUMN two_factor BOOLEAN NOT NULL DEFAULT FALSE;
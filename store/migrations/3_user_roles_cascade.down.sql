@@ -0,0 +1,3 @@
+ALTER TABLE user_roles
+DROP CONSTRAINT user_roles_user_id_fkey,
+ADD CONSTRAINT user_roles_user_id_fkey FOREIGN KEY (user_id) REFERENCES users;
@@ -0,0 +1,10 @@
+-- One row per login. Refresh tokens are only stored as sha256 hashes and are
+-- replaced every time they are used.
+CREATE TABLE sessions (
+ session_id         SERIAL      PRIMARY KEY
+,user_id            INTEGER     NOT NULL REFERENCES users ON DELETE CASCADE
+,refresh_token_hash VARCHAR(64) NOT NULL UNIQUE
+,created_on         TIMESTAMP   WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+,expires_on         TIMESTAMP   WITH TIME ZONE NOT NULL
+,revoked_on         TIMESTAMP   WITH TIME ZONE
+);
@@ -0,0 +1,8 @@
+CREATE TABLE password_resets (
+ password_reset_id SERIAL      PRIMARY KEY
+,user_id           INTEGER     NOT NULL REFERENCES users ON DELETE CASCADE
+,token_hash        VARCHAR(64) NOT NULL UNIQUE
+,created_on        TIMESTAMP   WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+,expires_on        TIMESTAMP   WITH TIME ZONE NOT NULL
+,used_on           TIMESTAMP   WITH TIME ZONE
+);
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN email_verified_on TIMESTAMP WITH TIME ZONE;
+
+-- accounts that existed before verification was required are trusted
+UPDATE users SET email_verified_on = created_on;
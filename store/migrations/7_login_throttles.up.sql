@@ -0,0 +1,6 @@
+CREATE TABLE login_throttles (
+ throttle_key    VARCHAR(255) PRIMARY KEY
+,failed_attempts INTEGER      NOT NULL DEFAULT 0
+,locked_until    TIMESTAMP    WITH TIME ZONE
+,updated_on      TIMESTAMP    WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,12 @@
+CREATE TABLE api_keys (
+ api_key_id   SERIAL       PRIMARY KEY
+,gym_id       INTEGER      NOT NULL REFERENCES gyms ON DELETE CASCADE
+,key_name     VARCHAR(100) NOT NULL DEFAULT ''
+,key_prefix   VARCHAR(16)  NOT NULL
+,key_hash     VARCHAR(64)  NOT NULL UNIQUE
+,scopes       TEXT[]       NOT NULL DEFAULT '{}'
+,created_on   TIMESTAMP    WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+,expires_on   TIMESTAMP    WITH TIME ZONE
+,revoked_on   TIMESTAMP    WITH TIME ZONE
+,last_used_on TIMESTAMP    WITH TIME ZONE
+);
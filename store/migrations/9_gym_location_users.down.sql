@@ -0,0 +1 @@
+ALTER TABLE gym_locations DROP COLUMN user_id;
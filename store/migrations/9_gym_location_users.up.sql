@@ -0,0 +1,2 @@
+-- the location role account that manages a gym location
+ALTER TABLE gym_locations ADD COLUMN user_id INTEGER REFERENCES users ON DELETE SET NULL;
@@ -0,0 +1,764 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+// NewPostgres returns a Store whose repositories run the datastore queries
+// on db. Each query is cancelled after queryTimeout, or only when its
+// context is if queryTimeout is 0.
+func NewPostgres(db datastore.DB, queryTimeout time.Duration) *Store {
+	p := postgres{db: db, queryTimeout: queryTimeout}
+
+	return &Store{
+		Transactor: p,
+
+		Addresses:       postgresAddresses{p},
+		APIKeys:         postgresAPIKeys{p},
+		BusinessHours:   postgresBusinessHours{p},
+		Features:        postgresFeatures{p},
+		GymFeatures:     postgresGymFeatures{p},
+		GymLocations:    postgresGymLocations{p},
+		Gyms:            postgresGyms{p},
+		IdempotencyKeys: postgresIdempotencyKeys{p},
+		Images:          postgresImages{p},
+		LoginThrottles:  postgresLoginThrottles{p},
+		Members:         postgresMembers{p},
+		Memberships:     postgresMemberships{p},
+		PasswordResets:  postgresPasswordResets{p},
+		Sessions:        postgresSessions{p},
+		Statuses:        postgresStatuses{p},
+		TwoFactors:      postgresTwoFactors{p},
+		Users:           postgresUsers{p},
+		Visits:          postgresVisits{p},
+	}
+}
+
+type postgres struct {
+	db           datastore.DB
+	queryTimeout time.Duration
+}
+
+// Transaction runs fn in a transaction on the database, or in the one p's
+// queries already run in.
+func (p postgres) Transaction(ctx context.Context, fn func(tx *Store) error) error {
+	db, ok := p.db.(*sql.DB)
+	if !ok {
+		return fn(NewPostgres(p.db, p.queryTimeout))
+	}
+
+	return datastore.Transaction(ctx, db, func(tx datastore.DB) error {
+		return fn(NewPostgres(tx, p.queryTimeout))
+	})
+}
+
+func (p postgres) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, p.queryTimeout)
+}
+
+type postgresAddresses struct {
+	postgres
+}
+
+func (r postgresAddresses) List(ctx context.Context, q datastore.Query) ([]models.Address, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetAddressList(ctx, r.db, q)
+}
+
+func (r postgresAddresses) Create(ctx context.Context, address models.Address) (*models.Address, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.CreateAddress(ctx, r.db, address)
+}
+
+type postgresAPIKeys struct {
+	postgres
+}
+
+func (r postgresAPIKeys) List(ctx context.Context, q datastore.Query) ([]models.APIKey, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetAPIKeyList(ctx, r.db, q)
+}
+
+func (r postgresAPIKeys) Count(ctx context.Context, q datastore.Query) (*int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetAPIKeyCount(ctx, r.db, q)
+}
+
+func (r postgresAPIKeys) Get(ctx context.Context, apiKeyID int64) (*models.APIKey, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetAPIKey(ctx, r.db, apiKeyID)
+}
+
+func (r postgresAPIKeys) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetAPIKeyByHash(ctx, r.db, keyHash)
+}
+
+func (r postgresAPIKeys) Create(ctx context.Context, apiKey models.APIKey) (*models.APIKey, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.CreateAPIKey(ctx, r.db, apiKey)
+}
+
+func (r postgresAPIKeys) Revoke(ctx context.Context, apiKeyID int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.RevokeAPIKey(ctx, r.db, apiKeyID)
+}
+
+func (r postgresAPIKeys) Touch(ctx context.Context, apiKeyID int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.TouchAPIKey(ctx, r.db, apiKeyID)
+}
+
+func (r postgresAPIKeys) Delete(ctx context.Context, apiKeyID int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.DeleteAPIKey(ctx, r.db, apiKeyID)
+}
+
+type postgresBusinessHours struct {
+	postgres
+}
+
+func (r postgresBusinessHours) List(ctx context.Context, q datastore.Query) ([]models.BusinessHour, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetBusinessHourList(ctx, r.db, q)
+}
+
+type postgresFeatures struct {
+	postgres
+}
+
+func (r postgresFeatures) List(ctx context.Context, q datastore.Query) ([]models.Feature, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetFeatureList(ctx, r.db, q)
+}
+
+type postgresGymFeatures struct {
+	postgres
+}
+
+func (r postgresGymFeatures) List(ctx context.Context, q datastore.Query) ([]models.GymFeature, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetGymFeatureList(ctx, r.db, q)
+}
+
+type postgresGymLocations struct {
+	postgres
+}
+
+func (r postgresGymLocations) List(ctx context.Context, scope datastore.Scope, q datastore.Query) ([]models.GymLocation, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetGymLocationList(ctx, r.db, scope, q)
+}
+
+func (r postgresGymLocations) Count(ctx context.Context, scope datastore.Scope, q datastore.Query) (*int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetGymLocationCount(ctx, r.db, scope, q)
+}
+
+func (r postgresGymLocations) Get(ctx context.Context, scope datastore.Scope, gymLocationID int64) (*models.GymLocation, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetGymLocation(ctx, r.db, scope, gymLocationID)
+}
+
+func (r postgresGymLocations) Create(ctx context.Context, gymLocation models.GymLocation) (*models.GymLocation, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.CreateGymLocation(ctx, r.db, gymLocation)
+}
+
+func (r postgresGymLocations) Update(ctx context.Context, gymLocationID int64, gymLocation models.GymLocation) (*models.GymLocation, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.UpdateGymLocation(ctx, r.db, gymLocationID, gymLocation)
+}
+
+func (r postgresGymLocations) Patch(ctx context.Context, gymLocationID int64, gymLocation models.GymLocation, fields []string) (*models.GymLocation, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.PatchGymLocation(ctx, r.db, gymLocationID, gymLocation, fields)
+}
+
+func (r postgresGymLocations) Delete(ctx context.Context, gymLocationID int64, version int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.DeleteGymLocation(ctx, r.db, gymLocationID, version)
+}
+
+func (r postgresGymLocations) Restore(ctx context.Context, gymLocationID int64) (*models.GymLocation, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.RestoreGymLocation(ctx, r.db, gymLocationID)
+}
+
+type postgresGyms struct {
+	postgres
+}
+
+func (r postgresGyms) List(ctx context.Context, q datastore.Query) ([]models.Gym, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetGymList(ctx, r.db, q)
+}
+
+func (r postgresGyms) Get(ctx context.Context, gymID int64) (*models.Gym, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetGym(ctx, r.db, gymID)
+}
+
+func (r postgresGyms) Delete(ctx context.Context, gymID int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.DeleteGym(ctx, r.db, gymID)
+}
+
+func (r postgresGyms) Restore(ctx context.Context, gymID int64) (*models.Gym, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.RestoreGym(ctx, r.db, gymID)
+}
+
+type postgresIdempotencyKeys struct {
+	postgres
+}
+
+func (r postgresIdempotencyKeys) Get(ctx context.Context, owner string, key string) (*models.IdempotencyKey, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetIdempotencyKey(ctx, r.db, owner, key)
+}
+
+func (r postgresIdempotencyKeys) Claim(ctx context.Context, key models.IdempotencyKey, window time.Duration) (*models.IdempotencyKey, bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.ClaimIdempotencyKey(ctx, r.db, key, window)
+}
+
+func (r postgresIdempotencyKeys) SaveResponse(ctx context.Context, key models.IdempotencyKey) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.SaveIdempotentResponse(ctx, r.db, key)
+}
+
+func (r postgresIdempotencyKeys) Delete(ctx context.Context, owner string, key string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.DeleteIdempotencyKey(ctx, r.db, owner, key)
+}
+
+type postgresImages struct {
+	postgres
+}
+
+func (r postgresImages) List(ctx context.Context, q datastore.Query) ([]models.Image, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetImageList(ctx, r.db, q)
+}
+
+type postgresLoginThrottles struct {
+	postgres
+}
+
+func (r postgresLoginThrottles) Get(ctx context.Context, throttleKey string) (*models.LoginThrottle, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetLoginThrottle(ctx, r.db, throttleKey)
+}
+
+func (r postgresLoginThrottles) RecordFailure(ctx context.Context, throttleKey string, window time.Duration) (*models.LoginThrottle, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.RecordLoginFailure(ctx, r.db, throttleKey, window)
+}
+
+func (r postgresLoginThrottles) Lock(ctx context.Context, throttleKey string, lockFor time.Duration) (*models.LoginThrottle, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.LockLogin(ctx, r.db, throttleKey, lockFor)
+}
+
+func (r postgresLoginThrottles) Delete(ctx context.Context, throttleKey string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.DeleteLoginThrottle(ctx, r.db, throttleKey)
+}
+
+type postgresMembers struct {
+	postgres
+}
+
+func (r postgresMembers) List(ctx context.Context, scope datastore.Scope, q datastore.Query) ([]models.Member, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetMemberList(ctx, r.db, scope, q)
+}
+
+func (r postgresMembers) Count(ctx context.Context, scope datastore.Scope, q datastore.Query) (*int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetMemberCount(ctx, r.db, scope, q)
+}
+
+func (r postgresMembers) Get(ctx context.Context, scope datastore.Scope, memberID int64) (*models.Member, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetMember(ctx, r.db, scope, memberID)
+}
+
+func (r postgresMembers) GetByEmail(ctx context.Context, scope datastore.Scope, email string) (*models.Member, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetMemberByEmail(ctx, r.db, scope, email)
+}
+
+func (r postgresMembers) GetByUserID(ctx context.Context, userID int64) (*models.Member, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetMemberByUserID(ctx, r.db, userID)
+}
+
+func (r postgresMembers) Create(ctx context.Context, member models.Member) (*models.Member, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.CreateMember(ctx, r.db, member)
+}
+
+func (r postgresMembers) Update(ctx context.Context, memberID int64, member models.Member) (*models.Member, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.UpdateMember(ctx, r.db, memberID, member)
+}
+
+func (r postgresMembers) Patch(ctx context.Context, memberID int64, member models.Member, fields []string) (*models.Member, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.PatchMember(ctx, r.db, memberID, member, fields)
+}
+
+func (r postgresMembers) Delete(ctx context.Context, memberID int64, version int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.DeleteMember(ctx, r.db, memberID, version)
+}
+
+func (r postgresMembers) Restore(ctx context.Context, memberID int64) (*models.Member, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.RestoreMember(ctx, r.db, memberID)
+}
+
+type postgresMemberships struct {
+	postgres
+}
+
+func (r postgresMemberships) Create(ctx context.Context, membership models.Membership) (*models.Membership, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.CreateMembership(ctx, r.db, membership)
+}
+
+type postgresPasswordResets struct {
+	postgres
+}
+
+func (r postgresPasswordResets) Create(ctx context.Context, passwordReset models.PasswordReset) (*models.PasswordReset, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.CreatePasswordReset(ctx, r.db, passwordReset)
+}
+
+func (r postgresPasswordResets) Consume(ctx context.Context, tokenHash string) (*models.PasswordReset, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.ConsumePasswordReset(ctx, r.db, tokenHash)
+}
+
+type postgresSessions struct {
+	postgres
+}
+
+func (r postgresSessions) Get(ctx context.Context, sessionID int64) (*models.Session, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetSession(ctx, r.db, sessionID)
+}
+
+func (r postgresSessions) GetByTokenHash(ctx context.Context, refreshTokenHash string) (*models.Session, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetSessionByTokenHash(ctx, r.db, refreshTokenHash)
+}
+
+func (r postgresSessions) Create(ctx context.Context, session models.Session) (*models.Session, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.CreateSession(ctx, r.db, session)
+}
+
+func (r postgresSessions) Update(ctx context.Context, sessionID int64, session models.Session) (*models.Session, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.UpdateSession(ctx, r.db, sessionID, session)
+}
+
+func (r postgresSessions) Rotate(ctx context.Context, sessionID int64, refreshTokenHash string, session models.Session) (*models.Session, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.RotateSession(ctx, r.db, sessionID, refreshTokenHash, session)
+}
+
+func (r postgresSessions) Revoke(ctx context.Context, sessionID int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.RevokeSession(ctx, r.db, sessionID)
+}
+
+func (r postgresSessions) RevokeUser(ctx context.Context, userID int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.RevokeUserSessions(ctx, r.db, userID)
+}
+
+func (r postgresSessions) RevokeOthers(ctx context.Context, userID int64, sessionID int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.RevokeOtherUserSessions(ctx, r.db, userID, sessionID)
+}
+
+type postgresStatuses struct {
+	postgres
+}
+
+func (r postgresStatuses) List(ctx context.Context, q datastore.Query) ([]models.Status, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetStatusList(ctx, r.db, q)
+}
+
+func (r postgresStatuses) Count(ctx context.Context, q datastore.Query) (*int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetStatusCount(ctx, r.db, q)
+}
+
+func (r postgresStatuses) Get(ctx context.Context, statusID int64) (*models.Status, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetStatus(ctx, r.db, statusID)
+}
+
+func (r postgresStatuses) Create(ctx context.Context, status models.Status) (*models.Status, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.CreateStatus(ctx, r.db, status)
+}
+
+func (r postgresStatuses) Update(ctx context.Context, statusID int64, status models.Status) (*models.Status, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.UpdateStatus(ctx, r.db, statusID, status)
+}
+
+func (r postgresStatuses) Patch(ctx context.Context, statusID int64, status models.Status, fields []string) (*models.Status, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.PatchStatus(ctx, r.db, statusID, status, fields)
+}
+
+func (r postgresStatuses) Delete(ctx context.Context, statusID int64, version int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.DeleteStatus(ctx, r.db, statusID, version)
+}
+
+type postgresTwoFactors struct {
+	postgres
+}
+
+func (r postgresTwoFactors) Get(ctx context.Context, userID int64) (*models.TwoFactor, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetTwoFactor(ctx, r.db, userID)
+}
+
+func (r postgresTwoFactors) Save(ctx context.Context, twoFactor models.TwoFactor) (*models.TwoFactor, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.SaveTwoFactor(ctx, r.db, twoFactor)
+}
+
+func (r postgresTwoFactors) Enable(ctx context.Context, userID int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.EnableTwoFactor(ctx, r.db, userID)
+}
+
+func (r postgresTwoFactors) UseStep(ctx context.Context, userID int64, step int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.UseTwoFactorStep(ctx, r.db, userID, step)
+}
+
+func (r postgresTwoFactors) Delete(ctx context.Context, userID int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.DeleteTwoFactor(ctx, r.db, userID)
+}
+
+func (r postgresTwoFactors) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.ReplaceRecoveryCodes(ctx, r.db, userID, codeHashes)
+}
+
+func (r postgresTwoFactors) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.UseRecoveryCode(ctx, r.db, userID, codeHash)
+}
+
+type postgresUsers struct {
+	postgres
+}
+
+func (r postgresUsers) List(ctx context.Context, scope datastore.Scope, q datastore.Query) ([]models.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetUserList(ctx, r.db, scope, q)
+}
+
+func (r postgresUsers) Count(ctx context.Context, scope datastore.Scope, q datastore.Query) (*int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetUserCount(ctx, r.db, scope, q)
+}
+
+func (r postgresUsers) Get(ctx context.Context, userID int64) (*models.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetUser(ctx, r.db, userID)
+}
+
+func (r postgresUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetUserByEmail(ctx, r.db, email)
+}
+
+func (r postgresUsers) Roles(ctx context.Context, userID int64) ([]*models.Role, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetUserRoles(ctx, r.db, userID)
+}
+
+func (r postgresUsers) Create(ctx context.Context, user models.User) (*models.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.CreateUser(ctx, r.db, user)
+}
+
+func (r postgresUsers) AddRole(ctx context.Context, userID int64, roleName string) (*models.Role, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	role, err := datastore.GetRoleByName(ctx, r.db, roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = datastore.CreateUserRole(ctx, r.db, models.UserRole{UserID: userID, RoleID: role.RoleID})
+	if err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func (r postgresUsers) Update(ctx context.Context, userID int64, user models.User) (*models.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.UpdateUser(ctx, r.db, userID, user)
+}
+
+func (r postgresUsers) Patch(ctx context.Context, userID int64, user models.User, fields []string) (*models.User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.PatchUser(ctx, r.db, userID, user, fields)
+}
+
+func (r postgresUsers) UpdatePassword(ctx context.Context, userID int64, password string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.UpdateUserPassword(ctx, r.db, userID, password)
+}
+
+func (r postgresUsers) VerifyEmail(ctx context.Context, userID int64, email string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.VerifyUserEmail(ctx, r.db, userID, email)
+}
+
+func (r postgresUsers) Delete(ctx context.Context, userID int64, version int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.DeleteUser(ctx, r.db, userID, version)
+}
+
+type postgresVisits struct {
+	postgres
+}
+
+func (r postgresVisits) List(ctx context.Context, scope datastore.Scope, q datastore.Query) ([]models.Visit, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetVisitList(ctx, r.db, scope, q)
+}
+
+func (r postgresVisits) Count(ctx context.Context, scope datastore.Scope, q datastore.Query) (*int, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetVisitCount(ctx, r.db, scope, q)
+}
+
+func (r postgresVisits) Get(ctx context.Context, scope datastore.Scope, visitID int64) (*models.Visit, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.GetVisit(ctx, r.db, scope, visitID)
+}
+
+func (r postgresVisits) Create(ctx context.Context, visit models.Visit) (*models.Visit, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.CreateVisit(ctx, r.db, visit)
+}
+
+func (r postgresVisits) Update(ctx context.Context, visitID int64, visit models.Visit) (*models.Visit, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.UpdateVisit(ctx, r.db, visitID, visit)
+}
+
+func (r postgresVisits) Patch(ctx context.Context, visitID int64, visit models.Visit, fields []string) (*models.Visit, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.PatchVisit(ctx, r.db, visitID, visit, fields)
+}
+
+func (r postgresVisits) Delete(ctx context.Context, visitID int64, version int64) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return datastore.DeleteVisit(ctx, r.db, visitID, version)
+}
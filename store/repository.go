@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/models"
+	"github.com/lukashambsch/anygym.api/store/datastore"
+)
+
+// Store holds the repositories the API reads and writes through. NewPostgres
+// builds one on the database, the memory package one for tests.
+type Store struct {
+	Transactor
+
+	Addresses       AddressRepository
+	APIKeys         APIKeyRepository
+	BusinessHours   BusinessHourRepository
+	Features        FeatureRepository
+	GymFeatures     GymFeatureRepository
+	GymLocations    GymLocationRepository
+	Gyms            GymRepository
+	IdempotencyKeys IdempotencyKeyRepository
+	Images          ImageRepository
+	LoginThrottles  LoginThrottleRepository
+	Members         MemberRepository
+	Memberships     MembershipRepository
+	PasswordResets  PasswordResetRepository
+	Sessions        SessionRepository
+	Statuses        StatusRepository
+	TwoFactors      TwoFactorRepository
+	Users           UserRepository
+	Visits          VisitRepository
+}
+
+// Transactor runs several repository calls atomically.
+type Transactor interface {
+	// Transaction calls fn with a Store whose repositories all work in one
+	// transaction. It's committed if fn returns nil and rolled back if it
+	// returns an error. Transactions started on tx join the one fn runs in.
+	Transaction(ctx context.Context, fn func(tx *Store) error) error
+}
+
+// Lookups that find nothing return sql.ErrNoRows, the same as the datastore
+// functions do.
+//
+// Updates and deletes of versioned rows only go ahead if the row is at the
+// version given, in the model for updates, or any version if that's 0. They
+// return datastore.ErrVersionMismatch if it isn't. Patches only change the
+// fields listed, by their json names, and leave the rest as they are.
+
+type AddressRepository interface {
+	List(ctx context.Context, q datastore.Query) ([]models.Address, error)
+	Create(ctx context.Context, address models.Address) (*models.Address, error)
+}
+
+type APIKeyRepository interface {
+	List(ctx context.Context, q datastore.Query) ([]models.APIKey, error)
+	Count(ctx context.Context, q datastore.Query) (*int, error)
+	Get(ctx context.Context, apiKeyID int64) (*models.APIKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	Create(ctx context.Context, apiKey models.APIKey) (*models.APIKey, error)
+	Revoke(ctx context.Context, apiKeyID int64) error
+	Touch(ctx context.Context, apiKeyID int64) error
+	Delete(ctx context.Context, apiKeyID int64) error
+}
+
+type BusinessHourRepository interface {
+	List(ctx context.Context, q datastore.Query) ([]models.BusinessHour, error)
+}
+
+type FeatureRepository interface {
+	List(ctx context.Context, q datastore.Query) ([]models.Feature, error)
+}
+
+type GymFeatureRepository interface {
+	List(ctx context.Context, q datastore.Query) ([]models.GymFeature, error)
+}
+
+type GymLocationRepository interface {
+	List(ctx context.Context, scope datastore.Scope, q datastore.Query) ([]models.GymLocation, error)
+	Count(ctx context.Context, scope datastore.Scope, q datastore.Query) (*int, error)
+	Get(ctx context.Context, scope datastore.Scope, gymLocationID int64) (*models.GymLocation, error)
+	Create(ctx context.Context, gymLocation models.GymLocation) (*models.GymLocation, error)
+	Update(ctx context.Context, gymLocationID int64, gymLocation models.GymLocation) (*models.GymLocation, error)
+	Patch(ctx context.Context, gymLocationID int64, gymLocation models.GymLocation, fields []string) (*models.GymLocation, error)
+	// Delete only marks the gym location deleted, Restore brings it back.
+	Delete(ctx context.Context, gymLocationID int64, version int64) error
+	Restore(ctx context.Context, gymLocationID int64) (*models.GymLocation, error)
+}
+
+type GymRepository interface {
+	List(ctx context.Context, q datastore.Query) ([]models.Gym, error)
+	Get(ctx context.Context, gymID int64) (*models.Gym, error)
+	// Delete only marks the gym deleted, Restore brings it back.
+	Delete(ctx context.Context, gymID int64) error
+	Restore(ctx context.Context, gymID int64) (*models.Gym, error)
+}
+
+type IdempotencyKeyRepository interface {
+	Get(ctx context.Context, owner string, key string) (*models.IdempotencyKey, error)
+	// Claim stores key for window unless its owner already has a key of that
+	// name that hasn't expired. It returns that one and false if so.
+	Claim(ctx context.Context, key models.IdempotencyKey, window time.Duration) (*models.IdempotencyKey, bool, error)
+	SaveResponse(ctx context.Context, key models.IdempotencyKey) error
+	Delete(ctx context.Context, owner string, key string) error
+}
+
+type ImageRepository interface {
+	List(ctx context.Context, q datastore.Query) ([]models.Image, error)
+}
+
+type LoginThrottleRepository interface {
+	Get(ctx context.Context, throttleKey string) (*models.LoginThrottle, error)
+	// RecordFailure counts a failed login, starting the count over if the
+	// last failure is older than window.
+	RecordFailure(ctx context.Context, throttleKey string, window time.Duration) (*models.LoginThrottle, error)
+	Lock(ctx context.Context, throttleKey string, lockFor time.Duration) (*models.LoginThrottle, error)
+	Delete(ctx context.Context, throttleKey string) error
+}
+
+type MemberRepository interface {
+	List(ctx context.Context, scope datastore.Scope, q datastore.Query) ([]models.Member, error)
+	Count(ctx context.Context, scope datastore.Scope, q datastore.Query) (*int, error)
+	Get(ctx context.Context, scope datastore.Scope, memberID int64) (*models.Member, error)
+	GetByEmail(ctx context.Context, scope datastore.Scope, email string) (*models.Member, error)
+	GetByUserID(ctx context.Context, userID int64) (*models.Member, error)
+	Create(ctx context.Context, member models.Member) (*models.Member, error)
+	Update(ctx context.Context, memberID int64, member models.Member) (*models.Member, error)
+	Patch(ctx context.Context, memberID int64, member models.Member, fields []string) (*models.Member, error)
+	// Delete only marks the member deleted, Restore brings them back.
+	Delete(ctx context.Context, memberID int64, version int64) error
+	Restore(ctx context.Context, memberID int64) (*models.Member, error)
+}
+
+type MembershipRepository interface {
+	Create(ctx context.Context, membership models.Membership) (*models.Membership, error)
+}
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, passwordReset models.PasswordReset) (*models.PasswordReset, error)
+	// Consume marks an unused, unexpired reset as used and returns it.
+	Consume(ctx context.Context, tokenHash string) (*models.PasswordReset, error)
+}
+
+type SessionRepository interface {
+	Get(ctx context.Context, sessionID int64) (*models.Session, error)
+	GetByTokenHash(ctx context.Context, refreshTokenHash string) (*models.Session, error)
+	Create(ctx context.Context, session models.Session) (*models.Session, error)
+	Update(ctx context.Context, sessionID int64, session models.Session) (*models.Session, error)
+	// Rotate sets the session's refresh token hash and expiry if it's open
+	// and its hash is still refreshTokenHash, and returns sql.ErrNoRows if
+	// not.
+	Rotate(ctx context.Context, sessionID int64, refreshTokenHash string, session models.Session) (*models.Session, error)
+	Revoke(ctx context.Context, sessionID int64) error
+	// RevokeUser revokes all of the user's sessions.
+	RevokeUser(ctx context.Context, userID int64) error
+	// RevokeOthers revokes all of the user's sessions but sessionID.
+	RevokeOthers(ctx context.Context, userID int64, sessionID int64) error
+}
+
+type StatusRepository interface {
+	List(ctx context.Context, q datastore.Query) ([]models.Status, error)
+	Count(ctx context.Context, q datastore.Query) (*int, error)
+	Get(ctx context.Context, statusID int64) (*models.Status, error)
+	Create(ctx context.Context, status models.Status) (*models.Status, error)
+	Update(ctx context.Context, statusID int64, status models.Status) (*models.Status, error)
+	Patch(ctx context.Context, statusID int64, status models.Status, fields []string) (*models.Status, error)
+	Delete(ctx context.Context, statusID int64, version int64) error
+}
+
+type TwoFactorRepository interface {
+	Get(ctx context.Context, userID int64) (*models.TwoFactor, error)
+	// Save starts a new enrolment for the user, replacing any earlier one.
+	Save(ctx context.Context, twoFactor models.TwoFactor) (*models.TwoFactor, error)
+	Enable(ctx context.Context, userID int64) error
+	// UseStep records that a code for step was accepted. It returns
+	// sql.ErrNoRows if a code for that step or a later one was already
+	// used.
+	UseStep(ctx context.Context, userID int64, step int64) error
+	Delete(ctx context.Context, userID int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error
+	// UseRecoveryCode returns sql.ErrNoRows if the user has no such unused
+	// code.
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error
+}
+
+type UserRepository interface {
+	List(ctx context.Context, scope datastore.Scope, q datastore.Query) ([]models.User, error)
+	Count(ctx context.Context, scope datastore.Scope, q datastore.Query) (*int, error)
+	Get(ctx context.Context, userID int64) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	Roles(ctx context.Context, userID int64) ([]*models.Role, error)
+	// Create hashes the user's Password.
+	Create(ctx context.Context, user models.User) (*models.User, error)
+	AddRole(ctx context.Context, userID int64, roleName string) (*models.Role, error)
+	// Update and Patch clear EmailVerifiedOn if the email changes.
+	Update(ctx context.Context, userID int64, user models.User) (*models.User, error)
+	Patch(ctx context.Context, userID int64, user models.User, fields []string) (*models.User, error)
+	UpdatePassword(ctx context.Context, userID int64, password string) error
+	// VerifyEmail returns sql.ErrNoRows if the user's email has changed.
+	VerifyEmail(ctx context.Context, userID int64, email string) error
+	Delete(ctx context.Context, userID int64, version int64) error
+}
+
+type VisitRepository interface {
+	List(ctx context.Context, scope datastore.Scope, q datastore.Query) ([]models.Visit, error)
+	Count(ctx context.Context, scope datastore.Scope, q datastore.Query) (*int, error)
+	Get(ctx context.Context, scope datastore.Scope, visitID int64) (*models.Visit, error)
+	Create(ctx context.Context, visit models.Visit) (*models.Visit, error)
+	Update(ctx context.Context, visitID int64, visit models.Visit) (*models.Visit, error)
+	Patch(ctx context.Context, visitID int64, visit models.Visit, fields []string) (*models.Visit, error)
+	Delete(ctx context.Context, visitID int64, version int64) error
+}
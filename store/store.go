@@ -3,29 +3,16 @@ package store
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"time"
 
 	_ "github.com/lib/pq"
 
     "github.com/lukashambsch/anygym.api/config"
 )
 
-var DB *sql.DB
-
-func init() {
-	var err error
-
-	DB, err = Open()
-	if err != nil {
-		log.Fatal(err)
-	}
-}
-
-func Open() (*sql.DB, error) {
-    var err error
-    var db *sql.DB
-
-	connectionInfo := fmt.Sprintf(
+// DataSourceName is the connection string for the configured database.
+func DataSourceName() string {
+	return fmt.Sprintf(
 		"user=%s dbname=%s password=%s host=%s port=%s sslmode=disable",
 		config.C.Get("datastore.user"),
 		config.C.Get("datastore.database"),
@@ -33,8 +20,19 @@ func Open() (*sql.DB, error) {
 		config.C.Get("datastore.host"),
 		config.C.Get("datastore.port"),
 	)
+}
+
+// QueryTimeout is how long a single query may run before it's cancelled, 0
+// if it isn't configured.
+func QueryTimeout() time.Duration {
+	return config.C.GetDuration("datastore.query_timeout")
+}
+
+func Open() (*sql.DB, error) {
+    var err error
+    var db *sql.DB
 
-    db, err = sql.Open("postgres", connectionInfo)
+    db, err = sql.Open("postgres", DataSourceName())
 	if err != nil {
 		return db, err
 	}
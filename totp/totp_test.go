@@ -0,0 +1,87 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"github.com/lukashambsch/anygym.api/totp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TOTP", func() {
+	// the SHA1 seed from RFC 6238 appendix B
+	var secret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	Describe("Code", func() {
+		It("should match the RFC 6238 test vectors", func() {
+			vectors := map[int64]string{
+				59:          "287082",
+				1111111109:  "081804",
+				1111111111:  "050471",
+				1234567890:  "005924",
+				2000000000:  "279037",
+				20000000000: "353130",
+			}
+
+			for unix, expected := range vectors {
+				code, err := totp.Code(secret, time.Unix(unix, 0))
+				Expect(err).To(BeNil())
+				Expect(code).To(Equal(expected))
+			}
+		})
+
+		It("should return an error for an invalid secret", func() {
+			_, err := totp.Code("not base32!", time.Now())
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Describe("Validate", func() {
+		var now = time.Unix(1111111111, 0)
+
+		It("should accept the current code", func() {
+			step, ok := totp.Validate(secret, "050471", now)
+			Expect(ok).To(BeTrue())
+			Expect(step).To(Equal(totp.Step(now)))
+		})
+
+		It("should accept the previous code", func() {
+			code, _ := totp.Code(secret, now.Add(-totp.Period))
+			_, ok := totp.Validate(secret, code, now)
+			Expect(ok).To(BeTrue())
+		})
+
+		It("should reject codes outside the skew", func() {
+			code, _ := totp.Code(secret, now.Add(-3*totp.Period))
+			_, ok := totp.Validate(secret, code, now)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should reject malformed codes", func() {
+			_, ok := totp.Validate(secret, "12345", now)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("GenerateSecret", func() {
+		It("should return a usable secret", func() {
+			generated, err := totp.GenerateSecret()
+			Expect(err).To(BeNil())
+			Expect(len(generated)).To(Equal(32))
+
+			_, err = totp.Code(generated, time.Now())
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Describe("URI", func() {
+		It("should build an otpauth uri", func() {
+			uri := totp.URI("AnyGym", "lukas@email.com", "ABCDEF")
+			Expect(uri).To(HavePrefix("otpauth://totp/AnyGym:lukas@email.com?"))
+			Expect(strings.Contains(uri, "secret=ABCDEF")).To(BeTrue())
+			Expect(strings.Contains(uri, "issuer=AnyGym")).To(BeTrue())
+		})
+	})
+})